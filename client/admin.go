@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type Tariffs struct {
+	Rates    map[string]float32 `json:"rates"`
+	Prices   map[string]float32 `json:"prices"`
+	Currency string             `json:"currency"`
+}
+
+// APIKey is a newly created key, the key itself is only returned once.
+type APIKey struct {
+	Id  string `json:"id"`
+	Key string `json:"key"`
+}
+
+type createKeyRequest struct {
+	ClientId string `json:"clientId"`
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// GetTariffs needs the operator or admin role.
+func (c *Client) GetTariffs(ctx context.Context) (*Tariffs, error) {
+	var res Tariffs
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tariffs", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CreateAPIKey needs the admin role.
+func (c *Client) CreateAPIKey(ctx context.Context, clientId string) (*APIKey, error) {
+	var res APIKey
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/apikeys", createKeyRequest{ClientId: clientId}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// RevokeAPIKey needs the admin role.
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/admin/apikeys/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *Client) Health(ctx context.Context) (string, error) {
+	var res healthResponse
+	if err := c.do(ctx, http.MethodGet, "/health", nil, &res); err != nil {
+		return "", err
+	}
+	return res.Status, nil
+}
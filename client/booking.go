@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	v1ShippingPath = "/api/v1/shipping"
+	v2ShippingPath = "/api/v2/shipping"
+)
+
+type Status string
+
+const (
+	StatusBooked         Status = "booked"
+	StatusPickedUp       Status = "picked_up"
+	StatusInTransit      Status = "in_transit"
+	StatusOutForDelivery Status = "out_for_delivery"
+	StatusDelivered      Status = "delivered"
+	StatusException      Status = "exception"
+	StatusReturned       Status = "returned"
+	StatusCancelling     Status = "cancelling"
+	StatusCancelled      Status = "cancelled"
+)
+
+// Policy picks the carrier option a booking ships with.
+type Policy string
+
+const (
+	PolicyCheapest Policy = "cheapest"
+	PolicyFastest  Policy = "fastest"
+)
+
+// ServiceLevel trades the price of a shipment for how fast it is delivered.
+type ServiceLevel string
+
+const (
+	ServiceLevelEconomy  ServiceLevel = "economy"
+	ServiceLevelStandard ServiceLevel = "standard"
+	ServiceLevelExpress  ServiceLevel = "express"
+)
+
+// Booking is a booking as returned by /api/v1, with a single weight and the
+// price in the major unit of Currency.
+type Booking struct {
+	Id          string  `json:"id"`
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Weight      float32 `json:"weight"`
+	Price       float32 `json:"price"`
+	Currency    string  `json:"currency"`
+	Status      Status  `json:"status"`
+	// Carrier and CarrierReference are empty for bookings made before
+	// carriers were integrated.
+	Carrier          string       `json:"carrier"`
+	CarrierReference string       `json:"carrierReference"`
+	ServiceLevel     ServiceLevel `json:"serviceLevel"`
+	// TransitDays, EstimatedPickup and EstimatedDelivery, dates as
+	// YYYY-MM-DD, are empty for bookings made before they were estimated.
+	TransitDays       int    `json:"transitDays"`
+	EstimatedPickup   string `json:"estimatedPickup"`
+	EstimatedDelivery string `json:"estimatedDelivery"`
+}
+
+// BookingPage is a page of bookings, NextOffset is nil on the last page.
+type BookingPage struct {
+	Bookings   []Booking `json:"bookings"`
+	NextOffset *int      `json:"nextOffset"`
+}
+
+// Quote is the price of shipping a parcel, the base price of its weight
+// class multiplied by the rate of the region and the multiplier of the
+// service level.
+type Quote struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Weight      float32 `json:"weight"`
+	Region      string  `json:"region"`
+	WeightClass string  `json:"weightClass"`
+	Rate        float32 `json:"rate"`
+	BasePrice   float32 `json:"basePrice"`
+	Price       float32 `json:"price"`
+	Currency    string  `json:"currency"`
+
+	ServiceLevel ServiceLevel `json:"serviceLevel"`
+	Multiplier   float32      `json:"multiplier"`
+	TransitDays  int          `json:"transitDays"`
+	// EstimatedPickup and EstimatedDelivery are dates as YYYY-MM-DD.
+	EstimatedPickup   string `json:"estimatedPickup"`
+	EstimatedDelivery string `json:"estimatedDelivery"`
+	// Options are the rates of the carriers serving the lane, best first.
+	Options []Option `json:"options"`
+}
+
+// Option is the rate of a carrier at one of its service levels, its Id
+// books it through BookingRequestV2.
+type Option struct {
+	Id           string  `json:"id"`
+	Carrier      string  `json:"carrier"`
+	ServiceLevel string  `json:"serviceLevel"`
+	Price        float32 `json:"price"`
+	Currency     string  `json:"currency"`
+	TransitDays  int     `json:"transitDays"`
+}
+
+type Address struct {
+	Name       string `json:"name,omitempty"`
+	Street     string `json:"street,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	City       string `json:"city,omitempty"`
+	Country    string `json:"country"`
+}
+
+// Parcel has its weight in kg and dimensions in cm.
+type Parcel struct {
+	Weight float32 `json:"weight"`
+	Length float32 `json:"length,omitempty"`
+	Width  float32 `json:"width,omitempty"`
+	Height float32 `json:"height,omitempty"`
+}
+
+// Money has a decimal amount in the major unit of the currency.
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type BookingRequestV2 struct {
+	Origin      Address  `json:"origin"`
+	Destination Address  `json:"destination"`
+	Parcels     []Parcel `json:"parcels"`
+	// Option or Policy pick the carrier option to ship with, without either
+	// the preferred carrier of the lane ships at the tariff price of
+	// ServiceLevel, standard if empty.
+	Option       string       `json:"option,omitempty"`
+	Policy       Policy       `json:"policy,omitempty"`
+	ServiceLevel ServiceLevel `json:"serviceLevel,omitempty"`
+}
+
+// BookingV2 is a booking as returned by /api/v2.
+type BookingV2 struct {
+	Id               string   `json:"id"`
+	Origin           Address  `json:"origin"`
+	Destination      Address  `json:"destination"`
+	Parcels          []Parcel `json:"parcels"`
+	Weight           float32  `json:"weight"`
+	Price            Money    `json:"price"`
+	Status           Status   `json:"status"`
+	Carrier          string   `json:"carrier"`
+	CarrierReference string   `json:"carrierReference"`
+
+	ServiceLevel      ServiceLevel `json:"serviceLevel"`
+	TransitDays       int          `json:"transitDays"`
+	EstimatedPickup   string       `json:"estimatedPickup"`
+	EstimatedDelivery string       `json:"estimatedDelivery"`
+}
+
+type bookShippingRequest struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Weight      float32 `json:"weight"`
+}
+
+type bookShippingResponse struct {
+	Id string `json:"id"`
+}
+
+// BookShipping books shipping between two locations and returns the id of
+// the booking.
+func (c *Client) BookShipping(ctx context.Context, origin, destination string, weight float32) (string, error) {
+	var res bookShippingResponse
+	req := bookShippingRequest{Origin: origin, Destination: destination, Weight: weight}
+	if err := c.do(ctx, http.MethodPost, v1ShippingPath, req, &res); err != nil {
+		return "", err
+	}
+	return res.Id, nil
+}
+
+func (c *Client) GetBooking(ctx context.Context, id string) (*Booking, error) {
+	var res Booking
+	if err := c.do(ctx, http.MethodGet, v1ShippingPath+"/"+url.PathEscape(id), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListBookings returns a page of the bookings of the client, oldest first. A
+// zero limit uses the page size of the server.
+func (c *Client) ListBookings(ctx context.Context, offset, limit int) (*BookingPage, error) {
+	query := url.Values{}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := v1ShippingPath
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var res BookingPage
+	if err := c.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// CancelBooking cancels a booking, bookings that are no longer booked fail
+// with ErrConflict.
+func (c *Client) CancelBooking(ctx context.Context, id string) (*Booking, error) {
+	var res Booking
+	if err := c.do(ctx, http.MethodPost, v1ShippingPath+"/"+url.PathEscape(id)+"/cancel", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Quote returns the price of shipping a parcel without booking it.
+func (c *Client) Quote(ctx context.Context, origin, destination string, weight float32) (*Quote, error) {
+	query := url.Values{}
+	query.Set("origin", origin)
+	query.Set("destination", destination)
+	query.Set("weight", strconv.FormatFloat(float64(weight), 'f', -1, 32))
+
+	var res Quote
+	if err := c.do(ctx, http.MethodGet, "/api/v1/quote?"+query.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// BookShippingV2 books shipping of one or more parcels between two addresses
+// and returns the id of the booking.
+func (c *Client) BookShippingV2(ctx context.Context, req BookingRequestV2) (string, error) {
+	var res bookShippingResponse
+	if err := c.do(ctx, http.MethodPost, v2ShippingPath, req, &res); err != nil {
+		return "", err
+	}
+	return res.Id, nil
+}
+
+func (c *Client) GetBookingV2(ctx context.Context, id string) (*BookingV2, error) {
+	var res BookingV2
+	if err := c.do(ctx, http.MethodGet, v2ShippingPath+"/"+url.PathEscape(id), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
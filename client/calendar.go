@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Calendar is the time zone, pickup cut-off and holidays of a location,
+// Default is set for locations without a calendar of their own.
+type Calendar struct {
+	Location string `json:"location"`
+	Default  bool   `json:"default"`
+	TimeZone string `json:"timeZone"`
+	// CutOff is a time of day as HH:MM.
+	CutOff   string    `json:"cutOff"`
+	Holidays []Holiday `json:"holidays"`
+}
+
+// Holiday is a date as YYYY-MM-DD, a yearly holiday recurs on its month and
+// day.
+type Holiday struct {
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+	Yearly bool   `json:"yearly"`
+}
+
+// PickupDate is the date, as YYYY-MM-DD, a parcel handed over at a location
+// At is picked up.
+type PickupDate struct {
+	Location string    `json:"location"`
+	At       time.Time `json:"at"`
+	Pickup   string    `json:"pickup"`
+	TimeZone string    `json:"timeZone"`
+	CutOff   string    `json:"cutOff"`
+}
+
+func (c *Client) GetCalendar(ctx context.Context, location string) (*Calendar, error) {
+	var res Calendar
+	if err := c.do(ctx, http.MethodGet, "/api/v1/calendars/"+url.PathEscape(location), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// NextPickup returns when a parcel handed over at the location at the time
+// is picked up.
+func (c *Client) NextPickup(ctx context.Context, location string, at time.Time) (*PickupDate, error) {
+	query := url.Values{}
+	query.Set("at", at.Format(time.RFC3339))
+
+	var res PickupDate
+	if err := c.do(ctx, http.MethodGet, "/api/v1/calendars/"+url.PathEscape(location)+"/pickup?"+query.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
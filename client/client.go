@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 5 * time.Second
+
+	apiKeyHeader        = "X-API-Key"
+	authorizationHeader = "Authorization"
+	userAgent           = "shipping-api-client"
+)
+
+type Config struct {
+	BaseUrl string
+	// APIKey is sent as X-API-Key, Token as a bearer token, a token takes
+	// precedence when both are set.
+	APIKey string
+	Token  string
+	// HTTPClient defaults to a new http.Client.
+	HTTPClient *http.Client
+	// Timeout limits every attempt of a request, zero means no limit beyond
+	// the context.
+	Timeout time.Duration
+	// MaxRetries defaults to DefaultMaxRetries, a negative value disables
+	// retries.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client calls the shipping api. Requests are retried with exponential
+// backoff on 429 and, for idempotent methods, on 5xx and transport errors.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+func New(config Config) *Client {
+	config.BaseUrl = strings.TrimSuffix(config.BaseUrl, "/")
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultMaxRetries
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = DefaultMaxBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	return &Client{config: config, httpClient: httpClient}
+}
+
+// do sends the request, retrying it when allowed, and decodes a successful
+// response into out. Failed responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		data, err := c.attempt(ctx, method, path, body)
+		if err == nil {
+			if out == nil || len(data) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("decoding response of %s %s: %w", method, path, err)
+			}
+			return nil
+		}
+
+		if attempt >= c.config.MaxRetries || !retryable(ctx, method, err) {
+			return err
+		}
+		if err := sleep(ctx, c.backoff(attempt, err)); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseUrl+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if c.config.Token != "" {
+		req.Header.Set(authorizationHeader, "Bearer "+c.config.Token)
+	} else if c.config.APIKey != "" {
+		req.Header.Set(apiKeyHeader, c.config.APIKey)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, newError(res, data)
+	}
+	return data, nil
+}
+
+// retryable reports whether a failed attempt may be repeated, only rate
+// limited requests are known not to have been handled so anything else is
+// only retried for idempotent methods.
+func retryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	apiError, ok := err.(*Error)
+	if ok && apiError.Status == http.StatusTooManyRequests {
+		return true
+	}
+	if !idempotent(method) {
+		return false
+	}
+	return !ok || apiError.Status >= http.StatusInternalServerError
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff doubles the delay for every attempt with jitter, a longer
+// Retry-After from the server is respected.
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	d := c.config.MaxBackoff
+	if attempt < 30 {
+		if exp := c.config.MinBackoff << uint(attempt); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+
+	if apiError, ok := err.(*Error); ok && apiError.RetryAfter > d {
+		d = apiError.RetryAfter
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
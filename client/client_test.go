@@ -0,0 +1,275 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type response struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// newTestServer answers with the responses in order and repeats the last one.
+func newTestServer(t *testing.T, responses ...response) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		res := responses[i]
+		for k, v := range res.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func newTestClient(url string) *Client {
+	return New(Config{BaseUrl: url, APIKey: "key", MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+}
+
+const notFound = `{"type":"urn:shipping-api:problem:booking_not_found","title":"Not Found","status":404,"detail":"booking id not found","code":"booking_not_found","requestId":"abc"}`
+
+func TestGetBooking(t *testing.T) {
+	testCases := []struct {
+		name          string
+		responses     []response
+		expectedCalls int32
+		expectedErr   error
+		expectedCode  Code
+	}{
+		{
+			name:          "ok",
+			responses:     []response{{status: http.StatusOK, body: `{"id":"id","origin":"SE","destination":"DK","weight":400,"price":3000,"currency":"SEK"}`}},
+			expectedCalls: 1,
+		},
+		{
+			name:          "not found",
+			responses:     []response{{status: http.StatusNotFound, body: notFound}},
+			expectedCalls: 1,
+			expectedErr:   ErrNotFound,
+			expectedCode:  CodeBookingNotFound,
+		},
+		{
+			name: "retried on server error",
+			responses: []response{
+				{status: http.StatusBadGateway, body: "bad gateway"},
+				{status: http.StatusOK, body: `{"id":"id","origin":"SE","destination":"DK","weight":400,"price":3000,"currency":"SEK"}`},
+			},
+			expectedCalls: 2,
+		},
+		{
+			name:          "gives up after retries",
+			responses:     []response{{status: http.StatusServiceUnavailable, body: "unavailable"}},
+			expectedCalls: DefaultMaxRetries + 1,
+			expectedErr:   ErrInternal,
+			expectedCode:  CodeInternal,
+		},
+		{
+			name: "retried when rate limited",
+			responses: []response{
+				{status: http.StatusTooManyRequests, body: `{"status":429,"code":"rate_limited"}`},
+				{status: http.StatusOK, body: `{"id":"id","origin":"SE","destination":"DK","weight":400,"price":3000,"currency":"SEK"}`},
+			},
+			expectedCalls: 2,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			server, calls := newTestServer(t, tc.responses...)
+
+			booking, err := newTestClient(server.URL).GetBooking(context.Background(), "id")
+			require.Equal(t, tc.expectedCalls, atomic.LoadInt32(calls))
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				require.Equal(t, tc.expectedCode, GetCode(err))
+				require.Nil(t, booking)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, &Booking{Id: "id", Origin: "SE", Destination: "DK", Weight: 400, Price: 3000, Currency: "SEK"}, booking)
+		})
+	}
+}
+
+func TestBookShipping(t *testing.T) {
+	testCases := []struct {
+		name          string
+		response      response
+		expectedCalls int32
+		expectedErr   error
+	}{
+		{
+			name:          "created",
+			response:      response{status: http.StatusCreated, body: `{"id":"id"}`},
+			expectedCalls: 1,
+		},
+		{
+			name:          "invalid request",
+			response:      response{status: http.StatusBadRequest, body: `{"status":400,"code":"validation_failed","errors":[{"field":"weight","code":"required","message":"weight is required"}]}`},
+			expectedCalls: 1,
+			expectedErr:   &Error{Code: CodeValidationFailed},
+		},
+		{
+			name:          "not retried on server error",
+			response:      response{status: http.StatusInternalServerError, body: `{"status":500,"code":"internal"}`},
+			expectedCalls: 1,
+			expectedErr:   ErrInternal,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			server, calls := newTestServer(t, tc.response)
+
+			id, err := newTestClient(server.URL).BookShipping(context.Background(), "SE", "DK", 400)
+			require.Equal(t, tc.expectedCalls, atomic.LoadInt32(calls))
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				require.Equal(t, "", id)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, "id", id)
+		})
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t, response{status: http.StatusBadRequest, body: `{"status":400,"code":"validation_failed","detail":"request body failed validation","errors":[{"field":"weight","code":"required","message":"weight is required"}],"requestId":"abc"}`})
+
+	_, err := newTestClient(server.URL).BookShipping(context.Background(), "SE", "DK", 0)
+
+	var apiError *Error
+	require.True(t, errors.As(err, &apiError))
+	require.Equal(t, http.StatusBadRequest, apiError.Status)
+	require.Equal(t, "abc", apiError.RequestId)
+	require.Equal(t, []FieldError{{Field: "weight", Code: FieldRequired, Message: "weight is required"}}, GetFields(err))
+	require.Equal(t, "400 validation_failed: request body failed validation", err.Error())
+	require.ErrorIs(t, err, ErrInput)
+	require.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestHeaders(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		config                Config
+		expectedAPIKey        string
+		expectedAuthorization string
+	}{
+		{
+			name:           "api key",
+			config:         Config{APIKey: "key"},
+			expectedAPIKey: "key",
+		},
+		{
+			name:                  "token",
+			config:                Config{Token: "token"},
+			expectedAuthorization: "Bearer token",
+		},
+		{
+			name:                  "token over api key",
+			config:                Config{APIKey: "key", Token: "token"},
+			expectedAuthorization: "Bearer token",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			headers := make(chan http.Header, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers <- r.Header
+				_ = json.NewEncoder(w).Encode(healthResponse{Status: "healthy"})
+			}))
+			defer server.Close()
+
+			tc.config.BaseUrl = server.URL + "/"
+			status, err := New(tc.config).Health(context.Background())
+			require.Nil(t, err)
+			require.Equal(t, "healthy", status)
+
+			header := <-headers
+			require.Equal(t, tc.expectedAPIKey, header.Get(apiKeyHeader))
+			require.Equal(t, tc.expectedAuthorization, header.Get(authorizationHeader))
+			require.Equal(t, userAgent, header.Get("User-Agent"))
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(healthResponse{Status: "healthy"})
+	}))
+	defer server.Close()
+
+	c := New(Config{BaseUrl: server.URL, Timeout: 50 * time.Millisecond, MinBackoff: time.Millisecond})
+	status, err := c.Health(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, "healthy", status)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestCanceled(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newTestServer(t, response{status: http.StatusServiceUnavailable, header: map[string]string{"Retry-After": "10"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newTestClient(server.URL).Health(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	c := New(Config{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	testCases := []struct {
+		attempt int
+		err     error
+		min     time.Duration
+		max     time.Duration
+	}{
+		{attempt: 0, err: ErrInternal, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, err: ErrInternal, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 10, err: ErrInternal, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 100, err: ErrInternal, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 0, err: &Error{Status: http.StatusTooManyRequests, RetryAfter: 3 * time.Second}, min: 3 * time.Second, max: 3 * time.Second},
+	}
+	for _, tc := range testCases {
+		d := c.backoff(tc.attempt, tc.err)
+		require.GreaterOrEqual(t, d, tc.min)
+		require.LessOrEqual(t, d, tc.max)
+	}
+}
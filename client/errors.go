@@ -0,0 +1,201 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Code identifies an error returned by the api, it matches the code of the
+// problem document. The codes mirror those of the server.
+type Code string
+
+const (
+	CodeUnknown          Code = "unknown"
+	CodeInternal         Code = "internal"
+	CodeInvalidRequest   Code = "invalid_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeNotFound         Code = "not_found"
+	CodeRouteNotFound    Code = "route_not_found"
+	CodeConflict         Code = "conflict"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeRateLimited      Code = "rate_limited"
+	CodeUnavailable      Code = "unavailable"
+
+	CodeMissingCredentials Code = "missing_credentials"
+	CodeInvalidAPIKey      Code = "invalid_api_key"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeAPIKeyNotFound     Code = "api_key_not_found"
+
+	CodeBookingNotFound       Code = "booking_not_found"
+	CodeBookingAlreadyExists  Code = "booking_already_exists"
+	CodeBookingNotCancellable Code = "booking_not_cancellable"
+
+	CodeSubscriptionNotFound Code = "subscription_not_found"
+	CodeDeliveryNotFound     Code = "delivery_not_found"
+	CodeDeliveryPending      Code = "delivery_pending"
+
+	CodeCarrierNotFound    Code = "carrier_not_found"
+	CodeCarrierUnavailable Code = "carrier_unavailable"
+	CodeLaneNotServed      Code = "lane_not_served"
+	CodeOptionNotFound     Code = "option_not_found"
+
+	CodeServiceLevelNotOffered Code = "service_level_not_offered"
+
+	CodePickupNotFound         Code = "pickup_not_found"
+	CodePickupAlreadyScheduled Code = "pickup_already_scheduled"
+	CodePickupSlotFull         Code = "pickup_slot_full"
+	CodePickupSlotUnavailable  Code = "pickup_slot_unavailable"
+	CodeBookingNotPickable     Code = "booking_not_pickable"
+
+	CodeLocationNotFound Code = "location_not_found"
+	CodeRateNotFound     Code = "rate_not_found"
+	CodePriceNotFound    Code = "price_not_found"
+	CodeWeightOutOfRange Code = "weight_out_of_range"
+
+	CodeInvalidQuery           Code = "invalid_query"
+	CodeQueryTooComplex        Code = "query_too_complex"
+	CodePersistedQueryNotFound Code = "persisted_query_not_found"
+	CodePersistedQueryRequired Code = "persisted_query_required"
+	CodePersistedQueryMismatch Code = "persisted_query_hash_mismatch"
+)
+
+// Field error codes.
+const (
+	FieldRequired    = "required"
+	FieldInvalid     = "invalid"
+	FieldInvalidType = "invalid_type"
+	FieldOutOfRange  = "out_of_range"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a failed response, decoded from its problem document when the
+// server sent one.
+type Error struct {
+	Status     int
+	Code       Code
+	Title      string
+	Detail     string
+	Instance   string
+	Fields     []FieldError
+	RequestId  string
+	RetryAfter time.Duration
+}
+
+// Sentinels to match the kind of an error with errors.Is.
+var (
+	ErrNotFound     = &Error{Status: http.StatusNotFound}
+	ErrConflict     = &Error{Status: http.StatusConflict}
+	ErrInput        = &Error{Status: http.StatusBadRequest}
+	ErrUnauthorized = &Error{Status: http.StatusUnauthorized}
+	ErrForbidden    = &Error{Status: http.StatusForbidden}
+	ErrRateLimited  = &Error{Status: http.StatusTooManyRequests}
+	ErrInternal     = &Error{Status: http.StatusInternalServerError}
+)
+
+type problem struct {
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance"`
+	Code      Code         `json:"code"`
+	Errors    []FieldError `json:"errors"`
+	RequestId string       `json:"requestId"`
+}
+
+func newError(res *http.Response, body []byte) *Error {
+	e := &Error{
+		Status:     res.StatusCode,
+		Code:       codeOfStatus(res.StatusCode),
+		Title:      http.StatusText(res.StatusCode),
+		RequestId:  res.Header.Get("X-Request-ID"),
+		RetryAfter: retryAfter(res.Header),
+	}
+
+	var p problem
+	if err := json.Unmarshal(body, &p); err != nil || p.Code == "" {
+		e.Detail = strings.TrimSpace(string(body))
+		return e
+	}
+
+	e.Code = p.Code
+	e.Detail = p.Detail
+	e.Instance = p.Instance
+	e.Fields = p.Errors
+	if p.Title != "" {
+		e.Title = p.Title
+	}
+	if p.RequestId != "" {
+		e.RequestId = p.RequestId
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.Status, e.Code)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Detail)
+}
+
+// Is reports whether e matches target. A sentinel matches any error of its
+// status, ErrInternal any 5xx, and a target with a code also needs the same
+// code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Status != 0 && t.Status != e.Status && (t.Status < http.StatusInternalServerError || e.Status < http.StatusInternalServerError) {
+		return false
+	}
+	return t.Code == "" || t.Code == e.Code
+}
+
+// GetCode returns the code of err, errors that are not from the api are
+// CodeUnknown.
+func GetCode(err error) Code {
+	var apiError *Error
+	if !errors.As(err, &apiError) {
+		return CodeUnknown
+	}
+	return apiError.Code
+}
+
+func GetFields(err error) []FieldError {
+	var apiError *Error
+	if !errors.As(err, &apiError) {
+		return nil
+	}
+	return apiError.Fields
+}
+
+func codeOfStatus(status int) Code {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusConflict:
+		return CodeConflict
+	case status == http.StatusUnauthorized:
+		return CodeUnauthorized
+	case status == http.StatusForbidden:
+		return CodeForbidden
+	case status == http.StatusTooManyRequests:
+		return CodeRateLimited
+	case status >= http.StatusInternalServerError:
+		return CodeInternal
+	case status >= http.StatusBadRequest:
+		return CodeInvalidRequest
+	default:
+		return CodeUnknown
+	}
+}
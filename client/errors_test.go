@@ -0,0 +1,46 @@
+package client
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// codes returns the values of the Code constants declared in a file, by name.
+func codes(t *testing.T, path string) map[string]string {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	require.Nil(t, err)
+
+	values := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "Code" {
+				continue
+			}
+			for i, name := range value.Names {
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				require.True(t, ok, name.Name)
+				values[name.Name], err = strconv.Unquote(lit.Value)
+				require.Nil(t, err)
+			}
+		}
+	}
+	return values
+}
+
+func TestCodesMatchServer(t *testing.T) {
+	t.Parallel()
+
+	server := codes(t, "../internal/errors/codes.go")
+	require.NotEmpty(t, server)
+	require.Equal(t, server, codes(t, "errors.go"))
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Slot is a pickup window on a date, as YYYY-MM-DD, and how many more
+// pickups it takes.
+type Slot struct {
+	Date string `json:"date"`
+	// Window is the time of day as HH:MM-HH:MM in the time zone of the origin.
+	Window    string `json:"window"`
+	Capacity  int    `json:"capacity"`
+	Available int    `json:"available"`
+}
+
+type Slots struct {
+	Origin string `json:"origin"`
+	Slots  []Slot `json:"slots"`
+}
+
+// PickupRequest reserves the window of Slots on the date, as YYYY-MM-DD.
+type PickupRequest struct {
+	Date   string `json:"date"`
+	Window string `json:"window"`
+}
+
+type Pickup struct {
+	BookingId string    `json:"bookingId"`
+	Origin    string    `json:"origin"`
+	Date      string    `json:"date"`
+	Window    string    `json:"window"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// ClientId is only set when listing the pickups of a day.
+	ClientId string `json:"clientId,omitempty"`
+}
+
+type pickupList struct {
+	Pickups []Pickup `json:"pickups"`
+}
+
+// PickupSlots returns the pickup slots at the origin on the given number of
+// business days from the date, as YYYY-MM-DD, on. An empty date starts at
+// the next pickup from now.
+func (c *Client) PickupSlots(ctx context.Context, origin, from string, days int) (*Slots, error) {
+	query := url.Values{}
+	query.Set("origin", origin)
+	if from != "" {
+		query.Set("from", from)
+	}
+	if days > 0 {
+		query.Set("days", strconv.Itoa(days))
+	}
+
+	var res Slots
+	if err := c.do(ctx, http.MethodGet, "/api/v1/pickups/slots?"+query.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SchedulePickup fails with CodePickupSlotFull when the slot has no capacity
+// left.
+func (c *Client) SchedulePickup(ctx context.Context, bookingId string, req PickupRequest) (*Pickup, error) {
+	var res Pickup
+	if err := c.do(ctx, http.MethodPost, v1ShippingPath+"/"+url.PathEscape(bookingId)+"/pickup", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) GetPickup(ctx context.Context, bookingId string) (*Pickup, error) {
+	var res Pickup
+	if err := c.do(ctx, http.MethodGet, v1ShippingPath+"/"+url.PathEscape(bookingId)+"/pickup", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ReschedulePickup keeps the pickup in its slot when the new one is full.
+func (c *Client) ReschedulePickup(ctx context.Context, bookingId string, req PickupRequest) (*Pickup, error) {
+	var res Pickup
+	if err := c.do(ctx, http.MethodPut, v1ShippingPath+"/"+url.PathEscape(bookingId)+"/pickup", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) CancelPickup(ctx context.Context, bookingId string) error {
+	return c.do(ctx, http.MethodDelete, v1ShippingPath+"/"+url.PathEscape(bookingId)+"/pickup", nil, nil)
+}
+
+// ListPickups returns the pickups of every client at the origin on the date,
+// as YYYY-MM-DD, and needs the operator or admin role.
+func (c *Client) ListPickups(ctx context.Context, origin, date string) ([]Pickup, error) {
+	query := url.Values{}
+	query.Set("origin", origin)
+	query.Set("date", date)
+
+	var res pickupList
+	if err := c.do(ctx, http.MethodGet, "/api/v1/pickups?"+query.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Pickups, nil
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TrackingCode tells what happened to a parcel at a scan.
+type TrackingCode string
+
+const (
+	TrackingPickedUp       TrackingCode = "picked_up"
+	TrackingArrivedAtHub   TrackingCode = "arrived_at_hub"
+	TrackingDepartedHub    TrackingCode = "departed_hub"
+	TrackingInTransit      TrackingCode = "in_transit"
+	TrackingOutForDelivery TrackingCode = "out_for_delivery"
+	TrackingDeliveryFailed TrackingCode = "delivery_failed"
+	TrackingException      TrackingCode = "exception"
+	TrackingDelivered      TrackingCode = "delivered"
+	TrackingReturned       TrackingCode = "returned"
+)
+
+// TrackingEvent is a carrier scan of a booking. Id and ReceivedAt are only
+// set on the events of a Tracking.
+type TrackingEvent struct {
+	Id          string       `json:"id,omitempty"`
+	BookingId   string       `json:"bookingId,omitempty"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Location    string       `json:"location"`
+	Code        TrackingCode `json:"code"`
+	Description string       `json:"description,omitempty"`
+	ReceivedAt  *time.Time   `json:"receivedAt,omitempty"`
+}
+
+// IngestResult tells how many of the reported events were new.
+type IngestResult struct {
+	Accepted   int `json:"accepted"`
+	Duplicates int `json:"duplicates"`
+}
+
+// Tracking is the status of a booking and its events, oldest first.
+type Tracking struct {
+	BookingId string          `json:"bookingId"`
+	Status    Status          `json:"status"`
+	Events    []TrackingEvent `json:"events"`
+}
+
+// IngestTrackingEvents reports carrier scans, of one or more bookings, and
+// needs the operator or admin role. Either every event is ingested or none
+// is, a single event is sent on its own rather than as a batch.
+func (c *Client) IngestTrackingEvents(ctx context.Context, events ...TrackingEvent) (*IngestResult, error) {
+	var in interface{} = events
+	if len(events) == 1 {
+		in = events[0]
+	}
+
+	var res IngestResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/tracking/events", in, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (c *Client) GetTracking(ctx context.Context, bookingId string) (*Tracking, error) {
+	var res Tracking
+	if err := c.do(ctx, http.MethodGet, v1ShippingPath+"/"+url.PathEscape(bookingId)+"/tracking", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
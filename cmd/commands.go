@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/slaengkast/shipping-api/client"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/metrics"
+
+	"github.com/urfave/cli/v2"
+)
+
+// shippingBackend is what the operating commands run against, either a
+// running server or the bookings file directly.
+type shippingBackend interface {
+	book(ctx context.Context, origin, destination string, weight float32) (bookingView, error)
+	get(ctx context.Context, id string) (bookingView, error)
+	list(ctx context.Context, offset, limit int) (bookingList, error)
+	cancel(ctx context.Context, id string) (bookingView, error)
+	quote(ctx context.Context, origin, destination string, weight float32) (quoteView, error)
+	close(ctx context.Context) error
+}
+
+type commandConfig struct {
+	url      string
+	apiKey   string
+	token    string
+	timeout  time.Duration
+	output   string
+	local    bool
+	clientId string
+}
+
+type shipmentConfig struct {
+	origin      string
+	destination string
+	weight      float64
+}
+
+func commandFlags(config *commandConfig) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "url",
+			Value:       "http://localhost",
+			Usage:       "Set the base url of the server",
+			EnvVars:     []string{"SHIPPING_API_URL"},
+			Destination: &config.url,
+		},
+		&cli.StringFlag{
+			Name:        "apiKey",
+			Usage:       "Set the api key used against the server",
+			EnvVars:     []string{"SHIPPING_API_KEY"},
+			Destination: &config.apiKey,
+		},
+		&cli.StringFlag{
+			Name:        "token",
+			Usage:       "Set the bearer token used against the server, takes precedence over --apiKey",
+			Destination: &config.token,
+		},
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Value:       10 * time.Second,
+			Usage:       "Set the maximum duration of every request to the server",
+			Destination: &config.timeout,
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Value:       outputTable,
+			Usage:       "Set the output format, valid values: table, json, yaml",
+			Destination: &config.output,
+		},
+		&cli.BoolFlag{
+			Name:        "local",
+			Usage:       "Use the bookings file directly instead of a server, requires --client",
+			Destination: &config.local,
+		},
+		&cli.StringFlag{
+			Name:        "client",
+			Usage:       "Set the client the bookings belong to when using --local",
+			Destination: &config.clientId,
+		},
+	}
+}
+
+func shipmentFlags(config *shipmentConfig) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "origin",
+			Usage:       "Set the country code of the origin",
+			Destination: &config.origin,
+		},
+		&cli.StringFlag{
+			Name:        "destination",
+			Usage:       "Set the country code of the destination",
+			Destination: &config.destination,
+		},
+		&cli.Float64Flag{
+			Name:        "weight",
+			Usage:       "Set the weight of the parcel in kg",
+			Destination: &config.weight,
+		},
+	}
+}
+
+// operatingCommands returns the commands that book and look up shipments,
+// bookingsFile is the global flag used by --local.
+func operatingCommands(logLevel, bookingsFile *string) []*cli.Command {
+	var (
+		config   commandConfig
+		shipment shipmentConfig
+		offset   int
+		limit    int
+	)
+
+	// withBackend runs f against the backend selected by the flags and writes
+	// what it returns in the selected output format.
+	withBackend := func(f func(ctx context.Context, b shippingBackend) (interface{}, error)) cli.ActionFunc {
+		return func(ctx *cli.Context) error {
+			configureLogging(*logLevel)
+			if !validOutput(config.output) {
+				return usageError{fmt.Errorf("unknown output %q", config.output)}
+			}
+
+			b, err := newBackend(config, *bookingsFile, ctx.App.ErrWriter)
+			if err != nil {
+				return err
+			}
+			v, err := f(ctx.Context, b)
+			if closeErr := b.close(ctx.Context); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			return write(ctx.App.Writer, ctx.App.ErrWriter, config.output, v)
+		}
+	}
+
+	return []*cli.Command{
+		{
+			Name:         "book",
+			Usage:        "Book shipping of a parcel",
+			OnUsageError: onUsageError,
+			Flags:        append(commandFlags(&config), shipmentFlags(&shipment)...),
+			Action: withBackend(func(ctx context.Context, b shippingBackend) (interface{}, error) {
+				return b.book(ctx, shipment.origin, shipment.destination, float32(shipment.weight))
+			}),
+		},
+		{
+			Name:         "get",
+			Usage:        "Show a booking",
+			OnUsageError: onUsageError,
+			ArgsUsage:    "[options] <id>",
+			Flags:        commandFlags(&config),
+			Action: func(ctx *cli.Context) error {
+				id, err := idArg(ctx)
+				if err != nil {
+					return err
+				}
+				return withBackend(func(ctx context.Context, b shippingBackend) (interface{}, error) {
+					return b.get(ctx, id)
+				})(ctx)
+			},
+		},
+		{
+			Name:         "quote",
+			Usage:        "Show the price of shipping a parcel without booking it",
+			OnUsageError: onUsageError,
+			Flags:        append(commandFlags(&config), shipmentFlags(&shipment)...),
+			Action: withBackend(func(ctx context.Context, b shippingBackend) (interface{}, error) {
+				return b.quote(ctx, shipment.origin, shipment.destination, float32(shipment.weight))
+			}),
+		},
+		{
+			Name:         "list",
+			Usage:        "List a page of bookings, oldest first",
+			OnUsageError: onUsageError,
+			Flags: append(commandFlags(&config),
+				&cli.IntFlag{
+					Name:        "offset",
+					Usage:       "Set the number of bookings to skip",
+					Destination: &offset,
+				},
+				&cli.IntFlag{
+					Name:        "limit",
+					Usage:       "Set the maximum number of bookings, the server decides if unset",
+					Destination: &limit,
+				},
+			),
+			Action: withBackend(func(ctx context.Context, b shippingBackend) (interface{}, error) {
+				return b.list(ctx, offset, limit)
+			}),
+		},
+		{
+			Name:         "cancel",
+			Usage:        "Cancel a booking",
+			OnUsageError: onUsageError,
+			ArgsUsage:    "[options] <id>",
+			Flags:        commandFlags(&config),
+			Action: func(ctx *cli.Context) error {
+				id, err := idArg(ctx)
+				if err != nil {
+					return err
+				}
+				return withBackend(func(ctx context.Context, b shippingBackend) (interface{}, error) {
+					return b.cancel(ctx, id)
+				})(ctx)
+			},
+		},
+	}
+}
+
+func idArg(ctx *cli.Context) (string, error) {
+	if ctx.NArg() != 1 {
+		return "", usageError{fmt.Errorf("%s takes exactly one booking id", ctx.Command.Name)}
+	}
+	return ctx.Args().First(), nil
+}
+
+// newBackend returns the backend selected by the config, warnings that do
+// not fail the command go to errW.
+func newBackend(config commandConfig, bookingsFile string, errW io.Writer) (shippingBackend, error) {
+	if !config.local {
+		return remoteBackend{client: client.New(client.Config{
+			BaseUrl: config.url,
+			APIKey:  config.apiKey,
+			Token:   config.token,
+			Timeout: config.timeout,
+		}), errW: errW}, nil
+	}
+
+	if config.clientId == "" {
+		return nil, usageError{fmt.Errorf("--local requires --client")}
+	}
+	if bookingsFile == "" {
+		return nil, usageError{fmt.Errorf("--local requires --bookingsFile")}
+	}
+	return newLocalBackend(bookingsFile, config.clientId)
+}
+
+type remoteBackend struct {
+	client *client.Client
+	errW   io.Writer
+}
+
+// book shows the booking as the server has it. The shipment is booked once
+// the POST succeeds, so failing to fetch it only warns, as failing the command
+// would have it retried and the shipment booked twice.
+func (b remoteBackend) book(ctx context.Context, origin, destination string, weight float32) (bookingView, error) {
+	id, err := b.client.BookShipping(ctx, origin, destination, weight)
+	if err != nil {
+		return bookingView{}, err
+	}
+	view, err := b.get(ctx, id)
+	if err != nil {
+		fmt.Fprintf(b.errW, "warning: booked %s but could not fetch the booking: %v\n", id, err)
+		return bookingView{Id: id, Origin: origin, Destination: destination, Weight: weight}, nil
+	}
+	return view, nil
+}
+
+func (b remoteBackend) get(ctx context.Context, id string) (bookingView, error) {
+	res, err := b.client.GetBooking(ctx, id)
+	if err != nil {
+		return bookingView{}, err
+	}
+	return fromClientBooking(*res), nil
+}
+
+func (b remoteBackend) list(ctx context.Context, offset, limit int) (bookingList, error) {
+	res, err := b.client.ListBookings(ctx, offset, limit)
+	if err != nil {
+		return bookingList{}, err
+	}
+	bookings := make([]bookingView, 0, len(res.Bookings))
+	for _, r := range res.Bookings {
+		bookings = append(bookings, fromClientBooking(r))
+	}
+	return bookingList{Bookings: bookings, NextOffset: res.NextOffset}, nil
+}
+
+func (b remoteBackend) cancel(ctx context.Context, id string) (bookingView, error) {
+	res, err := b.client.CancelBooking(ctx, id)
+	if err != nil {
+		return bookingView{}, err
+	}
+	return fromClientBooking(*res), nil
+}
+
+func (b remoteBackend) quote(ctx context.Context, origin, destination string, weight float32) (quoteView, error) {
+	res, err := b.client.Quote(ctx, origin, destination, weight)
+	if err != nil {
+		return quoteView{}, err
+	}
+	options := make([]optionView, 0, len(res.Options))
+	for _, o := range res.Options {
+		options = append(options, optionView(o))
+	}
+	return quoteView{
+		Origin:      res.Origin,
+		Destination: res.Destination,
+		Weight:      res.Weight,
+		Region:      res.Region,
+		WeightClass: res.WeightClass,
+		Rate:        res.Rate,
+		BasePrice:   res.BasePrice,
+		Price:       res.Price,
+		Currency:    res.Currency,
+		Level:       string(res.ServiceLevel),
+		TransitDays: res.TransitDays,
+		Pickup:      res.EstimatedPickup,
+		Delivery:    res.EstimatedDelivery,
+		Options:     options,
+	}, nil
+}
+
+func (b remoteBackend) close(_ context.Context) error {
+	return nil
+}
+
+func fromClientBooking(b client.Booking) bookingView {
+	return bookingView{
+		Id:          b.Id,
+		Origin:      b.Origin,
+		Destination: b.Destination,
+		Weight:      b.Weight,
+		Price:       b.Price,
+		Currency:    b.Currency,
+		Status:      string(b.Status),
+		Carrier:     b.Carrier,
+		Reference:   b.CarrierReference,
+		Level:       string(b.ServiceLevel),
+		Pickup:      b.EstimatedPickup,
+		Delivery:    b.EstimatedDelivery,
+	}
+}
+
+// localBackend runs the services in process against the bookings file, it
+// must not be used while a server writes to the same file.
+type localBackend struct {
+	bookingService *booking.Service
+	billingService billing.Service
+	clientId       string
+	closers        []closer
+}
+
+func newLocalBackend(bookingsFile, clientId string) (shippingBackend, error) {
+	m := metrics.New()
+	carrierService, err := newCarrierService([]string{defaultCarrierLane})
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := newCalendars(calendarConfig{cutOff: defaultPickupCutOff})
+	if err != nil {
+		return nil, err
+	}
+	billingService, closers, err := newBillingService(m, carrierService, calendars, billing.Config{CarrierTimeout: defaultCarrierQuoteTimeout, ServiceLevels: billing.DefaultServiceLevels()})
+	if err != nil {
+		return nil, err
+	}
+	bookingStore, err := booking.NewFileStore(bookingsFile)
+	if err != nil {
+		return nil, err
+	}
+	bookingService := booking.NewService(bookingStore, billingService, carrierService, m)
+
+	return localBackend{
+		bookingService: &bookingService,
+		billingService: billingService,
+		clientId:       clientId,
+		closers:        append(closers, bookingStore),
+	}, nil
+}
+
+func (b localBackend) book(ctx context.Context, origin, destination string, weight float32) (bookingView, error) {
+	id, err := b.bookingService.BookShipping(ctx, b.clientId, origin, destination, weight, booking.Selection{})
+	if err != nil {
+		return bookingView{}, err
+	}
+	return b.get(ctx, id)
+}
+
+func (b localBackend) get(ctx context.Context, id string) (bookingView, error) {
+	res, err := b.bookingService.GetBooking(ctx, b.clientId, id)
+	if err != nil {
+		return bookingView{}, err
+	}
+	return fromBooking(res), nil
+}
+
+func (b localBackend) list(ctx context.Context, offset, limit int) (bookingList, error) {
+	res, next, err := b.bookingService.ListBookings(ctx, b.clientId, offset, limit)
+	if err != nil {
+		return bookingList{}, err
+	}
+	bookings := make([]bookingView, 0, len(res))
+	for _, r := range res {
+		bookings = append(bookings, fromBooking(r))
+	}
+	list := bookingList{Bookings: bookings}
+	if next >= 0 {
+		list.NextOffset = &next
+	}
+	return list, nil
+}
+
+func (b localBackend) cancel(ctx context.Context, id string) (bookingView, error) {
+	res, err := b.bookingService.CancelBooking(ctx, b.clientId, id)
+	if err != nil {
+		return bookingView{}, err
+	}
+	return fromBooking(res), nil
+}
+
+func (b localBackend) quote(ctx context.Context, origin, destination string, weight float32) (quoteView, error) {
+	q, err := b.billingService.Quote(ctx, origin, destination, weight, billing.ServiceLevelStandard)
+	if err != nil {
+		return quoteView{}, err
+	}
+	rates, err := b.billingService.QuoteOptions(ctx, carrier.Request{
+		Origin:      carrier.Address{Country: origin},
+		Destination: carrier.Address{Country: destination},
+		Parcels:     []carrier.Parcel{{Weight: weight}},
+	}, billing.PolicyCheapest)
+	if err != nil {
+		return quoteView{}, err
+	}
+	options := make([]optionView, 0, len(rates))
+	for _, r := range rates {
+		options = append(options, optionView{Id: r.Id(), Carrier: r.Carrier, ServiceLevel: r.ServiceLevel, Price: r.Price, Currency: r.Currency, TransitDays: r.TransitDays})
+	}
+	return quoteView{
+		Origin:      origin,
+		Destination: destination,
+		Weight:      weight,
+		Region:      q.Region(),
+		WeightClass: q.WeightClass(),
+		Rate:        q.Rate(),
+		BasePrice:   q.BasePrice(),
+		Price:       q.Total(),
+		Currency:    carrier.Currency,
+		Level:       string(q.ServiceLevel()),
+		TransitDays: q.TransitDays(),
+		Pickup:      q.EstimatedPickup().Format(dateLayout),
+		Delivery:    q.EstimatedDelivery().Format(dateLayout),
+		Options:     options,
+	}, nil
+}
+
+func (b localBackend) close(ctx context.Context) error {
+	for _, c := range b.closers {
+		if err := c.Close(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookingGetter is implemented by the bookings of the booking package.
+type bookingGetter interface {
+	Id() string
+	Origin() string
+	Destination() string
+	Weight() float32
+	Price() float32
+	Status() booking.Status
+	Carrier() string
+	CarrierReference() string
+	ServiceLevel() billing.ServiceLevel
+	EstimatedPickup() time.Time
+	EstimatedDelivery() time.Time
+}
+
+func fromBooking(b bookingGetter) bookingView {
+	return bookingView{
+		Id:          b.Id(),
+		Origin:      b.Origin(),
+		Destination: b.Destination(),
+		Weight:      b.Weight(),
+		Price:       b.Price(),
+		Currency:    carrier.Currency,
+		Status:      string(b.Status()),
+		Carrier:     b.Carrier(),
+		Reference:   b.CarrierReference(),
+		Level:       string(b.ServiceLevel()),
+		Pickup:      formatDate(b.EstimatedPickup()),
+		Delivery:    formatDate(b.EstimatedDelivery()),
+	}
+}
+
+// formatDate returns the date of t, empty for the zero time.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
@@ -2,23 +2,71 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
+	// calendars name time zones that the host may not have
+	_ "time/tzdata"
 
+	"github.com/slaengkast/shipping-api/internal/auth"
 	"github.com/slaengkast/shipping-api/internal/billing"
 	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/events"
+	"github.com/slaengkast/shipping-api/internal/graphqlapi"
+	"github.com/slaengkast/shipping-api/internal/grpcapi"
+	"github.com/slaengkast/shipping-api/internal/health"
+	"github.com/slaengkast/shipping-api/internal/metrics"
+	"github.com/slaengkast/shipping-api/internal/pickup"
+	"github.com/slaengkast/shipping-api/internal/ratelimit"
 	"github.com/slaengkast/shipping-api/internal/server"
+	"github.com/slaengkast/shipping-api/internal/stream"
+	"github.com/slaengkast/shipping-api/internal/tracing"
+	"github.com/slaengkast/shipping-api/internal/tracking"
+	"github.com/slaengkast/shipping-api/internal/webhook"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
 )
 
+const dateLayout = "2006-01-02"
+
 func main() {
 	var (
-		logLevel string
-		port     int
+		logLevel          string
+		accessLogSampling uint
+		port              int
+		grpcPort          int
+		readTimeout       time.Duration
+		writeTimeout      time.Duration
+		idleTimeout       time.Duration
+		shutdownTimeout   time.Duration
+		drainDelay        time.Duration
+		apiKeysFile       string
+		bookingsFile      string
+		pickupsFile       string
+		trackingFile      string
+		clientId          string
+		keyId             string
+		jwt               jwtConfig
+		rateLimit         rateLimitConfig
+		tracing           tracingConfig
+		legacyDeprecation string
+		legacySunset      string
+		graphql           graphqlConfig
+		webhooks          webhook.Config
+		eventSinks        eventConfig
+		streams           stream.Config
+		carrierLanes      cli.StringSlice
+		billingConfig     billing.Config
+		calendars         calendarConfig
+		pickupSchedules   cli.StringSlice
+		trustedProxies    cli.StringSlice
 	)
 
 	app := &cli.App{
@@ -31,20 +79,347 @@ func main() {
 				Usage:       "Set the log level, valid values: debug, info, warn, error",
 				Destination: &logLevel,
 			},
+			&cli.UintFlag{
+				Name:        "accessLogSampling",
+				Value:       1,
+				Usage:       "Only log every n:th successful request, failed requests are always logged",
+				Destination: &accessLogSampling,
+			},
 			&cli.IntFlag{
 				Name:        "port",
 				Value:       80,
 				Usage:       "Set the server port",
 				Destination: &port,
 			},
+			&cli.IntFlag{
+				Name:        "grpcPort",
+				Value:       9090,
+				Usage:       "Set the gRPC server port",
+				Destination: &grpcPort,
+			},
+			&cli.DurationFlag{
+				Name:        "readTimeout",
+				Value:       10 * time.Second,
+				Usage:       "Set the maximum duration for reading a request",
+				Destination: &readTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "writeTimeout",
+				Value:       30 * time.Second,
+				Usage:       "Set the maximum duration for writing a response",
+				Destination: &writeTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "idleTimeout",
+				Value:       120 * time.Second,
+				Usage:       "Set the maximum duration to keep idle connections open",
+				Destination: &idleTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "shutdownTimeout",
+				Value:       30 * time.Second,
+				Usage:       "Set the maximum duration to wait for in-flight requests on shutdown",
+				Destination: &shutdownTimeout,
+			},
+			&cli.DurationFlag{
+				Name:        "drainDelay",
+				Value:       0,
+				Usage:       "Set how long /readyz reports not ready before draining starts on shutdown",
+				Destination: &drainDelay,
+			},
+			&cli.StringFlag{
+				Name:        "apiKeysFile",
+				Value:       "api-keys.json",
+				Usage:       "Set the file api keys are stored in",
+				Destination: &apiKeysFile,
+			},
+			&cli.StringFlag{
+				Name:        "bookingsFile",
+				Usage:       "Set the file bookings are stored in, bookings are only kept in memory if unset",
+				Destination: &bookingsFile,
+			},
+			&cli.StringFlag{
+				Name:        "pickupsFile",
+				Usage:       "Set the file pickups are stored in, required with --bookingsFile, pickups are only kept in memory if unset",
+				Destination: &pickupsFile,
+			},
+			&cli.StringFlag{
+				Name:        "trackingFile",
+				Usage:       "Set the file tracking events are stored in, required with --bookingsFile, tracking events are only kept in memory if unset",
+				Destination: &trackingFile,
+			},
+			&cli.StringFlag{
+				Name:        "jwksFile",
+				Usage:       "Set the JWKS file used to verify bearer tokens, bearer tokens are rejected if unset",
+				Destination: &jwt.jwksFile,
+			},
+			&cli.StringFlag{
+				Name:        "jwtIssuer",
+				Usage:       "Set the required issuer of bearer tokens",
+				Destination: &jwt.issuer,
+			},
+			&cli.StringFlag{
+				Name:        "jwtAudience",
+				Usage:       "Set the required audience of bearer tokens",
+				Destination: &jwt.audience,
+			},
+			&cli.StringFlag{
+				Name:        "jwtRolesClaim",
+				Value:       "roles",
+				Usage:       "Set the bearer token claim holding the roles, valid roles: customer, operator, admin",
+				Destination: &jwt.rolesClaim,
+			},
+			&cli.StringFlag{
+				Name:        "rateLimit",
+				Value:       "10:20",
+				Usage:       "Set the default rate limit per client and route as <requests per second>:<burst>",
+				Destination: &rateLimit.defaultLimit,
+			},
+			&cli.StringFlag{
+				Name:        "rateLimitRedisUrl",
+				Usage:       "Share the rate limits between instances through a Redis server, e.g. redis://localhost:6379/0, limits are kept per instance otherwise",
+				Destination: &rateLimit.redisUrl,
+			},
+			&cli.StringSliceFlag{
+				Name:        "trustedProxy",
+				Usage:       "Trust the X-Forwarded-For header of requests from an address or CIDR range, unauthenticated requests are rate limited by the client address",
+				Destination: &trustedProxies,
+			},
+			&cli.StringSliceFlag{
+				Name:        "routeRateLimit",
+				Usage:       "Override the rate limit of a route as \"<method> <route>=<requests per second>:<burst>\", e.g. \"POST /api/shipping=1:5\"",
+				Destination: &rateLimit.routeLimits,
+			},
+			&cli.StringFlag{
+				Name:        "traceExporter",
+				Value:       "none",
+				Usage:       "Set the trace exporter, valid values: none, stdout, otlp",
+				Destination: &tracing.exporter,
+			},
+			&cli.StringFlag{
+				Name:        "traceEndpoint",
+				Usage:       "Set the host:port of the OTLP HTTP collector, defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable",
+				Destination: &tracing.endpoint,
+			},
+			&cli.StringFlag{
+				Name:        "legacyApiDeprecation",
+				Value:       "2026-10-19",
+				Usage:       "Set the date, as YYYY-MM-DD, the unversioned /api routes were deprecated",
+				Destination: &legacyDeprecation,
+			},
+			&cli.StringFlag{
+				Name:        "legacyApiSunset",
+				Value:       "2027-04-30",
+				Usage:       "Set the date, as YYYY-MM-DD, the unversioned /api routes will be removed",
+				Destination: &legacySunset,
+			},
+			&cli.IntFlag{
+				Name:        "graphqlMaxComplexity",
+				Value:       1000,
+				Usage:       "Set the maximum complexity of a GraphQL query, every field costs one and fields below a list once per item",
+				Destination: &graphql.maxComplexity,
+			},
+			&cli.IntFlag{
+				Name:        "graphqlMaxDepth",
+				Value:       10,
+				Usage:       "Set the maximum depth of a GraphQL query",
+				Destination: &graphql.maxDepth,
+			},
+			&cli.StringFlag{
+				Name:        "graphqlPersistedQueries",
+				Usage:       "Set a JSON file of persisted GraphQL queries keyed by their SHA-256 hash",
+				Destination: &graphql.persistedQueriesFile,
+			},
+			&cli.IntFlag{
+				Name:        "graphqlMaxPersistedQueries",
+				Value:       graphqlapi.DefaultMaxPersistedQueries,
+				Usage:       "Set how many GraphQL queries clients may persist, the least recently used are evicted beyond it",
+				Destination: &graphql.maxPersistedQueries,
+			},
+			&cli.BoolFlag{
+				Name:        "graphqlPersistedOnly",
+				Usage:       "Only run persisted GraphQL queries, requires --graphqlPersistedQueries",
+				Destination: &graphql.persistedOnly,
+			},
+			&cli.IntFlag{
+				Name:        "webhookMaxAttempts",
+				Value:       8,
+				Usage:       "Set how many times a webhook delivery is attempted before it is dead lettered",
+				Destination: &webhooks.MaxAttempts,
+			},
+			&cli.DurationFlag{
+				Name:        "webhookMinBackoff",
+				Value:       10 * time.Second,
+				Usage:       "Set the wait after the first failed webhook attempt, it doubles with every attempt",
+				Destination: &webhooks.MinBackoff,
+			},
+			&cli.DurationFlag{
+				Name:        "webhookMaxBackoff",
+				Value:       time.Hour,
+				Usage:       "Set the maximum wait between webhook attempts",
+				Destination: &webhooks.MaxBackoff,
+			},
+			&cli.DurationFlag{
+				Name:        "webhookTimeout",
+				Value:       10 * time.Second,
+				Usage:       "Set the maximum duration of a webhook attempt",
+				Destination: &webhooks.Timeout,
+			},
+			&cli.IntFlag{
+				Name:        "webhookWorkers",
+				Value:       10,
+				Usage:       "Set how many subscriptions webhooks are delivered to at once",
+				Destination: &webhooks.Workers,
+			},
+			&cli.BoolFlag{
+				Name:        "webhookAllowPrivateNetworks",
+				Usage:       "Allow webhooks to be delivered to loopback, private and link local addresses, e.g. for local development",
+				Destination: &webhooks.AllowPrivateNetworks,
+			},
+			&cli.DurationFlag{
+				Name:        "streamHeartbeat",
+				Value:       15 * time.Second,
+				Usage:       "Set how long an event stream may be idle before a heartbeat is sent",
+				Destination: &streams.Heartbeat,
+			},
+			&cli.IntFlag{
+				Name:        "streamHistory",
+				Value:       1000,
+				Usage:       "Set how many of the latest events event streams can be resumed from",
+				Destination: &streams.History,
+			},
+			&cli.DurationFlag{
+				Name:        "outboxInterval",
+				Value:       time.Second,
+				Usage:       "Set how often stored booking events are relayed to the event sinks",
+				Destination: &eventSinks.interval,
+			},
+			&cli.StringFlag{
+				Name:        "eventFile",
+				Usage:       "Set a file booking events are appended to as JSON lines",
+				Destination: &eventSinks.file,
+			},
+			&cli.StringFlag{
+				Name:        "natsUrl",
+				Usage:       "Set a NATS server booking events are published to, e.g. nats://localhost:4222",
+				Destination: &eventSinks.natsUrl,
+			},
+			&cli.StringFlag{
+				Name:        "natsSubject",
+				Value:       "shipping.bookings",
+				Usage:       "Set the NATS subject booking events are published to",
+				Destination: &eventSinks.natsSubject,
+			},
+			&cli.StringFlag{
+				Name:        "kafkaRestUrl",
+				Usage:       "Set a Kafka REST proxy booking events are produced through",
+				Destination: &eventSinks.kafkaRestUrl,
+			},
+			&cli.StringFlag{
+				Name:        "kafkaTopic",
+				Value:       "shipping.bookings",
+				Usage:       "Set the Kafka topic booking events are produced to",
+				Destination: &eventSinks.kafkaTopic,
+			},
+			&cli.StringSliceFlag{
+				Name:        "carrierLane",
+				Value:       cli.NewStringSlice(defaultCarrierLane),
+				Usage:       "Assign carriers to a lane, in order of preference, as \"<origin>-<destination>=<carrier>[,<carrier>]\" where either country may be *, e.g. \"SE-*=fake\"",
+				Destination: &carrierLanes,
+			},
+			&cli.DurationFlag{
+				Name:        "carrierQuoteTimeout",
+				Value:       defaultCarrierQuoteTimeout,
+				Usage:       "Set how long every carrier may take to quote, carriers answering later are left out of the options",
+				Destination: &billingConfig.CarrierTimeout,
+			},
+			&cli.StringSliceFlag{
+				Name:        "calendar",
+				Usage:       "Load the time zone, pickup cut-off and holidays of a location from a JSON or iCalendar (.ics) file as \"<location>=<path>\", e.g. \"SE=se.ics\"",
+				Destination: &calendars.files,
+			},
+			&cli.StringFlag{
+				Name:        "pickupCutOff",
+				Value:       defaultPickupCutOff,
+				Usage:       "Set the time of day as HH:MM after which parcels are picked up the next business day, for locations without a calendar and calendars without a cut-off",
+				Destination: &calendars.cutOff,
+			},
+			&cli.StringSliceFlag{
+				Name:        "pickupSchedule",
+				Value:       cli.NewStringSlice(defaultPickupSchedule),
+				Usage:       "Set the pickup windows of a location and how many pickups each takes a day as \"<location>=<from>-<to>[,<from>-<to>]/<capacity>\" where location may be *, e.g. \"SE=09:00-12:00,13:00-17:00/20\"",
+				Destination: &pickupSchedules,
+			},
 		},
 		Action: func(ctx *cli.Context) error {
 			configureLogging(logLevel)
-			return run(port)
+			deprecation, err := time.Parse(dateLayout, legacyDeprecation)
+			if err != nil {
+				return fmt.Errorf("invalid legacyApiDeprecation: %w", err)
+			}
+			sunset, err := time.Parse(dateLayout, legacySunset)
+			if err != nil {
+				return fmt.Errorf("invalid legacyApiSunset: %w", err)
+			}
+			config := server.Config{
+				Port:              port,
+				AccessLogSampling: uint32(accessLogSampling),
+				ReadTimeout:       readTimeout,
+				WriteTimeout:      writeTimeout,
+				IdleTimeout:       idleTimeout,
+				DrainDelay:        drainDelay,
+				LegacyDeprecation: deprecation,
+				LegacySunset:      sunset,
+				TrustedProxies:    trustedProxies.Value(),
+			}
+			billingConfig.ServiceLevels = billing.DefaultServiceLevels()
+			return run(config, grpcapi.Config{Port: grpcPort}, graphql, webhooks, eventSinks, streams, carrierLanes.Value(), billingConfig, calendars, pickupSchedules.Value(), shutdownTimeout, apiKeysFile, bookingsFile, pickupsFile, trackingFile, jwt, rateLimit, tracing)
 		},
+		OnUsageError: onUsageError,
+		Commands: append([]*cli.Command{
+			{
+				Name:  "apikey",
+				Usage: "Manage api keys",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "create",
+						Usage: "Create an api key for a client, the key is only shown once",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "client",
+								Usage:       "Set the client the key belongs to",
+								Required:    true,
+								Destination: &clientId,
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							configureLogging(logLevel)
+							return createAPIKey(ctx.Context, apiKeysFile, clientId)
+						},
+					},
+					{
+						Name:  "revoke",
+						Usage: "Revoke an api key",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "id",
+								Usage:       "Set the id of the key to revoke",
+								Required:    true,
+								Destination: &keyId,
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							configureLogging(logLevel)
+							return revokeAPIKey(ctx.Context, apiKeysFile, keyId)
+						},
+					},
+				},
+			},
+		}, operatingCommands(&logLevel, &bookingsFile)...),
 	}
 	if err := app.Run(os.Args); err != nil {
 		log.Error().Err(err).Msg("")
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -65,7 +440,265 @@ func configureLogging(logLevel string) {
 	log.Logger = log.With().Caller().Logger()
 }
 
-func run(port int) error {
+func createAPIKey(ctx context.Context, apiKeysFile, clientId string) error {
+	keyStore, err := auth.NewFileKeyStore(apiKeysFile)
+	if err != nil {
+		return err
+	}
+
+	id, key, err := auth.NewService(keyStore).CreateKey(ctx, clientId)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("id: %s\nkey: %s\n", id, key)
+	return nil
+}
+
+func revokeAPIKey(ctx context.Context, apiKeysFile, id string) error {
+	keyStore, err := auth.NewFileKeyStore(apiKeysFile)
+	if err != nil {
+		return err
+	}
+
+	return auth.NewService(keyStore).RevokeKey(ctx, id)
+}
+
+type jwtConfig struct {
+	jwksFile   string
+	issuer     string
+	audience   string
+	rolesClaim string
+}
+
+// rateLimitRedisTimeout bounds every take from Redis, requests are let
+// through when it passes.
+const rateLimitRedisTimeout = 100 * time.Millisecond
+
+type rateLimitConfig struct {
+	defaultLimit string
+	routeLimits  cli.StringSlice
+	redisUrl     string
+}
+
+// newLimiter returns the limiter and, when the limits are shared through
+// Redis, the store to close on shutdown.
+func newLimiter(config rateLimitConfig) (ratelimit.Limiter, []closer, error) {
+	defaultLimit, err := ratelimit.ParseLimit(config.defaultLimit)
+	if err != nil {
+		return ratelimit.Limiter{}, nil, err
+	}
+
+	routeLimits := make(map[string]ratelimit.Limit)
+	for _, l := range config.routeLimits.Value() {
+		route, limit, err := ratelimit.ParseRouteLimit(l)
+		if err != nil {
+			return ratelimit.Limiter{}, nil, err
+		}
+		routeLimits[route] = limit
+	}
+
+	if config.redisUrl == "" {
+		return ratelimit.NewLimiter(ratelimit.NewInMemoryStore(), defaultLimit, routeLimits), nil, nil
+	}
+	store, err := ratelimit.NewRedisStore(config.redisUrl, rateLimitRedisTimeout)
+	if err != nil {
+		return ratelimit.Limiter{}, nil, err
+	}
+	return ratelimit.NewLimiter(store, defaultLimit, routeLimits), []closer{store}, nil
+}
+
+// defaultCarrierLane hands every shipment to the built in fake carriers.
+const defaultCarrierLane = "*-*=fake,fake-express"
+
+const defaultCarrierQuoteTimeout = 2 * time.Second
+
+const defaultPickupCutOff = "15:00"
+
+// newPickupService keeps the pickups in pickupsFile, which must be set when
+// the bookings are kept in a file so that their reserved slots are not lost
+// on a restart. The returned stores should be closed on shutdown.
+func newPickupService(pickupsFile, bookingsFile string, bookingService *booking.Service, calendars calendar.Calendars, schedules pickup.Schedules) (pickup.Service, []closer, error) {
+	if pickupsFile == "" {
+		if bookingsFile != "" {
+			return pickup.Service{}, nil, fmt.Errorf("pickupsFile must be set with bookingsFile, pickups would be lost on restart while their bookings are kept")
+		}
+		return pickup.NewService(pickup.NewInMemoryStore(), bookingService, calendars, schedules), nil, nil
+	}
+
+	store, err := pickup.NewFileStore(pickupsFile)
+	if err != nil {
+		return pickup.Service{}, nil, err
+	}
+	return pickup.NewService(store, bookingService, calendars, schedules), []closer{store}, nil
+}
+
+// newTrackingService keeps the tracking events in trackingFile, which must be
+// set when the bookings are kept in a file so that timelines are not lost and
+// scans replayed after a restart are still found to be duplicates. The
+// returned stores should be closed on shutdown.
+func newTrackingService(trackingFile, bookingsFile string, bookingService *booking.Service) (tracking.Service, []closer, error) {
+	if trackingFile == "" {
+		if bookingsFile != "" {
+			return tracking.Service{}, nil, fmt.Errorf("trackingFile must be set with bookingsFile, tracking events would be lost on restart while their bookings are kept")
+		}
+		return tracking.NewService(tracking.NewInMemoryStore(), bookingService), nil, nil
+	}
+
+	store, err := tracking.NewFileStore(trackingFile)
+	if err != nil {
+		return tracking.Service{}, nil, err
+	}
+	return tracking.NewService(store, bookingService), []closer{store}, nil
+}
+
+const defaultPickupSchedule = "*=09:00-12:00,13:00-17:00/20"
+
+func newPickupSchedules(schedules []string) (pickup.Schedules, error) {
+	pickupSchedules := make(pickup.Schedules, len(schedules))
+	for _, s := range schedules {
+		location, schedule, err := pickup.ParseSchedule(s)
+		if err != nil {
+			return nil, err
+		}
+		pickupSchedules[location] = schedule
+	}
+	return pickupSchedules, nil
+}
+
+type calendarConfig struct {
+	files  cli.StringSlice
+	cutOff string
+}
+
+// newCalendars loads the calendars of the locations, locations without one
+// get weekends off in UTC.
+func newCalendars(config calendarConfig) (calendar.Calendars, error) {
+	cutOff, err := calendar.ParseCutOff(config.cutOff)
+	if err != nil {
+		return calendar.Calendars{}, fmt.Errorf("invalid pickupCutOff: %w", err)
+	}
+
+	byLocation := map[string]calendar.Calendar{}
+	for _, f := range config.files.Value() {
+		location, path, ok := strings.Cut(f, "=")
+		if !ok || location == "" || path == "" {
+			return calendar.Calendars{}, fmt.Errorf("invalid calendar %q, must be <location>=<path>", f)
+		}
+		c, err := calendar.Load(path, cutOff)
+		if err != nil {
+			return calendar.Calendars{}, err
+		}
+		byLocation[location] = c
+		log.Info().Str("location", location).Int("holidays", len(c.Holidays())).Msg("loaded calendar")
+	}
+	return calendar.NewCalendars(calendar.New(calendar.Config{CutOff: cutOff}), byLocation), nil
+}
+
+func newCarrierService(lanes []string) (carrier.Service, error) {
+	carrierLanes := make(carrier.Lanes, len(lanes))
+	for _, l := range lanes {
+		lane, codes, err := carrier.ParseLane(l)
+		if err != nil {
+			return carrier.Service{}, err
+		}
+		carrierLanes[lane] = codes
+	}
+
+	return carrier.NewService(
+		carrierLanes,
+		carrier.NewFake("fake", carrier.FakeConfig{BasePrice: 50, PricePerKg: 10, TransitDays: 3, ServiceLevels: []string{"economy", "standard", "express"}}),
+		carrier.NewFake("fake-express", carrier.FakeConfig{BasePrice: 150, PricePerKg: 20, TransitDays: 1, ServiceLevels: []string{"standard", "express"}}),
+	)
+}
+
+type tracingConfig struct {
+	exporter string
+	endpoint string
+}
+
+type graphqlConfig struct {
+	maxComplexity        int
+	maxDepth             int
+	persistedQueriesFile string
+	maxPersistedQueries  int
+	persistedOnly        bool
+}
+
+func validateConfig(config server.Config, grpcConfig grpcapi.Config, shutdownTimeout time.Duration) error {
+	if config.Port < 1 || config.Port > 65535 {
+		return fmt.Errorf("invalid port %d", config.Port)
+	}
+	if grpcConfig.Port < 1 || grpcConfig.Port > 65535 {
+		return fmt.Errorf("invalid grpcPort %d", grpcConfig.Port)
+	}
+	if grpcConfig.Port == config.Port {
+		return fmt.Errorf("grpcPort must differ from port")
+	}
+	if config.ReadTimeout <= 0 || config.WriteTimeout <= 0 || config.IdleTimeout <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+	if config.DrainDelay >= shutdownTimeout {
+		return fmt.Errorf("drainDelay %s must be shorter than shutdownTimeout %s", config.DrainDelay, shutdownTimeout)
+	}
+	if config.LegacySunset.Before(config.LegacyDeprecation) {
+		return fmt.Errorf("legacyApiSunset must not be before legacyApiDeprecation")
+	}
+	return nil
+}
+
+type closer interface {
+	Close(context.Context) error
+}
+
+type eventConfig struct {
+	interval     time.Duration
+	file         string
+	natsUrl      string
+	natsSubject  string
+	kafkaRestUrl string
+	kafkaTopic   string
+}
+
+// newEventSinks sets up the configured external sinks, the returned closers
+// should be closed after the relay.
+func newEventSinks(config eventConfig) ([]events.Sink, []closer, error) {
+	if config.interval <= 0 {
+		return nil, nil, fmt.Errorf("outboxInterval must be positive")
+	}
+
+	var sinks []events.Sink
+	var closers []closer
+	if config.file != "" {
+		fileSink, err := events.NewFileSink(config.file)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, fileSink)
+		closers = append(closers, fileSink)
+	}
+	if config.natsUrl != "" {
+		natsSink, err := events.NewNATSSink(config.natsUrl, config.natsSubject, 10*time.Second)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, natsSink)
+		closers = append(closers, natsSink)
+	}
+	if config.kafkaRestUrl != "" {
+		kafkaSink, err := events.NewKafkaSink(config.kafkaRestUrl, config.kafkaTopic, 10*time.Second)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, kafkaSink)
+		closers = append(closers, kafkaSink)
+	}
+	return sinks, closers, nil
+}
+
+// newBillingService sets up the billing tables, the returned stores should be
+// closed on shutdown.
+func newBillingService(m *metrics.Metrics, carrierService carrier.Service, calendars calendar.Calendars, config billing.Config) (billing.Service, []closer, error) {
 	rateStore := billing.NewInMemoryRateStore(
 		map[string]float32{
 			"domestic":      1.0,
@@ -96,31 +729,219 @@ func run(port int) error {
 	for _, l := range locations {
 		location, err := billing.NewLocation(l.code, l.hasEuMembership)
 		if err != nil {
-			return err
+			return billing.Service{}, nil, err
 		}
 
 		if err := locationStore.AddLocation(context.Background(), location); err != nil {
-			return err
+			return billing.Service{}, nil, err
 		}
 	}
-	billingService := billing.NewService(rateStore, priceStore, locationStore)
 
-	bookingStore := booking.NewInMemoryStore()
-	bookingService := booking.NewService(bookingStore, billingService)
+	billingService := billing.NewService(
+		billing.NewInstrumentedRateStore(rateStore, m),
+		billing.NewInstrumentedPriceStore(priceStore, m),
+		billing.NewInstrumentedLocationStore(locationStore, m),
+		carrierService,
+		calendars,
+		config,
+		m,
+	)
+	return billingService, []closer{rateStore, priceStore, locationStore}, nil
+}
+
+func run(config server.Config, grpcConfig grpcapi.Config, graphqlConfig graphqlConfig, webhookConfig webhook.Config, eventConfig eventConfig, streamConfig stream.Config, carrierLanes []string, billingConfig billing.Config, calendarConfig calendarConfig, pickupSchedules []string, shutdownTimeout time.Duration, apiKeysFile, bookingsFile, pickupsFile, trackingFile string, jwt jwtConfig, rateLimit rateLimitConfig, tracingConfig tracingConfig) error {
+	if err := validateConfig(config, grpcConfig, shutdownTimeout); err != nil {
+		return err
+	}
+	if graphqlConfig.persistedOnly && graphqlConfig.persistedQueriesFile == "" {
+		return fmt.Errorf("graphqlPersistedOnly requires graphqlPersistedQueries")
+	}
+	if webhookConfig.MaxAttempts < 1 {
+		return fmt.Errorf("webhookMaxAttempts must be positive")
+	}
+	if webhookConfig.Workers < 1 {
+		return fmt.Errorf("webhookWorkers must be positive")
+	}
+	if webhookConfig.MinBackoff <= 0 || webhookConfig.Timeout <= 0 {
+		return fmt.Errorf("webhookMinBackoff and webhookTimeout must be positive")
+	}
+	if webhookConfig.MaxBackoff < webhookConfig.MinBackoff {
+		return fmt.Errorf("webhookMaxBackoff must not be shorter than webhookMinBackoff")
+	}
+	// streams end a second before the write timeout would cut them off
+	streamConfig.MaxDuration = config.WriteTimeout - time.Second
+	if streamConfig.Heartbeat <= 0 || streamConfig.Heartbeat >= streamConfig.MaxDuration {
+		return fmt.Errorf("streamHeartbeat must be positive and at least a second shorter than writeTimeout")
+	}
+	if streamConfig.History < 1 {
+		return fmt.Errorf("streamHistory must be at least 1")
+	}
+	streamConfig.Buffer = 64
+	if billingConfig.CarrierTimeout <= 0 {
+		return fmt.Errorf("carrierQuoteTimeout must be positive")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingConfig.exporter, tracingConfig.endpoint)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error().Err(err).Msg("failed to flush traces")
+		}
+	}()
+
+	m := metrics.New()
+
+	carrierService, err := newCarrierService(carrierLanes)
+	if err != nil {
+		return err
+	}
+	calendars, err := newCalendars(calendarConfig)
+	if err != nil {
+		return err
+	}
+	billingService, billingStores, err := newBillingService(m, carrierService, calendars, billingConfig)
+	if err != nil {
+		return err
+	}
+
+	bookingStore, err := booking.NewFileStore(bookingsFile)
+	if err != nil {
+		return err
+	}
+	bookingService := booking.NewService(booking.NewInstrumentedStore(bookingStore, m), billingService, carrierService, m)
+
+	schedules, err := newPickupSchedules(pickupSchedules)
+	if err != nil {
+		return err
+	}
+	pickupService, pickupStores, err := newPickupService(pickupsFile, bookingsFile, &bookingService, calendars, schedules)
+	if err != nil {
+		return err
+	}
+
+	webhookStore := webhook.NewInMemoryStore()
+	webhookService := webhook.NewService(webhookStore)
+	bus := events.NewBus()
+	bus.Subscribe(webhookService)
+	bus.Subscribe(pickupService)
+	trackingService, trackingStores, err := newTrackingService(trackingFile, bookingsFile, &bookingService)
+	if err != nil {
+		return err
+	}
+	broker, err := stream.NewBroker(streamConfig)
+	if err != nil {
+		return err
+	}
+	bus.Subscribe(broker)
+	// the sinks outside the process are relayed to on their own, so that one
+	// failing does not hold up the others or the bus
+	sinks, sinkClosers, err := newEventSinks(eventConfig)
+	if err != nil {
+		return err
+	}
+	relay := events.NewRelay(bookingStore, events.Config{Interval: eventConfig.interval, BatchSize: 100}, m, append([]events.Sink{bus}, sinks...)...)
+	webhookConfig.Interval = time.Second
+	webhookConfig.BatchSize = 100
+	dispatcher := webhook.NewDispatcher(webhookStore, webhookConfig, m)
+	webhookHandler := webhook.NewHandler(webhookService)
 
 	bookingHandler := booking.NewHandler(bookingService)
+	streamHandler := stream.NewHandler(broker, &bookingService, streamConfig)
+
+	keyStore, err := auth.NewFileKeyStore(apiKeysFile)
+	if err != nil {
+		return err
+	}
+	authService := auth.NewService(keyStore)
+	apiKeyHandler := auth.NewHandler(authService)
+	tariffHandler := billing.NewHandler(billingService)
+	calendarHandler := calendar.NewHandler(calendars)
+	pickupHandler := pickup.NewHandler(pickupService)
+	trackingHandler := tracking.NewHandler(trackingService)
+
+	queries := graphqlapi.NewPersistedQueries(graphqlConfig.maxPersistedQueries)
+	if graphqlConfig.persistedQueriesFile != "" {
+		if queries, err = graphqlapi.LoadPersistedQueries(graphqlConfig.persistedQueriesFile, graphqlConfig.maxPersistedQueries); err != nil {
+			return err
+		}
+		log.Info().Msgf("loaded %d persisted graphql queries", queries.Len())
+	}
+	schema, err := graphqlapi.NewSchema(&bookingService, billingService)
+	if err != nil {
+		return err
+	}
+	graphqlHandler := graphqlapi.NewHandler(schema, queries, graphqlapi.Config{
+		MaxComplexity: graphqlConfig.maxComplexity,
+		MaxDepth:      graphqlConfig.maxDepth,
+		PersistedOnly: graphqlConfig.persistedOnly,
+	})
+
+	var tokenVerifier *auth.TokenVerifier
+	if jwt.jwksFile != "" {
+		tokenVerifier, err = auth.NewTokenVerifier(jwt.jwksFile, jwt.issuer, jwt.audience, jwt.rolesClaim)
+		if err != nil {
+			return err
+		}
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	s := server.New(bookingHandler, port)
+	limiter, limiterClosers, err := newLimiter(rateLimit)
+	if err != nil {
+		return err
+	}
+
+	checker := health.NewChecker()
+	checker.Register("booking_store", time.Second, bookingStore.Ping)
+	checker.Register("billing_tables", time.Second, billingService.CheckTables)
+	checker.Register("config", time.Second, func(context.Context) error {
+		return validateConfig(config, grpcConfig, shutdownTimeout)
+	})
+
+	s := server.New(bookingHandler, tariffHandler, calendarHandler, pickupHandler, trackingHandler, apiKeyHandler, graphqlHandler, webhookHandler, streamHandler, authService, tokenVerifier, limiter, m, checker, config)
+	for _, c := range billingStores {
+		s.OnShutdown(c)
+	}
+	s.OnShutdown(keyStore, bookingStore, webhookStore, dispatcher)
+	for _, c := range pickupStores {
+		s.OnShutdown(c)
+	}
+	for _, c := range trackingStores {
+		s.OnShutdown(c)
+	}
+	for _, c := range sinkClosers {
+		s.OnShutdown(c)
+	}
+	for _, c := range limiterClosers {
+		s.OnShutdown(c)
+	}
+	s.OnShutdown(relay)
+	go dispatcher.Run()
+	go relay.Run()
 	go func() {
 		if err := s.Run(); err != nil {
 			log.Fatal().Err(err).Msg("")
 		}
 	}()
+
+	g := grpcapi.New(&bookingService, billingService, authService, tokenVerifier, limiter, m, grpcConfig)
+	go func() {
+		if err := g.Run(); err != nil {
+			log.Fatal().Err(err).Msg("")
+		}
+	}()
 	<-ctx.Done()
 
 	log.Info().Msg("Shutting down gracefully...")
-	return nil
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// The gRPC server is stopped first as the HTTP server closes the stores.
+	if err := g.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("failed to drain grpc server")
+	}
+	return s.Shutdown(shutdownCtx)
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+
+	"github.com/slaengkast/shipping-api/client"
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// Exit codes of the commands, scripts can tell failures apart without
+// parsing the output.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitInput        = 3
+	exitUnauthorized = 4
+	exitForbidden    = 5
+	exitNotFound     = 6
+	exitConflict     = 7
+	exitRateLimited  = 8
+	exitUnavailable  = 9
+)
+
+type bookingView struct {
+	Id          string  `json:"id" yaml:"id"`
+	Origin      string  `json:"origin" yaml:"origin"`
+	Destination string  `json:"destination" yaml:"destination"`
+	Weight      float32 `json:"weight" yaml:"weight"`
+	Price       float32 `json:"price" yaml:"price"`
+	Currency    string  `json:"currency" yaml:"currency"`
+	Status      string  `json:"status" yaml:"status"`
+	Carrier     string  `json:"carrier,omitempty" yaml:"carrier,omitempty"`
+	Reference   string  `json:"carrierReference,omitempty" yaml:"carrierReference,omitempty"`
+	Level       string  `json:"serviceLevel" yaml:"serviceLevel"`
+	Pickup      string  `json:"estimatedPickup,omitempty" yaml:"estimatedPickup,omitempty"`
+	Delivery    string  `json:"estimatedDelivery,omitempty" yaml:"estimatedDelivery,omitempty"`
+}
+
+type bookingList struct {
+	Bookings   []bookingView `json:"bookings" yaml:"bookings"`
+	NextOffset *int          `json:"nextOffset" yaml:"nextOffset"`
+}
+
+type quoteView struct {
+	Origin      string       `json:"origin" yaml:"origin"`
+	Destination string       `json:"destination" yaml:"destination"`
+	Weight      float32      `json:"weight" yaml:"weight"`
+	Region      string       `json:"region" yaml:"region"`
+	WeightClass string       `json:"weightClass" yaml:"weightClass"`
+	Rate        float32      `json:"rate" yaml:"rate"`
+	BasePrice   float32      `json:"basePrice" yaml:"basePrice"`
+	Price       float32      `json:"price" yaml:"price"`
+	Currency    string       `json:"currency" yaml:"currency"`
+	Level       string       `json:"serviceLevel" yaml:"serviceLevel"`
+	TransitDays int          `json:"transitDays" yaml:"transitDays"`
+	Pickup      string       `json:"estimatedPickup" yaml:"estimatedPickup"`
+	Delivery    string       `json:"estimatedDelivery" yaml:"estimatedDelivery"`
+	Options     []optionView `json:"options" yaml:"options"`
+}
+
+type optionView struct {
+	Id           string  `json:"id" yaml:"id"`
+	Carrier      string  `json:"carrier" yaml:"carrier"`
+	ServiceLevel string  `json:"serviceLevel" yaml:"serviceLevel"`
+	Price        float32 `json:"price" yaml:"price"`
+	Currency     string  `json:"currency" yaml:"currency"`
+	TransitDays  int     `json:"transitDays" yaml:"transitDays"`
+}
+
+func validOutput(output string) bool {
+	return output == outputTable || output == outputJSON || output == outputYAML
+}
+
+// write writes v to w in the output format, in a table the next offset of a
+// list goes to errW so that w only holds the bookings.
+func write(w, errW io.Writer, output string, v interface{}) error {
+	switch output {
+	case outputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(v)
+	case outputYAML:
+		e := yaml.NewEncoder(w)
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+		return e.Close()
+	}
+
+	t := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch v := v.(type) {
+	case bookingView:
+		writeBookings(t, v)
+	case bookingList:
+		writeBookings(t, v.Bookings...)
+		if v.NextOffset != nil {
+			fmt.Fprintf(errW, "next offset: %d\n", *v.NextOffset)
+		}
+	case quoteView:
+		fmt.Fprintln(t, "ORIGIN\tDESTINATION\tWEIGHT\tREGION\tWEIGHT CLASS\tRATE\tBASE PRICE\tPRICE\tCURRENCY\tSERVICE LEVEL\tTRANSIT DAYS\tPICKUP\tDELIVERY")
+		fmt.Fprintf(t, "%s\t%s\t%g\t%s\t%s\t%g\t%.2f\t%.2f\t%s\t%s\t%d\t%s\t%s\n",
+			v.Origin, v.Destination, v.Weight, v.Region, v.WeightClass, v.Rate, v.BasePrice, v.Price, v.Currency, v.Level, v.TransitDays, v.Pickup, v.Delivery)
+		fmt.Fprintln(t)
+		fmt.Fprintln(t, "OPTION\tCARRIER\tSERVICE LEVEL\tPRICE\tCURRENCY\tTRANSIT DAYS")
+		for _, o := range v.Options {
+			fmt.Fprintf(t, "%s\t%s\t%s\t%.2f\t%s\t%d\n", o.Id, o.Carrier, o.ServiceLevel, o.Price, o.Currency, o.TransitDays)
+		}
+	default:
+		return fmt.Errorf("no table output for %T", v)
+	}
+	return t.Flush()
+}
+
+func writeBookings(w io.Writer, bookings ...bookingView) {
+	fmt.Fprintln(w, "ID\tORIGIN\tDESTINATION\tWEIGHT\tPRICE\tCURRENCY\tSTATUS\tCARRIER\tREFERENCE\tSERVICE LEVEL\tPICKUP\tDELIVERY")
+	for _, b := range bookings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%g\t%.2f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", b.Id, b.Origin, b.Destination, b.Weight, b.Price, b.Currency, b.Status, b.Carrier, b.Reference, b.Level, b.Pickup, b.Delivery)
+	}
+}
+
+// usageError is a command used the wrong way, as opposed to a request that
+// failed.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+func onUsageError(_ *cli.Context, err error, _ bool) error {
+	return usageError{err}
+}
+
+// exitCode maps errors of the client, from a server, and of the services,
+// from --local, to the same exit codes.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var usageErr usageError
+	if stderrors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	var clientErr *client.Error
+	if stderrors.As(err, &clientErr) {
+		switch clientErr.Status {
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return exitInput
+		case http.StatusUnauthorized:
+			return exitUnauthorized
+		case http.StatusForbidden:
+			return exitForbidden
+		case http.StatusNotFound:
+			return exitNotFound
+		case http.StatusConflict:
+			return exitConflict
+		case http.StatusTooManyRequests:
+			return exitRateLimited
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return exitUnavailable
+		}
+		return exitError
+	}
+
+	switch errors.GetType(err) {
+	case errors.ErrorInput:
+		return exitInput
+	case errors.ErrorUnauthorized:
+		return exitUnauthorized
+	case errors.ErrorForbidden:
+		return exitForbidden
+	case errors.ErrorNotFound:
+		return exitNotFound
+	case errors.ErrorConflict:
+		return exitConflict
+	case errors.ErrorRateLimited:
+		return exitRateLimited
+	case errors.ErrorUnavailable:
+		return exitUnavailable
+	}
+
+	// requests that never got a response, the server is down or unreachable
+	var netErr interface{ Timeout() bool }
+	if stderrors.As(err, &netErr) {
+		return exitUnavailable
+	}
+	return exitError
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+const ClientIdKey = "clientId"
+
+type apiKey struct {
+	id       string
+	clientId string
+	hash     string
+	revoked  bool
+}
+
+func NewAPIKey(id, clientId, hash string, revoked bool) (*apiKey, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+	if clientId == "" {
+		return nil, errors.New("client id is empty")
+	}
+	if hash == "" {
+		return nil, errors.New("hash is empty")
+	}
+
+	return &apiKey{
+		id:       id,
+		clientId: clientId,
+		hash:     hash,
+		revoked:  revoked,
+	}, nil
+}
+
+func (k *apiKey) Id() string {
+	return k.id
+}
+
+func (k *apiKey) ClientId() string {
+	return k.clientId
+}
+
+func (k *apiKey) Hash() string {
+	return k.hash
+}
+
+func (k *apiKey) IsRevoked() bool {
+	return k.revoked
+}
+
+func (k *apiKey) revoke() {
+	k.revoked = true
+}
+
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
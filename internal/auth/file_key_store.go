@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type fileKeyStore struct {
+	inMemoryKeyStore
+	path string
+}
+
+func NewFileKeyStore(path string) (fileKeyStore, error) {
+	s := fileKeyStore{inMemoryKeyStore: NewInMemoryKeyStore(), path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, errors.WrapStore(err, "api_key", "load")
+	}
+
+	var models []keyModel
+	if err := json.Unmarshal(data, &models); err != nil {
+		return s, errors.WrapStore(err, "api_key", "load")
+	}
+	for _, m := range models {
+		s.keys[m.Id] = m
+	}
+
+	return s, nil
+}
+
+func (r fileKeyStore) AddKey(ctx context.Context, k *apiKey) error {
+	if err := r.inMemoryKeyStore.AddKey(ctx, k); err != nil {
+		return err
+	}
+	return r.save()
+}
+
+func (r fileKeyStore) UpdateKey(ctx context.Context, k *apiKey) error {
+	if err := r.inMemoryKeyStore.UpdateKey(ctx, k); err != nil {
+		return err
+	}
+	return r.save()
+}
+
+func (r fileKeyStore) Close(_ context.Context) error {
+	return r.save()
+}
+
+func (r fileKeyStore) save() error {
+	r.mtx.RLock()
+	models := make([]keyModel, 0, len(r.keys))
+	for _, m := range r.keys {
+		models = append(models, m)
+	}
+	r.mtx.RUnlock()
+
+	data, err := json.MarshalIndent(models, "", "  ")
+	if err != nil {
+		return errors.WrapStore(err, "api_key", "save")
+	}
+	if err := os.WriteFile(r.path, data, 0600); err != nil {
+		return errors.WrapStore(err, "api_key", "save")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createKeyRequest struct {
+	ClientId string `json:"clientId" binding:"required"`
+}
+
+type createKeyResponse struct {
+	Id  string `json:"id" binding:"required"`
+	Key string `json:"key" binding:"required"`
+}
+
+type handler struct {
+	authService Service
+}
+
+func NewHandler(authService Service) *handler {
+	return &handler{authService: authService}
+}
+
+func (h handler) CreateKey(c *gin.Context) {
+	var req createKeyRequest
+	if err := problem.Bind(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	id, key, err := h.authService.CreateKey(c, req.ClientId)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("%s/%s", c.Request.URL.Path, id))
+	c.JSON(http.StatusCreated, createKeyResponse{Id: id, Key: key})
+}
+
+func (h handler) RevokeKey(c *gin.Context) {
+	if err := h.authService.RevokeKey(c, c.Param("id")); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
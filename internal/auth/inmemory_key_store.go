@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"sync"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type keyModel struct {
+	Id       string `json:"id"`
+	ClientId string `json:"clientId"`
+	Hash     string `json:"hash"`
+	Revoked  bool   `json:"revoked"`
+}
+
+type inMemoryKeyStore struct {
+	keys map[string]keyModel
+	mtx  *sync.RWMutex
+}
+
+func NewInMemoryKeyStore() inMemoryKeyStore {
+	return inMemoryKeyStore{keys: make(map[string]keyModel, 0), mtx: &sync.RWMutex{}}
+}
+
+func (r inMemoryKeyStore) GetKey(_ context.Context, id string) (*apiKey, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, errors.NotFound(errors.CodeAPIKeyNotFound, "api key", id)
+	}
+	return unmarshalKey(k)
+}
+
+func (r inMemoryKeyStore) GetKeyByHash(_ context.Context, hash string) (*apiKey, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return unmarshalKey(k)
+		}
+	}
+	return nil, errors.FromCode(errors.CodeAPIKeyNotFound, "api key not found", errors.ErrorNotFound)
+}
+
+func (r inMemoryKeyStore) AddKey(_ context.Context, k *apiKey) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.keys[k.Id()]; ok {
+		return errors.FromMessage("api key already exists", errors.ErrorConflict)
+	}
+
+	r.keys[k.Id()] = marshalKey(k)
+	return nil
+}
+
+func (r inMemoryKeyStore) UpdateKey(_ context.Context, k *apiKey) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.keys[k.Id()]; !ok {
+		return errors.NotFound(errors.CodeAPIKeyNotFound, "api key", k.Id())
+	}
+
+	r.keys[k.Id()] = marshalKey(k)
+	return nil
+}
+
+func (r inMemoryKeyStore) Close(_ context.Context) error {
+	return nil
+}
+
+func unmarshalKey(k keyModel) (*apiKey, error) {
+	return NewAPIKey(k.Id, k.ClientId, k.Hash, k.Revoked)
+}
+
+func marshalKey(k *apiKey) keyModel {
+	return keyModel{
+		Id:       k.id,
+		ClientId: k.clientId,
+		Hash:     k.hash,
+		Revoked:  k.revoked,
+	}
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+const clockSkew = 30 * time.Second
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type TokenVerifier struct {
+	keys       map[string]crypto.PublicKey
+	issuer     string
+	audience   string
+	rolesClaim string
+	now        func() time.Time
+}
+
+func NewTokenVerifier(jwksFile, issuer, audience, rolesClaim string) (*TokenVerifier, error) {
+	keys, err := loadJWKS(jwksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenVerifier(keys, issuer, audience, rolesClaim), nil
+}
+
+func newTokenVerifier(keys map[string]crypto.PublicKey, issuer, audience, rolesClaim string) *TokenVerifier {
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	return &TokenVerifier{
+		keys:       keys,
+		issuer:     issuer,
+		audience:   audience,
+		rolesClaim: rolesClaim,
+		now:        time.Now,
+	}
+}
+
+// Verify checks the signature and registered claims of the token and returns
+// its subject together with the roles it grants. A nil verifier rejects all
+// tokens.
+func (v *TokenVerifier) Verify(_ context.Context, token string) (string, []Role, error) {
+	if v == nil {
+		return "", nil, errors.FromCode(errors.CodeUnauthorized, "bearer tokens are not enabled", errors.ErrorUnauthorized)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", nil, errors.FromCode(errors.CodeInvalidToken, "malformed token", errors.ErrorUnauthorized)
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", nil, errors.FromCode(errors.CodeInvalidToken, "malformed token header", errors.ErrorUnauthorized)
+	}
+
+	key, ok := v.keys[header.Kid]
+	if !ok {
+		return "", nil, errors.FromCode(errors.CodeInvalidToken, "unknown signing key", errors.ErrorUnauthorized)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, errors.FromCode(errors.CodeInvalidToken, "malformed token signature", errors.ErrorUnauthorized)
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return "", nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", nil, errors.FromCode(errors.CodeInvalidToken, "malformed token claims", errors.ErrorUnauthorized)
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return "", nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return "", nil, errors.FromCode(errors.CodeInvalidToken, "token has no subject", errors.ErrorUnauthorized)
+	}
+
+	return subject, rolesFromClaim(claims[v.rolesClaim]), nil
+}
+
+func (v *TokenVerifier) validateClaims(claims map[string]interface{}) error {
+	now := v.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.FromCode(errors.CodeInvalidToken, "token has no expiry", errors.ErrorUnauthorized)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return errors.FromCode(errors.CodeInvalidToken, "token has expired", errors.ErrorUnauthorized)
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.FromCode(errors.CodeInvalidToken, "token is not yet valid", errors.ErrorUnauthorized)
+	}
+
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return errors.FromCode(errors.CodeInvalidToken, "invalid token issuer", errors.ErrorUnauthorized)
+	}
+
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return errors.FromCode(errors.CodeInvalidToken, "invalid token audience", errors.ErrorUnauthorized)
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.FromCode(errors.CodeInvalidToken, "signing key does not match algorithm", errors.ErrorUnauthorized)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.FromCode(errors.CodeInvalidToken, "invalid token signature", errors.ErrorUnauthorized)
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.FromCode(errors.CodeInvalidToken, "signing key does not match algorithm", errors.ErrorUnauthorized)
+		}
+		if len(signature) != 64 {
+			return errors.FromCode(errors.CodeInvalidToken, "invalid token signature", errors.ErrorUnauthorized)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return errors.FromCode(errors.CodeInvalidToken, "invalid token signature", errors.ErrorUnauthorized)
+		}
+	default:
+		return errors.FromCode(errors.CodeInvalidToken, "unsupported token algorithm "+alg, errors.ErrorUnauthorized)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func hasAudience(claim interface{}, audience string) bool {
+	switch aud := claim.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func rolesFromClaim(claim interface{}) []Role {
+	var values []string
+	switch c := claim.(type) {
+	case string:
+		values = strings.Fields(c)
+	case []interface{}:
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	roles := make([]Role, 0, len(values))
+	for _, v := range values {
+		if r, ok := parseRole(v); ok {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nilf(t, err, "unexpected error")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nilf(t, err, "unexpected error")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nilf(t, err, "unexpected error")
+
+	jwksData, err := json.Marshal(jwks{Keys: []jwk{rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey)}})
+	require.Nilf(t, err, "unexpected error")
+	keys, err := parseJWKS(jwksData)
+	require.Nilf(t, err, "unexpected error")
+	verifier := newTokenVerifier(keys, "test-issuer", "shipping-api", "roles")
+
+	now := time.Now()
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":   "test-user",
+			"iss":   "test-issuer",
+			"aud":   []string{"shipping-api"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{"operator", "unknown"},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		token         func() string
+		expectedRoles []Role
+		shouldFail    bool
+	}{
+		{
+			name:          "valid RS256 token",
+			token:         func() string { return signRS256(t, "rsa", rsaKey, validClaims()) },
+			expectedRoles: []Role{RoleOperator},
+		},
+		{
+			name:          "valid ES256 token",
+			token:         func() string { return signES256(t, "ec", ecKey, validClaims()) },
+			expectedRoles: []Role{RoleOperator},
+		},
+		{
+			name: "space separated roles",
+			token: func() string {
+				claims := validClaims()
+				claims["roles"] = "customer admin"
+				return signRS256(t, "rsa", rsaKey, claims)
+			},
+			expectedRoles: []Role{RoleCustomer, RoleAdmin},
+		},
+		{
+			name:       "wrong signing key",
+			token:      func() string { return signRS256(t, "rsa", otherKey, validClaims()) },
+			shouldFail: true,
+		},
+		{
+			name:       "unknown key id",
+			token:      func() string { return signRS256(t, "unknown", rsaKey, validClaims()) },
+			shouldFail: true,
+		},
+		{
+			name:       "algorithm mismatch",
+			token:      func() string { return signRS256(t, "ec", rsaKey, validClaims()) },
+			shouldFail: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = now.Add(-time.Hour).Unix()
+				return signRS256(t, "rsa", rsaKey, claims)
+			},
+			shouldFail: true,
+		},
+		{
+			name: "not yet valid",
+			token: func() string {
+				claims := validClaims()
+				claims["nbf"] = now.Add(time.Hour).Unix()
+				return signRS256(t, "rsa", rsaKey, claims)
+			},
+			shouldFail: true,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "other-issuer"
+				return signRS256(t, "rsa", rsaKey, claims)
+			},
+			shouldFail: true,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other-audience"
+				return signRS256(t, "rsa", rsaKey, claims)
+			},
+			shouldFail: true,
+		},
+		{
+			name: "missing subject",
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "sub")
+				return signRS256(t, "rsa", rsaKey, claims)
+			},
+			shouldFail: true,
+		},
+		{
+			name:       "malformed",
+			token:      func() string { return "not-a-token" },
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			subject, roles, err := verifier.Verify(context.Background(), tc.token())
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, "test-user", subject)
+			require.Equal(t, tc.expectedRoles, roles)
+		})
+	}
+}
+
+func TestVerifyNilVerifier(t *testing.T) {
+	var verifier *TokenVerifier
+	_, _, err := verifier.Verify(context.Background(), "token")
+	require.NotNil(t, err, "expected an error, got nil")
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kid: kid,
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1}),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	return jwk{
+		Kid: kid,
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func signingInput(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(tokenHeader{Alg: alg, Kid: kid})
+	require.Nilf(t, err, "unexpected error")
+	payload, err := json.Marshal(claims)
+	require.Nilf(t, err, "unexpected error")
+	return fmt.Sprintf("%s.%s", base64.RawURLEncoding.EncodeToString(header), base64.RawURLEncoding.EncodeToString(payload))
+}
+
+func signRS256(t *testing.T, kid string, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	input := signingInput(t, "RS256", kid, claims)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.Nilf(t, err, "unexpected error")
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(t *testing.T, kid string, key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	input := signingInput(t, "ES256", kid, claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.Nilf(t, err, "unexpected error")
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func CreateKeyOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "createApiKey",
+		Summary:     "Create an api key for a client, the key is only returned once",
+		Tags:        []string{"admin"},
+		RequestBody: openapi.JSONBody(createKeyRequest{}),
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSONResponse("Api key created", createKeyResponse{}),
+		},
+	}
+}
+
+func RevokeKeyOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "revokeApiKey",
+		Summary:     "Revoke an api key",
+		Tags:        []string{"admin"},
+		Responses: map[string]openapi.Response{
+			"204": openapi.EmptyResponse("Api key revoked"),
+			"404": openapi.ProblemResponse("Api key not found"),
+		},
+	}
+}
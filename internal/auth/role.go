@@ -0,0 +1,51 @@
+package auth
+
+import "context"
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+const RolesKey = "roles"
+
+type rolesKey struct{}
+
+// WithRoles returns a context carrying the roles of the caller, for callers
+// that are not served by gin, which keeps them under RolesKey.
+func WithRoles(ctx context.Context, roles []Role) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// RolesFromContext returns the roles of the caller, set by WithRoles or by
+// the HTTP middleware.
+func RolesFromContext(ctx context.Context) []Role {
+	if roles, ok := ctx.Value(rolesKey{}).([]Role); ok {
+		return roles
+	}
+	roles, _ := ctx.Value(RolesKey).([]Role)
+	return roles
+}
+
+func parseRole(s string) (Role, bool) {
+	switch Role(s) {
+	case RoleCustomer, RoleOperator, RoleAdmin:
+		return Role(s), true
+	default:
+		return "", false
+	}
+}
+
+func HasAnyRole(roles []Role, allowed ...Role) bool {
+	for _, r := range roles {
+		for _, a := range allowed {
+			if r == a {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+
+	"github.com/google/uuid"
+)
+
+type keyStore interface {
+	GetKey(context.Context, string) (*apiKey, error)
+	GetKeyByHash(context.Context, string) (*apiKey, error)
+	AddKey(context.Context, *apiKey) error
+	UpdateKey(context.Context, *apiKey) error
+}
+
+type Service struct {
+	store keyStore
+}
+
+func NewService(store keyStore) Service {
+	return Service{
+		store: store,
+	}
+}
+
+// CreateKey returns the id and the plaintext key, the key itself is only
+// stored hashed and can not be recovered afterwards.
+func (s Service) CreateKey(ctx context.Context, clientId string) (string, string, error) {
+	logger := logging.FromContext(ctx, "auth")
+	logger.Info().Str("clientId", clientId).Msg("creating api key")
+
+	if clientId == "" {
+		return "", "", errors.Validation("empty client id", errors.FieldError{Field: "clientId", Code: errors.FieldRequired, Message: "clientId is required"})
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return "", "", errors.FromError(err, errors.ErrorInternal)
+	}
+
+	id := uuid.New().String()
+	k, err := NewAPIKey(id, clientId, hashKey(key), false)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.store.AddKey(ctx, k); err != nil {
+		return "", "", err
+	}
+	return id, key, nil
+}
+
+func (s Service) RevokeKey(ctx context.Context, id string) error {
+	logger := logging.FromContext(ctx, "auth")
+	logger.Info().Str("id", id).Msg("revoking api key")
+
+	k, err := s.store.GetKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	k.revoke()
+	return s.store.UpdateKey(ctx, k)
+}
+
+func (s Service) Authenticate(ctx context.Context, key string) (string, error) {
+	if key == "" {
+		return "", errors.FromCode(errors.CodeMissingCredentials, "missing api key", errors.ErrorUnauthorized)
+	}
+
+	k, err := s.store.GetKeyByHash(ctx, hashKey(key))
+	if err != nil || k.IsRevoked() {
+		return "", errors.FromCode(errors.CodeInvalidAPIKey, "invalid api key", errors.ErrorUnauthorized)
+	}
+
+	return k.ClientId(), nil
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateKey(t *testing.T) {
+	testCases := []struct {
+		name       string
+		clientId   string
+		shouldFail bool
+	}{
+		{
+			name:       "valid client",
+			clientId:   "test-client",
+			shouldFail: false,
+		},
+		{
+			name:       "empty client",
+			clientId:   "",
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			service := NewService(NewInMemoryKeyStore())
+
+			id, key, err := service.CreateKey(context.Background(), tc.clientId)
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.NotEqual(t, "", id, "expected id to not be empty")
+			require.NotEqual(t, "", key, "expected key to not be empty")
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		key        func(id, key string) string
+		revoke     bool
+		shouldFail bool
+	}{
+		{
+			name:       "valid key",
+			key:        func(_, key string) string { return key },
+			shouldFail: false,
+		},
+		{
+			name:       "empty key",
+			key:        func(_, _ string) string { return "" },
+			shouldFail: true,
+		},
+		{
+			name:       "unknown key",
+			key:        func(_, _ string) string { return "unknown" },
+			shouldFail: true,
+		},
+		{
+			name:       "key id instead of key",
+			key:        func(id, _ string) string { return id },
+			shouldFail: true,
+		},
+		{
+			name:       "revoked key",
+			key:        func(_, key string) string { return key },
+			revoke:     true,
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			service := NewService(NewInMemoryKeyStore())
+
+			id, key, err := service.CreateKey(ctx, "test-client")
+			require.Nilf(t, err, "unexpected error")
+			if tc.revoke {
+				require.Nilf(t, service.RevokeKey(ctx, id), "unexpected error")
+			}
+
+			clientId, err := service.Authenticate(ctx, tc.key(id, key))
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, "test-client", clientId)
+		})
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/keys.json"
+
+	store, err := NewFileKeyStore(path)
+	require.Nilf(t, err, "unexpected error")
+	_, key, err := NewService(store).CreateKey(ctx, "test-client")
+	require.Nilf(t, err, "unexpected error")
+
+	reloaded, err := NewFileKeyStore(path)
+	require.Nilf(t, err, "unexpected error")
+	clientId, err := NewService(reloaded).Authenticate(ctx, key)
+	require.Nilf(t, err, "unexpected error")
+	require.Equal(t, "test-client", clientId)
+}
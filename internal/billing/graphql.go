@@ -0,0 +1,213 @@
+package billing
+
+import (
+	"sort"
+
+	"github.com/slaengkast/shipping-api/internal/carrier"
+
+	"github.com/graphql-go/graphql"
+)
+
+var LocationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Location",
+	Fields: graphql.Fields{
+		"code": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*location).code, nil
+			},
+		},
+		"euMember": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*location).hasEUMembership, nil
+			},
+		},
+	},
+})
+
+var ServiceLevelType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ServiceLevel",
+	Values: graphql.EnumValueConfigMap{
+		"ECONOMY":  &graphql.EnumValueConfig{Value: ServiceLevelEconomy},
+		"STANDARD": &graphql.EnumValueConfig{Value: ServiceLevelStandard},
+		"EXPRESS":  &graphql.EnumValueConfig{Value: ServiceLevelExpress},
+	},
+})
+
+var QuoteType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "PriceBreakdown",
+	Description: "The base price of the weight class multiplied by the rate of the region and the multiplier of the service level.",
+	Fields: graphql.Fields{
+		"region": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*quote).region, nil
+			},
+		},
+		"weightClass": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*quote).weightClass, nil
+			},
+		},
+		"rate": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Float),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*quote).rate), nil
+			},
+		},
+		"basePrice": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Float),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*quote).basePrice), nil
+			},
+		},
+		"total": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Float),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*quote).Total()), nil
+			},
+		},
+		"currency": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return carrier.Currency, nil
+			},
+		},
+		"serviceLevel": &graphql.Field{
+			Type: graphql.NewNonNull(ServiceLevelType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*quote).serviceLevel, nil
+			},
+		},
+		"multiplier": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Float),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return float64(p.Source.(*quote).multiplier), nil
+			},
+		},
+		"transitDays": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*quote).transitDays, nil
+			},
+		},
+		"estimatedPickup": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Date as YYYY-MM-DD when handed over now.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*quote).estimatedPickup.Format("2006-01-02"), nil
+			},
+		},
+		"estimatedDelivery": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Date as YYYY-MM-DD when handed over now.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*quote).estimatedDelivery.Format("2006-01-02"), nil
+			},
+		},
+	},
+})
+
+type tariff struct {
+	name  string
+	value float32
+}
+
+func tariffType(name, key, value string) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			key: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(tariff).name, nil
+				},
+			},
+			value: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return float64(p.Source.(tariff).value), nil
+				},
+			},
+		},
+	})
+}
+
+var (
+	rateType  = tariffType("Rate", "region", "rate")
+	priceType = tariffType("Price", "weightClass", "price")
+)
+
+// GraphQLQueries returns the location and quote fields of the query type.
+func GraphQLQueries(service Service) graphql.Fields {
+	return graphql.Fields{
+		"locations": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(LocationType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return service.GetLocations(p.Context)
+			},
+		},
+		"location": &graphql.Field{
+			Type: LocationType,
+			Args: graphql.FieldConfigArgument{
+				"code": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return service.GetLocation(p.Context, p.Args["code"].(string))
+			},
+		},
+		"quote": &graphql.Field{
+			Type: graphql.NewNonNull(QuoteType),
+			Args: graphql.FieldConfigArgument{
+				"origin":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"destination": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				"weight":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)},
+				"serviceLevel": &graphql.ArgumentConfig{
+					Type:         ServiceLevelType,
+					DefaultValue: ServiceLevelStandard,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return service.Quote(p.Context, p.Args["origin"].(string), p.Args["destination"].(string), float32(p.Args["weight"].(float64)), p.Args["serviceLevel"].(ServiceLevel))
+			},
+		},
+	}
+}
+
+// GraphQLTariffQueries returns the rate and price table fields of the query
+// type, like GetTariffs they are meant for operators.
+func GraphQLTariffQueries(service Service) graphql.Fields {
+	return graphql.Fields{
+		"rates": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(rateType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				rates, _, err := service.GetTariffs(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				return sortedTariffs(rates), nil
+			},
+		},
+		"prices": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(priceType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				_, prices, err := service.GetTariffs(p.Context)
+				if err != nil {
+					return nil, err
+				}
+				return sortedTariffs(prices), nil
+			},
+		},
+	}
+}
+
+func sortedTariffs(m map[string]float32) []tariff {
+	tariffs := make([]tariff, 0, len(m))
+	for name, value := range m {
+		tariffs = append(tariffs, tariff{name: name, value: value})
+	}
+	sort.Slice(tariffs, func(i, j int) bool { return tariffs[i].name < tariffs[j].name })
+	return tariffs
+}
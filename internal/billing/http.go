@@ -0,0 +1,134 @@
+package billing
+
+import (
+	"net/http"
+
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type getTariffsResponse struct {
+	Rates    map[string]float32 `json:"rates" binding:"required"`
+	Prices   map[string]float32 `json:"prices" binding:"required"`
+	Currency string             `json:"currency" binding:"required"`
+}
+
+type handler struct {
+	billingService Service
+}
+
+func NewHandler(billingService Service) *handler {
+	return &handler{billingService: billingService}
+}
+
+func (h handler) GetTariffs(c *gin.Context) {
+	rates, prices, err := h.billingService.GetTariffs(c)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getTariffsResponse{
+		Rates:    rates,
+		Prices:   prices,
+		Currency: carrier.Currency,
+	})
+}
+
+type getQuoteRequest struct {
+	Origin       string       `form:"origin" json:"origin" binding:"required"`
+	Destination  string       `form:"destination" json:"destination" binding:"required"`
+	Weight       float32      `form:"weight" json:"weight" binding:"required,gt=0"`
+	Policy       Policy       `form:"policy" json:"policy,omitempty" binding:"omitempty,oneof=cheapest fastest" description:"Ranks the carrier options, defaults to cheapest"`
+	ServiceLevel ServiceLevel `form:"serviceLevel" json:"serviceLevel,omitempty" binding:"omitempty,oneof=economy standard express" description:"Defaults to standard, also limits the carrier options"`
+}
+
+type quoteOption struct {
+	Id           string  `json:"id" binding:"required" description:"Books the option when passed as option"`
+	Carrier      string  `json:"carrier" binding:"required"`
+	ServiceLevel string  `json:"serviceLevel" binding:"required"`
+	Price        float32 `json:"price" binding:"required"`
+	Currency     string  `json:"currency" binding:"required"`
+	TransitDays  int     `json:"transitDays" binding:"required"`
+}
+
+type getQuoteResponse struct {
+	Origin      string  `json:"origin" binding:"required"`
+	Destination string  `json:"destination" binding:"required"`
+	Weight      float32 `json:"weight" binding:"required"`
+	Region      string  `json:"region" binding:"required"`
+	WeightClass string  `json:"weightClass" binding:"required"`
+	Rate        float32 `json:"rate" binding:"required"`
+	BasePrice   float32 `json:"basePrice" binding:"required"`
+	Price       float32 `json:"price" binding:"required" description:"Base price multiplied by the rate and the multiplier"`
+	Currency    string  `json:"currency" binding:"required"`
+	// ServiceLevel, Multiplier and TransitDays are those of the service level
+	// the price is for.
+	ServiceLevel      ServiceLevel `json:"serviceLevel" binding:"required"`
+	Multiplier        float32      `json:"multiplier" binding:"required"`
+	TransitDays       int          `json:"transitDays" binding:"required"`
+	EstimatedPickup   string       `json:"estimatedPickup" binding:"required" description:"Date as YYYY-MM-DD when handed over now"`
+	EstimatedDelivery string       `json:"estimatedDelivery" binding:"required" description:"Date as YYYY-MM-DD when handed over now"`
+	// Options are the rates of the carriers serving the lane, best first.
+	Options []quoteOption `json:"options" binding:"required"`
+}
+
+func (h handler) GetQuote(c *gin.Context) {
+	var req getQuoteRequest
+	if err := problem.BindQuery(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	q, err := h.billingService.Quote(c, req.Origin, req.Destination, req.Weight, req.ServiceLevel)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+	options, err := h.billingService.QuoteOptions(c, carrier.Request{
+		Origin:       carrier.Address{Country: req.Origin},
+		Destination:  carrier.Address{Country: req.Destination},
+		Parcels:      []carrier.Parcel{{Weight: req.Weight}},
+		ServiceLevel: string(req.ServiceLevel),
+	}, req.Policy)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, getQuoteResponse{
+		Origin:      req.Origin,
+		Destination: req.Destination,
+		Weight:      req.Weight,
+		Region:      q.Region(),
+		WeightClass: q.WeightClass(),
+		Rate:        q.Rate(),
+		BasePrice:   q.BasePrice(),
+		Price:       q.Total(),
+		Currency:    carrier.Currency,
+
+		ServiceLevel:      q.ServiceLevel(),
+		Multiplier:        q.Multiplier(),
+		TransitDays:       q.TransitDays(),
+		EstimatedPickup:   q.EstimatedPickup().Format("2006-01-02"),
+		EstimatedDelivery: q.EstimatedDelivery().Format("2006-01-02"),
+		Options:           toQuoteOptions(options),
+	})
+}
+
+func toQuoteOptions(rates []carrier.Rate) []quoteOption {
+	options := make([]quoteOption, 0, len(rates))
+	for _, r := range rates {
+		options = append(options, quoteOption{
+			Id:           r.Id(),
+			Carrier:      r.Carrier,
+			ServiceLevel: r.ServiceLevel,
+			Price:        r.Price,
+			Currency:     r.Currency,
+			TransitDays:  r.TransitDays,
+		})
+	}
+	return options
+}
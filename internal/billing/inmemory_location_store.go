@@ -3,6 +3,7 @@ package billing
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/slaengkast/shipping-api/internal/errors"
@@ -28,12 +29,30 @@ func (r inMemoryLocationStore) GetByCode(_ context.Context, code string) (*locat
 
 	l, ok := r.locations[code]
 	if !ok {
-		return nil, errors.FromMessage(fmt.Sprintf("no location with code %s", code), errors.ErrorInput)
+		return nil, errors.WithEntity(errors.FromCode(errors.CodeLocationNotFound, fmt.Sprintf("no location with code %s", code), errors.ErrorInput), "location", code)
 	}
 
 	return unmarshalLocation(l)
 }
 
+// GetLocations returns all locations ordered by code.
+func (r inMemoryLocationStore) GetLocations(_ context.Context) ([]*location, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	locations := make([]*location, 0, len(r.locations))
+	for _, l := range r.locations {
+		location, err := unmarshalLocation(l)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].code < locations[j].code })
+
+	return locations, nil
+}
+
 func (r inMemoryLocationStore) AddLocation(_ context.Context, location *location) error {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
@@ -43,6 +62,10 @@ func (r inMemoryLocationStore) AddLocation(_ context.Context, location *location
 	return nil
 }
 
+func (r inMemoryLocationStore) Close(_ context.Context) error {
+	return nil
+}
+
 func unmarshalLocation(l locationModel) (*location, error) {
 	return NewLocation(l.code, l.hasEUMembership)
 }
@@ -19,8 +19,20 @@ func NewInMemoryRateStore(rates map[string]float32) inMemoryRateStore {
 
 func (r inMemoryRateStore) GetRateByRegion(ctx context.Context, region string) (float32, error) {
 	if _, ok := r.rates[region]; !ok {
-		return 0, errors.FromMessage(fmt.Sprintf("no rate found for region %s", region), errors.ErrorInternal)
+		return 0, errors.WithEntity(errors.FromCode(errors.CodeRateNotFound, fmt.Sprintf("no rate found for region %s", region), errors.ErrorInternal), "rate", region)
 	}
 
 	return r.rates[region], nil
 }
+
+func (r inMemoryRateStore) GetRates(ctx context.Context) (map[string]float32, error) {
+	rates := make(map[string]float32, len(r.rates))
+	for region, rate := range r.rates {
+		rates[region] = rate
+	}
+	return rates, nil
+}
+
+func (r inMemoryRateStore) Close(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type storeMetrics interface {
+	StoreOperation(store, operation string, duration time.Duration, err error)
+}
+
+type instrumentedRateStore struct {
+	store   rateStore
+	metrics storeMetrics
+}
+
+func NewInstrumentedRateStore(store rateStore, metrics storeMetrics) instrumentedRateStore {
+	return instrumentedRateStore{store: store, metrics: metrics}
+}
+
+func (r instrumentedRateStore) GetRateByRegion(ctx context.Context, region string) (float32, error) {
+	ctx, span := tracer.Start(ctx, "rateStore.GetRateByRegion", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	rate, err := r.store.GetRateByRegion(ctx, region)
+	err = errors.WrapStore(err, "rate", "get_rate_by_region")
+	r.metrics.StoreOperation("rate", "get_rate_by_region", time.Since(start), err)
+	logging.StoreOperation(ctx, "rate", "get_rate_by_region", time.Since(start), err)
+	tracing.End(span, err)
+	return rate, err
+}
+
+func (r instrumentedRateStore) GetRates(ctx context.Context) (map[string]float32, error) {
+	ctx, span := tracer.Start(ctx, "rateStore.GetRates", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	rates, err := r.store.GetRates(ctx)
+	err = errors.WrapStore(err, "rate", "get_rates")
+	r.metrics.StoreOperation("rate", "get_rates", time.Since(start), err)
+	logging.StoreOperation(ctx, "rate", "get_rates", time.Since(start), err)
+	tracing.End(span, err)
+	return rates, err
+}
+
+type instrumentedPriceStore struct {
+	store   priceStore
+	metrics storeMetrics
+}
+
+func NewInstrumentedPriceStore(store priceStore, metrics storeMetrics) instrumentedPriceStore {
+	return instrumentedPriceStore{store: store, metrics: metrics}
+}
+
+func (r instrumentedPriceStore) GetPriceByWeightClass(ctx context.Context, class string) (float32, error) {
+	ctx, span := tracer.Start(ctx, "priceStore.GetPriceByWeightClass", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	price, err := r.store.GetPriceByWeightClass(ctx, class)
+	err = errors.WrapStore(err, "price", "get_price_by_weight_class")
+	r.metrics.StoreOperation("price", "get_price_by_weight_class", time.Since(start), err)
+	logging.StoreOperation(ctx, "price", "get_price_by_weight_class", time.Since(start), err)
+	tracing.End(span, err)
+	return price, err
+}
+
+func (r instrumentedPriceStore) GetPrices(ctx context.Context) (map[string]float32, error) {
+	ctx, span := tracer.Start(ctx, "priceStore.GetPrices", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	prices, err := r.store.GetPrices(ctx)
+	err = errors.WrapStore(err, "price", "get_prices")
+	r.metrics.StoreOperation("price", "get_prices", time.Since(start), err)
+	logging.StoreOperation(ctx, "price", "get_prices", time.Since(start), err)
+	tracing.End(span, err)
+	return prices, err
+}
+
+type instrumentedLocationStore struct {
+	store   locationStore
+	metrics storeMetrics
+}
+
+func NewInstrumentedLocationStore(store locationStore, metrics storeMetrics) instrumentedLocationStore {
+	return instrumentedLocationStore{store: store, metrics: metrics}
+}
+
+func (r instrumentedLocationStore) GetByCode(ctx context.Context, code string) (*location, error) {
+	ctx, span := tracer.Start(ctx, "locationStore.GetByCode", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	l, err := r.store.GetByCode(ctx, code)
+	err = errors.WrapStore(err, "location", "get_by_code")
+	r.metrics.StoreOperation("location", "get_by_code", time.Since(start), err)
+	logging.StoreOperation(ctx, "location", "get_by_code", time.Since(start), err)
+	tracing.End(span, err)
+	return l, err
+}
+
+func (r instrumentedLocationStore) GetLocations(ctx context.Context) ([]*location, error) {
+	ctx, span := tracer.Start(ctx, "locationStore.GetLocations", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	locations, err := r.store.GetLocations(ctx)
+	err = errors.WrapStore(err, "location", "get_locations")
+	r.metrics.StoreOperation("location", "get_locations", time.Since(start), err)
+	logging.StoreOperation(ctx, "location", "get_locations", time.Since(start), err)
+	tracing.End(span, err)
+	return locations, err
+}
@@ -19,8 +19,20 @@ func NewInMemoryPriceStore(prices map[string]float32) inMemoryPriceStore {
 
 func (r inMemoryPriceStore) GetPriceByWeightClass(ctx context.Context, class string) (float32, error) {
 	if _, ok := r.prices[class]; !ok {
-		return 0, errors.FromMessage(fmt.Sprintf("no price found for class %s", class), errors.ErrorInternal)
+		return 0, errors.WithEntity(errors.FromCode(errors.CodePriceNotFound, fmt.Sprintf("no price found for class %s", class), errors.ErrorInternal), "price", class)
 	}
 
 	return r.prices[class], nil
 }
+
+func (r inMemoryPriceStore) GetPrices(ctx context.Context) (map[string]float32, error) {
+	prices := make(map[string]float32, len(r.prices))
+	for class, price := range r.prices {
+		prices[class] = price
+	}
+	return prices, nil
+}
+
+func (r inMemoryPriceStore) Close(_ context.Context) error {
+	return nil
+}
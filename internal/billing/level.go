@@ -0,0 +1,54 @@
+package billing
+
+import (
+	"fmt"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+// ServiceLevel trades the price of a shipment for how fast it is delivered.
+type ServiceLevel string
+
+const (
+	ServiceLevelEconomy  ServiceLevel = "economy"
+	ServiceLevelStandard ServiceLevel = "standard"
+	ServiceLevelExpress  ServiceLevel = "express"
+)
+
+var serviceLevels = []ServiceLevel{ServiceLevelEconomy, ServiceLevelStandard, ServiceLevelExpress}
+
+func (l ServiceLevel) valid() bool {
+	return l == ServiceLevelEconomy || l == ServiceLevelStandard || l == ServiceLevelExpress
+}
+
+// Level is the tariff of a service level, the multiplier of the price and
+// the transit days by region.
+type Level struct {
+	Multiplier  float32
+	TransitDays map[string]int
+}
+
+// DefaultServiceLevels returns the levels shipments are offered at unless
+// configured otherwise, standard keeps the tariff price.
+func DefaultServiceLevels() map[ServiceLevel]Level {
+	return map[ServiceLevel]Level{
+		ServiceLevelEconomy:  {Multiplier: 0.8, TransitDays: map[string]int{"domestic": 3, "eu": 5, "international": 10}},
+		ServiceLevelStandard: {Multiplier: 1, TransitDays: map[string]int{"domestic": 2, "eu": 3, "international": 6}},
+		ServiceLevelExpress:  {Multiplier: 1.8, TransitDays: map[string]int{"domestic": 1, "eu": 1, "international": 3}},
+	}
+}
+
+// level returns the tariff of the service level, standard if empty.
+func (s Service) level(serviceLevel ServiceLevel) (ServiceLevel, Level, error) {
+	if serviceLevel == "" {
+		serviceLevel = ServiceLevelStandard
+	}
+	if !serviceLevel.valid() {
+		return "", Level{}, errors.Validation("invalid service level", errors.FieldError{Field: "serviceLevel", Code: errors.FieldInvalid, Message: "serviceLevel must be economy, standard or express"})
+	}
+	level, ok := s.config.ServiceLevels[serviceLevel]
+	if !ok {
+		return "", Level{}, errors.FromCode(errors.CodeServiceLevelNotOffered, fmt.Sprintf("service level %s is not offered", serviceLevel), errors.ErrorInput)
+	}
+	return serviceLevel, level, nil
+}
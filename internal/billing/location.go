@@ -25,6 +25,8 @@ func (l location) GetCode() string {
 	return l.code
 }
 
+var regions = []string{"domestic", "eu", "international"}
+
 func getRegion(origin, destination *location) string {
 	switch {
 	case origin.GetCode() == destination.GetCode():
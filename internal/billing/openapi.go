@@ -0,0 +1,28 @@
+package billing
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func GetTariffsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getTariffs",
+		Summary:     "List rates per region and prices per weight class",
+		Tags:        []string{"tariffs"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Tariffs", getTariffsResponse{}),
+		},
+	}
+}
+
+func GetQuoteOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getQuote",
+		Summary:     "Quote the price of shipping a parcel without booking it",
+		Tags:        []string{"tariffs"},
+		Parameters:  openapi.QueryParameters(getQuoteRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Price breakdown", getQuoteResponse{}),
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Policy ranks the carrier options of a shipment.
+type Policy string
+
+const (
+	PolicyCheapest Policy = "cheapest"
+	PolicyFastest  Policy = "fastest"
+)
+
+func (p Policy) valid() bool {
+	return p == PolicyCheapest || p == PolicyFastest
+}
+
+// QuoteOptions asks every carrier serving the lane of the shipment for its
+// rates at once and returns them ranked by the policy, cheapest if empty.
+// Only rates at the service level of the request are kept if it has one.
+// Carriers failing or not answering within the carrier timeout are left out.
+func (s Service) QuoteOptions(ctx context.Context, req carrier.Request, policy Policy) (_ []carrier.Rate, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.QuoteOptions", trace.WithAttributes(
+		attribute.String("billing.origin", req.Origin.Country),
+		attribute.String("billing.destination", req.Destination.Country),
+		attribute.String("billing.policy", string(policy)),
+		attribute.String("billing.service_level", req.ServiceLevel),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if req.Origin.Country == "" {
+		return nil, errors.Validation("empty origin", errors.FieldError{Field: "origin", Code: errors.FieldRequired, Message: "origin is required"})
+	}
+	if req.Destination.Country == "" {
+		return nil, errors.Validation("empty destination", errors.FieldError{Field: "destination", Code: errors.FieldRequired, Message: "destination is required"})
+	}
+	if policy == "" {
+		policy = PolicyCheapest
+	}
+	if !policy.valid() {
+		return nil, errors.Validation("invalid policy", errors.FieldError{Field: "policy", Code: errors.FieldInvalid, Message: "policy must be cheapest or fastest"})
+	}
+	if req.ServiceLevel != "" {
+		if _, _, err := s.level(ServiceLevel(req.ServiceLevel)); err != nil {
+			return nil, err
+		}
+	}
+
+	adapters := s.carrierService.Carriers(req.Origin.Country, req.Destination.Country)
+	if len(adapters) == 0 {
+		return nil, errors.FromCode(errors.CodeLaneNotServed, fmt.Sprintf("no carrier serves %s to %s", req.Origin.Country, req.Destination.Country), errors.ErrorInput)
+	}
+
+	results := make([][]carrier.Rate, len(adapters))
+	errs := make([]error, len(adapters))
+	var wg sync.WaitGroup
+	for i := range adapters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.quoteCarrier(ctx, adapters[i], req)
+		}(i)
+	}
+	wg.Wait()
+
+	logger := logging.FromContext(ctx, "billing")
+	options := []carrier.Rate{}
+	quoted := false
+	for i, rates := range results {
+		if errs[i] != nil {
+			s.metrics.LookupFailed("carrier_rate", errs[i])
+			logger.Warn().Err(errs[i]).Str("carrier", adapters[i].Code()).Msg("carrier failed to quote")
+			continue
+		}
+		quoted = true
+		for _, r := range rates {
+			if req.ServiceLevel == "" || r.ServiceLevel == req.ServiceLevel {
+				options = append(options, r)
+			}
+		}
+	}
+	if !quoted {
+		return nil, errors.FromCode(errors.CodeCarrierUnavailable, fmt.Sprintf("no carrier serving %s to %s quoted", req.Origin.Country, req.Destination.Country), errors.ErrorUnavailable)
+	}
+	if len(options) == 0 {
+		err := errors.FromCode(errors.CodeServiceLevelNotOffered, fmt.Sprintf("no carrier offers %s from %s to %s", req.ServiceLevel, req.Origin.Country, req.Destination.Country), errors.ErrorInput)
+		return nil, errors.WithField(err, "serviceLevel")
+	}
+
+	rank(options, policy)
+	span.SetAttributes(attribute.Int("billing.options", len(options)))
+	return options, nil
+}
+
+// SelectOption returns the option with the id, or the best option by the
+// policy if id is empty.
+func (s Service) SelectOption(ctx context.Context, req carrier.Request, id string, policy Policy) (carrier.Rate, error) {
+	options, err := s.QuoteOptions(ctx, req, policy)
+	if err != nil {
+		return carrier.Rate{}, err
+	}
+	if id == "" {
+		return options[0], nil
+	}
+	for _, o := range options {
+		if o.Id() == id {
+			return o, nil
+		}
+	}
+	err = errors.FromCode(errors.CodeOptionNotFound, fmt.Sprintf("option %s is not offered for the shipment", id), errors.ErrorInput)
+	return carrier.Rate{}, errors.WithField(err, "option")
+}
+
+// quoteCarrier bounds the quote of a carrier by the carrier timeout, also
+// when the adapter ignores its context.
+func (s Service) quoteCarrier(ctx context.Context, a carrier.Adapter, req carrier.Request) ([]carrier.Rate, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.CarrierTimeout)
+	defer cancel()
+
+	type result struct {
+		rates []carrier.Rate
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rates, err := a.Quote(ctx, req)
+		done <- result{rates, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.rates, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("carrier %s did not quote in time: %w", a.Code(), ctx.Err())
+	}
+}
+
+// rank orders the options by the policy, options ranking the same keep the
+// order of preference of their carriers.
+func rank(options []carrier.Rate, policy Policy) {
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if policy == PolicyFastest && a.TransitDays != b.TransitDays {
+			return a.TransitDays < b.TransitDays
+		}
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+		return a.TransitDays < b.TransitDays
+	})
+}
@@ -0,0 +1,187 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteOptions(t *testing.T) {
+	failing := carrier.NewFake("failing", carrier.FakeConfig{})
+	failing.Fail(errors.New("connection refused"))
+	cheap := carrier.NewFake("cheap", carrier.FakeConfig{BasePrice: 100, TransitDays: 5})
+	express := carrier.NewFake("express", carrier.FakeConfig{BasePrice: 300, TransitDays: 1})
+	slow := carrier.NewFake("slow", carrier.FakeConfig{BasePrice: 10, TransitDays: 1, Delay: time.Second})
+	also := carrier.NewFake("also", carrier.FakeConfig{BasePrice: 100, TransitDays: 5})
+	levels := carrier.NewFake("levels", carrier.FakeConfig{BasePrice: 100, TransitDays: 3, ServiceLevels: []string{"economy", "standard", "express"}})
+
+	testCases := []struct {
+		name         string
+		adapters     []carrier.Adapter
+		origin       string
+		policy       Policy
+		serviceLevel string
+		expectedIds  []string
+		expectedCode apierrors.Code
+	}{
+		{
+			name:        "cheapest by default",
+			adapters:    []carrier.Adapter{express, cheap},
+			origin:      "SE",
+			expectedIds: []string{"cheap:standard", "express:standard"},
+		},
+		{
+			name:        "fastest",
+			adapters:    []carrier.Adapter{cheap, express},
+			origin:      "SE",
+			policy:      PolicyFastest,
+			expectedIds: []string{"express:standard", "cheap:standard"},
+		},
+		{
+			name:        "ties keep carrier preference",
+			adapters:    []carrier.Adapter{also, cheap},
+			origin:      "SE",
+			expectedIds: []string{"also:standard", "cheap:standard"},
+		},
+		{
+			name:        "failing and slow carriers left out",
+			adapters:    []carrier.Adapter{failing, slow, cheap},
+			origin:      "SE",
+			expectedIds: []string{"cheap:standard"},
+		},
+		{
+			name:        "every service level",
+			adapters:    []carrier.Adapter{levels},
+			origin:      "SE",
+			policy:      PolicyFastest,
+			expectedIds: []string{"levels:express", "levels:standard", "levels:economy"},
+		},
+		{
+			name:         "only the service level",
+			adapters:     []carrier.Adapter{levels, cheap, express},
+			origin:       "SE",
+			serviceLevel: "express",
+			expectedIds:  []string{"levels:express"},
+		},
+		{
+			name:         "service level not offered",
+			adapters:     []carrier.Adapter{cheap},
+			origin:       "SE",
+			serviceLevel: "economy",
+			expectedCode: apierrors.CodeServiceLevelNotOffered,
+		},
+		{
+			name:         "invalid service level",
+			adapters:     []carrier.Adapter{cheap},
+			origin:       "SE",
+			serviceLevel: "overnight",
+			expectedCode: apierrors.CodeValidationFailed,
+		},
+		{
+			name:         "every carrier failing",
+			adapters:     []carrier.Adapter{failing, slow},
+			origin:       "SE",
+			expectedCode: apierrors.CodeCarrierUnavailable,
+		},
+		{
+			name:         "lane not served",
+			origin:       "SE",
+			expectedCode: apierrors.CodeLaneNotServed,
+		},
+		{
+			name:         "invalid policy",
+			adapters:     []carrier.Adapter{cheap},
+			origin:       "SE",
+			policy:       "slowest",
+			expectedCode: apierrors.CodeValidationFailed,
+		},
+		{
+			name:         "missing origin",
+			adapters:     []carrier.Adapter{cheap},
+			expectedCode: apierrors.CodeValidationFailed,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			service := NewService(ratestoreMock{}, pricestoreMock{}, locationstoreMock{}, carrierServiceMock{adapters: tc.adapters}, calendar.NewCalendars(calendar.New(calendar.Config{}), nil), Config{CarrierTimeout: 50 * time.Millisecond, ServiceLevels: DefaultServiceLevels()}, metricsMock{})
+			req := carrier.Request{Origin: carrier.Address{Country: tc.origin}, Destination: carrier.Address{Country: "DK"}, Parcels: []carrier.Parcel{{Weight: 1}}, ServiceLevel: tc.serviceLevel}
+
+			start := time.Now()
+			options, err := service.QuoteOptions(context.Background(), req, tc.policy)
+			require.Less(t, time.Since(start), time.Second, "expected slow carriers not to hold up the quote")
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			ids := []string{}
+			for _, o := range options {
+				ids = append(ids, o.Id())
+			}
+			require.Equal(t, tc.expectedIds, ids)
+		})
+	}
+}
+
+func TestSelectOption(t *testing.T) {
+	cheap := carrier.NewFake("cheap", carrier.FakeConfig{BasePrice: 100, TransitDays: 5})
+	express := carrier.NewFake("express", carrier.FakeConfig{BasePrice: 300, TransitDays: 1})
+	service := NewService(ratestoreMock{}, pricestoreMock{}, locationstoreMock{}, carrierServiceMock{adapters: []carrier.Adapter{cheap, express}}, calendar.NewCalendars(calendar.New(calendar.Config{}), nil), Config{CarrierTimeout: time.Second, ServiceLevels: DefaultServiceLevels()}, metricsMock{})
+	req := carrier.Request{Origin: carrier.Address{Country: "SE"}, Destination: carrier.Address{Country: "DK"}, Parcels: []carrier.Parcel{{Weight: 1}}}
+
+	testCases := []struct {
+		name            string
+		id              string
+		policy          Policy
+		expectedCarrier string
+		expectedCode    apierrors.Code
+	}{
+		{
+			name:            "cheapest",
+			policy:          PolicyCheapest,
+			expectedCarrier: "cheap",
+		},
+		{
+			name:            "fastest",
+			policy:          PolicyFastest,
+			expectedCarrier: "express",
+		},
+		{
+			name:            "by id",
+			id:              "express:standard",
+			expectedCarrier: "express",
+		},
+		{
+			name:         "unknown id",
+			id:           "missing:standard",
+			expectedCode: apierrors.CodeOptionNotFound,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			option, err := service.SelectOption(context.Background(), req, tc.id, tc.policy)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				require.Equal(t, apierrors.ErrorInput, apierrors.GetType(err))
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedCarrier, option.Carrier)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package billing
+
+import "time"
+
+// quote is the breakdown of a shipping cost, the base price of the weight
+// class multiplied by the rate of the region and the multiplier of the
+// service level.
+type quote struct {
+	region       string
+	weightClass  string
+	rate         float32
+	basePrice    float32
+	serviceLevel ServiceLevel
+	multiplier   float32
+	transitDays  int
+	// estimatedPickup and estimatedDelivery assume the parcel is handed over
+	// when quoted.
+	estimatedPickup   time.Time
+	estimatedDelivery time.Time
+}
+
+func (q quote) Region() string {
+	return q.region
+}
+
+func (q quote) WeightClass() string {
+	return q.weightClass
+}
+
+func (q quote) Rate() float32 {
+	return q.rate
+}
+
+func (q quote) BasePrice() float32 {
+	return q.basePrice
+}
+
+func (q quote) ServiceLevel() ServiceLevel {
+	return q.serviceLevel
+}
+
+func (q quote) Multiplier() float32 {
+	return q.multiplier
+}
+
+func (q quote) TransitDays() int {
+	return q.transitDays
+}
+
+func (q quote) EstimatedPickup() time.Time {
+	return q.estimatedPickup
+}
+
+func (q quote) EstimatedDelivery() time.Time {
+	return q.estimatedDelivery
+}
+
+func (q quote) Total() float32 {
+	return q.basePrice * q.rate * q.multiplier
+}
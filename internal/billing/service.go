@@ -2,81 +2,280 @@ package billing
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/slaengkast/shipping-api/internal/carrier"
 	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/tracing"
 
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("github.com/slaengkast/shipping-api/internal/billing")
+
 type rateStore interface {
 	GetRateByRegion(context.Context, string) (float32, error)
+	GetRates(context.Context) (map[string]float32, error)
 }
 
 type priceStore interface {
 	GetPriceByWeightClass(context.Context, string) (float32, error)
+	GetPrices(context.Context) (map[string]float32, error)
 }
 
 type locationStore interface {
 	GetByCode(context.Context, string) (*location, error)
+	GetLocations(context.Context) ([]*location, error)
+}
+
+type carrierService interface {
+	Carriers(origin, destination string) []carrier.Adapter
+}
+
+type calendars interface {
+	Estimate(origin, destination string, at time.Time, transitDays int) (pickup, delivery time.Time)
+}
+
+type metrics interface {
+	PriceQuoted(region string, price float32)
+	LookupFailed(lookup string, err error)
+}
+
+type Config struct {
+	// CarrierTimeout bounds the quote of every carrier when shopping rates.
+	CarrierTimeout time.Duration
+	// ServiceLevels are the levels shipments are offered at.
+	ServiceLevels map[ServiceLevel]Level
 }
 
 type Service struct {
-	rateStore     rateStore
-	priceStore    priceStore
-	locationStore locationStore
-	logger        zerolog.Logger
+	rateStore      rateStore
+	priceStore     priceStore
+	locationStore  locationStore
+	carrierService carrierService
+	calendars      calendars
+	config         Config
+	metrics        metrics
 }
 
-func NewService(ratestore rateStore, pricestore priceStore, locationstore locationStore) Service {
+func NewService(ratestore rateStore, pricestore priceStore, locationstore locationStore, carrierService carrierService, calendars calendars, config Config, metrics metrics) Service {
 	return Service{
-		rateStore:     ratestore,
-		priceStore:    pricestore,
-		locationStore: locationstore,
-		logger:        log.With().Str("component", "booking").Logger(),
+		rateStore:      ratestore,
+		priceStore:     pricestore,
+		locationStore:  locationstore,
+		carrierService: carrierService,
+		calendars:      calendars,
+		config:         config,
+		metrics:        metrics,
 	}
 }
 
-func (s Service) CalculateShippingCost(ctx context.Context, origin, destination string, weight float32) (float32, error) {
-	s.logger.Info().Str("origin", origin).Str("destination", destination).Float32("weight", weight)
+func (s Service) CalculateShippingCost(ctx context.Context, origin, destination string, weight float32, serviceLevel ServiceLevel) (_ float32, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.CalculateShippingCost", trace.WithAttributes(
+		attribute.String("billing.origin", origin),
+		attribute.String("billing.destination", destination),
+		attribute.Float64("billing.weight", float64(weight)),
+		attribute.String("billing.service_level", string(serviceLevel)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	q, err := s.quote(ctx, origin, destination, weight, serviceLevel)
+	if err != nil {
+		return 0, err
+	}
+
+	span.SetAttributes(attribute.String("billing.region", q.region), attribute.Float64("billing.price", float64(q.Total())))
+	return q.Total(), nil
+}
+
+// Quote returns how the shipping cost of a parcel is made up.
+func (s Service) Quote(ctx context.Context, origin, destination string, weight float32, serviceLevel ServiceLevel) (_ *quote, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.Quote", trace.WithAttributes(
+		attribute.String("billing.origin", origin),
+		attribute.String("billing.destination", destination),
+		attribute.Float64("billing.weight", float64(weight)),
+		attribute.String("billing.service_level", string(serviceLevel)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return s.quote(ctx, origin, destination, weight, serviceLevel)
+}
+
+// TransitDays returns how many business days a shipment at the service level
+// takes between origin and destination.
+func (s Service) TransitDays(ctx context.Context, origin, destination string, serviceLevel ServiceLevel) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.TransitDays", trace.WithAttributes(
+		attribute.String("billing.origin", origin),
+		attribute.String("billing.destination", destination),
+		attribute.String("billing.service_level", string(serviceLevel)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	_, level, err := s.level(serviceLevel)
+	if err != nil {
+		return 0, err
+	}
+	region, err := s.region(ctx, origin, destination)
+	if err != nil {
+		return 0, err
+	}
+	return level.TransitDays[region], nil
+}
+
+// Estimate returns the dates a shipment handed over at origin at the time is
+// picked up and arrives at destination after the transit days, skipping
+// weekends and holidays at both.
+func (s Service) Estimate(origin, destination string, at time.Time, transitDays int) (pickup, delivery time.Time) {
+	return s.calendars.Estimate(origin, destination, at, transitDays)
+}
+
+func (s Service) quote(ctx context.Context, origin, destination string, weight float32, serviceLevel ServiceLevel) (*quote, error) {
+	logger := logging.FromContext(ctx, "billing")
+	logger.Debug().Str("origin", origin).Str("destination", destination).Float32("weight", weight).Str("serviceLevel", string(serviceLevel)).Msg("calculating shipping cost")
+
+	serviceLevel, level, err := s.level(serviceLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := s.region(ctx, origin, destination)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := s.rateStore.GetRateByRegion(ctx, region)
+	if err != nil {
+		s.metrics.LookupFailed("rate", err)
+		return nil, err
+	}
+
+	weightClass, err := calculateWeightClass(weight)
+	if err != nil {
+		s.metrics.LookupFailed("weight_class", err)
+		return nil, err
+	}
+
+	price, err := s.priceStore.GetPriceByWeightClass(ctx, weightClass)
+	if err != nil {
+		s.metrics.LookupFailed("price", err)
+		return nil, err
+	}
+
+	pickup, delivery := s.Estimate(origin, destination, time.Now(), level.TransitDays[region])
+	q := &quote{
+		region:       region,
+		weightClass:  weightClass,
+		rate:         rate,
+		basePrice:    price,
+		serviceLevel: serviceLevel,
+		multiplier:   level.Multiplier,
+		transitDays:  level.TransitDays[region],
 
+		estimatedPickup:   pickup,
+		estimatedDelivery: delivery,
+	}
+	s.metrics.PriceQuoted(region, q.Total())
+	logger.Debug().Str("region", region).Str("weightClass", weightClass).Float32("price", q.Total()).Msg("calculated shipping cost")
+	return q, nil
+}
+
+func (s Service) region(ctx context.Context, origin, destination string) (string, error) {
 	if origin == "" {
-		return 0, errors.FromMessage("empty origin", errors.ErrorInput)
+		return "", errors.Validation("empty origin", errors.FieldError{Field: "origin", Code: errors.FieldRequired, Message: "origin is required"})
 	}
 	if destination == "" {
-		return 0, errors.FromMessage("empty destination", errors.ErrorInput)
+		return "", errors.Validation("empty destination", errors.FieldError{Field: "destination", Code: errors.FieldRequired, Message: "destination is required"})
 	}
 
 	originLocation, err := s.locationStore.GetByCode(ctx, origin)
 	if err != nil {
-		return 0, err
+		s.metrics.LookupFailed("location", err)
+		return "", errors.WithField(err, "origin")
 	}
 
 	destinationLocation, err := s.locationStore.GetByCode(ctx, destination)
 	if err != nil {
-		return 0, err
+		s.metrics.LookupFailed("location", err)
+		return "", errors.WithField(err, "destination")
 	}
 
-	rate, err := s.rateStore.GetRateByRegion(ctx, getRegion(originLocation, destinationLocation))
+	return getRegion(originLocation, destinationLocation), nil
+}
+
+func (s Service) GetLocation(ctx context.Context, code string) (_ *location, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.GetLocation", trace.WithAttributes(attribute.String("billing.location", code)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.locationStore.GetByCode(ctx, code)
+}
+
+func (s Service) GetLocations(ctx context.Context) (_ []*location, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.GetLocations")
+	defer func() { tracing.End(span, err) }()
+
+	return s.locationStore.GetLocations(ctx)
+}
+
+func (s Service) GetTariffs(ctx context.Context) (_ map[string]float32, _ map[string]float32, err error) {
+	ctx, span := tracer.Start(ctx, "billing.Service.GetTariffs")
+	defer func() { tracing.End(span, err) }()
+
+	rates, err := s.rateStore.GetRates(ctx)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 
-	weightClass, err := calculateWeightClass(weight)
+	prices, err := s.priceStore.GetPrices(ctx)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 
-	price, err := s.priceStore.GetPriceByWeightClass(ctx, weightClass)
+	return rates, prices, nil
+}
+
+// CheckTables verifies that there is a rate for every region and a price for
+// every weight class, without them no shipping cost can be calculated. The
+// standard level and the transit days of every offered level for every
+// region are checked as well.
+func (s Service) CheckTables(ctx context.Context) error {
+	rates, prices, err := s.GetTariffs(ctx)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	totalPrice := price * rate
-	return totalPrice, nil
+	for _, region := range regions {
+		if _, ok := rates[region]; !ok {
+			return errors.FromCode(errors.CodeRateNotFound, fmt.Sprintf("no rate for region %s", region), errors.ErrorInternal)
+		}
+	}
+	for _, class := range weightClasses {
+		if _, ok := prices[class]; !ok {
+			return errors.FromCode(errors.CodePriceNotFound, fmt.Sprintf("no price for weight class %s", class), errors.ErrorInternal)
+		}
+	}
+	if _, ok := s.config.ServiceLevels[ServiceLevelStandard]; !ok {
+		return errors.FromCode(errors.CodeServiceLevelNotOffered, "standard service level is not offered", errors.ErrorInternal)
+	}
+	for _, serviceLevel := range serviceLevels {
+		level, ok := s.config.ServiceLevels[serviceLevel]
+		if !ok {
+			continue
+		}
+		for _, region := range regions {
+			if _, ok := level.TransitDays[region]; !ok {
+				return errors.FromCode(errors.CodeServiceLevelNotOffered, fmt.Sprintf("no transit days for region %s at service level %s", region, serviceLevel), errors.ErrorInternal)
+			}
+		}
+	}
+
+	return nil
 }
 
-var ErrorInvalidWeight = errors.FromMessage("invalid weight", errors.ErrorInput)
+var weightClasses = []string{"small", "medium", "large", "huge"}
+
+var ErrorInvalidWeight = errors.FromCode(errors.CodeWeightOutOfRange, "invalid weight, must be between 0 and 1000", errors.ErrorInput)
 
 func calculateWeightClass(weight float32) (string, error) {
 	switch {
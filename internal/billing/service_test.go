@@ -4,6 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
 
 	"github.com/stretchr/testify/require"
 )
@@ -38,6 +43,7 @@ func TestBookShipping(t *testing.T) {
 		origin         string
 		destination    string
 		weight         float32
+		serviceLevel   ServiceLevel
 		locationReturn locationReturn
 		priceReturn    priceReturn
 		rateReturn     rateReturn
@@ -53,6 +59,28 @@ func TestBookShipping(t *testing.T) {
 			locationReturn: successfulLocation,
 			shouldFail:     false,
 		},
+		{
+			name:           "express booking",
+			origin:         "SE",
+			destination:    "SE",
+			weight:         100,
+			serviceLevel:   ServiceLevelExpress,
+			rateReturn:     successfulRate,
+			priceReturn:    successfulPrice,
+			locationReturn: successfulLocation,
+			shouldFail:     false,
+		},
+		{
+			name:           "unknown service level",
+			origin:         "SE",
+			destination:    "SE",
+			weight:         100,
+			serviceLevel:   "overnight",
+			rateReturn:     successfulRate,
+			priceReturn:    successfulPrice,
+			locationReturn: successfulLocation,
+			shouldFail:     true,
+		},
 		{
 			name:           "bad origin",
 			origin:         "",
@@ -141,6 +169,7 @@ func TestBookShipping(t *testing.T) {
 				tc.origin,
 				tc.destination,
 				tc.weight,
+				tc.serviceLevel,
 			)
 			if tc.shouldFail {
 				require.NotNil(t, err, "expected an error, got nil")
@@ -153,6 +182,236 @@ func TestBookShipping(t *testing.T) {
 	}
 }
 
+func TestQuote(t *testing.T) {
+	testCases := []struct {
+		name                string
+		origin              *location
+		destination         *location
+		weight              float32
+		serviceLevel        ServiceLevel
+		expectedRegion      string
+		expectedWeightClass string
+		expectedLevel       ServiceLevel
+		expectedTransitDays int
+		expectedTotal       float32
+	}{
+		{
+			name:                "domestic",
+			origin:              &location{code: "SE", hasEUMembership: true},
+			destination:         &location{code: "SE", hasEUMembership: true},
+			weight:              5,
+			expectedRegion:      "domestic",
+			expectedWeightClass: "small",
+			expectedLevel:       ServiceLevelStandard,
+			expectedTransitDays: 2,
+			expectedTotal:       200,
+		},
+		{
+			name:                "international",
+			origin:              &location{code: "SE", hasEUMembership: true},
+			destination:         &location{code: "US", hasEUMembership: false},
+			weight:              30,
+			expectedRegion:      "international",
+			expectedWeightClass: "large",
+			expectedLevel:       ServiceLevelStandard,
+			expectedTransitDays: 6,
+			expectedTotal:       200,
+		},
+		{
+			name:                "express",
+			origin:              &location{code: "SE", hasEUMembership: true},
+			destination:         &location{code: "DK", hasEUMembership: true},
+			weight:              5,
+			serviceLevel:        ServiceLevelExpress,
+			expectedRegion:      "eu",
+			expectedWeightClass: "small",
+			expectedLevel:       ServiceLevelExpress,
+			expectedTransitDays: 1,
+			expectedTotal:       360,
+		},
+		{
+			name:                "economy",
+			origin:              &location{code: "SE", hasEUMembership: true},
+			destination:         &location{code: "DK", hasEUMembership: true},
+			weight:              5,
+			serviceLevel:        ServiceLevelEconomy,
+			expectedRegion:      "eu",
+			expectedWeightClass: "small",
+			expectedLevel:       ServiceLevelEconomy,
+			expectedTransitDays: 5,
+			expectedTotal:       160,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.ratestore.rate = successfulRate.rate
+			bundle.pricestore.price = successfulPrice.price
+			bundle.locationstore.byCode = map[string]*location{
+				tc.origin.code:      tc.origin,
+				tc.destination.code: tc.destination,
+			}
+
+			q, err := bundle.service.Quote(context.Background(), tc.origin.code, tc.destination.code, tc.weight, tc.serviceLevel)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedLevel, q.ServiceLevel())
+			require.Equal(t, tc.expectedTransitDays, q.TransitDays())
+			require.False(t, q.EstimatedPickup().Before(time.Now().UTC().AddDate(0, 0, -1)), "expected the pickup from today on")
+			require.False(t, q.EstimatedDelivery().Before(q.EstimatedPickup().AddDate(0, 0, tc.expectedTransitDays)), "expected the delivery after the transit days")
+			require.Equal(t, tc.expectedRegion, q.Region())
+			require.Equal(t, tc.expectedWeightClass, q.WeightClass())
+			require.Equal(t, successfulRate.rate, q.Rate())
+			require.Equal(t, successfulPrice.price, q.BasePrice())
+			require.InDelta(t, tc.expectedTotal, q.Total(), 1e-6)
+		})
+	}
+}
+
+func TestTransitDays(t *testing.T) {
+	bundle := newTestBundle()
+	bundle.service.config.ServiceLevels = map[ServiceLevel]Level{ServiceLevelStandard: DefaultServiceLevels()[ServiceLevelStandard]}
+	bundle.locationstore.location = &location{code: "SE"}
+
+	days, err := bundle.service.TransitDays(context.Background(), "SE", "SE", "")
+	require.Nil(t, err)
+	require.Equal(t, 2, days)
+
+	_, err = bundle.service.TransitDays(context.Background(), "SE", "SE", ServiceLevelExpress)
+	require.Equal(t, apierrors.CodeServiceLevelNotOffered, apierrors.GetCode(err))
+}
+
+func TestGetTariffs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		priceReturn priceReturn
+		rateReturn  rateReturn
+		shouldFail  bool
+	}{
+		{
+			name:        "good tariffs",
+			rateReturn:  successfulRate,
+			priceReturn: successfulPrice,
+			shouldFail:  false,
+		},
+		{
+			name:        "rate error",
+			rateReturn:  errorRate,
+			priceReturn: successfulPrice,
+			shouldFail:  true,
+		},
+		{
+			name:        "price error",
+			rateReturn:  successfulRate,
+			priceReturn: errorPrice,
+			shouldFail:  true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.ratestore.rate = tc.rateReturn.rate
+			bundle.ratestore.err = tc.rateReturn.err
+			bundle.pricestore.price = tc.priceReturn.price
+			bundle.pricestore.err = tc.priceReturn.err
+			rates, prices, err := bundle.service.GetTariffs(context.Background())
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.rateReturn.rate, rates["domestic"])
+			require.Equal(t, tc.priceReturn.price, prices["small"])
+		})
+	}
+}
+
+func TestCheckTables(t *testing.T) {
+	allRates := map[string]float32{"domestic": 1, "eu": 1.5, "international": 2.5}
+	allPrices := map[string]float32{"small": 100, "medium": 300, "large": 500, "huge": 2000}
+	standardOnly := map[ServiceLevel]Level{ServiceLevelStandard: DefaultServiceLevels()[ServiceLevelStandard]}
+
+	testCases := []struct {
+		name          string
+		rates         map[string]float32
+		prices        map[string]float32
+		serviceLevels map[ServiceLevel]Level
+		shouldFail    bool
+	}{
+		{
+			name:          "complete tables",
+			rates:         allRates,
+			prices:        allPrices,
+			serviceLevels: DefaultServiceLevels(),
+			shouldFail:    false,
+		},
+		{
+			name:          "standard level only",
+			rates:         allRates,
+			prices:        allPrices,
+			serviceLevels: standardOnly,
+			shouldFail:    false,
+		},
+		{
+			name:          "missing rate",
+			rates:         map[string]float32{"domestic": 1, "eu": 1.5},
+			prices:        allPrices,
+			serviceLevels: DefaultServiceLevels(),
+			shouldFail:    true,
+		},
+		{
+			name:          "missing price",
+			rates:         allRates,
+			prices:        map[string]float32{"small": 100},
+			serviceLevels: DefaultServiceLevels(),
+			shouldFail:    true,
+		},
+		{
+			name:          "missing standard level",
+			rates:         allRates,
+			prices:        allPrices,
+			serviceLevels: map[ServiceLevel]Level{ServiceLevelExpress: DefaultServiceLevels()[ServiceLevelExpress]},
+			shouldFail:    true,
+		},
+		{
+			name:          "missing transit days",
+			rates:         allRates,
+			prices:        allPrices,
+			serviceLevels: map[ServiceLevel]Level{ServiceLevelStandard: {Multiplier: 1, TransitDays: map[string]int{"domestic": 1}}},
+			shouldFail:    true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			service := NewService(
+				NewInMemoryRateStore(tc.rates),
+				NewInMemoryPriceStore(tc.prices),
+				NewInMemoryLocationStore(),
+				carrierServiceMock{},
+				calendar.NewCalendars(calendar.New(calendar.Config{}), nil),
+				Config{ServiceLevels: tc.serviceLevels},
+				metricsMock{},
+			)
+			err := service.CheckTables(context.Background())
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+		})
+	}
+}
+
 type ratestoreMock struct {
 	rate float32
 	err  error
@@ -162,6 +421,10 @@ func (r ratestoreMock) GetRateByRegion(_ context.Context, region string) (float3
 	return r.rate, r.err
 }
 
+func (r ratestoreMock) GetRates(_ context.Context) (map[string]float32, error) {
+	return map[string]float32{"domestic": r.rate}, r.err
+}
+
 type pricestoreMock struct {
 	price float32
 	err   error
@@ -171,15 +434,41 @@ func (r pricestoreMock) GetPriceByWeightClass(_ context.Context, class string) (
 	return r.price, r.err
 }
 
+func (r pricestoreMock) GetPrices(_ context.Context) (map[string]float32, error) {
+	return map[string]float32{"small": r.price}, r.err
+}
+
 type locationstoreMock struct {
 	location *location
+	byCode   map[string]*location
 	err      error
 }
 
 func (r locationstoreMock) GetByCode(_ context.Context, code string) (*location, error) {
+	if l, ok := r.byCode[code]; ok {
+		return l, nil
+	}
 	return r.location, r.err
 }
 
+func (r locationstoreMock) GetLocations(_ context.Context) ([]*location, error) {
+	return []*location{r.location}, r.err
+}
+
+type carrierServiceMock struct {
+	adapters []carrier.Adapter
+}
+
+func (c carrierServiceMock) Carriers(origin, destination string) []carrier.Adapter {
+	return c.adapters
+}
+
+type metricsMock struct{}
+
+func (m metricsMock) PriceQuoted(region string, price float32) {}
+
+func (m metricsMock) LookupFailed(lookup string, err error) {}
+
 type bundle struct {
 	service       Service
 	ratestore     *ratestoreMock
@@ -192,7 +481,7 @@ func newTestBundle() bundle {
 	pricestore := &pricestoreMock{}
 	ratestore := &ratestoreMock{}
 	return bundle{
-		service:       NewService(ratestore, pricestore, locationstore),
+		service:       NewService(ratestore, pricestore, locationstore, carrierServiceMock{}, calendar.NewCalendars(calendar.New(calendar.Config{}), nil), Config{ServiceLevels: DefaultServiceLevels()}, metricsMock{}),
 		ratestore:     ratestore,
 		pricestore:    pricestore,
 		locationstore: locationstore,
@@ -0,0 +1,47 @@
+package booking
+
+import (
+	"errors"
+)
+
+type address struct {
+	name       string
+	street     string
+	postalCode string
+	city       string
+	country    string
+}
+
+func NewAddress(name, street, postalCode, city, country string) (address, error) {
+	if country == "" {
+		return address{}, errors.New("country is empty")
+	}
+
+	return address{
+		name:       name,
+		street:     street,
+		postalCode: postalCode,
+		city:       city,
+		country:    country,
+	}, nil
+}
+
+func (a address) Name() string {
+	return a.name
+}
+
+func (a address) Street() string {
+	return a.street
+}
+
+func (a address) PostalCode() string {
+	return a.postalCode
+}
+
+func (a address) City() string {
+	return a.city
+}
+
+func (a address) Country() string {
+	return a.country
+}
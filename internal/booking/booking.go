@@ -2,20 +2,40 @@ package booking
 
 import (
 	"errors"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/billing"
 )
 
+const dateLayout = "2006-01-02"
+
 type booking struct {
-	id          string
-	origin      string
-	destination string
-	weight      float32
-	price       float32
+	id                 string
+	clientId           string
+	origin             string
+	destination        string
+	weight             float32
+	price              float32
+	originAddress      address
+	destinationAddress address
+	parcels            []parcel
+	history            []statusChange
+	carrier            string
+	carrierReference   string
+	serviceLevel       billing.ServiceLevel
+	transitDays        int
+	estimatedPickup    time.Time
+	estimatedDelivery  time.Time
+	events             []Event
 }
 
-func NewBooking(id string, origin, destination string, weight float32, price float32) (*booking, error) {
+func NewBooking(id, clientId string, origin, destination string, weight float32, price float32) (*booking, error) {
 	if id == "" {
 		return nil, errors.New("id is empty")
 	}
+	if clientId == "" {
+		return nil, errors.New("client id is empty")
+	}
 	if origin == "" {
 		return nil, errors.New("origin is empty")
 	}
@@ -30,18 +50,52 @@ func NewBooking(id string, origin, destination string, weight float32, price flo
 	}
 
 	return &booking{
-		id:          id,
-		origin:      origin,
-		destination: destination,
-		weight:      weight,
-		price:       price,
+		id:                 id,
+		clientId:           clientId,
+		origin:             origin,
+		destination:        destination,
+		weight:             weight,
+		price:              price,
+		originAddress:      address{country: origin},
+		destinationAddress: address{country: destination},
+		parcels:            []parcel{{weight: weight}},
+		history:            []statusChange{{status: StatusBooked, at: time.Now().UTC()}},
+		serviceLevel:       billing.ServiceLevelStandard,
 	}, nil
 }
 
+// NewParcelBooking returns a booking between two addresses, its origin and
+// destination are the countries of the addresses and its weight the total
+// weight of the parcels.
+func NewParcelBooking(id, clientId string, origin, destination address, parcels []parcel, price float32) (*booking, error) {
+	if len(parcels) == 0 {
+		return nil, errors.New("no parcels")
+	}
+
+	var weight float32
+	for _, p := range parcels {
+		weight += p.weight
+	}
+
+	b, err := NewBooking(id, clientId, origin.country, destination.country, weight, price)
+	if err != nil {
+		return nil, err
+	}
+
+	b.originAddress = origin
+	b.destinationAddress = destination
+	b.parcels = append([]parcel(nil), parcels...)
+	return b, nil
+}
+
 func (s *booking) Id() string {
 	return s.id
 }
 
+func (s *booking) ClientId() string {
+	return s.clientId
+}
+
 func (s *booking) Origin() string {
 	return s.origin
 }
@@ -57,3 +111,103 @@ func (s *booking) Weight() float32 {
 func (s *booking) Price() float32 {
 	return s.price
 }
+
+func (s *booking) OriginAddress() address {
+	return s.originAddress
+}
+
+func (s *booking) DestinationAddress() address {
+	return s.destinationAddress
+}
+
+func (s *booking) Parcels() []parcel {
+	return append([]parcel(nil), s.parcels...)
+}
+
+// Carrier is the code of the carrier the booking was handed to, empty for
+// bookings made before carriers were integrated.
+func (s *booking) Carrier() string {
+	return s.carrier
+}
+
+// CarrierReference is what the carrier calls the shipment.
+func (s *booking) CarrierReference() string {
+	return s.carrierReference
+}
+
+func (s *booking) assignCarrier(carrier, reference string) {
+	s.carrier = carrier
+	s.carrierReference = reference
+}
+
+// ServiceLevel is standard for bookings made before service levels.
+func (s *booking) ServiceLevel() billing.ServiceLevel {
+	return s.serviceLevel
+}
+
+// TransitDays, EstimatedPickup and EstimatedDelivery are zero for bookings
+// made before they were estimated.
+func (s *booking) TransitDays() int {
+	return s.transitDays
+}
+
+func (s *booking) EstimatedPickup() time.Time {
+	return s.estimatedPickup
+}
+
+func (s *booking) EstimatedDelivery() time.Time {
+	return s.estimatedDelivery
+}
+
+func (s *booking) schedule(serviceLevel billing.ServiceLevel, transitDays int, estimatedPickup, estimatedDelivery time.Time) {
+	s.serviceLevel = serviceLevel
+	s.transitDays = transitDays
+	s.estimatedPickup = estimatedPickup
+	s.estimatedDelivery = estimatedDelivery
+}
+
+func (s *booking) Status() Status {
+	return s.history[len(s.history)-1].status
+}
+
+func (s *booking) CreatedAt() time.Time {
+	return s.history[0].at
+}
+
+// StatusHistory returns every status the booking has had, oldest first.
+func (s *booking) StatusHistory() []statusChange {
+	return append([]statusChange(nil), s.history...)
+}
+
+// statusChangedAt returns when the booking got its current status.
+func (s *booking) statusChangedAt() time.Time {
+	return s.history[len(s.history)-1].at
+}
+
+func (s *booking) setStatus(status Status, at time.Time) {
+	s.history = append(s.history, statusChange{status: status, at: at})
+}
+
+// undoStatus takes the booking back to the status it had before the current
+// one, for changes that could not be carried through.
+func (s *booking) undoStatus() {
+	if len(s.history) > 1 {
+		s.history = s.history[:len(s.history)-1]
+	}
+}
+
+// formatDate returns the date of t, empty for the zero time.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
+
+// parseDate parses a date written by formatDate.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, s)
+}
@@ -10,6 +10,7 @@ func TestNewBooking(t *testing.T) {
 	testCases := []struct {
 		name        string
 		id          string
+		clientId    string
 		origin      string
 		destination string
 		weight      float32
@@ -19,6 +20,7 @@ func TestNewBooking(t *testing.T) {
 		{
 			name:        "valid booking",
 			id:          "test-id",
+			clientId:    "test-client",
 			origin:      "SE",
 			destination: "DK",
 			weight:      300,
@@ -27,6 +29,17 @@ func TestNewBooking(t *testing.T) {
 		{
 			name:        "missing id",
 			id:          "",
+			clientId:    "test-client",
+			origin:      "SE",
+			destination: "DK",
+			weight:      300,
+			price:       300,
+			shouldFail:  true,
+		},
+		{
+			name:        "missing client id",
+			id:          "test-id",
+			clientId:    "",
 			origin:      "SE",
 			destination: "DK",
 			weight:      300,
@@ -36,6 +49,7 @@ func TestNewBooking(t *testing.T) {
 		{
 			name:        "missing origin",
 			id:          "test-id",
+			clientId:    "test-client",
 			origin:      "",
 			destination: "DK",
 			weight:      300,
@@ -45,6 +59,7 @@ func TestNewBooking(t *testing.T) {
 		{
 			name:        "missing destination",
 			id:          "test-id",
+			clientId:    "test-client",
 			origin:      "SE",
 			destination: "",
 			weight:      300,
@@ -54,6 +69,7 @@ func TestNewBooking(t *testing.T) {
 		{
 			name:        "bad weight",
 			id:          "test-id",
+			clientId:    "test-client",
 			origin:      "SE",
 			destination: "DK",
 			weight:      0,
@@ -63,6 +79,7 @@ func TestNewBooking(t *testing.T) {
 		{
 			name:        "bad price",
 			id:          "test-id",
+			clientId:    "test-client",
 			origin:      "SE",
 			destination: "DK",
 			weight:      300,
@@ -74,7 +91,7 @@ func TestNewBooking(t *testing.T) {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			_, err := NewBooking(tc.id, tc.origin, tc.destination, tc.weight, tc.price)
+			_, err := NewBooking(tc.id, tc.clientId, tc.origin, tc.destination, tc.weight, tc.price)
 
 			if tc.shouldFail {
 				require.NotNil(t, err, "expected an error, got nil")
@@ -0,0 +1,97 @@
+// Package bookingtest provides a booking service for the tests of the
+// packages that act on bookings.
+package bookingtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Price is what every booking of the bundle costs.
+const Price = 100
+
+type billingMock struct{}
+
+func (b billingMock) CalculateShippingCost(_ context.Context, _, _ string, _ float32, _ billing.ServiceLevel) (float32, error) {
+	return Price, nil
+}
+
+func (b billingMock) TransitDays(_ context.Context, _, _ string, _ billing.ServiceLevel) (int, error) {
+	return 2, nil
+}
+
+func (b billingMock) Estimate(_, _ string, at time.Time, transitDays int) (time.Time, time.Time) {
+	return at, at.AddDate(0, 0, transitDays)
+}
+
+func (b billingMock) SelectOption(_ context.Context, _ carrier.Request, _ string, _ billing.Policy) (carrier.Rate, error) {
+	return carrier.Rate{Carrier: "fake", Price: Price}, nil
+}
+
+type metricsMock struct{}
+
+func (m metricsMock) BookingCreated(origin, destination string) {}
+
+// Outbox is the outbox of the booking store.
+type Outbox interface {
+	PendingEvents(ctx context.Context, after string, limit int) ([]booking.Event, error)
+	MarkPublished(ctx context.Context, ids ...string) error
+}
+
+// Carrier is the fake carrier every booking ships with.
+type Carrier interface {
+	// Fail makes every call to the carrier fail with err until it is called
+	// with nil.
+	Fail(err error)
+}
+
+// Handler takes the events Relay publishes.
+type Handler interface {
+	Publish(ctx context.Context, e booking.Event) error
+}
+
+// Bundle is a booking service on an in-memory store, shipping with a fake
+// carrier.
+type Bundle struct {
+	BookingService *booking.Service
+	Store          Outbox
+	Carrier        Carrier
+}
+
+func New(t *testing.T) Bundle {
+	fake := carrier.NewFake("fake", carrier.FakeConfig{})
+	carrierService, err := carrier.NewService(carrier.Lanes{"*-*": {"fake"}}, fake)
+	require.Nil(t, err)
+	store := booking.NewInMemoryStore()
+	bookingService := booking.NewService(store, billingMock{}, carrierService, metricsMock{})
+	return Bundle{BookingService: &bookingService, Store: store, Carrier: fake}
+}
+
+// Book books a shipment of the client from the origin to DK and returns its
+// id.
+func (b Bundle) Book(t *testing.T, clientId, origin string) string {
+	id, err := b.BookingService.BookShipping(context.Background(), clientId, origin, "DK", 1, booking.Selection{})
+	require.Nil(t, err)
+	return id
+}
+
+// Relay publishes the pending events to the handlers and marks them
+// published, as the outbox relay does.
+func (b Bundle) Relay(t *testing.T, handlers ...Handler) {
+	ctx := context.Background()
+	events, err := b.Store.PendingEvents(ctx, "", 100)
+	require.Nil(t, err)
+	for _, e := range events {
+		for _, h := range handlers {
+			require.Nil(t, h.Publish(ctx, e))
+		}
+		require.Nil(t, b.Store.MarkPublished(ctx, e.Id))
+	}
+}
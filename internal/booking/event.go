@@ -0,0 +1,59 @@
+package booking
+
+import (
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/carrier"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventBookingCreated       EventType = "booking.created"
+	EventBookingPriced        EventType = "booking.priced"
+	EventBookingStatusChanged EventType = "booking.status_changed"
+	EventBookingCancelled     EventType = "booking.cancelled"
+)
+
+var EventTypes = []EventType{EventBookingCreated, EventBookingPriced, EventBookingStatusChanged, EventBookingCancelled}
+
+// Event tells that a booking has changed. Events are recorded on the booking
+// and written to the outbox of the store together with it, so an event exists
+// exactly when its change was stored. Cancelling a booking records
+// booking.cancelled rather than booking.status_changed.
+type Event struct {
+	Id         string
+	Type       EventType
+	OccurredAt time.Time
+	ClientId   string
+	BookingId  string
+	Status     Status
+	// Price and Currency are only set on booking.priced.
+	Price    float32
+	Currency string
+}
+
+// record adds an event about the current state of the booking.
+func (s *booking) record(t EventType, at time.Time) {
+	e := Event{
+		Id:         uuid.New().String(),
+		Type:       t,
+		OccurredAt: at,
+		ClientId:   s.clientId,
+		BookingId:  s.id,
+		Status:     s.Status(),
+	}
+	if t == EventBookingPriced {
+		e.Price = s.price
+		e.Currency = carrier.Currency
+	}
+	s.events = append(s.events, e)
+}
+
+// Events returns the events recorded since the booking was created or
+// loaded, the store adds them to its outbox.
+func (s *booking) Events() []Event {
+	return append([]Event(nil), s.events...)
+}
@@ -0,0 +1,352 @@
+package booking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type fileAddress struct {
+	Name       string `json:"name,omitempty"`
+	Street     string `json:"street,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	City       string `json:"city,omitempty"`
+	Country    string `json:"country"`
+}
+
+type fileParcel struct {
+	Weight float32 `json:"weight"`
+	Length float32 `json:"length,omitempty"`
+	Width  float32 `json:"width,omitempty"`
+	Height float32 `json:"height,omitempty"`
+}
+
+type fileStatusChange struct {
+	Status Status    `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+type fileBooking struct {
+	Id               string             `json:"id"`
+	ClientId         string             `json:"clientId"`
+	Origin           fileAddress        `json:"origin"`
+	Destination      fileAddress        `json:"destination"`
+	Parcels          []fileParcel       `json:"parcels"`
+	Price            float32            `json:"price"`
+	History          []fileStatusChange `json:"history"`
+	Carrier          string             `json:"carrier,omitempty"`
+	CarrierReference string             `json:"carrierReference,omitempty"`
+	// ServiceLevel is empty in files written before service levels.
+	ServiceLevel      billing.ServiceLevel `json:"serviceLevel,omitempty"`
+	TransitDays       int                  `json:"transitDays,omitempty"`
+	EstimatedPickup   string               `json:"estimatedPickup,omitempty"`
+	EstimatedDelivery string               `json:"estimatedDelivery,omitempty"`
+}
+
+type fileEvent struct {
+	Id         string    `json:"id"`
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	ClientId   string    `json:"clientId"`
+	BookingId  string    `json:"bookingId"`
+	Status     Status    `json:"status"`
+	Price      float32   `json:"price,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+}
+
+// fileContent is what the file holds. Files written before the outbox hold
+// only the array of bookings.
+type fileContent struct {
+	Bookings []fileBooking `json:"bookings"`
+	Outbox   []fileEvent   `json:"outbox"`
+}
+
+// fileStore keeps the bookings in memory and writes all of them to a JSON
+// file on every change, without a path they are only kept in memory. A
+// change is written under the lock of the store and undone in memory when
+// the write fails, so that neither readers nor the outbox relay ever see a
+// booking or an event that is not in the file. Saves replace the file whole,
+// so a crash never leaves half a file.
+type fileStore struct {
+	inMemoryStore
+	path    string
+	saveMtx *sync.Mutex
+}
+
+func NewFileStore(path string) (fileStore, error) {
+	s := fileStore{inMemoryStore: NewInMemoryStore(), path: path, saveMtx: &sync.Mutex{}}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, errors.WrapStore(err, "booking", "load")
+	}
+
+	var content fileContent
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &content.Bookings)
+	} else {
+		err = json.Unmarshal(data, &content)
+	}
+	if err != nil {
+		return s, errors.WrapStore(err, "booking", "load")
+	}
+	for _, b := range content.Bookings {
+		m, err := fromFileBooking(b)
+		if err != nil {
+			return s, errors.WrapStore(err, "booking", "load")
+		}
+		s.bookings[m.id] = m
+	}
+	for _, e := range content.Outbox {
+		*s.outbox = append(*s.outbox, Event(e))
+	}
+
+	return s, nil
+}
+
+func (r fileStore) AddBooking(_ context.Context, b *booking) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	undo := r.keep(b.Id())
+	events := b.events
+	if err := r.addBooking(b); err != nil {
+		return err
+	}
+	if err := r.commit(undo); err != nil {
+		b.events = events
+		return err
+	}
+	return nil
+}
+
+func (r fileStore) UpdateBooking(_ context.Context, b *booking) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	undo := r.keep(b.Id())
+	events := b.events
+	if err := r.updateBooking(b); err != nil {
+		return err
+	}
+	if err := r.commit(undo); err != nil {
+		b.events = events
+		return err
+	}
+	return nil
+}
+
+func (r fileStore) TransitionBooking(_ context.Context, id string, transition func(*booking) (bool, error)) (*booking, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	undo := r.keep(id)
+	b, changed, err := r.transitionBooking(id, transition)
+	if err != nil || !changed {
+		return b, err
+	}
+	if err := r.commit(undo); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r fileStore) MarkPublished(_ context.Context, ids ...string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	undo := r.keep()
+	r.markPublished(ids...)
+	return r.commit(undo)
+}
+
+func (r fileStore) Close(_ context.Context) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.save()
+}
+
+// keep returns a func that puts back the bookings with the ids and the outbox
+// as they are now. The lock must be held.
+func (r fileStore) keep(ids ...string) func() {
+	kept := make(map[string]bookingModel, len(ids))
+	for _, id := range ids {
+		if m, ok := r.bookings[id]; ok {
+			kept[id] = m
+		}
+	}
+	// appends to the outbox leave its first elements as they are and
+	// markPublished replaces it, so the slice itself is a copy
+	outbox := *r.outbox
+	return func() {
+		for _, id := range ids {
+			if m, ok := kept[id]; ok {
+				r.bookings[id] = m
+			} else {
+				delete(r.bookings, id)
+			}
+		}
+		*r.outbox = outbox
+	}
+}
+
+// commit saves the change made in memory, or undoes it if the save fails.
+// The lock must be held.
+func (r fileStore) commit(undo func()) error {
+	if err := r.save(); err != nil {
+		undo()
+		return err
+	}
+	return nil
+}
+
+// save writes the bookings and the outbox to the file. The lock must be held,
+// for reading at least.
+func (r fileStore) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	r.saveMtx.Lock()
+	defer r.saveMtx.Unlock()
+
+	content := fileContent{
+		Bookings: make([]fileBooking, 0, len(r.bookings)),
+		Outbox:   make([]fileEvent, 0, len(*r.outbox)),
+	}
+	for _, m := range r.bookings {
+		content.Bookings = append(content.Bookings, toFileBooking(m))
+	}
+	for _, e := range *r.outbox {
+		content.Outbox = append(content.Outbox, fileEvent(e))
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return errors.WrapStore(err, "booking", "save")
+	}
+	if err := writeFile(r.path, data); err != nil {
+		return errors.WrapStore(err, "booking", "save")
+	}
+	return nil
+}
+
+// writeFile writes the data to a temporary file next to path and renames it
+// over path once it is synced.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func toFileBooking(m bookingModel) fileBooking {
+	parcels := make([]fileParcel, 0, len(m.parcels))
+	for _, p := range m.parcels {
+		parcels = append(parcels, fileParcel{Weight: p.weight, Length: p.length, Width: p.width, Height: p.height})
+	}
+	history := make([]fileStatusChange, 0, len(m.history))
+	for _, c := range m.history {
+		history = append(history, fileStatusChange{Status: c.status, At: c.at})
+	}
+
+	return fileBooking{
+		Id:               m.id,
+		ClientId:         m.clientId,
+		Origin:           toFileAddress(m.originAddress),
+		Destination:      toFileAddress(m.destinationAddress),
+		Parcels:          parcels,
+		Price:            m.price,
+		History:          history,
+		Carrier:          m.carrier,
+		CarrierReference: m.carrierReference,
+
+		ServiceLevel:      m.serviceLevel,
+		TransitDays:       m.transitDays,
+		EstimatedPickup:   formatDate(m.estimatedPickup),
+		EstimatedDelivery: formatDate(m.estimatedDelivery),
+	}
+}
+
+// fromFileBooking goes through the constructors so that a hand edited file
+// can not load an invalid booking.
+func fromFileBooking(f fileBooking) (bookingModel, error) {
+	origin, err := NewAddress(f.Origin.Name, f.Origin.Street, f.Origin.PostalCode, f.Origin.City, f.Origin.Country)
+	if err != nil {
+		return bookingModel{}, err
+	}
+	destination, err := NewAddress(f.Destination.Name, f.Destination.Street, f.Destination.PostalCode, f.Destination.City, f.Destination.Country)
+	if err != nil {
+		return bookingModel{}, err
+	}
+	parcels := make([]parcel, 0, len(f.Parcels))
+	for _, p := range f.Parcels {
+		parcel, err := NewParcel(p.Weight, p.Length, p.Width, p.Height)
+		if err != nil {
+			return bookingModel{}, err
+		}
+		parcels = append(parcels, parcel)
+	}
+
+	b, err := NewParcelBooking(f.Id, f.ClientId, origin, destination, parcels, f.Price)
+	if err != nil {
+		return bookingModel{}, err
+	}
+	if len(f.History) > 0 {
+		b.history = make([]statusChange, 0, len(f.History))
+		for _, c := range f.History {
+			b.history = append(b.history, statusChange{status: c.Status, at: c.At})
+		}
+	}
+	b.assignCarrier(f.Carrier, f.CarrierReference)
+	if f.ServiceLevel != "" {
+		estimatedPickup, err := parseDate(f.EstimatedPickup)
+		if err != nil {
+			return bookingModel{}, err
+		}
+		estimatedDelivery, err := parseDate(f.EstimatedDelivery)
+		if err != nil {
+			return bookingModel{}, err
+		}
+		b.schedule(f.ServiceLevel, f.TransitDays, estimatedPickup, estimatedDelivery)
+	}
+	return marshalBooking(b), nil
+}
+
+func toFileAddress(a address) fileAddress {
+	return fileAddress{
+		Name:       a.name,
+		Street:     a.street,
+		PostalCode: a.postalCode,
+		City:       a.city,
+		Country:    a.country,
+	}
+}
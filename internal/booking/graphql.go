@@ -0,0 +1,304 @@
+package booking
+
+import (
+	"context"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+
+	"github.com/graphql-go/graphql"
+)
+
+// GraphQLLinks resolves the billing objects a booking refers to, so that
+// they can be fetched together with the booking.
+type GraphQLLinks struct {
+	LocationType *graphql.Object
+	Location     func(ctx context.Context, code string) (interface{}, error)
+	QuoteType    *graphql.Object
+	Quote        func(ctx context.Context, origin, destination string, weight float32, serviceLevel billing.ServiceLevel) (interface{}, error)
+	// ServiceLevelType is the enum of the service levels of quotes.
+	ServiceLevelType *graphql.Enum
+}
+
+var addressType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Address",
+	Fields: graphql.Fields{
+		"name":       stringField(func(a address) string { return a.name }),
+		"street":     stringField(func(a address) string { return a.street }),
+		"postalCode": stringField(func(a address) string { return a.postalCode }),
+		"city":       stringField(func(a address) string { return a.city }),
+		"country":    stringField(func(a address) string { return a.country }),
+	},
+})
+
+var parcelType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Parcel",
+	Fields: graphql.Fields{
+		"weight": floatField(func(p parcel) float32 { return p.weight }),
+		"length": floatField(func(p parcel) float32 { return p.length }),
+		"width":  floatField(func(p parcel) float32 { return p.width }),
+		"height": floatField(func(p parcel) float32 { return p.height }),
+	},
+})
+
+var statusType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "BookingStatus",
+	Values: graphql.EnumValueConfigMap{
+		"BOOKED":           &graphql.EnumValueConfig{Value: StatusBooked},
+		"PICKED_UP":        &graphql.EnumValueConfig{Value: StatusPickedUp},
+		"IN_TRANSIT":       &graphql.EnumValueConfig{Value: StatusInTransit},
+		"OUT_FOR_DELIVERY": &graphql.EnumValueConfig{Value: StatusOutForDelivery},
+		"DELIVERED":        &graphql.EnumValueConfig{Value: StatusDelivered},
+		"EXCEPTION":        &graphql.EnumValueConfig{Value: StatusException},
+		"RETURNED":         &graphql.EnumValueConfig{Value: StatusReturned},
+		"CANCELLING":       &graphql.EnumValueConfig{Value: StatusCancelling},
+		"CANCELLED":        &graphql.EnumValueConfig{Value: StatusCancelled},
+	},
+})
+
+var statusChangeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "StatusChange",
+	Fields: graphql.Fields{
+		"status": &graphql.Field{
+			Type: graphql.NewNonNull(statusType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(statusChange).status, nil
+			},
+		},
+		"at": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(statusChange).at, nil
+			},
+		},
+	},
+})
+
+func stringField(get func(address) string) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(address)), nil
+		},
+	}
+}
+
+func floatField(get func(parcel) float32) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Float),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return float64(get(p.Source.(parcel))), nil
+		},
+	}
+}
+
+func bookingType(links GraphQLLinks) *graphql.Object {
+	location := func(code func(*booking) string) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewNonNull(links.LocationType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return links.Location(p.Context, code(p.Source.(*booking)))
+			},
+		}
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Booking",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).id, nil
+				},
+			},
+			"origin":      location(func(b *booking) string { return b.origin }),
+			"destination": location(func(b *booking) string { return b.destination }),
+			"originAddress": &graphql.Field{
+				Type: graphql.NewNonNull(addressType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).originAddress, nil
+				},
+			},
+			"destinationAddress": &graphql.Field{
+				Type: graphql.NewNonNull(addressType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).destinationAddress, nil
+				},
+			},
+			"parcels": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(parcelType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).Parcels(), nil
+				},
+			},
+			"weight": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return float64(p.Source.(*booking).weight), nil
+				},
+			},
+			"price": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return float64(p.Source.(*booking).price), nil
+				},
+			},
+			"currency": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return carrier.Currency, nil
+				},
+			},
+			"priceBreakdown": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(links.QuoteType))),
+				Description: "How the price of each parcel is made up with the current tariffs.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					b := p.Source.(*booking)
+					quotes := make([]interface{}, 0, len(b.parcels))
+					for _, parcel := range b.parcels {
+						q, err := links.Quote(p.Context, b.origin, b.destination, parcel.weight, b.serviceLevel)
+						if err != nil {
+							return nil, err
+						}
+						quotes = append(quotes, q)
+					}
+					return quotes, nil
+				},
+			},
+			"status": &graphql.Field{
+				Type: graphql.NewNonNull(statusType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).Status(), nil
+				},
+			},
+			"carrier": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Code of the carrier the booking was handed to.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optional(p.Source.(*booking).Carrier()), nil
+				},
+			},
+			"carrierReference": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Reference of the shipment at the carrier.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optional(p.Source.(*booking).CarrierReference()), nil
+				},
+			},
+			"serviceLevel": &graphql.Field{
+				Type: graphql.NewNonNull(links.ServiceLevelType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).ServiceLevel(), nil
+				},
+			},
+			"transitDays": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Business days from booking to delivery.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if p.Source.(*booking).TransitDays() == 0 {
+						return nil, nil
+					}
+					return p.Source.(*booking).TransitDays(), nil
+				},
+			},
+			"estimatedPickup": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Date as YYYY-MM-DD.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optional(formatDate(p.Source.(*booking).EstimatedPickup())), nil
+				},
+			},
+			"estimatedDelivery": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Date as YYYY-MM-DD.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return optional(formatDate(p.Source.(*booking).EstimatedDelivery())), nil
+				},
+			},
+			"statusHistory": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statusChangeType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).StatusHistory(), nil
+				},
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*booking).CreatedAt(), nil
+				},
+			},
+		},
+	})
+}
+
+type bookingPage struct {
+	bookings []*booking
+	next     int
+}
+
+// GraphQLQueries returns the booking fields of the query type, bookings are
+// scoped to the client in the context like in the HTTP API.
+func GraphQLQueries(service *Service, links GraphQLLinks) graphql.Fields {
+	bookingType := bookingType(links)
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BookingPage",
+		Fields: graphql.Fields{
+			"bookings": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookingType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(bookingPage).bookings, nil
+				},
+			},
+			"nextOffset": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "The offset of the next page, null on the last page.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if next := p.Source.(bookingPage).next; next >= 0 {
+						return next, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"booking": &graphql.Field{
+			Type: bookingType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return service.ViewBooking(p.Context, clientId(p.Context), p.Args["id"].(string))
+			},
+		},
+		"bookings": &graphql.Field{
+			Type: graphql.NewNonNull(pageType),
+			Args: graphql.FieldConfigArgument{
+				"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultPageSize},
+				"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				bookings, next, err := service.ListBookings(p.Context, clientId(p.Context), p.Args["offset"].(int), p.Args["limit"].(int))
+				if err != nil {
+					return nil, err
+				}
+				return bookingPage{bookings: bookings, next: next}, nil
+			},
+		},
+	}
+}
+
+func clientId(ctx context.Context) string {
+	clientId, _ := ctx.Value(auth.ClientIdKey).(string)
+	return clientId
+}
+
+// optional resolves empty strings to null.
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -4,15 +4,23 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/problem"
 
 	"github.com/gin-gonic/gin"
 )
 
 type bookShippingRequest struct {
-	Origin      string  `json:"origin" binding:"required"`
-	Destination string  `json:"destination" binding:"required"`
-	Weight      float32 `json:"weight" binding:"required"`
+	Origin      string         `json:"origin" binding:"required"`
+	Destination string         `json:"destination" binding:"required"`
+	Weight      float32        `json:"weight" binding:"required"`
+	Option      string         `json:"option,omitempty" description:"Id of a carrier option from the quote, without option or policy the preferred carrier of the lane ships at the tariff price"`
+	Policy      billing.Policy `json:"policy,omitempty" binding:"omitempty,oneof=cheapest fastest" description:"Ship with the best carrier option by the policy, must not be set together with option"`
+	// ServiceLevel narrows the carrier options to the level, without option
+	// or policy it picks the tariff.
+	ServiceLevel billing.ServiceLevel `json:"serviceLevel,omitempty" binding:"omitempty,oneof=economy standard express" description:"Defaults to standard"`
 }
 
 type bookShippingResponse struct {
@@ -29,15 +37,15 @@ func NewHandler(bookingService Service) *handler {
 
 func (h handler) BookShipping(c *gin.Context) {
 	var req bookShippingRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := problem.Bind(c, &req); err != nil {
+		problem.Write(c, err)
 		return
 	}
 
-	id, err := h.bookingService.BookShipping(c, req.Origin, req.Destination, req.Weight)
+	id, err := h.bookingService.BookShipping(c, c.GetString(auth.ClientIdKey), req.Origin, req.Destination, req.Weight, Selection{Option: req.Option, Policy: req.Policy, ServiceLevel: req.ServiceLevel})
 
 	if err != nil {
-		handleError(c, err)
+		problem.Write(c, err)
 		return
 	}
 
@@ -45,55 +53,101 @@ func (h handler) BookShipping(c *gin.Context) {
 		Id: string(id),
 	}
 
-  c.Header("Location", fmt.Sprintf("%s/%s", c.Request.URL.Path, id))
+	c.Header("Location", fmt.Sprintf("%s/%s", c.Request.URL.Path, id))
 	c.JSON(http.StatusCreated, res)
 }
 
 type getBookingResponse struct {
-	Id          string  `json:"id" binding:"required"`
-	Origin      string  `json:"origin" binding:"required"`
-	Destination string  `json:"destination" binding:"required"`
-	Weight      float32 `json:"weight" binding:"required"`
-	Price       float32 `json:"price" binding:"required"`
-	Currency    string  `json:"currency" binding:"required"`
+	Id               string  `json:"id" binding:"required"`
+	Origin           string  `json:"origin" binding:"required"`
+	Destination      string  `json:"destination" binding:"required"`
+	Weight           float32 `json:"weight" binding:"required"`
+	Price            float32 `json:"price" binding:"required"`
+	Currency         string  `json:"currency" binding:"required"`
+	Status           Status  `json:"status" binding:"required,oneof=booked picked_up in_transit out_for_delivery delivered exception returned cancelling cancelled"`
+	Carrier          string  `json:"carrier,omitempty"`
+	CarrierReference string  `json:"carrierReference,omitempty"`
+
+	ServiceLevel      billing.ServiceLevel `json:"serviceLevel" binding:"required,oneof=economy standard express"`
+	TransitDays       int                  `json:"transitDays,omitempty"`
+	EstimatedPickup   string               `json:"estimatedPickup,omitempty" description:"Date as YYYY-MM-DD"`
+	EstimatedDelivery string               `json:"estimatedDelivery,omitempty" description:"Date as YYYY-MM-DD"`
+}
+
+type listBookingsRequest struct {
+	Limit  int `form:"limit" json:"limit" binding:"gte=0,lte=500"`
+	Offset int `form:"offset" json:"offset" binding:"gte=0"`
+}
+
+type listBookingsResponse struct {
+	Bookings   []getBookingResponse `json:"bookings" binding:"required"`
+	NextOffset *int                 `json:"nextOffset" description:"Offset of the next page, null on the last page"`
 }
 
 func (h handler) GetBooking(c *gin.Context) {
 	id := c.Param("id")
 
-	sh, err := h.bookingService.GetBooking(c, id)
+	sh, err := h.bookingService.ViewBooking(c, c.GetString(auth.ClientIdKey), id)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toBookingResponse(sh))
+}
+
+func (h handler) ListBookings(c *gin.Context) {
+	var req listBookingsRequest
+	if err := problem.BindQuery(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	bookings, next, err := h.bookingService.ListBookings(c, c.GetString(auth.ClientIdKey), req.Offset, req.Limit)
 	if err != nil {
-		handleError(c, err)
+		problem.Write(c, err)
 		return
 	}
-	response := getBookingResponse{
-		Id:          sh.Id(),
-		Origin:      sh.Origin(),
-		Destination: sh.Destination(),
-		Weight:      sh.Weight(),
-		Price:       sh.Price(),
-		Currency:    "SEK",
+
+	res := listBookingsResponse{Bookings: make([]getBookingResponse, 0, len(bookings)), NextOffset: nextOffset(next)}
+	for _, b := range bookings {
+		res.Bookings = append(res.Bookings, toBookingResponse(b))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h handler) CancelBooking(c *gin.Context) {
+	b, err := h.bookingService.CancelBooking(c, c.GetString(auth.ClientIdKey), c.Param("id"))
+	if err != nil {
+		problem.Write(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, toBookingResponse(b))
 }
 
-func handleError(c *gin.Context, err error) {
-	apiError, ok := err.(errors.APIError)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+func toBookingResponse(b *booking) getBookingResponse {
+	return getBookingResponse{
+		Id:               b.Id(),
+		Origin:           b.Origin(),
+		Destination:      b.Destination(),
+		Weight:           b.Weight(),
+		Price:            b.Price(),
+		Currency:         carrier.Currency,
+		Status:           b.Status(),
+		Carrier:          b.Carrier(),
+		CarrierReference: b.CarrierReference(),
+
+		ServiceLevel:      b.ServiceLevel(),
+		TransitDays:       b.TransitDays(),
+		EstimatedPickup:   formatDate(b.EstimatedPickup()),
+		EstimatedDelivery: formatDate(b.EstimatedDelivery()),
 	}
+}
 
-	switch apiError.GetType() {
-	case errors.ErrorNotFound:
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	case errors.ErrorInput:
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	case errors.ErrorConflict:
-		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-	case errors.ErrorInternal:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+func nextOffset(next int) *int {
+	if next < 0 {
+		return nil
 	}
+	return &next
 }
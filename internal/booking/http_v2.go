@@ -0,0 +1,178 @@
+package booking
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type addressV2 struct {
+	Name       string `json:"name,omitempty"`
+	Street     string `json:"street,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	City       string `json:"city,omitempty"`
+	Country    string `json:"country" binding:"required,len=2" description:"ISO 3166-1 alpha-2 country code"`
+}
+
+type parcelV2 struct {
+	Weight float32 `json:"weight" binding:"required,gt=0" description:"Weight in kg"`
+	Length float32 `json:"length,omitempty" binding:"gte=0" description:"Length in cm"`
+	Width  float32 `json:"width,omitempty" binding:"gte=0" description:"Width in cm"`
+	Height float32 `json:"height,omitempty" binding:"gte=0" description:"Height in cm"`
+}
+
+type money struct {
+	Amount   string `json:"amount" binding:"required" description:"Decimal amount in the major unit of the currency"`
+	Currency string `json:"currency" binding:"required" description:"ISO 4217 currency code"`
+}
+
+type bookShippingRequestV2 struct {
+	Origin      addressV2      `json:"origin" binding:"required"`
+	Destination addressV2      `json:"destination" binding:"required"`
+	Parcels     []parcelV2     `json:"parcels" binding:"required,min=1,dive"`
+	Option      string         `json:"option,omitempty" description:"Id of a carrier option from the quote, without option or policy the preferred carrier of the lane ships at the tariff price"`
+	Policy      billing.Policy `json:"policy,omitempty" binding:"omitempty,oneof=cheapest fastest" description:"Ship with the best carrier option by the policy, must not be set together with option"`
+	// ServiceLevel narrows the carrier options to the level, without option
+	// or policy it picks the tariff.
+	ServiceLevel billing.ServiceLevel `json:"serviceLevel,omitempty" binding:"omitempty,oneof=economy standard express" description:"Defaults to standard"`
+}
+
+type getBookingResponseV2 struct {
+	Id               string     `json:"id" binding:"required"`
+	Origin           addressV2  `json:"origin" binding:"required"`
+	Destination      addressV2  `json:"destination" binding:"required"`
+	Parcels          []parcelV2 `json:"parcels" binding:"required"`
+	Weight           float32    `json:"weight" binding:"required" description:"Total weight in kg"`
+	Price            money      `json:"price" binding:"required"`
+	Status           Status     `json:"status" binding:"required,oneof=booked picked_up in_transit out_for_delivery delivered exception returned cancelling cancelled"`
+	Carrier          string     `json:"carrier,omitempty"`
+	CarrierReference string     `json:"carrierReference,omitempty"`
+
+	ServiceLevel      billing.ServiceLevel `json:"serviceLevel" binding:"required,oneof=economy standard express"`
+	TransitDays       int                  `json:"transitDays,omitempty"`
+	EstimatedPickup   string               `json:"estimatedPickup,omitempty" description:"Date as YYYY-MM-DD"`
+	EstimatedDelivery string               `json:"estimatedDelivery,omitempty" description:"Date as YYYY-MM-DD"`
+}
+
+type listBookingsResponseV2 struct {
+	Bookings   []getBookingResponseV2 `json:"bookings" binding:"required"`
+	NextOffset *int                   `json:"nextOffset" description:"Offset of the next page, null on the last page"`
+}
+
+func (h handler) BookShippingV2(c *gin.Context) {
+	var req bookShippingRequestV2
+	if err := problem.Bind(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	origin, err := NewAddress(req.Origin.Name, req.Origin.Street, req.Origin.PostalCode, req.Origin.City, req.Origin.Country)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+	destination, err := NewAddress(req.Destination.Name, req.Destination.Street, req.Destination.PostalCode, req.Destination.City, req.Destination.Country)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	parcels := make([]parcel, 0, len(req.Parcels))
+	for _, p := range req.Parcels {
+		parcel, err := NewParcel(p.Weight, p.Length, p.Width, p.Height)
+		if err != nil {
+			problem.Write(c, err)
+			return
+		}
+		parcels = append(parcels, parcel)
+	}
+
+	id, err := h.bookingService.BookParcels(c, c.GetString(auth.ClientIdKey), origin, destination, parcels, Selection{Option: req.Option, Policy: req.Policy, ServiceLevel: req.ServiceLevel})
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("%s/%s", c.Request.URL.Path, id))
+	c.JSON(http.StatusCreated, bookShippingResponse{Id: id})
+}
+
+func (h handler) GetBookingV2(c *gin.Context) {
+	b, err := h.bookingService.ViewBooking(c, c.GetString(auth.ClientIdKey), c.Param("id"))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toBookingResponseV2(b))
+}
+
+func (h handler) ListBookingsV2(c *gin.Context) {
+	var req listBookingsRequest
+	if err := problem.BindQuery(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	bookings, next, err := h.bookingService.ListBookings(c, c.GetString(auth.ClientIdKey), req.Offset, req.Limit)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	res := listBookingsResponseV2{Bookings: make([]getBookingResponseV2, 0, len(bookings)), NextOffset: nextOffset(next)}
+	for _, b := range bookings {
+		res.Bookings = append(res.Bookings, toBookingResponseV2(b))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h handler) CancelBookingV2(c *gin.Context) {
+	b, err := h.bookingService.CancelBooking(c, c.GetString(auth.ClientIdKey), c.Param("id"))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toBookingResponseV2(b))
+}
+
+func toBookingResponseV2(b *booking) getBookingResponseV2 {
+	parcels := make([]parcelV2, 0, len(b.Parcels()))
+	for _, p := range b.Parcels() {
+		parcels = append(parcels, parcelV2{Weight: p.Weight(), Length: p.Length(), Width: p.Width(), Height: p.Height()})
+	}
+
+	return getBookingResponseV2{
+		Id:               b.Id(),
+		Origin:           toAddressV2(b.OriginAddress()),
+		Destination:      toAddressV2(b.DestinationAddress()),
+		Parcels:          parcels,
+		Weight:           b.Weight(),
+		Price:            money{Amount: fmt.Sprintf("%.2f", b.Price()), Currency: carrier.Currency},
+		Status:           b.Status(),
+		Carrier:          b.Carrier(),
+		CarrierReference: b.CarrierReference(),
+
+		ServiceLevel:      b.ServiceLevel(),
+		TransitDays:       b.TransitDays(),
+		EstimatedPickup:   formatDate(b.EstimatedPickup()),
+		EstimatedDelivery: formatDate(b.EstimatedDelivery()),
+	}
+}
+
+func toAddressV2(a address) addressV2 {
+	return addressV2{
+		Name:       a.Name(),
+		Street:     a.Street(),
+		PostalCode: a.PostalCode(),
+		City:       a.City(),
+		Country:    a.Country(),
+	}
+}
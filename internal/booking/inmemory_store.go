@@ -2,28 +2,45 @@ package booking
 
 import (
 	"context"
-	"fmt"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/slaengkast/shipping-api/internal/billing"
 	"github.com/slaengkast/shipping-api/internal/errors"
 )
 
 type bookingModel struct {
-	id          string
-	origin      string
-	destination string
-	weight      float32
-	price       float32
-	currency    string
+	id                 string
+	clientId           string
+	origin             string
+	destination        string
+	weight             float32
+	price              float32
+	currency           string
+	originAddress      address
+	destinationAddress address
+	parcels            []parcel
+	history            []statusChange
+	carrier            string
+	carrierReference   string
+	serviceLevel       billing.ServiceLevel
+	transitDays        int
+	estimatedPickup    time.Time
+	estimatedDelivery  time.Time
+	createdAt          time.Time
 }
 
+// inMemoryStore keeps the events of the stored bookings in an outbox, in the
+// order they were stored, until they are marked as published.
 type inMemoryStore struct {
 	bookings map[string]bookingModel
+	outbox   *[]Event
 	mtx      *sync.RWMutex
 }
 
 func NewInMemoryStore() inMemoryStore {
-	return inMemoryStore{bookings: make(map[string]bookingModel, 0), mtx: &sync.RWMutex{}}
+	return inMemoryStore{bookings: make(map[string]bookingModel, 0), outbox: &[]Event{}, mtx: &sync.RWMutex{}}
 }
 
 func (r inMemoryStore) GetBooking(_ context.Context, id string) (*booking, error) {
@@ -32,39 +49,231 @@ func (r inMemoryStore) GetBooking(_ context.Context, id string) (*booking, error
 
 	bookingModel, ok := r.bookings[id]
 	if !ok {
-		return nil, errors.FromMessage(fmt.Sprintf("booking %s not found", id), errors.ErrorNotFound)
+		return nil, errors.NotFound(errors.CodeBookingNotFound, "booking", id)
 	}
 	return unmarshalBooking(bookingModel)
 }
 
-func (r inMemoryStore) AddBooking(ctx context.Context, sh *booking) error {
+func (r inMemoryStore) AddBooking(_ context.Context, sh *booking) error {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
+	return r.addBooking(sh)
+}
+
+// addBooking is AddBooking with the lock held.
+func (r inMemoryStore) addBooking(sh *booking) error {
 	if _, ok := r.bookings[sh.Id()]; ok {
-		return errors.FromMessage("booking already exists", errors.ErrorConflict)
+		return errors.WithEntity(errors.FromCode(errors.CodeBookingAlreadyExists, "booking already exists", errors.ErrorConflict), "booking", sh.Id())
 	}
 
 	r.bookings[sh.Id()] = marshalBooking(sh)
+	r.addEvents(sh)
+	return nil
+}
+
+func (r inMemoryStore) UpdateBooking(_ context.Context, b *booking) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.updateBooking(b)
+}
+
+// updateBooking is UpdateBooking with the lock held.
+func (r inMemoryStore) updateBooking(b *booking) error {
+	if _, ok := r.bookings[b.Id()]; !ok {
+		return errors.NotFound(errors.CodeBookingNotFound, "booking", b.Id())
+	}
+
+	r.bookings[b.Id()] = marshalBooking(b)
+	r.addEvents(b)
+	return nil
+}
+
+// TransitionBooking runs transition on the stored booking and stores the
+// result under the same lock, so that the booking can not change between
+// checking its status and moving it on. Nothing is stored if transition
+// fails or tells that it left the booking as it was.
+func (r inMemoryStore) TransitionBooking(_ context.Context, id string, transition func(*booking) (bool, error)) (*booking, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	b, _, err := r.transitionBooking(id, transition)
+	return b, err
+}
+
+// transitionBooking is TransitionBooking with the lock held, it also tells
+// whether the booking was stored.
+func (r inMemoryStore) transitionBooking(id string, transition func(*booking) (bool, error)) (*booking, bool, error) {
+	m, ok := r.bookings[id]
+	if !ok {
+		return nil, false, errors.NotFound(errors.CodeBookingNotFound, "booking", id)
+	}
+	b, err := unmarshalBooking(m)
+	if err != nil {
+		return nil, false, err
+	}
+	changed, err := transition(b)
+	if err != nil {
+		return nil, false, err
+	}
+	if !changed {
+		return b, false, nil
+	}
+
+	r.bookings[id] = marshalBooking(b)
+	r.addEvents(b)
+	return b, true, nil
+}
+
+// addEvents moves the events recorded on b to the outbox, so that storing b
+// again does not add them twice. The lock must be held.
+func (r inMemoryStore) addEvents(b *booking) {
+	*r.outbox = append(*r.outbox, b.events...)
+	b.events = nil
+}
+
+// PendingEvents returns at most limit events that have not been published,
+// oldest first, starting after the event with the id after. Events are
+// published oldest first, so when after has been published already, or is
+// empty, they start at the oldest pending event.
+func (r inMemoryStore) PendingEvents(_ context.Context, after string, limit int) ([]Event, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	events := *r.outbox
+	if after != "" {
+		for i, e := range events {
+			if e.Id == after {
+				events = events[i+1:]
+				break
+			}
+		}
+	}
+	if limit < len(events) {
+		events = events[:limit]
+	}
+	return append([]Event(nil), events...), nil
+}
+
+// MarkPublished removes the events with the ids from the outbox.
+func (r inMemoryStore) MarkPublished(_ context.Context, ids ...string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.markPublished(ids...)
+	return nil
+}
+
+// markPublished is MarkPublished with the lock held. It replaces the outbox
+// rather than changing it in place.
+func (r inMemoryStore) markPublished(ids ...string) {
+	published := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		published[id] = true
+	}
+	pending := make([]Event, 0, len(*r.outbox))
+	for _, e := range *r.outbox {
+		if !published[e.Id] {
+			pending = append(pending, e)
+		}
+	}
+	*r.outbox = pending
+}
+
+// ListBookings returns at most limit bookings of the client in the order they
+// were added, skipping the first offset.
+func (r inMemoryStore) ListBookings(_ context.Context, clientId string, offset, limit int) ([]*booking, error) {
+	r.mtx.RLock()
+	models := make([]bookingModel, 0)
+	for _, m := range r.bookings {
+		if m.clientId == clientId {
+			models = append(models, m)
+		}
+	}
+	r.mtx.RUnlock()
+
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].createdAt.Equal(models[j].createdAt) {
+			return models[i].id < models[j].id
+		}
+		return models[i].createdAt.Before(models[j].createdAt)
+	})
+
+	if offset >= len(models) {
+		return []*booking{}, nil
+	}
+	models = models[offset:]
+	if limit < len(models) {
+		models = models[:limit]
+	}
+
+	bookings := make([]*booking, 0, len(models))
+	for _, m := range models {
+		b, err := unmarshalBooking(m)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+	return bookings, nil
+}
+
+func (r inMemoryStore) Ping(_ context.Context) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if r.bookings == nil {
+		return errors.FromMessage("booking store is not initialized", errors.ErrorInternal)
+	}
+	return nil
+}
+
+func (r inMemoryStore) Close(_ context.Context) error {
 	return nil
 }
 
 func unmarshalBooking(bookingModel bookingModel) (*booking, error) {
-	return NewBooking(
+	b, err := NewParcelBooking(
 		bookingModel.id,
-		bookingModel.origin,
-		bookingModel.destination,
-		bookingModel.weight,
+		bookingModel.clientId,
+		bookingModel.originAddress,
+		bookingModel.destinationAddress,
+		bookingModel.parcels,
 		bookingModel.price,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bookingModel.history) > 0 {
+		b.history = append([]statusChange(nil), bookingModel.history...)
+	}
+	b.assignCarrier(bookingModel.carrier, bookingModel.carrierReference)
+	if bookingModel.serviceLevel != "" {
+		b.schedule(bookingModel.serviceLevel, bookingModel.transitDays, bookingModel.estimatedPickup, bookingModel.estimatedDelivery)
+	}
+	return b, nil
 }
 
 func marshalBooking(b *booking) bookingModel {
 	return bookingModel{
-		id:          b.id,
-		origin:      b.origin,
-		destination: b.destination,
-		weight:      b.weight,
-		price:       b.price,
+		id:                 b.id,
+		clientId:           b.clientId,
+		origin:             b.origin,
+		destination:        b.destination,
+		weight:             b.weight,
+		price:              b.price,
+		originAddress:      b.originAddress,
+		destinationAddress: b.destinationAddress,
+		parcels:            b.Parcels(),
+		history:            b.StatusHistory(),
+		carrier:            b.carrier,
+		carrierReference:   b.carrierReference,
+		serviceLevel:       b.serviceLevel,
+		transitDays:        b.transitDays,
+		estimatedPickup:    b.estimatedPickup,
+		estimatedDelivery:  b.estimatedDelivery,
+		createdAt:          b.CreatedAt(),
 	}
 }
@@ -0,0 +1,80 @@
+package booking
+
+import (
+	"context"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type storeMetrics interface {
+	StoreOperation(store, operation string, duration time.Duration, err error)
+}
+
+type instrumentedStore struct {
+	store   store
+	metrics storeMetrics
+}
+
+func NewInstrumentedStore(store store, metrics storeMetrics) instrumentedStore {
+	return instrumentedStore{store: store, metrics: metrics}
+}
+
+func (r instrumentedStore) GetBooking(ctx context.Context, id string) (*booking, error) {
+	ctx, span := tracer.Start(ctx, "bookingStore.GetBooking", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	b, err := r.store.GetBooking(ctx, id)
+	err = errors.WrapStore(err, "booking", "get_booking")
+	r.metrics.StoreOperation("booking", "get_booking", time.Since(start), err)
+	logging.StoreOperation(ctx, "booking", "get_booking", time.Since(start), err)
+	tracing.End(span, err)
+	return b, err
+}
+
+func (r instrumentedStore) AddBooking(ctx context.Context, b *booking) error {
+	ctx, span := tracer.Start(ctx, "bookingStore.AddBooking", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	err := r.store.AddBooking(ctx, b)
+	err = errors.WrapStore(err, "booking", "add_booking")
+	r.metrics.StoreOperation("booking", "add_booking", time.Since(start), err)
+	logging.StoreOperation(ctx, "booking", "add_booking", time.Since(start), err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r instrumentedStore) UpdateBooking(ctx context.Context, b *booking) error {
+	ctx, span := tracer.Start(ctx, "bookingStore.UpdateBooking", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	err := r.store.UpdateBooking(ctx, b)
+	err = errors.WrapStore(err, "booking", "update_booking")
+	r.metrics.StoreOperation("booking", "update_booking", time.Since(start), err)
+	logging.StoreOperation(ctx, "booking", "update_booking", time.Since(start), err)
+	tracing.End(span, err)
+	return err
+}
+
+func (r instrumentedStore) TransitionBooking(ctx context.Context, id string, transition func(*booking) (bool, error)) (*booking, error) {
+	ctx, span := tracer.Start(ctx, "bookingStore.TransitionBooking", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	b, err := r.store.TransitionBooking(ctx, id, transition)
+	err = errors.WrapStore(err, "booking", "transition_booking")
+	r.metrics.StoreOperation("booking", "transition_booking", time.Since(start), err)
+	logging.StoreOperation(ctx, "booking", "transition_booking", time.Since(start), err)
+	tracing.End(span, err)
+	return b, err
+}
+
+func (r instrumentedStore) ListBookings(ctx context.Context, clientId string, offset, limit int) ([]*booking, error) {
+	ctx, span := tracer.Start(ctx, "bookingStore.ListBookings", trace.WithSpanKind(trace.SpanKindClient))
+	start := time.Now()
+	bookings, err := r.store.ListBookings(ctx, clientId, offset, limit)
+	err = errors.WrapStore(err, "booking", "list_bookings")
+	r.metrics.StoreOperation("booking", "list_bookings", time.Since(start), err)
+	logging.StoreOperation(ctx, "booking", "list_bookings", time.Since(start), err)
+	tracing.End(span, err)
+	return bookings, err
+}
@@ -0,0 +1,103 @@
+package booking
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func BookShippingOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "bookShipping",
+		Summary:     "Book shipping of a parcel",
+		Tags:        []string{"shipping"},
+		RequestBody: openapi.JSONBody(bookShippingRequest{}),
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSONResponse("Booking created", bookShippingResponse{}),
+		},
+	}
+}
+
+func GetBookingOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getBooking",
+		Summary:     "Get a booking of the client",
+		Tags:        []string{"shipping"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Booking", getBookingResponse{}),
+			"404": openapi.ProblemResponse("Booking not found"),
+		},
+	}
+}
+
+func BookShippingV2Operation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "bookShippingV2",
+		Summary:     "Book shipping of one or more parcels between two addresses",
+		Tags:        []string{"shipping"},
+		RequestBody: openapi.JSONBody(bookShippingRequestV2{}),
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSONResponse("Booking created", bookShippingResponse{}),
+		},
+	}
+}
+
+func GetBookingV2Operation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getBookingV2",
+		Summary:     "Get a booking of the client with addresses, parcels and price",
+		Tags:        []string{"shipping"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Booking", getBookingResponseV2{}),
+			"404": openapi.ProblemResponse("Booking not found"),
+		},
+	}
+}
+
+func ListBookingsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "listBookings",
+		Summary:     "List the bookings of the client, oldest first",
+		Tags:        []string{"shipping"},
+		Parameters:  openapi.QueryParameters(listBookingsRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Page of bookings", listBookingsResponse{}),
+		},
+	}
+}
+
+func CancelBookingOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "cancelBooking",
+		Summary:     "Cancel a booking of the client",
+		Tags:        []string{"shipping"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Cancelled booking", getBookingResponse{}),
+			"404": openapi.ProblemResponse("Booking not found"),
+			"409": openapi.ProblemResponse("Booking can no longer be cancelled"),
+		},
+	}
+}
+
+func ListBookingsV2Operation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "listBookingsV2",
+		Summary:     "List the bookings of the client with addresses, parcels and price, oldest first",
+		Tags:        []string{"shipping"},
+		Parameters:  openapi.QueryParameters(listBookingsRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Page of bookings", listBookingsResponseV2{}),
+		},
+	}
+}
+
+func CancelBookingV2Operation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "cancelBookingV2",
+		Summary:     "Cancel a booking of the client",
+		Tags:        []string{"shipping"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Cancelled booking", getBookingResponseV2{}),
+			"404": openapi.ProblemResponse("Booking not found"),
+			"409": openapi.ProblemResponse("Booking can no longer be cancelled"),
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package booking
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/openapi"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schema   *openapi.Schema
+		value    interface{}
+		required []string
+	}{
+		{
+			name:     "book shipping request",
+			schema:   BookShippingOperation().Schema(),
+			value:    bookShippingRequest{Origin: "SE", Destination: "DK", Weight: 10, Option: "fake:standard", Policy: billing.PolicyFastest, ServiceLevel: billing.ServiceLevelStandard},
+			required: []string{"origin", "destination", "weight"},
+		},
+		{
+			name:     "book shipping response",
+			schema:   BookShippingOperation().Responses["201"].Content["application/json"].Schema,
+			value:    bookShippingResponse{Id: "id"},
+			required: []string{"id"},
+		},
+		{
+			name:     "get booking response",
+			schema:   GetBookingOperation().Responses["200"].Content["application/json"].Schema,
+			value:    getBookingResponse{Id: "id", Origin: "SE", Destination: "DK", Weight: 10, Price: 50, Currency: "SEK", Status: StatusBooked, Carrier: "fake", CarrierReference: "fake-00000001", ServiceLevel: billing.ServiceLevelExpress, TransitDays: 1, EstimatedPickup: "2026-10-19", EstimatedDelivery: "2026-10-20"},
+			required: []string{"id", "origin", "destination", "weight", "price", "currency", "status", "serviceLevel"},
+		},
+		{
+			name:   "list bookings response",
+			schema: ListBookingsOperation().Responses["200"].Content["application/json"].Schema,
+			value: listBookingsResponse{
+				Bookings: []getBookingResponse{{Id: "id", Origin: "SE", Destination: "DK", Weight: 10, Price: 50, Currency: "SEK", Status: StatusCancelled, ServiceLevel: billing.ServiceLevelStandard}},
+			},
+			required: []string{"bookings"},
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := json.Marshal(tc.value)
+			require.Nil(t, err)
+
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			var value map[string]interface{}
+			require.Nil(t, decoder.Decode(&value))
+
+			require.Empty(t, tc.schema.Validate(value))
+			require.ElementsMatch(t, tc.required, tc.schema.Required)
+			require.Len(t, tc.schema.Properties, len(value))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package booking
+
+import (
+	"errors"
+)
+
+type parcel struct {
+	weight float32
+	length float32
+	width  float32
+	height float32
+}
+
+func NewParcel(weight, length, width, height float32) (parcel, error) {
+	if weight <= 0 {
+		return parcel{}, errors.New("invalid weight")
+	}
+	if length < 0 || width < 0 || height < 0 {
+		return parcel{}, errors.New("invalid dimensions")
+	}
+
+	return parcel{weight: weight, length: length, width: width, height: height}, nil
+}
+
+func (p parcel) Weight() float32 {
+	return p.weight
+}
+
+func (p parcel) Length() float32 {
+	return p.length
+}
+
+func (p parcel) Width() float32 {
+	return p.width
+}
+
+func (p parcel) Height() float32 {
+	return p.height
+}
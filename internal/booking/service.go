@@ -2,53 +2,294 @@ package booking
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/carrier"
 	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/tracing"
 
 	"github.com/google/uuid"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("github.com/slaengkast/shipping-api/internal/booking")
+
 type store interface {
 	GetBooking(context.Context, string) (*booking, error)
 	AddBooking(context.Context, *booking) error
+	UpdateBooking(context.Context, *booking) error
+	// TransitionBooking stores the change transition makes to the booking,
+	// atomically with reading it, if transition tells that it changed it.
+	TransitionBooking(ctx context.Context, id string, transition func(*booking) (bool, error)) (*booking, error)
+	ListBookings(ctx context.Context, clientId string, offset, limit int) ([]*booking, error)
 }
 
 type billingService interface {
-	CalculateShippingCost(context.Context, string, string, float32) (float32, error)
+	CalculateShippingCost(ctx context.Context, origin, destination string, weight float32, serviceLevel billing.ServiceLevel) (float32, error)
+	TransitDays(ctx context.Context, origin, destination string, serviceLevel billing.ServiceLevel) (int, error)
+	Estimate(origin, destination string, at time.Time, transitDays int) (pickup, delivery time.Time)
+	SelectOption(ctx context.Context, req carrier.Request, id string, policy billing.Policy) (carrier.Rate, error)
+}
+
+type carrierService interface {
+	CreateShipment(ctx context.Context, code string, req carrier.Request) (string, string, error)
+	CancelShipment(ctx context.Context, code, reference string) error
+}
+
+type metrics interface {
+	BookingCreated(origin, destination string)
 }
 
 type Service struct {
 	store          store
 	billingService billingService
-	logger         zerolog.Logger
+	carrierService carrierService
+	metrics        metrics
+}
+
+// Selection picks the carrier option a booking ships with, by its id or by a
+// policy, among the options at the service level if it has one. Without
+// option and policy the preferred carrier of the lane ships at the tariff
+// price of the service level, standard if empty.
+type Selection struct {
+	Option       string
+	Policy       billing.Policy
+	ServiceLevel billing.ServiceLevel
 }
 
-func NewService(store store, billingService billingService) Service {
+func (s Selection) validate() error {
+	if s.Option != "" && s.Policy != "" {
+		return errors.Validation("both option and policy", errors.FieldError{Field: "policy", Code: errors.FieldInvalid, Message: "policy must not be set together with option"})
+	}
+	return nil
+}
+
+func NewService(store store, billingService billingService, carrierService carrierService, metrics metrics) Service {
 	return Service{
 		store:          store,
 		billingService: billingService,
-		logger:         log.With().Str("component", "booking").Logger(),
+		carrierService: carrierService,
+		metrics:        metrics,
 	}
 }
 
-func (s *Service) GetBooking(ctx context.Context, id string) (*booking, error) {
-	s.logger.Debug().Str("id", id).Msg("")
+// GetBooking returns a booking of the client, it is what every change of a
+// booking is checked against.
+func (s *Service) GetBooking(ctx context.Context, clientId, id string) (_ *booking, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.GetBooking", trace.WithAttributes(attribute.String("booking.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.FromContext(ctx, "booking")
+	logger.Debug().Str("clientId", clientId).Str("id", id).Msg("getting booking")
+
+	b, err := s.store.GetBooking(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.ClientId() != clientId {
+		return nil, errors.NotFound(errors.CodeBookingNotFound, "booking", id)
+	}
+	return b, nil
+}
+
+// ViewBooking is GetBooking for reading a booking, operators and admins read
+// the bookings of every client but only change their own.
+func (s *Service) ViewBooking(ctx context.Context, clientId, id string) (_ *booking, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.ViewBooking", trace.WithAttributes(attribute.String("booking.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	if !auth.HasAnyRole(auth.RolesFromContext(ctx), auth.RoleOperator, auth.RoleAdmin) {
+		return s.GetBooking(ctx, clientId, id)
+	}
+
+	logger := logging.FromContext(ctx, "booking")
+	logger.Debug().Str("clientId", clientId).Str("id", id).Msg("viewing booking")
+
 	return s.store.GetBooking(ctx, id)
 }
 
-func (s *Service) BookShipping(ctx context.Context, origin, destination string, weight float32) (string, error) {
-	s.logger.Info().Str("origin", origin).Str("destination", destination).Float32("weight", weight).Msg("")
+// CancelBooking cancels a booking of the client, only bookings that have not
+// moved on from booked can be cancelled. The booking is stored as cancelling
+// while the carrier cancels the shipment, so that it can not be picked up or
+// cancelled again meanwhile, and booking.cancelled is only recorded once the
+// carrier has cancelled. If the carrier fails the booking is booked again,
+// without any event since none was recorded.
+func (s *Service) CancelBooking(ctx context.Context, clientId, id string) (_ *booking, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.CancelBooking", trace.WithAttributes(attribute.String("booking.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.FromContext(ctx, "booking")
+	logger.Info().Str("clientId", clientId).Str("id", id).Msg("cancelling booking")
+
+	if _, err := s.GetBooking(ctx, clientId, id); err != nil {
+		return nil, err
+	}
+	// checked again under the lock of the store, the booking may have been
+	// picked up or cancelled since it was read
+	b, err := s.store.TransitionBooking(ctx, id, func(b *booking) (bool, error) {
+		if b.Status() != StatusBooked {
+			return false, errors.WithEntity(errors.FromCode(errors.CodeBookingNotCancellable, fmt.Sprintf("booking is %s", b.Status()), errors.ErrorConflict), "booking", id)
+		}
+		b.setStatus(StatusCancelling, time.Now().UTC())
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Carrier() != "" {
+		// a shipment the carrier does not know has nothing to cancel
+		err := s.carrierService.CancelShipment(ctx, b.Carrier(), b.CarrierReference())
+		if err != nil && errors.GetType(err) != errors.ErrorNotFound {
+			s.restore(ctx, b)
+			return nil, err
+		}
+		if err != nil {
+			logger.Warn().Err(err).Str("id", id).Msg("carrier does not know the shipment of the cancelled booking")
+		}
+	}
+
+	b, err = s.store.TransitionBooking(ctx, id, func(b *booking) (bool, error) {
+		if b.Status() != StatusCancelling {
+			return false, errors.FromMessage(fmt.Sprintf("booking is %s, not %s", b.Status(), StatusCancelling), errors.ErrorInternal)
+		}
+		// the booking goes from booked to cancelled in its history
+		now := time.Now().UTC()
+		b.undoStatus()
+		b.setStatus(StatusCancelled, now)
+		b.record(EventBookingCancelled, now)
+		return true, nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("id", id).Msg("failed to store the cancelled booking, its shipment is cancelled and it is left cancelling")
+		return nil, err
+	}
+	return b, nil
+}
+
+// restore books a booking again whose shipment the carrier failed to cancel.
+// A booking that can not be restored is left cancelling with its shipment,
+// which is logged to be sorted out by hand.
+func (s *Service) restore(ctx context.Context, b *booking) {
+	logger := logging.FromContext(ctx, "booking")
+
+	_, err := s.store.TransitionBooking(ctx, b.Id(), func(b *booking) (bool, error) {
+		if b.Status() != StatusCancelling {
+			return false, nil
+		}
+		b.undoStatus()
+		return true, nil
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("id", b.Id()).Str("carrier", b.Carrier()).Str("reference", b.CarrierReference()).Msg("failed to restore booking, it is left cancelling with its shipment")
+	}
+}
+
+// FindBooking returns a booking of any client, for services acting on behalf
+// of the carriers rather than a client.
+func (s *Service) FindBooking(ctx context.Context, id string) (_ *booking, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.FindBooking", trace.WithAttributes(attribute.String("booking.id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.GetBooking(ctx, id)
+}
+
+// TrackStatus moves a booking to the status a carrier reported at the time.
+// Reports older than the current status and reports about bookings that are
+// delivered, returned or cancelled leave the booking as it is, so reports can
+// arrive in any order.
+func (s *Service) TrackStatus(ctx context.Context, id string, status Status, at time.Time) (_ *booking, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.TrackStatus", trace.WithAttributes(
+		attribute.String("booking.id", id),
+		attribute.String("booking.status", string(status)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return s.store.TransitionBooking(ctx, id, func(b *booking) (bool, error) {
+		// a booking being cancelled takes the status of the cancellation
+		if b.Status() == status || b.Status().final() || b.Status() == StatusCancelling || at.Before(b.statusChangedAt()) {
+			return false, nil
+		}
+
+		logger := logging.FromContext(ctx, "booking")
+		logger.Info().Str("id", id).Str("from", string(b.Status())).Str("to", string(status)).Msg("tracked booking status")
+
+		b.setStatus(status, at.UTC())
+		b.record(EventBookingStatusChanged, at.UTC())
+		return true, nil
+	})
+}
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// ListBookings returns a page of the bookings of the client, oldest first,
+// together with the offset of the next page or -1 when there is none.
+func (s *Service) ListBookings(ctx context.Context, clientId string, offset, pageSize int) (_ []*booking, next int, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.ListBookings", trace.WithAttributes(
+		attribute.Int("booking.offset", offset),
+		attribute.Int("booking.page_size", pageSize),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if clientId == "" {
+		return nil, 0, errors.FromCode(errors.CodeMissingCredentials, "empty client id", errors.ErrorUnauthorized)
+	}
+	if offset < 0 {
+		return nil, 0, errors.Validation("negative offset", errors.FieldError{Field: "offset", Code: errors.FieldOutOfRange, Message: "offset must not be negative"})
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	// fetch one more than asked for to know if there is a next page
+	bookings, err := s.store.ListBookings(ctx, clientId, offset, pageSize+1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(bookings) > pageSize {
+		return bookings[:pageSize], offset + pageSize, nil
+	}
+	return bookings, -1, nil
+}
+
+func (s *Service) BookShipping(ctx context.Context, clientId, origin, destination string, weight float32, selection Selection) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.BookShipping", trace.WithAttributes(
+		attribute.String("booking.origin", origin),
+		attribute.String("booking.destination", destination),
+		attribute.Float64("booking.weight", float64(weight)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.FromContext(ctx, "booking")
+	logger.Info().Str("clientId", clientId).Str("origin", origin).Str("destination", destination).Float32("weight", weight).Msg("booking shipping")
 
+	if clientId == "" {
+		return "", errors.FromCode(errors.CodeMissingCredentials, "empty client id", errors.ErrorUnauthorized)
+	}
 	if origin == "" {
-		return "", errors.FromMessage("empty origin", errors.ErrorInput)
+		return "", errors.Validation("empty origin", errors.FieldError{Field: "origin", Code: errors.FieldRequired, Message: "origin is required"})
 	}
 	if destination == "" {
-		return "", errors.FromMessage("empty destination", errors.ErrorInput)
+		return "", errors.Validation("empty destination", errors.FieldError{Field: "destination", Code: errors.FieldRequired, Message: "destination is required"})
+	}
+	if err := selection.validate(); err != nil {
+		return "", err
 	}
 
-	price, err := s.billingService.CalculateShippingCost(ctx, origin, destination, weight)
+	rate, err := s.selectRate(ctx, address{country: origin}, address{country: destination}, []parcel{{weight: weight}}, selection)
 	if err != nil {
 		return "", err
 	}
@@ -56,14 +297,142 @@ func (s *Service) BookShipping(ctx context.Context, origin, destination string,
 	id := uuid.New().String()
 	sh, err := NewBooking(
 		id,
+		clientId,
 		origin,
 		destination,
 		weight,
-		price,
+		rate.Price,
 	)
 	if err != nil {
 		return "", err
 	}
+	if err := s.ship(ctx, sh, rate); err != nil {
+		return "", err
+	}
+
+	s.metrics.BookingCreated(origin, destination)
+	logger.Info().Str("id", id).Float32("price", rate.Price).Str("serviceLevel", rate.ServiceLevel).Msg("booked shipping")
+	return id, nil
+}
+
+func (s *Service) BookParcels(ctx context.Context, clientId string, origin, destination address, parcels []parcel, selection Selection) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "booking.Service.BookParcels", trace.WithAttributes(
+		attribute.String("booking.origin", origin.country),
+		attribute.String("booking.destination", destination.country),
+		attribute.Int("booking.parcels", len(parcels)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.FromContext(ctx, "booking")
+	logger.Info().Str("clientId", clientId).Str("origin", origin.country).Str("destination", destination.country).Int("parcels", len(parcels)).Msg("booking parcels")
+
+	if clientId == "" {
+		return "", errors.FromCode(errors.CodeMissingCredentials, "empty client id", errors.ErrorUnauthorized)
+	}
+	if origin.country == "" {
+		return "", errors.Validation("empty origin country", errors.FieldError{Field: "origin.country", Code: errors.FieldRequired, Message: "origin.country is required"})
+	}
+	if destination.country == "" {
+		return "", errors.Validation("empty destination country", errors.FieldError{Field: "destination.country", Code: errors.FieldRequired, Message: "destination.country is required"})
+	}
+	if len(parcels) == 0 {
+		return "", errors.Validation("no parcels", errors.FieldError{Field: "parcels", Code: errors.FieldRequired, Message: "parcels must contain at least one parcel"})
+	}
+	if err := selection.validate(); err != nil {
+		return "", err
+	}
+
+	rate, err := s.selectRate(ctx, origin, destination, parcels, selection)
+	if err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	b, err := NewParcelBooking(id, clientId, origin, destination, parcels, rate.Price)
+	if err != nil {
+		return "", err
+	}
+	if err := s.ship(ctx, b, rate); err != nil {
+		return "", err
+	}
+
+	s.metrics.BookingCreated(origin.country, destination.country)
+	logger.Info().Str("id", id).Float32("price", rate.Price).Str("serviceLevel", rate.ServiceLevel).Msg("booked parcels")
+	return id, nil
+}
+
+// selectRate returns the carrier option picked by the selection, or the
+// tariff rate without a carrier if it picks no option.
+func (s *Service) selectRate(ctx context.Context, origin, destination address, parcels []parcel, selection Selection) (carrier.Rate, error) {
+	if selection.Option == "" && selection.Policy == "" {
+		return s.tariffRate(ctx, origin.country, destination.country, parcels, selection.ServiceLevel)
+	}
+	return s.billingService.SelectOption(ctx, toCarrierRequest("", origin, destination, parcels, selection.ServiceLevel), selection.Option, selection.Policy)
+}
+
+// tariffRate prices the parcels by the tariff of the service level, standard
+// if empty.
+func (s *Service) tariffRate(ctx context.Context, origin, destination string, parcels []parcel, serviceLevel billing.ServiceLevel) (carrier.Rate, error) {
+	if serviceLevel == "" {
+		serviceLevel = billing.ServiceLevelStandard
+	}
+
+	rate := carrier.Rate{ServiceLevel: string(serviceLevel)}
+	for _, p := range parcels {
+		price, err := s.billingService.CalculateShippingCost(ctx, origin, destination, p.weight, serviceLevel)
+		if err != nil {
+			return carrier.Rate{}, err
+		}
+		rate.Price += price
+	}
+
+	transitDays, err := s.billingService.TransitDays(ctx, origin, destination, serviceLevel)
+	if err != nil {
+		return carrier.Rate{}, err
+	}
+	rate.TransitDays = transitDays
+	return rate, nil
+}
+
+// ship hands a new booking to the carrier of the rate, or the preferred
+// carrier of its lane if the rate has none, and stores it. The shipment is
+// cancelled again if the booking could not be stored.
+func (s *Service) ship(ctx context.Context, b *booking, rate carrier.Rate) error {
+	pickup, delivery := s.billingService.Estimate(b.origin, b.destination, b.CreatedAt(), rate.TransitDays)
+	b.schedule(billing.ServiceLevel(rate.ServiceLevel), rate.TransitDays, pickup, delivery)
+
+	code, reference, err := s.carrierService.CreateShipment(ctx, rate.Carrier, toCarrierRequest(b.id, b.originAddress, b.destinationAddress, b.parcels, b.serviceLevel))
+	if err != nil {
+		return err
+	}
+	b.assignCarrier(code, reference)
+	b.record(EventBookingCreated, b.CreatedAt())
+	b.record(EventBookingPriced, b.CreatedAt())
+
+	if err := s.store.AddBooking(ctx, b); err != nil {
+		if cancelErr := s.carrierService.CancelShipment(ctx, code, reference); cancelErr != nil {
+			logger := logging.FromContext(ctx, "booking")
+			logger.Error().Err(cancelErr).Str("carrier", code).Str("reference", reference).Msg("failed to cancel the shipment of an unstored booking")
+		}
+		return err
+	}
+	return nil
+}
+
+func toCarrierRequest(id string, origin, destination address, parcels []parcel, serviceLevel billing.ServiceLevel) carrier.Request {
+	carrierParcels := make([]carrier.Parcel, 0, len(parcels))
+	for _, p := range parcels {
+		carrierParcels = append(carrierParcels, carrier.Parcel{Weight: p.weight, Length: p.length, Width: p.width, Height: p.height})
+	}
+	return carrier.Request{
+		BookingId:    id,
+		Origin:       toCarrierAddress(origin),
+		Destination:  toCarrierAddress(destination),
+		Parcels:      carrierParcels,
+		ServiceLevel: string(serviceLevel),
+	}
+}
 
-	return id, s.store.AddBooking(ctx, sh)
+func toCarrierAddress(a address) carrier.Address {
+	return carrier.Address{Name: a.name, Street: a.street, PostalCode: a.postalCode, City: a.city, Country: a.country}
 }
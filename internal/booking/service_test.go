@@ -3,7 +3,17 @@ package booking
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
 
 	"github.com/stretchr/testify/require"
 )
@@ -21,30 +31,43 @@ type storeReturn struct {
 var (
 	successfulBilling = billingReturn{50, nil}
 	errorBilling      = billingReturn{0, errors.New("billing error")}
-	successfulStore   = storeReturn{&booking{id: "test-id"}, nil}
+	successfulStore   = storeReturn{&booking{id: "test-id", clientId: "test-client"}, nil}
 	errorStore        = storeReturn{nil, errors.New("store error")}
 )
 
 func TestBookShipping(t *testing.T) {
 	testCases := []struct {
 		name          string
+		clientId      string
 		origin        string
 		destination   string
 		weight        float32
 		billingReturn billingReturn
 		storeReturn   storeReturn
+		carrierErr    error
 		shouldFail    bool
 	}{
 		{
 			name:          "good booking",
+			clientId:      "test-client",
 			origin:        "SE",
 			destination:   "SE",
 			billingReturn: successfulBilling,
 			storeReturn:   successfulStore,
 			shouldFail:    false,
 		},
+		{
+			name:          "missing client id",
+			clientId:      "",
+			origin:        "SE",
+			destination:   "SE",
+			billingReturn: successfulBilling,
+			storeReturn:   successfulStore,
+			shouldFail:    true,
+		},
 		{
 			name:          "bad origin",
+			clientId:      "test-client",
 			origin:        "",
 			destination:   "DK",
 			billingReturn: successfulBilling,
@@ -53,6 +76,7 @@ func TestBookShipping(t *testing.T) {
 		},
 		{
 			name:          "bad destination",
+			clientId:      "test-client",
 			origin:        "SE",
 			destination:   "",
 			billingReturn: successfulBilling,
@@ -61,6 +85,7 @@ func TestBookShipping(t *testing.T) {
 		},
 		{
 			name:          "billing error",
+			clientId:      "test-client",
 			origin:        "SE",
 			destination:   "SE",
 			billingReturn: errorBilling,
@@ -69,12 +94,23 @@ func TestBookShipping(t *testing.T) {
 		},
 		{
 			name:          "store error",
+			clientId:      "test-client",
 			origin:        "SE",
 			destination:   "SE",
 			billingReturn: successfulBilling,
 			storeReturn:   errorStore,
 			shouldFail:    true,
 		},
+		{
+			name:          "carrier error",
+			clientId:      "test-client",
+			origin:        "SE",
+			destination:   "SE",
+			billingReturn: successfulBilling,
+			storeReturn:   successfulStore,
+			carrierErr:    apierrors.FromCode(apierrors.CodeCarrierUnavailable, "carrier down", apierrors.ErrorUnavailable),
+			shouldFail:    true,
+		},
 	}
 
 	for i := range testCases {
@@ -86,11 +122,14 @@ func TestBookShipping(t *testing.T) {
 			bundle.billingService.err = tc.billingReturn.err
 			bundle.store.sh = tc.storeReturn.sh
 			bundle.store.err = tc.storeReturn.err
+			bundle.carrierService.err = tc.carrierErr
 			id, err := bundle.service.BookShipping(
 				context.Background(),
+				tc.clientId,
 				tc.origin,
 				tc.destination,
 				10,
+				Selection{},
 			)
 			if tc.shouldFail {
 				require.NotNil(t, err, "expected an error, got nil")
@@ -106,16 +145,40 @@ func TestBookShipping(t *testing.T) {
 func TestGetBooking(t *testing.T) {
 	testCases := []struct {
 		name        string
+		clientId    string
+		roles       []auth.Role
 		storeReturn storeReturn
 		shouldFail  bool
 	}{
 		{
 			name:        "good booking",
+			clientId:    "test-client",
 			storeReturn: successfulStore,
 			shouldFail:  false,
 		},
+		{
+			name:        "other client",
+			clientId:    "other-client",
+			storeReturn: successfulStore,
+			shouldFail:  true,
+		},
+		{
+			name:        "other customer",
+			clientId:    "other-client",
+			roles:       []auth.Role{auth.RoleCustomer},
+			storeReturn: successfulStore,
+			shouldFail:  true,
+		},
+		{
+			name:        "operator",
+			clientId:    "operator",
+			roles:       []auth.Role{auth.RoleOperator},
+			storeReturn: successfulStore,
+			shouldFail:  true,
+		},
 		{
 			name:        "store error",
+			clientId:    "test-client",
 			storeReturn: errorStore,
 			shouldFail:  true,
 		},
@@ -128,7 +191,8 @@ func TestGetBooking(t *testing.T) {
 			bundle.store.sh = tc.storeReturn.sh
 			bundle.store.err = tc.storeReturn.err
 			actual, err := bundle.service.GetBooking(
-				context.Background(),
+				auth.WithRoles(context.Background(), tc.roles),
+				tc.clientId,
 				"mock-id",
 			)
 			if tc.shouldFail {
@@ -142,40 +206,970 @@ func TestGetBooking(t *testing.T) {
 	}
 }
 
+func TestViewBooking(t *testing.T) {
+	testCases := []struct {
+		name        string
+		clientId    string
+		roles       []auth.Role
+		storeReturn storeReturn
+		shouldFail  bool
+	}{
+		{
+			name:        "good booking",
+			clientId:    "test-client",
+			roles:       []auth.Role{auth.RoleCustomer},
+			storeReturn: successfulStore,
+			shouldFail:  false,
+		},
+		{
+			name:        "other customer",
+			clientId:    "other-client",
+			roles:       []auth.Role{auth.RoleCustomer},
+			storeReturn: successfulStore,
+			shouldFail:  true,
+		},
+		{
+			name:        "operator",
+			clientId:    "operator",
+			roles:       []auth.Role{auth.RoleOperator},
+			storeReturn: successfulStore,
+			shouldFail:  false,
+		},
+		{
+			name:        "admin",
+			clientId:    "admin",
+			roles:       []auth.Role{auth.RoleAdmin},
+			storeReturn: successfulStore,
+			shouldFail:  false,
+		},
+		{
+			name:        "store error",
+			clientId:    "admin",
+			roles:       []auth.Role{auth.RoleAdmin},
+			storeReturn: errorStore,
+			shouldFail:  true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.store.sh = tc.storeReturn.sh
+			bundle.store.err = tc.storeReturn.err
+			actual, err := bundle.service.ViewBooking(
+				auth.WithRoles(context.Background(), tc.roles),
+				tc.clientId,
+				"mock-id",
+			)
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.storeReturn.sh.id, actual.id)
+		})
+	}
+}
+
+func TestBookParcels(t *testing.T) {
+	se, _ := NewAddress("Sender", "Storgatan 1", "11122", "Stockholm", "SE")
+	dk, _ := NewAddress("", "", "", "", "DK")
+	small, _ := NewParcel(5, 10, 10, 10)
+	large, _ := NewParcel(40, 0, 0, 0)
+
+	testCases := []struct {
+		name          string
+		clientId      string
+		origin        address
+		destination   address
+		parcels       []parcel
+		billingReturn billingReturn
+		storeReturn   storeReturn
+		expectedPrice float32
+		shouldFail    bool
+	}{
+		{
+			name:          "good booking",
+			clientId:      "test-client",
+			origin:        se,
+			destination:   dk,
+			parcels:       []parcel{small, large},
+			billingReturn: successfulBilling,
+			storeReturn:   successfulStore,
+			expectedPrice: 100,
+			shouldFail:    false,
+		},
+		{
+			name:          "missing client id",
+			origin:        se,
+			destination:   dk,
+			parcels:       []parcel{small},
+			billingReturn: successfulBilling,
+			storeReturn:   successfulStore,
+			shouldFail:    true,
+		},
+		{
+			name:          "missing origin country",
+			clientId:      "test-client",
+			origin:        address{},
+			destination:   dk,
+			parcels:       []parcel{small},
+			billingReturn: successfulBilling,
+			storeReturn:   successfulStore,
+			shouldFail:    true,
+		},
+		{
+			name:          "no parcels",
+			clientId:      "test-client",
+			origin:        se,
+			destination:   dk,
+			billingReturn: successfulBilling,
+			storeReturn:   successfulStore,
+			shouldFail:    true,
+		},
+		{
+			name:          "billing error",
+			clientId:      "test-client",
+			origin:        se,
+			destination:   dk,
+			parcels:       []parcel{small},
+			billingReturn: errorBilling,
+			storeReturn:   successfulStore,
+			shouldFail:    true,
+		},
+		{
+			name:          "store error",
+			clientId:      "test-client",
+			origin:        se,
+			destination:   dk,
+			parcels:       []parcel{small},
+			billingReturn: successfulBilling,
+			storeReturn:   errorStore,
+			shouldFail:    true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.billingService.price = tc.billingReturn.price
+			bundle.billingService.err = tc.billingReturn.err
+			bundle.store.sh = tc.storeReturn.sh
+			bundle.store.err = tc.storeReturn.err
+			id, err := bundle.service.BookParcels(context.Background(), tc.clientId, tc.origin, tc.destination, tc.parcels, Selection{})
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.NotEqual(t, "", id, "expected bookingId to not be empty")
+			require.Equal(t, tc.expectedPrice, bundle.store.added.Price())
+			require.Equal(t, float32(45), bundle.store.added.Weight())
+			require.Equal(t, "Stockholm", bundle.store.added.OriginAddress().City())
+		})
+	}
+}
+
+func TestListBookings(t *testing.T) {
+	list := make([]*booking, 0, 5)
+	for i := 0; i < 5; i++ {
+		b, _ := NewBooking(fmt.Sprintf("id-%d", i), "test-client", "SE", "DK", 1, 100)
+		list = append(list, b)
+	}
+
+	testCases := []struct {
+		name         string
+		clientId     string
+		offset       int
+		pageSize     int
+		err          error
+		expectedIds  []string
+		expectedNext int
+		shouldFail   bool
+	}{
+		{
+			name:         "first page",
+			clientId:     "test-client",
+			offset:       0,
+			pageSize:     2,
+			expectedIds:  []string{"id-0", "id-1"},
+			expectedNext: 2,
+		},
+		{
+			name:         "last page",
+			clientId:     "test-client",
+			offset:       3,
+			pageSize:     2,
+			expectedIds:  []string{"id-3", "id-4"},
+			expectedNext: -1,
+		},
+		{
+			name:         "default page size",
+			clientId:     "test-client",
+			expectedIds:  []string{"id-0", "id-1", "id-2", "id-3", "id-4"},
+			expectedNext: -1,
+		},
+		{
+			name:         "past the end",
+			clientId:     "test-client",
+			offset:       10,
+			pageSize:     2,
+			expectedIds:  []string{},
+			expectedNext: -1,
+		},
+		{
+			name:       "missing client id",
+			shouldFail: true,
+		},
+		{
+			name:       "negative offset",
+			clientId:   "test-client",
+			offset:     -1,
+			shouldFail: true,
+		},
+		{
+			name:       "store error",
+			clientId:   "test-client",
+			err:        errors.New("store error"),
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.store.list = list
+			bundle.store.err = tc.err
+			bookings, next, err := bundle.service.ListBookings(context.Background(), tc.clientId, tc.offset, tc.pageSize)
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			ids := make([]string, 0, len(bookings))
+			for _, b := range bookings {
+				ids = append(ids, b.Id())
+			}
+			require.Equal(t, tc.expectedIds, ids)
+			require.Equal(t, tc.expectedNext, next)
+		})
+	}
+}
+
 type billingServiceMock struct {
-	price float32
-	err   error
+	price       float32
+	err         error
+	transitDays int
+	rate        carrier.Rate
+	selectErr   error
 }
 
-func (s billingServiceMock) CalculateShippingCost(_ context.Context, origin, destination string, weight float32) (float32, error) {
+func (s billingServiceMock) CalculateShippingCost(_ context.Context, origin, destination string, weight float32, serviceLevel billing.ServiceLevel) (float32, error) {
+	if serviceLevel == billing.ServiceLevelExpress {
+		return 2 * s.price, s.err
+	}
 	return s.price, s.err
 }
 
+func (s billingServiceMock) TransitDays(_ context.Context, origin, destination string, serviceLevel billing.ServiceLevel) (int, error) {
+	return s.transitDays, s.err
+}
+
+func (s billingServiceMock) Estimate(_, _ string, at time.Time, transitDays int) (time.Time, time.Time) {
+	pickup := at.AddDate(0, 0, 1)
+	return pickup, pickup.AddDate(0, 0, transitDays)
+}
+
+func (s billingServiceMock) SelectOption(_ context.Context, req carrier.Request, id string, policy billing.Policy) (carrier.Rate, error) {
+	return s.rate, s.selectErr
+}
+
 type storeMock struct {
-	sh  *booking
-	err error
+	sh      *booking
+	added   *booking
+	updated *booking
+	list    []*booking
+	err     error
+	// updateErr makes only updates fail.
+	updateErr error
 }
 
-func (r storeMock) AddBooking(_ context.Context, sh *booking) error {
+func (r *storeMock) AddBooking(_ context.Context, sh *booking) error {
+	r.added = sh
 	return r.err
 }
 
-func (r storeMock) GetBooking(_ context.Context, id string) (*booking, error) {
+func (r *storeMock) UpdateBooking(_ context.Context, b *booking) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.updated = b
+	return r.err
+}
+
+func (r *storeMock) TransitionBooking(_ context.Context, id string, transition func(*booking) (bool, error)) (*booking, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.updateErr != nil {
+		return nil, r.updateErr
+	}
+	changed, err := transition(r.sh)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return r.sh, nil
+	}
+	r.updated = r.sh
+	return r.sh, nil
+}
+
+func (r *storeMock) ListBookings(_ context.Context, clientId string, offset, limit int) ([]*booking, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if offset >= len(r.list) {
+		return []*booking{}, nil
+	}
+	list := r.list[offset:]
+	if limit < len(list) {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (r *storeMock) GetBooking(_ context.Context, id string) (*booking, error) {
 	return r.sh, r.err
 }
 
+type carrierServiceMock struct {
+	created   []carrier.Request
+	cancelled []string
+	err       error
+	cancelErr error
+}
+
+func (c *carrierServiceMock) CreateShipment(_ context.Context, code string, req carrier.Request) (string, string, error) {
+	if c.err != nil {
+		return "", "", c.err
+	}
+	if code == "" {
+		code = "test-carrier"
+	}
+	c.created = append(c.created, req)
+	return code, fmt.Sprintf("ref-%d", len(c.created)), nil
+}
+
+func (c *carrierServiceMock) CancelShipment(_ context.Context, code, reference string) error {
+	c.cancelled = append(c.cancelled, code+"/"+reference)
+	return c.cancelErr
+}
+
+type metricsMock struct{}
+
+func (m metricsMock) BookingCreated(origin, destination string) {}
+
 type bundle struct {
 	service        Service
 	store          *storeMock
 	billingService *billingServiceMock
+	carrierService *carrierServiceMock
 }
 
 func newTestBundle() bundle {
 	store := &storeMock{}
 	billingService := &billingServiceMock{}
+	carrierService := &carrierServiceMock{}
 	return bundle{
-		service:        NewService(store, billingService),
+		service:        NewService(store, billingService, carrierService, metricsMock{}),
 		store:          store,
 		billingService: billingService,
+		carrierService: carrierService,
+	}
+}
+
+func TestShipWithCarrier(t *testing.T) {
+	testCases := []struct {
+		name              string
+		storeErr          error
+		carrierErr        error
+		expectedType      apierrors.ErrorType
+		expectedCancelled []string
+	}{
+		{
+			name: "assigned",
+		},
+		{
+			name:         "carrier unavailable",
+			carrierErr:   apierrors.FromCode(apierrors.CodeCarrierUnavailable, "carrier down", apierrors.ErrorUnavailable),
+			expectedType: apierrors.ErrorUnavailable,
+		},
+		{
+			name:              "store error cancels the shipment",
+			storeErr:          errors.New("store error"),
+			expectedType:      apierrors.ErrorUnknown,
+			expectedCancelled: []string{"test-carrier/ref-1"},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.billingService.price = 50
+			bundle.store.err = tc.storeErr
+			bundle.carrierService.err = tc.carrierErr
+			origin, _ := NewAddress("Sender", "Storgatan 1", "11122", "Stockholm", "SE")
+			destination, _ := NewAddress("", "", "", "", "DK")
+			small, _ := NewParcel(5, 30, 20, 10)
+
+			id, err := bundle.service.BookParcels(context.Background(), "test-client", origin, destination, []parcel{small, small}, Selection{})
+			require.Equal(t, tc.expectedCancelled, bundle.carrierService.cancelled)
+			if tc.storeErr != nil || tc.carrierErr != nil {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedType, apierrors.GetType(err))
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Len(t, bundle.carrierService.created, 1)
+			req := bundle.carrierService.created[0]
+			require.Equal(t, id, req.BookingId)
+			require.Equal(t, "Stockholm", req.Origin.City)
+			require.Equal(t, "DK", req.Destination.Country)
+			require.Equal(t, float32(10), req.Weight())
+			require.Equal(t, "test-carrier", bundle.store.added.Carrier())
+			require.Equal(t, "ref-1", bundle.store.added.CarrierReference())
+		})
+	}
+}
+
+func TestBookWithSelection(t *testing.T) {
+	testCases := []struct {
+		name            string
+		selection       Selection
+		selectErr       error
+		expectedCode    apierrors.Code
+		expectedCarrier string
+		expectedPrice   float32
+		expectedLevel   billing.ServiceLevel
+		expectedTransit int
+	}{
+		{
+			name:            "preferred carrier at tariff price",
+			expectedCarrier: "test-carrier",
+			expectedPrice:   50,
+			expectedLevel:   billing.ServiceLevelStandard,
+			expectedTransit: 3,
+		},
+		{
+			name:            "preferred carrier at tariff price of service level",
+			selection:       Selection{ServiceLevel: billing.ServiceLevelExpress},
+			expectedCarrier: "test-carrier",
+			expectedPrice:   100,
+			expectedLevel:   billing.ServiceLevelExpress,
+			expectedTransit: 3,
+		},
+		{
+			name:            "by policy",
+			selection:       Selection{Policy: billing.PolicyFastest},
+			expectedCarrier: "express",
+			expectedPrice:   250,
+			expectedLevel:   billing.ServiceLevelStandard,
+			expectedTransit: 1,
+		},
+		{
+			name:            "by option",
+			selection:       Selection{Option: "express:standard"},
+			expectedCarrier: "express",
+			expectedPrice:   250,
+			expectedLevel:   billing.ServiceLevelStandard,
+			expectedTransit: 1,
+		},
+		{
+			name:         "option and policy",
+			selection:    Selection{Option: "express:standard", Policy: billing.PolicyCheapest},
+			expectedCode: apierrors.CodeValidationFailed,
+		},
+		{
+			name:         "option not offered",
+			selection:    Selection{Option: "missing:standard"},
+			selectErr:    apierrors.FromCode(apierrors.CodeOptionNotFound, "option missing:standard is not offered", apierrors.ErrorInput),
+			expectedCode: apierrors.CodeOptionNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			bundle.billingService.price = 50
+			bundle.billingService.transitDays = 3
+			bundle.billingService.rate = carrier.Rate{Carrier: "express", ServiceLevel: "standard", Price: 250, Currency: "SEK", TransitDays: 1}
+			bundle.billingService.selectErr = tc.selectErr
+
+			_, err := bundle.service.BookShipping(context.Background(), "test-client", "SE", "DK", 10, tc.selection)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				require.Empty(t, bundle.carrierService.created)
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			b := bundle.store.added
+			require.Equal(t, tc.expectedCarrier, b.Carrier())
+			require.Equal(t, tc.expectedPrice, b.Price())
+			require.Equal(t, tc.expectedLevel, b.ServiceLevel())
+			require.Equal(t, string(tc.expectedLevel), bundle.carrierService.created[0].ServiceLevel)
+			require.Equal(t, tc.expectedTransit, b.TransitDays())
+			require.Equal(t, b.CreatedAt().AddDate(0, 0, 1), b.EstimatedPickup())
+			require.Equal(t, b.CreatedAt().AddDate(0, 0, 1+tc.expectedTransit), b.EstimatedDelivery())
+		})
+	}
+}
+
+func TestCancelBooking(t *testing.T) {
+	cancelled, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+	cancelled.setStatus(StatusCancelled, cancelled.CreatedAt())
+
+	testCases := []struct {
+		name              string
+		clientId          string
+		storeReturn       func() storeReturn
+		cancelErr         error
+		updateErr         error
+		expectedCode      apierrors.Code
+		expectedCancelled []string
+		expectedRestored  bool
+	}{
+		{
+			name:     "booked",
+			clientId: "test-client",
+			storeReturn: func() storeReturn {
+				b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+				return storeReturn{b, nil}
+			},
+		},
+		{
+			name:     "booked with carrier",
+			clientId: "test-client",
+			storeReturn: func() storeReturn {
+				b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+				b.assignCarrier("test-carrier", "ref-1")
+				return storeReturn{b, nil}
+			},
+			expectedCancelled: []string{"test-carrier/ref-1"},
+		},
+		{
+			name:     "shipment unknown to carrier",
+			clientId: "test-client",
+			storeReturn: func() storeReturn {
+				b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+				b.assignCarrier("test-carrier", "ref-1")
+				return storeReturn{b, nil}
+			},
+			cancelErr:         apierrors.NotFound(apierrors.CodeNotFound, "shipment", "ref-1"),
+			expectedCancelled: []string{"test-carrier/ref-1"},
+		},
+		{
+			name:     "carrier unavailable",
+			clientId: "test-client",
+			storeReturn: func() storeReturn {
+				b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+				b.assignCarrier("test-carrier", "ref-1")
+				return storeReturn{b, nil}
+			},
+			cancelErr:         apierrors.FromCode(apierrors.CodeCarrierUnavailable, "carrier down", apierrors.ErrorUnavailable),
+			expectedCode:      apierrors.CodeCarrierUnavailable,
+			expectedCancelled: []string{"test-carrier/ref-1"},
+			expectedRestored:  true,
+		},
+		{
+			name:     "update error",
+			clientId: "test-client",
+			storeReturn: func() storeReturn {
+				b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+				b.assignCarrier("test-carrier", "ref-1")
+				return storeReturn{b, nil}
+			},
+			updateErr:    errors.New("store error"),
+			expectedCode: apierrors.CodeUnknown,
+		},
+		{
+			name:         "other client",
+			clientId:     "other-client",
+			storeReturn:  func() storeReturn { return successfulStore },
+			expectedCode: apierrors.CodeBookingNotFound,
+		},
+		{
+			name:     "being cancelled",
+			clientId: "test-client",
+			storeReturn: func() storeReturn {
+				b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+				b.setStatus(StatusCancelling, b.CreatedAt())
+				return storeReturn{b, nil}
+			},
+			expectedCode: apierrors.CodeBookingNotCancellable,
+		},
+		{
+			name:         "already cancelled",
+			clientId:     "test-client",
+			storeReturn:  func() storeReturn { return storeReturn{cancelled, nil} },
+			expectedCode: apierrors.CodeBookingNotCancellable,
+		},
+		{
+			name:         "store error",
+			clientId:     "test-client",
+			storeReturn:  func() storeReturn { return errorStore },
+			expectedCode: apierrors.CodeUnknown,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			storeReturn := tc.storeReturn()
+			bundle.store.sh = storeReturn.sh
+			bundle.store.err = storeReturn.err
+			bundle.store.updateErr = tc.updateErr
+			bundle.carrierService.cancelErr = tc.cancelErr
+
+			b, err := bundle.service.CancelBooking(context.Background(), tc.clientId, "test-id")
+			require.Equal(t, tc.expectedCancelled, bundle.carrierService.cancelled)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				if tc.expectedRestored {
+					require.Equal(t, StatusBooked, bundle.store.updated.Status(), "expected the booking to be booked again")
+					require.Len(t, bundle.store.updated.StatusHistory(), 1)
+					require.Empty(t, bundle.store.updated.Events(), "expected no event for a cancellation the carrier failed")
+					return
+				}
+				require.Nil(t, bundle.store.updated)
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, StatusCancelled, b.Status())
+			require.Len(t, b.StatusHistory(), 2)
+			require.Equal(t, StatusBooked, b.StatusHistory()[0].Status())
+			require.Equal(t, b, bundle.store.updated)
+			events := b.Events()
+			require.Len(t, events, 1)
+			require.Equal(t, EventBookingCancelled, events[0].Type)
+		})
+	}
+}
+
+func TestTrackStatus(t *testing.T) {
+	createdAt := time.Date(2030, 1, 7, 8, 0, 0, 0, time.UTC)
+	newBooking := func(statuses ...Status) func() storeReturn {
+		return func() storeReturn {
+			b, _ := NewBooking("test-id", "test-client", "SE", "DK", 1, 100)
+			b.history[0].at = createdAt
+			for i, status := range statuses {
+				b.setStatus(status, createdAt.Add(time.Duration(i+1)*time.Hour))
+			}
+			return storeReturn{b, nil}
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		storeReturn     func() storeReturn
+		status          Status
+		at              time.Time
+		expectedCode    apierrors.Code
+		expectedStatus  Status
+		expectedChanged bool
+	}{
+		{
+			name:            "picked up",
+			storeReturn:     newBooking(),
+			status:          StatusPickedUp,
+			at:              createdAt.Add(time.Hour),
+			expectedStatus:  StatusPickedUp,
+			expectedChanged: true,
+		},
+		{
+			name:            "exception cleared",
+			storeReturn:     newBooking(StatusPickedUp, StatusException),
+			status:          StatusInTransit,
+			at:              createdAt.Add(3 * time.Hour),
+			expectedStatus:  StatusInTransit,
+			expectedChanged: true,
+		},
+		{
+			name:           "same status",
+			storeReturn:    newBooking(StatusPickedUp),
+			status:         StatusPickedUp,
+			at:             createdAt.Add(2 * time.Hour),
+			expectedStatus: StatusPickedUp,
+		},
+		{
+			name:           "older than the status",
+			storeReturn:    newBooking(StatusPickedUp, StatusInTransit),
+			status:         StatusPickedUp,
+			at:             createdAt.Add(90 * time.Minute),
+			expectedStatus: StatusInTransit,
+		},
+		{
+			name:           "being cancelled",
+			storeReturn:    newBooking(StatusCancelling),
+			status:         StatusPickedUp,
+			at:             createdAt.Add(2 * time.Hour),
+			expectedStatus: StatusCancelling,
+		},
+		{
+			name:           "delivered",
+			storeReturn:    newBooking(StatusPickedUp, StatusDelivered),
+			status:         StatusException,
+			at:             createdAt.Add(3 * time.Hour),
+			expectedStatus: StatusDelivered,
+		},
+		{
+			name:           "cancelled",
+			storeReturn:    newBooking(StatusCancelled),
+			status:         StatusPickedUp,
+			at:             createdAt.Add(2 * time.Hour),
+			expectedStatus: StatusCancelled,
+		},
+		{
+			name:         "store error",
+			storeReturn:  func() storeReturn { return errorStore },
+			status:       StatusPickedUp,
+			at:           createdAt.Add(time.Hour),
+			expectedCode: apierrors.CodeUnknown,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			bundle := newTestBundle()
+			storeReturn := tc.storeReturn()
+			bundle.store.sh = storeReturn.sh
+			bundle.store.err = storeReturn.err
+
+			b, err := bundle.service.TrackStatus(context.Background(), "test-id", tc.status, tc.at)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedStatus, b.Status())
+			if !tc.expectedChanged {
+				require.Nil(t, bundle.store.updated)
+				require.Empty(t, b.Events())
+				return
+			}
+			require.Equal(t, b, bundle.store.updated)
+			require.Equal(t, tc.at, b.statusChangedAt())
+			require.Len(t, b.Events(), 1)
+			require.Equal(t, EventBookingStatusChanged, b.Events()[0].Type)
+			require.Equal(t, tc.expectedStatus, b.Events()[0].Status)
+		})
 	}
 }
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/bookings.json"
+
+	store, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	service := NewService(store, billingServiceMock{price: 50, transitDays: 2}, &carrierServiceMock{}, metricsMock{})
+	origin, _ := NewAddress("Sender", "Storgatan 1", "11122", "Stockholm", "SE")
+	destination, _ := NewAddress("", "", "", "", "DK")
+	small, _ := NewParcel(5, 30, 20, 10)
+	id, err := service.BookParcels(ctx, "test-client", origin, destination, []parcel{small, small}, Selection{ServiceLevel: billing.ServiceLevelEconomy})
+	require.Nilf(t, err, "unexpected error")
+	_, err = service.CancelBooking(ctx, "test-client", id)
+	require.Nilf(t, err, "unexpected error")
+
+	reloaded, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	reloadedService := NewService(reloaded, billingServiceMock{}, &carrierServiceMock{}, metricsMock{})
+	b, err := reloadedService.GetBooking(ctx, "test-client", id)
+	require.Nilf(t, err, "unexpected error")
+	require.Equal(t, "Stockholm", b.OriginAddress().City())
+	require.Len(t, b.Parcels(), 2)
+	require.Equal(t, float32(10), b.Weight())
+	require.Equal(t, float32(100), b.Price())
+	require.Equal(t, StatusCancelled, b.Status())
+	require.Len(t, b.StatusHistory(), 2)
+	require.Equal(t, "test-carrier", b.Carrier())
+	require.Equal(t, "ref-1", b.CarrierReference())
+	require.Equal(t, billing.ServiceLevelEconomy, b.ServiceLevel())
+	require.Equal(t, 2, b.TransitDays())
+	require.Equal(t, formatDate(b.CreatedAt().AddDate(0, 0, 1)), formatDate(b.EstimatedPickup()))
+	require.Equal(t, formatDate(b.CreatedAt().AddDate(0, 0, 3)), formatDate(b.EstimatedDelivery()))
+}
+
+func TestFileStoreOutbox(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/bookings.json"
+
+	store, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	service := NewService(store, billingServiceMock{price: 50}, &carrierServiceMock{}, metricsMock{})
+	_, err = service.BookShipping(ctx, "test-client", "SE", "DK", 10, Selection{})
+	require.Nilf(t, err, "unexpected error")
+	pending, err := store.PendingEvents(ctx, "", 1)
+	require.Nilf(t, err, "unexpected error")
+	require.Nil(t, store.MarkPublished(ctx, pending[0].Id))
+
+	reloaded, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	pending, err = reloaded.PendingEvents(ctx, "", 10)
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, pending, 1, "expected the unpublished event to survive a restart")
+	require.Equal(t, EventBookingPriced, pending[0].Type)
+	require.Equal(t, float32(50), pending[0].Price)
+}
+
+func TestFileStoreConcurrentSaves(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bookings.json")
+
+	store, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	service := NewService(store, billingServiceMock{price: 50}, &carrierServiceMock{}, metricsMock{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.BookShipping(ctx, "test-client", "SE", "DK", 10, Selection{})
+			require.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// every booking is in the file whichever save finished last
+	reloaded, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	bookings, err := reloaded.ListBookings(ctx, "test-client", 0, 100)
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, bookings, 20)
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, entries, 1, "expected no temporary files to be left")
+}
+
+func TestFileStoreSaveError(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "data")
+	require.Nil(t, os.Mkdir(dir, 0700))
+
+	store, err := NewFileStore(filepath.Join(dir, "bookings.json"))
+	require.Nilf(t, err, "unexpected error")
+	service := NewService(store, billingServiceMock{price: 50}, &carrierServiceMock{}, metricsMock{})
+	id, err := service.BookShipping(ctx, "test-client", "SE", "DK", 10, Selection{})
+	require.Nilf(t, err, "unexpected error")
+
+	// every save fails from here on
+	require.Nil(t, os.RemoveAll(dir))
+
+	_, err = service.BookShipping(ctx, "test-client", "SE", "DK", 10, Selection{})
+	require.NotNil(t, err, "expected an error, got nil")
+	bookings, err := store.ListBookings(ctx, "test-client", 0, 10)
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, bookings, 1, "expected the booking that failed to save to not be kept")
+
+	_, err = service.CancelBooking(ctx, "test-client", id)
+	require.NotNil(t, err, "expected an error, got nil")
+	b, err := store.GetBooking(ctx, id)
+	require.Nilf(t, err, "unexpected error")
+	require.Equal(t, StatusBooked, b.Status())
+
+	pending, err := store.PendingEvents(ctx, "", 10)
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, pending, 2, "expected only the events of the saved booking")
+	for _, e := range pending {
+		require.Equal(t, id, e.BookingId)
+	}
+	require.NotNil(t, store.MarkPublished(ctx, pending[0].Id))
+	pending, err = store.PendingEvents(ctx, "", 10)
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, pending, 2)
+
+	// a report that changes nothing does not save
+	_, err = service.TrackStatus(ctx, id, StatusBooked, time.Now())
+	require.Nilf(t, err, "unexpected error")
+}
+
+func TestFileStoreLegacyFormat(t *testing.T) {
+	path := t.TempDir() + "/bookings.json"
+	legacy := `[{"id":"test-id","clientId":"test-client","origin":{"country":"SE"},"destination":{"country":"DK"},"parcels":[{"weight":1}],"price":100,"history":[]}]`
+	require.Nil(t, os.WriteFile(path, []byte(legacy), 0600))
+
+	store, err := NewFileStore(path)
+	require.Nilf(t, err, "unexpected error")
+	b, err := store.GetBooking(context.Background(), "test-id")
+	require.Nilf(t, err, "unexpected error")
+	require.Equal(t, "test-client", b.ClientId())
+	require.Equal(t, billing.ServiceLevelStandard, b.ServiceLevel())
+	require.True(t, b.EstimatedPickup().IsZero())
+	require.True(t, b.EstimatedDelivery().IsZero())
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	service := NewService(store, billingServiceMock{price: 50}, &carrierServiceMock{}, metricsMock{})
+
+	id, err := service.BookShipping(ctx, "test-client", "SE", "DK", 10, Selection{})
+	require.Nil(t, err)
+	_, err = service.CancelBooking(ctx, "test-client", id)
+	require.Nil(t, err)
+
+	events, err := store.PendingEvents(ctx, "", 10)
+	require.Nil(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, EventBookingCreated, events[0].Type)
+	require.Equal(t, StatusBooked, events[0].Status)
+	require.Equal(t, EventBookingPriced, events[1].Type)
+	require.Equal(t, float32(50), events[1].Price)
+	require.Equal(t, "SEK", events[1].Currency)
+	require.Equal(t, EventBookingCancelled, events[2].Type)
+	require.Equal(t, StatusCancelled, events[2].Status)
+	ids := map[string]bool{}
+	for _, e := range events {
+		require.Equal(t, id, e.BookingId)
+		require.Equal(t, "test-client", e.ClientId)
+		ids[e.Id] = true
+	}
+	require.Len(t, ids, 3, "expected every event to have its own id")
+
+	after, err := store.PendingEvents(ctx, events[0].Id, 1)
+	require.Nil(t, err)
+	require.Equal(t, events[1:2], after)
+
+	require.Nil(t, store.MarkPublished(ctx, events[0].Id, events[1].Id))
+	pending, err := store.PendingEvents(ctx, "", 10)
+	require.Nil(t, err)
+	require.Equal(t, events[2:], pending)
+	pending, err = store.PendingEvents(ctx, events[1].Id, 10)
+	require.Nil(t, err)
+	require.Equal(t, events[2:], pending, "expected a published event to start at the oldest pending event")
+}
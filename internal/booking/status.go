@@ -0,0 +1,37 @@
+package booking
+
+import "time"
+
+type Status string
+
+const (
+	StatusBooked         Status = "booked"
+	StatusPickedUp       Status = "picked_up"
+	StatusInTransit      Status = "in_transit"
+	StatusOutForDelivery Status = "out_for_delivery"
+	StatusDelivered      Status = "delivered"
+	StatusException      Status = "exception"
+	StatusReturned       Status = "returned"
+	// StatusCancelling is a booking whose shipment the carrier is cancelling,
+	// it is cancelled or booked again once the carrier is done.
+	StatusCancelling Status = "cancelling"
+	StatusCancelled  Status = "cancelled"
+)
+
+// final tells whether a booking with the status no longer changes.
+func (s Status) final() bool {
+	return s == StatusDelivered || s == StatusReturned || s == StatusCancelled
+}
+
+type statusChange struct {
+	status Status
+	at     time.Time
+}
+
+func (c statusChange) Status() Status {
+	return c.status
+}
+
+func (c statusChange) At() time.Time {
+	return c.at
+}
@@ -0,0 +1,131 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Holiday is a day without pickups or deliveries, a yearly holiday falls on
+// the month and day of Date every year.
+type Holiday struct {
+	Date   time.Time
+	Name   string
+	Yearly bool
+}
+
+type Config struct {
+	// TimeZone is the zone of the cut-off, UTC if nil.
+	TimeZone *time.Location
+	// CutOff is the time of day after which parcels handed over are picked
+	// up the next business day.
+	CutOff   time.Duration
+	Holidays []Holiday
+}
+
+// Calendar tells business days from weekends and holidays of a location and
+// when parcels handed over there are picked up. Dates are midnight UTC of the
+// day in the time zone of the calendar.
+type Calendar struct {
+	timeZone *time.Location
+	cutOff   time.Duration
+	holidays map[string]Holiday
+	yearly   map[string]Holiday
+}
+
+func New(config Config) Calendar {
+	c := Calendar{timeZone: config.TimeZone, cutOff: config.CutOff, holidays: map[string]Holiday{}, yearly: map[string]Holiday{}}
+	if c.timeZone == nil {
+		c.timeZone = time.UTC
+	}
+	for _, h := range config.Holidays {
+		h.Date = date(h.Date)
+		if h.Yearly {
+			c.yearly[h.Date.Format("01-02")] = h
+			continue
+		}
+		c.holidays[h.Date.Format(dateLayout)] = h
+	}
+	return c
+}
+
+func (c Calendar) TimeZone() *time.Location {
+	return c.timeZone
+}
+
+func (c Calendar) CutOff() time.Duration {
+	return c.cutOff
+}
+
+// Holidays returns the holidays of the calendar by date.
+func (c Calendar) Holidays() []Holiday {
+	holidays := make([]Holiday, 0, len(c.holidays)+len(c.yearly))
+	for _, h := range c.holidays {
+		holidays = append(holidays, h)
+	}
+	for _, h := range c.yearly {
+		holidays = append(holidays, h)
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+	return holidays
+}
+
+func (c Calendar) IsBusinessDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	if _, ok := c.holidays[t.Format(dateLayout)]; ok {
+		return false
+	}
+	_, ok := c.yearly[t.Format("01-02")]
+	return !ok
+}
+
+// AddBusinessDays returns the date the given number of business days after
+// the date of t, with zero days the first business day from t on.
+func (c Calendar) AddBusinessDays(t time.Time, days int) time.Time {
+	d := date(t)
+	for !c.IsBusinessDay(d) {
+		d = d.AddDate(0, 0, 1)
+	}
+	for days > 0 {
+		d = d.AddDate(0, 0, 1)
+		if c.IsBusinessDay(d) {
+			days--
+		}
+	}
+	return d
+}
+
+// NextPickup returns the date a parcel handed over at the time is picked up,
+// the same day on business days before the cut-off and else the next
+// business day.
+func (c Calendar) NextPickup(at time.Time) time.Time {
+	local := at.In(c.timeZone)
+	today := date(local)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	if c.IsBusinessDay(today) && sinceMidnight < c.cutOff {
+		return today
+	}
+	return c.AddBusinessDays(today.AddDate(0, 0, 1), 0)
+}
+
+// ParseCutOff parses a time of day as 15:04.
+func ParseCutOff(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cut-off %q, must be a time of day as 15:04", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatCutOff(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// date returns midnight UTC of the day of t in its own zone.
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
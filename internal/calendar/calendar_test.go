@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBusinessDays(t *testing.T) {
+	// 2026-10-16 is a friday
+	friday := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+	saturday := friday.AddDate(0, 0, 1)
+
+	testCases := []struct {
+		name     string
+		calendar Calendar
+		from     time.Time
+		days     int
+		expected string
+	}{
+		{
+			name:     "same day",
+			calendar: New(Config{}),
+			from:     friday,
+			expected: "2026-10-16",
+		},
+		{
+			name:     "over the weekend",
+			calendar: New(Config{}),
+			from:     friday,
+			days:     1,
+			expected: "2026-10-19",
+		},
+		{
+			name:     "from a weekend",
+			calendar: New(Config{}),
+			from:     saturday,
+			days:     2,
+			expected: "2026-10-21",
+		},
+		{
+			name:     "over a holiday",
+			calendar: New(Config{Holidays: []Holiday{{Date: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)}}}),
+			from:     friday,
+			days:     1,
+			expected: "2026-10-20",
+		},
+		{
+			name:     "over a yearly holiday",
+			calendar: New(Config{Holidays: []Holiday{{Date: time.Date(2020, 10, 19, 0, 0, 0, 0, time.UTC), Yearly: true}}}),
+			from:     friday,
+			days:     1,
+			expected: "2026-10-20",
+		},
+		{
+			name:     "a week",
+			calendar: New(Config{}),
+			from:     friday,
+			days:     5,
+			expected: "2026-10-23",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, tc.calendar.AddBusinessDays(tc.from, tc.days).Format(dateLayout))
+		})
+	}
+}
+
+func TestNextPickup(t *testing.T) {
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	calendar := New(Config{
+		TimeZone: stockholm,
+		CutOff:   15 * time.Hour,
+		Holidays: []Holiday{{Date: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), Name: "Closed"}},
+	})
+
+	testCases := []struct {
+		name     string
+		at       time.Time
+		expected string
+	}{
+		{
+			name:     "before the cut-off",
+			at:       time.Date(2026, 10, 15, 12, 59, 0, 0, time.UTC),
+			expected: "2026-10-15",
+		},
+		{
+			name:     "after the cut-off in the time zone",
+			at:       time.Date(2026, 10, 15, 13, 0, 0, 0, time.UTC),
+			expected: "2026-10-16",
+		},
+		{
+			name:     "after the cut-off before a weekend and holiday",
+			at:       time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC),
+			expected: "2026-10-20",
+		},
+		{
+			name:     "next day in the time zone",
+			at:       time.Date(2026, 10, 14, 22, 30, 0, 0, time.UTC),
+			expected: "2026-10-15",
+		},
+		{
+			name:     "on a weekend",
+			at:       time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+			expected: "2026-10-20",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, calendar.NextPickup(tc.at).Format(dateLayout))
+		})
+	}
+}
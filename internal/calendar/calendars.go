@@ -0,0 +1,52 @@
+package calendar
+
+import (
+	"time"
+)
+
+// Calendars holds the calendars of locations by code, locations without a
+// calendar of their own use the default calendar.
+type Calendars struct {
+	byLocation map[string]Calendar
+	fallback   Calendar
+}
+
+func NewCalendars(fallback Calendar, byLocation map[string]Calendar) Calendars {
+	calendars := Calendars{byLocation: make(map[string]Calendar, len(byLocation)), fallback: fallback}
+	for code, c := range byLocation {
+		calendars.byLocation[code] = c
+	}
+	return calendars
+}
+
+// Get returns the calendar of the location and whether it has one of its
+// own.
+func (c Calendars) Get(location string) (Calendar, bool) {
+	calendar, ok := c.byLocation[location]
+	if !ok {
+		return c.fallback, false
+	}
+	return calendar, true
+}
+
+// Estimate returns when a parcel handed over at origin at the time is picked
+// up, and when it is delivered at destination after the transit days. Only
+// days that are business days at both origin and destination count as
+// transit days.
+func (c Calendars) Estimate(origin, destination string, at time.Time, transitDays int) (pickup, delivery time.Time) {
+	from, _ := c.Get(origin)
+	to, _ := c.Get(destination)
+
+	pickup = from.NextPickup(at)
+	delivery = pickup
+	for transitDays > 0 {
+		delivery = delivery.AddDate(0, 0, 1)
+		if from.IsBusinessDay(delivery) && to.IsBusinessDay(delivery) {
+			transitDays--
+		}
+	}
+	for !to.IsBusinessDay(delivery) {
+		delivery = delivery.AddDate(0, 0, 1)
+	}
+	return pickup, delivery
+}
@@ -0,0 +1,81 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	// 2026-10-15 is a thursday
+	thursday := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	calendars := NewCalendars(New(Config{CutOff: 15 * time.Hour}), map[string]Calendar{
+		"SE": New(Config{CutOff: 12 * time.Hour, Holidays: []Holiday{{Date: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}}}),
+		"DE": New(Config{CutOff: 15 * time.Hour, Holidays: []Holiday{{Date: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)}}}),
+	})
+
+	testCases := []struct {
+		name             string
+		origin           string
+		destination      string
+		at               time.Time
+		transitDays      int
+		expectedPickup   string
+		expectedDelivery string
+	}{
+		{
+			name:             "default calendars",
+			origin:           "NO",
+			destination:      "DK",
+			at:               thursday,
+			transitDays:      2,
+			expectedPickup:   "2026-10-15",
+			expectedDelivery: "2026-10-19",
+		},
+		{
+			name:             "holiday at the destination",
+			origin:           "NO",
+			destination:      "DE",
+			at:               thursday,
+			transitDays:      3,
+			expectedPickup:   "2026-10-15",
+			expectedDelivery: "2026-10-21",
+		},
+		{
+			name:             "holiday at the origin",
+			origin:           "SE",
+			destination:      "DK",
+			at:               thursday,
+			transitDays:      1,
+			expectedPickup:   "2026-10-15",
+			expectedDelivery: "2026-10-19",
+		},
+		{
+			name:             "holidays at both after the cut-off",
+			origin:           "SE",
+			destination:      "DE",
+			at:               thursday.Add(3 * time.Hour),
+			transitDays:      1,
+			expectedPickup:   "2026-10-19",
+			expectedDelivery: "2026-10-21",
+		},
+		{
+			name:             "no transit days",
+			origin:           "DE",
+			destination:      "DE",
+			at:               thursday,
+			expectedPickup:   "2026-10-15",
+			expectedDelivery: "2026-10-15",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pickup, delivery := calendars.Estimate(tc.origin, tc.destination, tc.at, tc.transitDays)
+			require.Equal(t, tc.expectedPickup, pickup.Format(dateLayout))
+			require.Equal(t, tc.expectedDelivery, delivery.Format(dateLayout))
+		})
+	}
+}
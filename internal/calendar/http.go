@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type holidayResponse struct {
+	Date   string `json:"date" binding:"required" description:"Date as YYYY-MM-DD, of the first year for yearly holidays"`
+	Name   string `json:"name"`
+	Yearly bool   `json:"yearly" binding:"required"`
+}
+
+type getCalendarResponse struct {
+	Location string            `json:"location" binding:"required"`
+	Default  bool              `json:"default" binding:"required" description:"Whether the location has no calendar of its own and uses the default calendar"`
+	TimeZone string            `json:"timeZone" binding:"required"`
+	CutOff   string            `json:"cutOff" binding:"required" description:"Time of day as HH:MM after which parcels are picked up the next business day"`
+	Holidays []holidayResponse `json:"holidays" binding:"required"`
+}
+
+type getPickupRequest struct {
+	At time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00" description:"When the parcel is handed over as RFC 3339, defaults to now"`
+}
+
+type getPickupResponse struct {
+	Location string    `json:"location" binding:"required"`
+	At       time.Time `json:"at" binding:"required"`
+	Pickup   string    `json:"pickup" binding:"required" description:"Date as YYYY-MM-DD"`
+	TimeZone string    `json:"timeZone" binding:"required"`
+	CutOff   string    `json:"cutOff" binding:"required"`
+}
+
+type handler struct {
+	calendars Calendars
+}
+
+func NewHandler(calendars Calendars) *handler {
+	return &handler{calendars: calendars}
+}
+
+func (h handler) GetCalendar(c *gin.Context) {
+	location := c.Param("location")
+	calendar, ok := h.calendars.Get(location)
+
+	holidays := make([]holidayResponse, 0, len(calendar.holidays)+len(calendar.yearly))
+	for _, holiday := range calendar.Holidays() {
+		holidays = append(holidays, holidayResponse{Date: holiday.Date.Format(dateLayout), Name: holiday.Name, Yearly: holiday.Yearly})
+	}
+	c.JSON(http.StatusOK, getCalendarResponse{
+		Location: location,
+		Default:  !ok,
+		TimeZone: calendar.TimeZone().String(),
+		CutOff:   formatCutOff(calendar.CutOff()),
+		Holidays: holidays,
+	})
+}
+
+func (h handler) GetPickup(c *gin.Context) {
+	var req getPickupRequest
+	if err := problem.BindQuery(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+	if req.At.IsZero() {
+		req.At = time.Now()
+	}
+
+	location := c.Param("location")
+	calendar, _ := h.calendars.Get(location)
+	c.JSON(http.StatusOK, getPickupResponse{
+		Location: location,
+		At:       req.At,
+		Pickup:   calendar.NextPickup(req.At).Format(dateLayout),
+		TimeZone: calendar.TimeZone().String(),
+		CutOff:   formatCutOff(calendar.CutOff()),
+	})
+}
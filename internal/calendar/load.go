@@ -0,0 +1,222 @@
+package calendar
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type fileCalendar struct {
+	TimeZone string        `json:"timeZone"`
+	CutOff   string        `json:"cutOff"`
+	Holidays []fileHoliday `json:"holidays"`
+}
+
+type fileHoliday struct {
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+	Yearly bool   `json:"yearly"`
+}
+
+// Load reads a calendar from a JSON or iCalendar (.ics) file. The cut-off
+// applies when the file does not set one, iCalendar files never do.
+func Load(path string, cutOff time.Duration) (Calendar, error) {
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		return loadICal(path, cutOff)
+	}
+	return loadJSON(path, cutOff)
+}
+
+func loadJSON(path string, cutOff time.Duration) (Calendar, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("read calendar %s: %w", path, err)
+	}
+	var file fileCalendar
+	if err := json.Unmarshal(content, &file); err != nil {
+		return Calendar{}, fmt.Errorf("parse calendar %s: %w", path, err)
+	}
+
+	config := Config{CutOff: cutOff}
+	if config.TimeZone, err = loadLocation(file.TimeZone); err != nil {
+		return Calendar{}, fmt.Errorf("calendar %s: %w", path, err)
+	}
+	if file.CutOff != "" {
+		if config.CutOff, err = ParseCutOff(file.CutOff); err != nil {
+			return Calendar{}, fmt.Errorf("calendar %s: %w", path, err)
+		}
+	}
+	for _, h := range file.Holidays {
+		d, err := time.Parse(dateLayout, h.Date)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("calendar %s: invalid holiday date %q", path, h.Date)
+		}
+		config.Holidays = append(config.Holidays, Holiday{Date: d, Name: h.Name, Yearly: h.Yearly})
+	}
+	return New(config), nil
+}
+
+// icalEvent is a VEVENT as read, its dates are parsed once the time zone of
+// the calendar is known.
+type icalEvent struct {
+	start, end             string
+	startParams, endParams string
+	name                   string
+	yearly                 bool
+}
+
+// loadICal reads the all-day events of an iCalendar file as holidays, events
+// spanning several days give a holiday per day and events with a yearly
+// recurrence rule give yearly holidays. Events with a time of day are only
+// holidays if they start and end at midnight in the time zone of the
+// calendar, other events are skipped.
+func loadICal(path string, cutOff time.Duration) (Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("read calendar %s: %w", path, err)
+	}
+	defer f.Close()
+
+	lines, err := unfold(f)
+	if err != nil {
+		return Calendar{}, fmt.Errorf("read calendar %s: %w", path, err)
+	}
+
+	config := Config{CutOff: cutOff, TimeZone: time.UTC}
+	var (
+		events []icalEvent
+		// components holds the components the line is in, properties are
+		// only read directly within a VEVENT, not from its alarms
+		components []string
+		event      icalEvent
+	)
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, params, _ := strings.Cut(key, ";")
+		inEvent := len(components) > 0 && components[len(components)-1] == "VEVENT"
+		switch {
+		case key == "BEGIN":
+			components = append(components, value)
+			if value == "VEVENT" {
+				event = icalEvent{}
+			}
+		case key == "END":
+			if len(components) > 0 {
+				components = components[:len(components)-1]
+			}
+			if value == "VEVENT" && inEvent {
+				events = append(events, event)
+			}
+		case key == "X-WR-TIMEZONE" && len(components) <= 1:
+			if config.TimeZone, err = loadLocation(value); err != nil {
+				return Calendar{}, fmt.Errorf("calendar %s: %w", path, err)
+			}
+		case !inEvent:
+		case key == "DTSTART":
+			event.start, event.startParams = value, params
+		case key == "DTEND":
+			event.end, event.endParams = value, params
+		case key == "SUMMARY":
+			event.name = value
+		case key == "RRULE":
+			event.yearly = strings.Contains(value, "FREQ=YEARLY")
+		}
+	}
+
+	for _, e := range events {
+		if e.start == "" {
+			return Calendar{}, fmt.Errorf("calendar %s: event %q without start", path, e.name)
+		}
+		start, allDay, err := parseICalDate(e.start, e.startParams, config.TimeZone)
+		if err != nil {
+			return Calendar{}, fmt.Errorf("calendar %s: %w", path, err)
+		}
+		end := start.AddDate(0, 0, 1)
+		if e.end != "" {
+			d, endAllDay, err := parseICalDate(e.end, e.endParams, config.TimeZone)
+			if err != nil {
+				return Calendar{}, fmt.Errorf("calendar %s: %w", path, err)
+			}
+			allDay = allDay && endAllDay
+			if d.After(start) {
+				end = d
+			}
+		}
+		if !allDay {
+			continue
+		}
+		for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+			config.Holidays = append(config.Holidays, Holiday{Date: d, Name: e.name, Yearly: e.yearly})
+		}
+	}
+	return New(config), nil
+}
+
+// unfold returns the content lines of an iCalendar file, joining lines
+// continued with leading whitespace.
+func unfold(f *os.File) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalDate returns the date of a DTSTART or DTEND and whether it is a
+// whole day. Times in UTC or with a TZID are converted to the time zone of
+// the calendar, times without either are in it already, and are whole days
+// when they are at midnight.
+func parseICalDate(value, params string, location *time.Location) (time.Time, bool, error) {
+	if strings.Contains(params, "VALUE=DATE") || len(value) == len("20060102") {
+		d, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+		}
+		return d, true, nil
+	}
+
+	in := location
+	for _, param := range strings.Split(params, ";") {
+		if strings.HasPrefix(param, "TZID=") {
+			name := strings.TrimPrefix(param, "TZID=")
+			l, err := time.LoadLocation(strings.Trim(name, `"`))
+			if err != nil {
+				return time.Time{}, false, fmt.Errorf("unknown time zone %q", name)
+			}
+			in = l
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		in = time.UTC
+	}
+	t, err := time.ParseInLocation("20060102T150405", strings.TrimSuffix(value, "Z"), in)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	t = t.In(location)
+	return date(t), t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return location, nil
+}
@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name             string
+		file             string
+		content          string
+		expectedTimeZone string
+		expectedCutOff   time.Duration
+		expectedHolidays []Holiday
+		expectedErr      bool
+	}{
+		{
+			name: "json",
+			file: "se.json",
+			content: `{"timeZone": "Europe/Stockholm", "cutOff": "14:30", "holidays": [
+				{"date": "2026-12-24", "name": "Christmas Eve", "yearly": true},
+				{"date": "2026-06-19", "name": "Midsummer Eve"}
+			]}`,
+			expectedTimeZone: "Europe/Stockholm",
+			expectedCutOff:   14*time.Hour + 30*time.Minute,
+			expectedHolidays: []Holiday{
+				{Date: time.Date(2026, 6, 19, 0, 0, 0, 0, time.UTC), Name: "Midsummer Eve"},
+				{Date: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), Name: "Christmas Eve", Yearly: true},
+			},
+		},
+		{
+			name:             "json with defaults",
+			file:             "dk.json",
+			content:          `{}`,
+			expectedTimeZone: "UTC",
+			expectedCutOff:   15 * time.Hour,
+			expectedHolidays: []Holiday{},
+		},
+		{
+			name: "ical",
+			file: "de.ics",
+			content: "BEGIN:VCALENDAR\r\nX-WR-TIMEZONE:Europe/Berlin\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261003\r\nDTEND;VALUE=DATE:20261004\r\nSUMMARY:Tag der Deutschen\r\n  Einheit\r\nRRULE:FREQ=YEARLY\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261224\r\nDTEND;VALUE=DATE:20261227\r\nSUMMARY:Weihnachten\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			expectedTimeZone: "Europe/Berlin",
+			expectedCutOff:   15 * time.Hour,
+			expectedHolidays: []Holiday{
+				{Date: time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), Name: "Tag der Deutschen Einheit", Yearly: true},
+				{Date: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), Name: "Weihnachten"},
+				{Date: time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC), Name: "Weihnachten"},
+				{Date: time.Date(2026, 12, 26, 0, 0, 0, 0, time.UTC), Name: "Weihnachten"},
+			},
+		},
+		{
+			name: "ical times",
+			file: "se.ics",
+			content: "BEGIN:VCALENDAR\r\nX-WR-TIMEZONE:Europe/Stockholm\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART:20261223T230000Z\r\nDTEND:20261224T230000Z\r\nSUMMARY:Julafton\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART;TZID=Europe/London:20261230T230000\r\nDTEND;TZID=Europe/London:20261231T230000\r\nSUMMARY:Nyårsafton\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART:20260106T000000\r\nDTEND:20260107T000000\r\nSUMMARY:Trettondedag jul\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART:20261224T100000Z\r\nDTEND:20261224T110000Z\r\nSUMMARY:Kalle Anka\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			expectedTimeZone: "Europe/Stockholm",
+			expectedCutOff:   15 * time.Hour,
+			expectedHolidays: []Holiday{
+				{Date: time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC), Name: "Trettondedag jul"},
+				{Date: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), Name: "Julafton"},
+				{Date: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), Name: "Nyårsafton"},
+			},
+		},
+		{
+			name: "ical summary of event only",
+			file: "dk.ics",
+			content: "BEGIN:VCALENDAR\r\nSUMMARY:Helligdage\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261224\r\n" +
+				"BEGIN:VALARM\r\nSUMMARY:Påmindelse\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261225\r\nSUMMARY:Juledag\r\nEND:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			expectedTimeZone: "UTC",
+			expectedCutOff:   15 * time.Hour,
+			expectedHolidays: []Holiday{
+				{Date: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)},
+				{Date: time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC), Name: "Juledag"},
+			},
+		},
+		{
+			name:        "ical line too long",
+			file:        "xx.ics",
+			content:     "BEGIN:VCALENDAR\r\nX-LONG:" + strings.Repeat("x", 70000) + "\r\nEND:VCALENDAR\r\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown time zone",
+			file:        "xx.json",
+			content:     `{"timeZone": "Europe/Nowhere"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid cut-off",
+			file:        "xx.json",
+			content:     `{"cutOff": "3pm"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid holiday",
+			file:        "xx.ics",
+			content:     "BEGIN:VEVENT\nDTSTART:2026-12-24\nEND:VEVENT\n",
+			expectedErr: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), tc.file)
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			calendar, err := Load(path, 15*time.Hour)
+			if tc.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedTimeZone, calendar.TimeZone().String())
+			require.Equal(t, tc.expectedCutOff, calendar.CutOff())
+			require.Equal(t, tc.expectedHolidays, calendar.Holidays())
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package calendar
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func GetCalendarOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getCalendar",
+		Summary:     "Get the time zone, pickup cut-off and holidays of a location",
+		Tags:        []string{"calendars"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Calendar", getCalendarResponse{}),
+		},
+	}
+}
+
+func GetPickupOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getNextPickup",
+		Summary:     "Get the date a parcel handed over at a location is picked up",
+		Tags:        []string{"calendars"},
+		Parameters:  openapi.QueryParameters(getPickupRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Next pickup", getPickupResponse{}),
+		},
+	}
+}
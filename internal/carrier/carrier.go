@@ -0,0 +1,82 @@
+package carrier
+
+import (
+	"context"
+	"time"
+)
+
+type Address struct {
+	Name       string
+	Street     string
+	PostalCode string
+	City       string
+	Country    string
+}
+
+type Parcel struct {
+	Weight float32
+	Length float32
+	Width  float32
+	Height float32
+}
+
+// Request describes a shipment to a carrier.
+type Request struct {
+	BookingId   string
+	Origin      Address
+	Destination Address
+	Parcels     []Parcel
+	// ServiceLevel is the level the shipment is sent at, any level when
+	// quoting if empty.
+	ServiceLevel string
+}
+
+func (r Request) Weight() float32 {
+	var weight float32
+	for _, p := range r.Parcels {
+		weight += p.Weight
+	}
+	return weight
+}
+
+// Currency is what carriers quote in, prices are never converted so it is
+// the currency of every price of the API.
+const Currency = "SEK"
+
+// Rate is what a carrier asks for a shipment at one of its service levels.
+type Rate struct {
+	Carrier      string
+	ServiceLevel string
+	Price        float32
+	Currency     string
+	TransitDays  int
+}
+
+// Id identifies the rate among the options of a shipment, it stays the same
+// between quoting and booking.
+func (r Rate) Id() string {
+	return r.Carrier + ":" + r.ServiceLevel
+}
+
+// TrackingEvent is a scan of a shipment reported by its carrier.
+type TrackingEvent struct {
+	At          time.Time
+	Location    string
+	Code        string
+	Description string
+}
+
+// Adapter integrates a carrier. Errors other than those of the errors
+// package are taken as the carrier being unavailable.
+type Adapter interface {
+	// Code identifies the carrier, it is stored on the bookings it carries.
+	Code() string
+	// Quote returns a rate for every service level the carrier offers for
+	// the shipment.
+	Quote(context.Context, Request) ([]Rate, error)
+	// CreateShipment hands the shipment to the carrier and returns the
+	// reference the carrier knows it by.
+	CreateShipment(context.Context, Request) (string, error)
+	CancelShipment(ctx context.Context, reference string) error
+	Track(ctx context.Context, reference string) ([]TrackingEvent, error)
+}
@@ -0,0 +1,170 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type FakeConfig struct {
+	BasePrice   float32
+	PricePerKg  float32
+	TransitDays int
+	// Delay is how long quoting takes, it makes the fake a slow carrier.
+	Delay time.Duration
+	// ServiceLevels are the levels the fake quotes, standard only if empty.
+	ServiceLevels []string
+}
+
+// fakeLevels adjust the standard price and transit days of the fake by
+// service level.
+var fakeLevels = map[string]struct {
+	price float32
+	days  int
+}{
+	"economy":  {price: 0.8, days: 2},
+	"standard": {price: 1, days: 0},
+	"express":  {price: 1.8, days: -2},
+}
+
+type fakeShipment struct {
+	request   Request
+	createdAt time.Time
+	cancelled *time.Time
+}
+
+// fake is a carrier within the process, it accepts every shipment and prices
+// it by weight. Failures are set up with Fail.
+type fake struct {
+	code      string
+	config    FakeConfig
+	shipments map[string]*fakeShipment
+	next      *int
+	err       *error
+	mtx       *sync.Mutex
+}
+
+func NewFake(code string, config FakeConfig) fake {
+	next := 1
+	var err error
+	return fake{code: code, config: config, shipments: map[string]*fakeShipment{}, next: &next, err: &err, mtx: &sync.Mutex{}}
+}
+
+// Fail makes every call fail with err until it is called with nil.
+func (f fake) Fail(err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	*f.err = err
+}
+
+func (f fake) Code() string {
+	return f.code
+}
+
+func (f fake) Quote(ctx context.Context, req Request) ([]Rate, error) {
+	if f.config.Delay > 0 {
+		select {
+		case <-time.After(f.config.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if *f.err != nil {
+		return nil, *f.err
+	}
+	levels := f.config.ServiceLevels
+	if len(levels) == 0 {
+		levels = []string{"standard"}
+	}
+	rates := make([]Rate, 0, len(levels))
+	for _, level := range levels {
+		adjust, ok := fakeLevels[level]
+		if !ok {
+			return nil, fmt.Errorf("fake carrier %s has unknown service level %s", f.code, level)
+		}
+		days := f.config.TransitDays + adjust.days
+		if days < 1 {
+			days = 1
+		}
+		rates = append(rates, Rate{
+			Carrier:      f.code,
+			ServiceLevel: level,
+			Price:        (f.config.BasePrice + f.config.PricePerKg*req.Weight()) * adjust.price,
+			Currency:     Currency,
+			TransitDays:  days,
+		})
+	}
+	return rates, nil
+}
+
+func (f fake) CreateShipment(_ context.Context, req Request) (string, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if *f.err != nil {
+		return "", *f.err
+	}
+	reference := fmt.Sprintf("%s-%08d", f.code, *f.next)
+	*f.next++
+	f.shipments[reference] = &fakeShipment{request: req, createdAt: time.Now().UTC()}
+	return reference, nil
+}
+
+func (f fake) CancelShipment(_ context.Context, reference string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if *f.err != nil {
+		return *f.err
+	}
+	s, ok := f.shipments[reference]
+	if !ok {
+		return errors.NotFound(errors.CodeNotFound, "shipment", reference)
+	}
+	if s.cancelled == nil {
+		now := time.Now().UTC()
+		s.cancelled = &now
+	}
+	return nil
+}
+
+func (f fake) Track(_ context.Context, reference string) ([]TrackingEvent, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if *f.err != nil {
+		return nil, *f.err
+	}
+	s, ok := f.shipments[reference]
+	if !ok {
+		return nil, errors.NotFound(errors.CodeNotFound, "shipment", reference)
+	}
+
+	events := []TrackingEvent{{At: s.createdAt, Location: s.request.Origin.Country, Code: "created", Description: "Shipment information received"}}
+	if s.cancelled != nil {
+		events = append(events, TrackingEvent{At: *s.cancelled, Location: s.request.Origin.Country, Code: "cancelled", Description: "Shipment cancelled"})
+	}
+	return events, nil
+}
+
+// Shipments returns how many shipments were created and not cancelled.
+func (f fake) Shipments() int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	active := 0
+	for _, s := range f.shipments {
+		if s.cancelled == nil {
+			active++
+		}
+	}
+	return active
+}
@@ -0,0 +1,56 @@
+package carrier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnyCountry matches every origin or destination of a lane.
+const AnyCountry = "*"
+
+// Lanes maps lanes such as SE-DK, SE-* or *-* to the codes of the carriers
+// serving them, in order of preference.
+type Lanes map[string][]string
+
+func lane(origin, destination string) string {
+	return origin + "-" + destination
+}
+
+// ParseLane parses a lane assignment such as SE-DK=postnord,dhl.
+func ParseLane(s string) (string, []string, error) {
+	l, carriers, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid lane %q, expected <origin>-<destination>=<carrier>[,<carrier>]", s)
+	}
+	origin, destination, ok := strings.Cut(l, "-")
+	if !ok || origin == "" || destination == "" {
+		return "", nil, fmt.Errorf("invalid lane %q, expected <origin>-<destination>", l)
+	}
+
+	codes := []string{}
+	for _, c := range strings.Split(carriers, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			codes = append(codes, c)
+		}
+	}
+	if len(codes) == 0 {
+		return "", nil, fmt.Errorf("lane %q has no carriers", l)
+	}
+	return lane(strings.ToUpper(origin), strings.ToUpper(destination)), codes, nil
+}
+
+// carriers returns the carriers of the most specific lane matching origin and
+// destination.
+func (l Lanes) carriers(origin, destination string) []string {
+	for _, key := range []string{
+		lane(origin, destination),
+		lane(origin, AnyCountry),
+		lane(AnyCountry, destination),
+		lane(AnyCountry, AnyCountry),
+	} {
+		if codes, ok := l[key]; ok {
+			return codes
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package carrier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLane(t *testing.T) {
+	testCases := []struct {
+		name             string
+		lane             string
+		expectedLane     string
+		expectedCarriers []string
+		shouldFail       bool
+	}{
+		{
+			name:             "single carrier",
+			lane:             "SE-DK=postnord",
+			expectedLane:     "SE-DK",
+			expectedCarriers: []string{"postnord"},
+		},
+		{
+			name:             "carriers in order of preference",
+			lane:             "se-*=dhl, postnord",
+			expectedLane:     "SE-*",
+			expectedCarriers: []string{"dhl", "postnord"},
+		},
+		{
+			name:       "missing carriers",
+			lane:       "SE-DK",
+			shouldFail: true,
+		},
+		{
+			name:       "empty carriers",
+			lane:       "SE-DK= ,",
+			shouldFail: true,
+		},
+		{
+			name:       "missing destination",
+			lane:       "SE=postnord",
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			lane, carriers, err := ParseLane(tc.lane)
+
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedLane, lane)
+			require.Equal(t, tc.expectedCarriers, carriers)
+		})
+	}
+}
+
+func TestLaneCarriers(t *testing.T) {
+	lanes := Lanes{
+		"SE-DK": {"exact"},
+		"SE-*":  {"from-se"},
+		"*-DK":  {"to-dk"},
+		"*-*":   {"any"},
+	}
+
+	testCases := []struct {
+		name        string
+		lanes       Lanes
+		origin      string
+		destination string
+		expected    []string
+	}{
+		{
+			name:        "exact lane",
+			lanes:       lanes,
+			origin:      "SE",
+			destination: "DK",
+			expected:    []string{"exact"},
+		},
+		{
+			name:        "any destination",
+			lanes:       lanes,
+			origin:      "SE",
+			destination: "DE",
+			expected:    []string{"from-se"},
+		},
+		{
+			name:        "any origin",
+			lanes:       lanes,
+			origin:      "DE",
+			destination: "DK",
+			expected:    []string{"to-dk"},
+		},
+		{
+			name:        "any lane",
+			lanes:       lanes,
+			origin:      "DE",
+			destination: "US",
+			expected:    []string{"any"},
+		},
+		{
+			name:        "not served",
+			lanes:       Lanes{"SE-DK": {"exact"}},
+			origin:      "DE",
+			destination: "US",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, tc.lanes.carriers(tc.origin, tc.destination))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package carrier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("github.com/slaengkast/shipping-api/internal/carrier")
+
+// Service picks the carrier of a lane and passes calls on to its adapter.
+type Service struct {
+	adapters map[string]Adapter
+	lanes    Lanes
+}
+
+// NewService fails if a lane names a carrier without an adapter.
+func NewService(lanes Lanes, adapters ...Adapter) (Service, error) {
+	s := Service{adapters: make(map[string]Adapter, len(adapters)), lanes: lanes}
+	for _, a := range adapters {
+		s.adapters[a.Code()] = a
+	}
+	for l, codes := range lanes {
+		for _, code := range codes {
+			if _, ok := s.adapters[code]; !ok {
+				return Service{}, fmt.Errorf("lane %s uses unknown carrier %q", l, code)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Carriers returns the adapters of the carriers serving the lane, in order of
+// preference.
+func (s Service) Carriers(origin, destination string) []Adapter {
+	codes := s.lanes.carriers(origin, destination)
+	adapters := make([]Adapter, 0, len(codes))
+	for _, code := range codes {
+		adapters = append(adapters, s.adapters[code])
+	}
+	return adapters
+}
+
+// Select returns the preferred carrier of the lane.
+func (s Service) Select(origin, destination string) (Adapter, error) {
+	carriers := s.Carriers(origin, destination)
+	if len(carriers) == 0 {
+		return nil, errors.FromCode(errors.CodeLaneNotServed, fmt.Sprintf("no carrier serves %s to %s", origin, destination), errors.ErrorInput)
+	}
+	return carriers[0], nil
+}
+
+// CreateShipment hands the shipment to the carrier with the code, or to the
+// preferred carrier of its lane if code is empty, and returns the code of the
+// carrier and its reference. Without a code the next carrier of the lane is
+// tried when a carrier fails, the error of the preferred carrier is returned
+// if all of them fail.
+func (s Service) CreateShipment(ctx context.Context, code string, req Request) (_, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "carrier.Service.CreateShipment", trace.WithAttributes(attribute.String("booking.id", req.BookingId)))
+	defer func() { tracing.End(span, err) }()
+
+	carriers, err := s.shipping(code, req.Origin.Country, req.Destination.Country)
+	if err != nil {
+		return "", "", err
+	}
+
+	logger := logging.FromContext(ctx, "carrier")
+	var firstErr error
+	for _, a := range carriers {
+		reference, err := a.CreateShipment(ctx, req)
+		if err != nil {
+			err = unavailable(err, a.Code(), "create shipment")
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			logger.Warn().Err(err).Str("carrier", a.Code()).Str("booking", req.BookingId).Msg("failed to create shipment")
+			continue
+		}
+
+		span.SetAttributes(attribute.String("carrier", a.Code()))
+		logger.Info().Str("carrier", a.Code()).Str("reference", reference).Str("booking", req.BookingId).Msg("created shipment")
+		return a.Code(), reference, nil
+	}
+	return "", "", firstErr
+}
+
+func (s Service) CancelShipment(ctx context.Context, code, reference string) (err error) {
+	ctx, span := tracer.Start(ctx, "carrier.Service.CancelShipment", trace.WithAttributes(attribute.String("carrier", code)))
+	defer func() { tracing.End(span, err) }()
+
+	a, err := s.adapter(code)
+	if err != nil {
+		return err
+	}
+	if err := a.CancelShipment(ctx, reference); err != nil {
+		return unavailable(err, code, "cancel shipment")
+	}
+	return nil
+}
+
+func (s Service) Track(ctx context.Context, code, reference string) (_ []TrackingEvent, err error) {
+	ctx, span := tracer.Start(ctx, "carrier.Service.Track", trace.WithAttributes(attribute.String("carrier", code)))
+	defer func() { tracing.End(span, err) }()
+
+	a, err := s.adapter(code)
+	if err != nil {
+		return nil, err
+	}
+	events, err := a.Track(ctx, reference)
+	if err != nil {
+		return nil, unavailable(err, code, "track")
+	}
+	return events, nil
+}
+
+// shipping returns the carrier with the code if it serves the lane, or every
+// carrier of the lane in order of preference if code is empty.
+func (s Service) shipping(code, origin, destination string) ([]Adapter, error) {
+	if code == "" {
+		if _, err := s.Select(origin, destination); err != nil {
+			return nil, err
+		}
+		return s.Carriers(origin, destination), nil
+	}
+	for _, a := range s.Carriers(origin, destination) {
+		if a.Code() == code {
+			return []Adapter{a}, nil
+		}
+	}
+	return nil, errors.FromCode(errors.CodeLaneNotServed, fmt.Sprintf("carrier %s does not serve %s to %s", code, origin, destination), errors.ErrorInput)
+}
+
+func (s Service) adapter(code string) (Adapter, error) {
+	a, ok := s.adapters[code]
+	if !ok {
+		return nil, errors.NotFound(errors.CodeCarrierNotFound, "carrier", code)
+	}
+	return a, nil
+}
+
+// unavailable keeps the errors the adapter typed itself, anything else is the
+// carrier failing.
+func unavailable(err error, code, operation string) error {
+	if errors.GetType(err) != errors.ErrorUnknown {
+		return errors.WithEntity(err, "carrier", code)
+	}
+	err = errors.FromCode(errors.CodeCarrierUnavailable, fmt.Sprintf("carrier %s failed to %s: %s", code, operation, err), errors.ErrorUnavailable)
+	return errors.WithEntity(err, "carrier", code)
+}
@@ -0,0 +1,175 @@
+package carrier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewService(t *testing.T) {
+	_, err := NewService(Lanes{"SE-DK": {"fake", "missing"}}, NewFake("fake", FakeConfig{}))
+	require.NotNil(t, err, "expected an error for a lane with an unknown carrier")
+
+	_, err = NewService(Lanes{"SE-DK": {"fake"}}, NewFake("fake", FakeConfig{}))
+	require.Nil(t, err)
+}
+
+func TestCreateShipment(t *testing.T) {
+	testCases := []struct {
+		name              string
+		origin            string
+		destination       string
+		carrier           string
+		carrierErr        error
+		secondErr         error
+		expectedCarrier   string
+		expectedReference string
+		expectedCode      apierrors.Code
+		expectedType      apierrors.ErrorType
+	}{
+		{
+			name:              "preferred carrier",
+			origin:            "SE",
+			destination:       "DK",
+			expectedCarrier:   "first",
+			expectedReference: "first-00000001",
+		},
+		{
+			name:              "fallback lane",
+			origin:            "DE",
+			destination:       "DK",
+			expectedCarrier:   "second",
+			expectedReference: "second-00000001",
+		},
+		{
+			name:              "chosen carrier",
+			origin:            "SE",
+			destination:       "DK",
+			carrier:           "second",
+			expectedCarrier:   "second",
+			expectedReference: "second-00000001",
+		},
+		{
+			name:         "chosen carrier not serving lane",
+			origin:       "DE",
+			destination:  "DK",
+			carrier:      "first",
+			expectedCode: apierrors.CodeLaneNotServed,
+			expectedType: apierrors.ErrorInput,
+		},
+		{
+			name:         "lane not served",
+			origin:       "DE",
+			destination:  "US",
+			expectedCode: apierrors.CodeLaneNotServed,
+			expectedType: apierrors.ErrorInput,
+		},
+		{
+			name:              "preferred carrier failing",
+			origin:            "SE",
+			destination:       "DK",
+			carrierErr:        errors.New("connection refused"),
+			expectedCarrier:   "second",
+			expectedReference: "second-00000001",
+		},
+		{
+			name:         "chosen carrier failing",
+			origin:       "SE",
+			destination:  "DK",
+			carrier:      "first",
+			carrierErr:   errors.New("connection refused"),
+			expectedCode: apierrors.CodeCarrierUnavailable,
+			expectedType: apierrors.ErrorUnavailable,
+		},
+		{
+			name:         "every carrier failing",
+			origin:       "SE",
+			destination:  "DK",
+			carrierErr:   errors.New("connection refused"),
+			secondErr:    errors.New("connection reset"),
+			expectedCode: apierrors.CodeCarrierUnavailable,
+			expectedType: apierrors.ErrorUnavailable,
+		},
+		{
+			name:         "error of the preferred carrier",
+			origin:       "SE",
+			destination:  "DK",
+			carrierErr:   apierrors.Validation("too heavy", apierrors.FieldError{Field: "weight", Code: apierrors.FieldOutOfRange}),
+			secondErr:    errors.New("connection refused"),
+			expectedCode: apierrors.CodeValidationFailed,
+			expectedType: apierrors.ErrorInput,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			first := NewFake("first", FakeConfig{})
+			first.Fail(tc.carrierErr)
+			second := NewFake("second", FakeConfig{})
+			second.Fail(tc.secondErr)
+			service, err := NewService(Lanes{"SE-*": {"first", "second"}, "*-DK": {"second"}}, first, second)
+			require.Nil(t, err)
+
+			req := Request{BookingId: "test-id", Origin: Address{Country: tc.origin}, Destination: Address{Country: tc.destination}, Parcels: []Parcel{{Weight: 1}}}
+			code, reference, err := service.CreateShipment(context.Background(), tc.carrier, req)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				require.Equal(t, tc.expectedType, apierrors.GetType(err))
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedCarrier, code)
+			require.Equal(t, tc.expectedReference, reference)
+		})
+	}
+}
+
+func TestCancelShipment(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("fake", FakeConfig{})
+	service, err := NewService(Lanes{"*-*": {"fake"}}, fake)
+	require.Nil(t, err)
+
+	req := Request{BookingId: "test-id", Origin: Address{Country: "SE"}, Destination: Address{Country: "DK"}}
+	code, reference, err := service.CreateShipment(ctx, "", req)
+	require.Nil(t, err)
+	require.Equal(t, 1, fake.Shipments())
+
+	require.Nil(t, service.CancelShipment(ctx, code, reference))
+	require.Nil(t, service.CancelShipment(ctx, code, reference), "expected cancelling twice to succeed")
+	require.Equal(t, 0, fake.Shipments())
+
+	events, err := service.Track(ctx, code, reference)
+	require.Nil(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "cancelled", events[1].Code)
+
+	err = service.CancelShipment(ctx, "missing", reference)
+	require.Equal(t, apierrors.CodeCarrierNotFound, apierrors.GetCode(err))
+	err = service.CancelShipment(ctx, code, "unknown")
+	require.Equal(t, apierrors.ErrorNotFound, apierrors.GetType(err))
+}
+
+func TestFakeQuote(t *testing.T) {
+	fake := NewFake("fake", FakeConfig{BasePrice: 50, PricePerKg: 10, TransitDays: 3})
+
+	rates, err := fake.Quote(context.Background(), Request{Parcels: []Parcel{{Weight: 2}, {Weight: 3}}})
+	require.Nil(t, err)
+	require.Equal(t, []Rate{{Carrier: "fake", ServiceLevel: "standard", Price: 100, Currency: "SEK", TransitDays: 3}}, rates)
+	require.Equal(t, "fake:standard", rates[0].Id())
+
+	fake = NewFake("fake", FakeConfig{BasePrice: 50, PricePerKg: 10, TransitDays: 3, ServiceLevels: []string{"economy", "express"}})
+	rates, err = fake.Quote(context.Background(), Request{Parcels: []Parcel{{Weight: 5}}})
+	require.Nil(t, err)
+	require.Equal(t, []Rate{
+		{Carrier: "fake", ServiceLevel: "economy", Price: 80, Currency: "SEK", TransitDays: 5},
+		{Carrier: "fake", ServiceLevel: "express", Price: 180, Currency: "SEK", TransitDays: 1},
+	}, rates)
+}
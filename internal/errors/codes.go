@@ -0,0 +1,87 @@
+package errors
+
+// Code identifies an error for API clients, codes are part of the API and must
+// not change once published.
+type Code string
+
+const (
+	CodeUnknown          Code = "unknown"
+	CodeInternal         Code = "internal"
+	CodeInvalidRequest   Code = "invalid_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeNotFound         Code = "not_found"
+	CodeRouteNotFound    Code = "route_not_found"
+	CodeConflict         Code = "conflict"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeRateLimited      Code = "rate_limited"
+	CodeUnavailable      Code = "unavailable"
+
+	CodeMissingCredentials Code = "missing_credentials"
+	CodeInvalidAPIKey      Code = "invalid_api_key"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeAPIKeyNotFound     Code = "api_key_not_found"
+
+	CodeBookingNotFound       Code = "booking_not_found"
+	CodeBookingAlreadyExists  Code = "booking_already_exists"
+	CodeBookingNotCancellable Code = "booking_not_cancellable"
+
+	CodeSubscriptionNotFound Code = "subscription_not_found"
+	CodeDeliveryNotFound     Code = "delivery_not_found"
+	CodeDeliveryPending      Code = "delivery_pending"
+
+	CodeCarrierNotFound    Code = "carrier_not_found"
+	CodeCarrierUnavailable Code = "carrier_unavailable"
+	CodeLaneNotServed      Code = "lane_not_served"
+	CodeOptionNotFound     Code = "option_not_found"
+
+	CodeServiceLevelNotOffered Code = "service_level_not_offered"
+
+	CodePickupNotFound         Code = "pickup_not_found"
+	CodePickupAlreadyScheduled Code = "pickup_already_scheduled"
+	CodePickupSlotFull         Code = "pickup_slot_full"
+	CodePickupSlotUnavailable  Code = "pickup_slot_unavailable"
+	CodeBookingNotPickable     Code = "booking_not_pickable"
+
+	CodeLocationNotFound Code = "location_not_found"
+	CodeRateNotFound     Code = "rate_not_found"
+	CodePriceNotFound    Code = "price_not_found"
+	CodeWeightOutOfRange Code = "weight_out_of_range"
+
+	CodeInvalidQuery           Code = "invalid_query"
+	CodeQueryTooComplex        Code = "query_too_complex"
+	CodePersistedQueryNotFound Code = "persisted_query_not_found"
+	CodePersistedQueryRequired Code = "persisted_query_required"
+	CodePersistedQueryMismatch Code = "persisted_query_hash_mismatch"
+)
+
+// Field error codes.
+const (
+	FieldRequired    = "required"
+	FieldInvalid     = "invalid"
+	FieldInvalidType = "invalid_type"
+	FieldOutOfRange  = "out_of_range"
+)
+
+func codeOfType(t ErrorType) Code {
+	switch t {
+	case ErrorNotFound:
+		return CodeNotFound
+	case ErrorConflict:
+		return CodeConflict
+	case ErrorInternal:
+		return CodeInternal
+	case ErrorInput:
+		return CodeInvalidRequest
+	case ErrorUnauthorized:
+		return CodeUnauthorized
+	case ErrorForbidden:
+		return CodeForbidden
+	case ErrorRateLimited:
+		return CodeRateLimited
+	case ErrorUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeUnknown
+	}
+}
@@ -2,6 +2,8 @@ package errors
 
 import (
 	errs "errors"
+	"fmt"
+	"net/http"
 )
 
 type ErrorType int
@@ -12,14 +14,77 @@ const (
 	ErrorUnknown
 	ErrorInternal
 	ErrorInput
+	ErrorUnauthorized
+	ErrorForbidden
+	ErrorRateLimited
+	ErrorUnavailable
 )
 
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorNotFound:
+		return "not_found"
+	case ErrorConflict:
+		return "conflict"
+	case ErrorInternal:
+		return "internal"
+	case ErrorInput:
+		return "input"
+	case ErrorUnauthorized:
+		return "unauthorized"
+	case ErrorForbidden:
+		return "forbidden"
+	case ErrorRateLimited:
+		return "rate_limited"
+	case ErrorUnavailable:
+		return "unavailable"
+	default:
+		return "unknown"
+	}
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type APIError struct {
-	t   ErrorType
-	err error
+	t       ErrorType
+	code    Code
+	err     error
+	fields  []FieldError
+	context Context
 }
 
+// Context describes what an error is about, it is carried along when the
+// error is wrapped so that logs can tell which entity failed.
+type Context struct {
+	Entity    string
+	Id        string
+	Field     string
+	Operation string
+}
+
+// Sentinels to match the type of an error with errors.Is.
+var (
+	ErrNotFound     = APIError{t: ErrorNotFound}
+	ErrConflict     = APIError{t: ErrorConflict}
+	ErrInternal     = APIError{t: ErrorInternal}
+	ErrInput        = APIError{t: ErrorInput}
+	ErrUnauthorized = APIError{t: ErrorUnauthorized}
+	ErrForbidden    = APIError{t: ErrorForbidden}
+	ErrRateLimited  = APIError{t: ErrorRateLimited}
+	ErrUnavailable  = APIError{t: ErrorUnavailable}
+)
+
+// FromError gives err the type t, the code, fields and context of an APIError
+// within err are kept and err stays reachable through Unwrap.
 func FromError(err error, t ErrorType) error {
+	var apiError APIError
+	if errs.As(err, &apiError) {
+		return APIError{t: t, code: apiError.code, err: cause(err), fields: apiError.fields, context: apiError.context}
+	}
 	return APIError{t: t, err: err}
 }
 
@@ -27,10 +92,213 @@ func FromMessage(msg string, t ErrorType) error {
 	return APIError{t: t, err: errs.New(msg)}
 }
 
+func FromCode(code Code, msg string, t ErrorType) error {
+	return APIError{t: t, code: code, err: errs.New(msg)}
+}
+
+// Validation returns an input error listing every invalid field.
+func Validation(msg string, fields ...FieldError) error {
+	return APIError{t: ErrorInput, code: CodeValidationFailed, err: errs.New(msg), fields: fields}
+}
+
+// NotFound returns a not found error for the entity with the given id.
+func NotFound(code Code, entity, id string) error {
+	return APIError{
+		t:       ErrorNotFound,
+		code:    code,
+		err:     fmt.Errorf("%s %s not found", entity, id),
+		context: Context{Entity: entity, Id: id},
+	}
+}
+
+// Wrap prefixes the message of err with msg, keeping its type, code, fields
+// and context.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+
+	apiError := APIError{t: ErrorUnknown}
+	errs.As(err, &apiError)
+	apiError.err = fmt.Errorf("%s: %w", msg, err)
+	return apiError
+}
+
+// WrapStore records the store operation that failed on err. Errors that are
+// not already APIErrors, such as driver errors, become internal errors so
+// their details are never shown to clients.
+func WrapStore(err error, store, operation string) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiError APIError
+	if !errs.As(err, &apiError) {
+		return APIError{
+			t:       ErrorInternal,
+			err:     fmt.Errorf("%s store %s: %w", store, operation, err),
+			context: Context{Entity: store, Operation: operation},
+		}
+	}
+
+	apiError.err = cause(err)
+	if apiError.context.Entity == "" {
+		apiError.context.Entity = store
+	}
+	apiError.context.Operation = operation
+	return apiError
+}
+
+// WithEntity attaches the entity and id err is about.
+func WithEntity(err error, entity, id string) error {
+	return withContext(err, func(c *Context) {
+		c.Entity = entity
+		c.Id = id
+	})
+}
+
+// WithField attaches the request field err is about.
+func WithField(err error, field string) error {
+	return withContext(err, func(c *Context) {
+		c.Field = field
+	})
+}
+
+func withContext(err error, set func(*Context)) error {
+	if err == nil {
+		return nil
+	}
+
+	apiError := APIError{t: ErrorUnknown}
+	errs.As(err, &apiError)
+	apiError.err = cause(err)
+	set(&apiError.context)
+	return apiError
+}
+
+// cause avoids nesting an APIError directly inside another one.
+func cause(err error) error {
+	if apiError, ok := err.(APIError); ok {
+		return apiError.err
+	}
+	return err
+}
+
 func (e APIError) Error() string {
+	if e.err == nil {
+		return e.t.String()
+	}
 	return e.err.Error()
 }
 
+func (e APIError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether e matches target. A sentinel without a message matches
+// any error of its type, and of its code when it has one, other APIErrors
+// match when the cause of target is part of the cause of e.
+func (e APIError) Is(target error) bool {
+	t, ok := target.(APIError)
+	if !ok {
+		return false
+	}
+	if t.err == nil {
+		return e.t == t.t && (t.code == "" || e.GetCode() == t.code)
+	}
+	return e.err != nil && errs.Is(e.err, t.err)
+}
+
 func (e APIError) GetType() ErrorType {
 	return e.t
 }
+
+// GetCode returns the code of the error, errors created without one get the
+// generic code of their type.
+func (e APIError) GetCode() Code {
+	if e.code != "" {
+		return e.code
+	}
+	return codeOfType(e.t)
+}
+
+func (e APIError) GetFields() []FieldError {
+	return e.fields
+}
+
+func (e APIError) GetContext() Context {
+	return e.context
+}
+
+func GetType(err error) ErrorType {
+	var apiError APIError
+	if !errs.As(err, &apiError) {
+		return ErrorUnknown
+	}
+	return apiError.GetType()
+}
+
+func GetCode(err error) Code {
+	var apiError APIError
+	if !errs.As(err, &apiError) {
+		return CodeUnknown
+	}
+	return apiError.GetCode()
+}
+
+func GetFields(err error) []FieldError {
+	var apiError APIError
+	if !errs.As(err, &apiError) {
+		return nil
+	}
+	return apiError.GetFields()
+}
+
+func GetContext(err error) Context {
+	var apiError APIError
+	if !errs.As(err, &apiError) {
+		return Context{}
+	}
+	return apiError.GetContext()
+}
+
+// Fields returns the non empty context of err as log fields.
+func (c Context) Fields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if c.Entity != "" {
+		fields["entity"] = c.Entity
+	}
+	if c.Id != "" {
+		fields["entityId"] = c.Id
+	}
+	if c.Field != "" {
+		fields["field"] = c.Field
+	}
+	if c.Operation != "" {
+		fields["operation"] = c.Operation
+	}
+	return fields
+}
+
+func HTTPStatus(err error) int {
+	switch GetType(err) {
+	case ErrorNotFound:
+		return http.StatusNotFound
+	case ErrorInput:
+		return http.StatusBadRequest
+	case ErrorUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorForbidden:
+		return http.StatusForbidden
+	case ErrorConflict:
+		return http.StatusConflict
+	case ErrorRateLimited:
+		return http.StatusTooManyRequests
+	case ErrorUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrorInternal:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,170 @@
+package errors
+
+import (
+	errs "errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errDriver = errs.New("connection refused")
+
+func TestIs(t *testing.T) {
+	invalidWeight := FromCode(CodeWeightOutOfRange, "invalid weight", ErrorInput)
+
+	testCases := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{
+			name:     "sentinel of same type",
+			err:      NotFound(CodeBookingNotFound, "booking", "id"),
+			target:   ErrNotFound,
+			expected: true,
+		},
+		{
+			name:     "sentinel of other type",
+			err:      NotFound(CodeBookingNotFound, "booking", "id"),
+			target:   ErrConflict,
+			expected: false,
+		},
+		{
+			name:     "sentinel with code",
+			err:      NotFound(CodeBookingNotFound, "booking", "id"),
+			target:   APIError{t: ErrorNotFound, code: CodeBookingNotFound},
+			expected: true,
+		},
+		{
+			name:     "sentinel with other code",
+			err:      NotFound(CodeBookingNotFound, "booking", "id"),
+			target:   APIError{t: ErrorNotFound, code: CodeAPIKeyNotFound},
+			expected: false,
+		},
+		{
+			name:     "same error",
+			err:      invalidWeight,
+			target:   invalidWeight,
+			expected: true,
+		},
+		{
+			name:     "wrapped error",
+			err:      Wrap(invalidWeight, "calculating price"),
+			target:   invalidWeight,
+			expected: true,
+		},
+		{
+			name:     "error with context",
+			err:      WithField(invalidWeight, "weight"),
+			target:   invalidWeight,
+			expected: true,
+		},
+		{
+			name:     "error with same message",
+			err:      FromCode(CodeWeightOutOfRange, "invalid weight", ErrorInput),
+			target:   invalidWeight,
+			expected: false,
+		},
+		{
+			name:     "cause of store error",
+			err:      WrapStore(errDriver, "booking", "add_booking"),
+			target:   errDriver,
+			expected: true,
+		},
+		{
+			name:     "cause through fmt",
+			err:      fmt.Errorf("booking: %w", FromError(errDriver, ErrorInternal)),
+			target:   ErrInternal,
+			expected: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, errs.Is(tc.err, tc.target))
+		})
+	}
+}
+
+func TestWrapStore(t *testing.T) {
+	testCases := []struct {
+		name            string
+		err             error
+		expectedType    ErrorType
+		expectedCode    Code
+		expectedContext Context
+		expectedMessage string
+	}{
+		{
+			name:            "driver error",
+			err:             errDriver,
+			expectedType:    ErrorInternal,
+			expectedCode:    CodeInternal,
+			expectedContext: Context{Entity: "booking", Operation: "get_booking"},
+			expectedMessage: "booking store get_booking: connection refused",
+		},
+		{
+			name:            "api error",
+			err:             NotFound(CodeBookingNotFound, "booking", "id"),
+			expectedType:    ErrorNotFound,
+			expectedCode:    CodeBookingNotFound,
+			expectedContext: Context{Entity: "booking", Id: "id", Operation: "get_booking"},
+			expectedMessage: "booking id not found",
+		},
+		{
+			name:            "wrapped api error",
+			err:             fmt.Errorf("lookup: %w", WithField(FromCode(CodeLocationNotFound, "no location with code XX", ErrorInput), "origin")),
+			expectedType:    ErrorInput,
+			expectedCode:    CodeLocationNotFound,
+			expectedContext: Context{Entity: "booking", Field: "origin", Operation: "get_booking"},
+			expectedMessage: "lookup: no location with code XX",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := WrapStore(tc.err, "booking", "get_booking")
+			require.Equal(t, tc.expectedType, GetType(err))
+			require.Equal(t, tc.expectedCode, GetCode(err))
+			require.Equal(t, tc.expectedContext, GetContext(err))
+			require.Equal(t, tc.expectedMessage, err.Error())
+			require.True(t, errs.Is(err, tc.err))
+		})
+	}
+
+	require.Nil(t, WrapStore(nil, "booking", "get_booking"))
+}
+
+func TestFromError(t *testing.T) {
+	t.Parallel()
+
+	inner := Validation("empty origin", FieldError{Field: "origin", Code: FieldRequired})
+	err := FromError(fmt.Errorf("booking: %w", inner), ErrorConflict)
+
+	require.Equal(t, ErrorConflict, GetType(err))
+	require.Equal(t, CodeValidationFailed, GetCode(err))
+	require.Equal(t, "booking: empty origin", err.Error())
+	require.Len(t, GetFields(err), 1)
+	require.True(t, errs.Is(err, inner))
+
+	var apiError APIError
+	require.True(t, errs.As(fmt.Errorf("wrapped: %w", err), &apiError))
+	require.Equal(t, http.StatusConflict, HTTPStatus(apiError))
+}
+
+func TestContextFields(t *testing.T) {
+	t.Parallel()
+
+	err := WithField(NotFound(CodeBookingNotFound, "booking", "id"), "id")
+	require.Equal(t, map[string]interface{}{
+		"entity":   "booking",
+		"entityId": "id",
+		"field":    "id",
+	}, GetContext(err).Fields())
+	require.Equal(t, map[string]interface{}{}, GetContext(errDriver).Fields())
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+type handler interface {
+	Publish(context.Context, booking.Event) error
+}
+
+// Bus is a sink that hands the events to handlers in the same process.
+type Bus struct {
+	handlers *[]handler
+	mtx      *sync.RWMutex
+}
+
+func NewBus() Bus {
+	return Bus{handlers: &[]handler{}, mtx: &sync.RWMutex{}}
+}
+
+func (b Bus) Subscribe(h handler) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	*b.handlers = append(*b.handlers, h)
+}
+
+// Publish hands the event to every handler and fails if any of them did. The
+// relay then publishes the event again to all of them, so handlers have to
+// tolerate seeing an event twice. Sinks outside the process, which may fail
+// for a while, are given to the relay as sinks of their own rather than
+// subscribed to the bus.
+func (b Bus) Publish(ctx context.Context, e booking.Event) error {
+	b.mtx.RLock()
+	handlers := *b.handlers
+	b.mtx.RUnlock()
+
+	var firstErr error
+	for _, h := range handlers {
+		if err := h.Publish(ctx, e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+// envelope is how an event is written by the sinks that leave the process.
+type envelope struct {
+	Id         string            `json:"id"`
+	Type       booking.EventType `json:"type"`
+	OccurredAt time.Time         `json:"occurredAt"`
+	ClientId   string            `json:"clientId"`
+	BookingId  string            `json:"bookingId"`
+	Status     booking.Status    `json:"status"`
+	Price      float32           `json:"price,omitempty"`
+	Currency   string            `json:"currency,omitempty"`
+}
+
+func newEnvelope(e booking.Event) envelope {
+	return envelope{
+		Id:         e.Id,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		ClientId:   e.ClientId,
+		BookingId:  e.BookingId,
+		Status:     e.Status,
+		Price:      e.Price,
+		Currency:   e.Currency,
+	}
+}
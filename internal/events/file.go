@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+// fileSink appends the events to a file, one JSON object per line.
+type fileSink struct {
+	file *os.File
+	mtx  *sync.Mutex
+}
+
+func NewFileSink(path string) (fileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fileSink{}, errors.WrapStore(err, "event", "open")
+	}
+	return fileSink{file: f, mtx: &sync.Mutex{}}, nil
+}
+
+func (s fileSink) Publish(_ context.Context, e booking.Event) error {
+	line, err := json.Marshal(newEnvelope(e))
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return errors.WrapStore(err, "event", "write")
+	}
+	return nil
+}
+
+func (s fileSink) Close(_ context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.file.Close()
+}
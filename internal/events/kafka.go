@@ -0,0 +1,109 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+)
+
+type kafkaRecord struct {
+	Key   string   `json:"key"`
+	Value envelope `json:"value"`
+}
+
+type kafkaRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+// kafkaSink produces the events to a Kafka topic through a REST proxy. The
+// booking id is the record key so that the events of a booking land on the
+// same partition and keep their order.
+type kafkaSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewKafkaSink(baseUrl, topic string, timeout time.Duration) (kafkaSink, error) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return kafkaSink{}, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return kafkaSink{}, fmt.Errorf("invalid kafka rest url %q", baseUrl)
+	}
+	if topic == "" {
+		return kafkaSink{}, fmt.Errorf("kafka topic must be set")
+	}
+
+	return kafkaSink{
+		url:    strings.TrimSuffix(baseUrl, "/") + "/topics/" + url.PathEscape(topic),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s kafkaSink) Publish(ctx context.Context, e booking.Event) error {
+	body, err := json.Marshal(kafkaRequest{Records: []kafkaRecord{{Key: e.BookingId, Value: newEnvelope(e)}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("kafka rest proxy responded %d: %s", res.StatusCode, bytes.TrimSpace(data))
+	}
+
+	var produced kafkaResponse
+	if err := json.Unmarshal(data, &produced); err != nil {
+		return err
+	}
+	for _, o := range produced.Offsets {
+		if o.Error != nil {
+			return fmt.Errorf("kafka: %s", *o.Error)
+		}
+	}
+	return nil
+}
+
+// Close drops the idle connections to the REST proxy.
+func (s kafkaSink) Close(_ context.Context) error {
+	s.client.CloseIdleConnections()
+	return nil
+}
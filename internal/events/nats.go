@@ -0,0 +1,180 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+const natsDefaultPort = "4222"
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+// natsSink publishes the events to a NATS subject over the plain text
+// protocol. Every publish is followed by a PING so that it only succeeds once
+// the server has taken the message. The connection is made on the first
+// publish and made again after any failure.
+type natsSink struct {
+	address string
+	connect natsConnect
+	subject string
+	timeout time.Duration
+	conn    *natsConn
+	mtx     *sync.Mutex
+}
+
+type natsConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func NewNATSSink(rawUrl, subject string, timeout time.Duration) (*natsSink, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid nats url %q", rawUrl)
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid nats subject %q", subject)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = natsDefaultPort
+	}
+	connect := natsConnect{Name: "shipping-api", Lang: "go"}
+	if u.User != nil {
+		connect.User = u.User.Username()
+		connect.Pass, _ = u.User.Password()
+	}
+	return &natsSink{
+		address: net.JoinHostPort(u.Hostname(), port),
+		connect: connect,
+		subject: subject,
+		timeout: timeout,
+		mtx:     &sync.Mutex{},
+	}, nil
+}
+
+func (s *natsSink) Publish(ctx context.Context, e booking.Event) error {
+	payload, err := json.Marshal(newEnvelope(e))
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn == nil {
+		if s.conn, err = s.dial(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.publish(ctx, payload); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *natsSink) Close(_ context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *natsSink) dial(ctx context.Context) (*natsConn, error) {
+	d := net.Dialer{Timeout: s.timeout}
+	c, err := d.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &natsConn{Conn: c, r: bufio.NewReader(c)}
+	if err := conn.SetDeadline(s.deadline(ctx)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	line, err := conn.readLine()
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("nats: expected INFO, got %q", line)
+	}
+	if err == nil {
+		var connect []byte
+		if connect, err = json.Marshal(s.connect); err == nil {
+			_, err = fmt.Fprintf(conn, "CONNECT %s\r\n", connect)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *natsSink) publish(ctx context.Context, payload []byte) error {
+	if err := s.conn.SetDeadline(s.deadline(ctx)); err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", s.subject, len(payload), payload)
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+
+	for {
+		line, err := s.conn.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (s *natsSink) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+func (c *natsConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/logging"
+)
+
+type outbox interface {
+	PendingEvents(ctx context.Context, after string, limit int) ([]booking.Event, error)
+	MarkPublished(ctx context.Context, ids ...string) error
+}
+
+// Sink takes the events the relay publishes.
+type Sink interface {
+	Publish(context.Context, booking.Event) error
+}
+
+type metrics interface {
+	EventRelayed(event, outcome string)
+}
+
+type Config struct {
+	// Interval is how often the outbox is looked at.
+	Interval  time.Duration
+	BatchSize int
+}
+
+// relay publishes the events in the outbox of the booking store to every sink,
+// in the order they were stored. Every sink has its own cursor, the last event
+// it took, so a failing sink is retried from where it stopped without holding
+// up the others or having them see its events again. An event is only removed
+// from the outbox once all sinks have taken it. The cursors are kept in
+// memory, after a restart the sinks get the events that are still in the
+// outbox again.
+type relay struct {
+	outbox   outbox
+	sinks    []Sink
+	cursors  []string
+	taken    map[string]int
+	unmarked []string // taken by all sinks, not yet marked published
+	config   Config
+	metrics  metrics
+	mtx      *sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce *sync.Once
+}
+
+func NewRelay(outbox outbox, config Config, metrics metrics, sinks ...Sink) *relay {
+	return &relay{
+		outbox:   outbox,
+		sinks:    sinks,
+		cursors:  make([]string, len(sinks)),
+		taken:    make(map[string]int),
+		config:   config,
+		metrics:  metrics,
+		mtx:      &sync.Mutex{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		stopOnce: &sync.Once{},
+	}
+}
+
+// Run relays pending events every interval until the relay is closed.
+func (r *relay) Run() {
+	defer close(r.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if _, err := r.RelayPending(ctx); err != nil && ctx.Err() == nil {
+				logger := logging.FromContext(ctx, "events")
+				logger.Error().Err(err).Msg("failed to relay events")
+			}
+		}
+	}
+}
+
+// Close stops the relay, events that were not relayed stay in the outbox.
+func (r *relay) Close(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RelayPending hands every sink a batch of the events after its cursor and
+// returns how many events all sinks have now taken and left the outbox. A
+// sink stops at the first event it fails to take so that the order is kept,
+// it is tried again from that event on the next call while the other sinks
+// carry on. The first error of a sink is returned.
+func (r *relay) RelayPending(ctx context.Context) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var firstErr error
+	for i, s := range r.sinks {
+		events, err := r.outbox.PendingEvents(ctx, r.cursors[i], r.config.BatchSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, e := range events {
+			if err := s.Publish(ctx, e); err != nil {
+				r.metrics.EventRelayed(string(e.Type), "failed")
+				if firstErr == nil {
+					firstErr = err
+				}
+				break
+			}
+			r.metrics.EventRelayed(string(e.Type), "published")
+			r.cursors[i] = e.Id
+			r.taken[e.Id]++
+			if r.taken[e.Id] == len(r.sinks) {
+				delete(r.taken, e.Id)
+				r.unmarked = append(r.unmarked, e.Id)
+			}
+		}
+	}
+
+	published := len(r.unmarked)
+	if published > 0 {
+		if err := r.outbox.MarkPublished(ctx, r.unmarked...); err != nil {
+			return 0, err
+		}
+		r.unmarked = nil
+	}
+	return published, firstErr
+}
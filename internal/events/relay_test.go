@@ -0,0 +1,210 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/booking/bookingtest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type metricsMock struct{}
+
+func (m metricsMock) EventRelayed(event, outcome string) {}
+
+type outboxMock struct {
+	pending   []booking.Event
+	published []string
+	err       error
+}
+
+func (o *outboxMock) PendingEvents(_ context.Context, after string, limit int) ([]booking.Event, error) {
+	pending := o.pending
+	for i, e := range pending {
+		if e.Id == after {
+			pending = pending[i+1:]
+			break
+		}
+	}
+	if limit < len(pending) {
+		return pending[:limit], o.err
+	}
+	return pending, o.err
+}
+
+func (o *outboxMock) MarkPublished(_ context.Context, ids ...string) error {
+	o.published = append(o.published, ids...)
+	return nil
+}
+
+type sinkMock struct {
+	events []booking.Event
+	// failOn makes publishing the event with the id fail.
+	failOn string
+}
+
+func (s *sinkMock) Publish(_ context.Context, e booking.Event) error {
+	if e.Id == s.failOn {
+		return errors.New("sink error")
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func testEvents(ids ...string) []booking.Event {
+	events := make([]booking.Event, 0, len(ids))
+	for _, id := range ids {
+		events = append(events, booking.Event{Id: id, Type: booking.EventBookingCreated, BookingId: "booking-" + id})
+	}
+	return events
+}
+
+func TestRelayPending(t *testing.T) {
+	testCases := []struct {
+		name              string
+		pending           []booking.Event
+		batchSize         int
+		failOn            string
+		outboxErr         error
+		expectedPublished []string
+		expectedFirst     int
+		shouldFail        bool
+	}{
+		{
+			name:              "all published",
+			pending:           testEvents("1", "2", "3"),
+			batchSize:         10,
+			expectedPublished: []string{"1", "2", "3"},
+			expectedFirst:     3,
+		},
+		{
+			name:              "batch",
+			pending:           testEvents("1", "2", "3"),
+			batchSize:         2,
+			expectedPublished: []string{"1", "2"},
+			expectedFirst:     2,
+		},
+		{
+			name:              "stops at failing sink",
+			pending:           testEvents("1", "2", "3"),
+			batchSize:         10,
+			failOn:            "2",
+			expectedPublished: []string{"1"},
+			expectedFirst:     3,
+			shouldFail:        true,
+		},
+		{
+			name:       "outbox error",
+			pending:    testEvents("1"),
+			batchSize:  10,
+			outboxErr:  errors.New("store error"),
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			outbox := &outboxMock{pending: tc.pending, err: tc.outboxErr}
+			first := &sinkMock{}
+			second := &sinkMock{failOn: tc.failOn}
+			r := NewRelay(outbox, Config{BatchSize: tc.batchSize}, metricsMock{}, first, second)
+
+			published, err := r.RelayPending(context.Background())
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, len(tc.expectedPublished), published)
+			require.Equal(t, tc.expectedPublished, outbox.published)
+			require.Len(t, first.events, tc.expectedFirst, "expected a failing sink to not hold up the others")
+			for i, id := range tc.expectedPublished {
+				require.Equal(t, id, second.events[i].Id, "expected the events in outbox order")
+			}
+		})
+	}
+}
+
+func TestRelayRetriesFailingSink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	outbox := &outboxMock{pending: testEvents("1", "2", "3")}
+	first := &sinkMock{}
+	second := &sinkMock{failOn: "2"}
+	r := NewRelay(outbox, Config{BatchSize: 10}, metricsMock{}, first, second)
+
+	_, err := r.RelayPending(ctx)
+	require.NotNil(t, err, "expected an error, got nil")
+
+	second.failOn = ""
+	published, err := r.RelayPending(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, published)
+	require.Equal(t, []string{"1", "2", "3"}, outbox.published)
+	require.Equal(t, testEvents("1", "2", "3"), first.events, "expected the sink that took the events to not get them again")
+	require.Equal(t, testEvents("1", "2", "3"), second.events, "expected the failing sink to resume where it stopped")
+}
+
+func TestRelayFromStore(t *testing.T) {
+	ctx := context.Background()
+	bundle := bookingtest.New(t)
+	bus := NewBus()
+	received := &sinkMock{}
+	bus.Subscribe(received)
+	r := NewRelay(bundle.Store, Config{BatchSize: 10}, metricsMock{}, bus)
+
+	id := bundle.Book(t, "test-client", "SE")
+
+	published, err := r.RelayPending(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, published)
+	require.Equal(t, booking.EventBookingCreated, received.events[0].Type)
+	require.Equal(t, booking.EventBookingPriced, received.events[1].Type)
+	require.Equal(t, id, received.events[1].BookingId)
+
+	published, err = r.RelayPending(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 0, published, "expected published events to leave the outbox")
+}
+
+func TestRunAndClose(t *testing.T) {
+	t.Parallel()
+
+	outbox := &outboxMock{pending: testEvents("1")}
+	received := make(chan booking.Event, 10)
+	bus := NewBus()
+	bus.Subscribe(handlerFunc(func(_ context.Context, e booking.Event) error {
+		select {
+		case received <- e:
+		default:
+		}
+		return nil
+	}))
+	r := NewRelay(outbox, Config{Interval: time.Millisecond, BatchSize: 10}, metricsMock{}, bus)
+	go r.Run()
+
+	select {
+	case e := <-received:
+		require.Equal(t, "1", e.Id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the running relay to publish")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.Nil(t, r.Close(ctx))
+	require.Nil(t, r.Close(ctx), "expected closing twice to be fine")
+}
+
+type handlerFunc func(context.Context, booking.Event) error
+
+func (f handlerFunc) Publish(ctx context.Context, e booking.Event) error {
+	return f(ctx, e)
+}
@@ -0,0 +1,217 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testEvent = booking.Event{
+	Id:         "event-id",
+	Type:       booking.EventBookingPriced,
+	OccurredAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+	ClientId:   "test-client",
+	BookingId:  "booking-id",
+	Status:     booking.StatusBooked,
+	Price:      150,
+	Currency:   "SEK",
+}
+
+func TestFileSink(t *testing.T) {
+	path := t.TempDir() + "/events.jsonl"
+	sink, err := NewFileSink(path)
+	require.Nil(t, err)
+	require.Nil(t, sink.Publish(context.Background(), testEvent))
+	require.Nil(t, sink.Publish(context.Background(), testEvent))
+	require.Nil(t, sink.Close(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var e envelope
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &e))
+	require.Equal(t, newEnvelope(testEvent), e)
+}
+
+func TestKafkaSink(t *testing.T) {
+	testCases := []struct {
+		name       string
+		status     int
+		response   string
+		shouldFail bool
+	}{
+		{
+			name:     "produced",
+			status:   http.StatusOK,
+			response: `{"offsets":[{"partition":0,"offset":7,"error_code":null,"error":null}]}`,
+		},
+		{
+			name:       "record error",
+			status:     http.StatusOK,
+			response:   `{"offsets":[{"partition":null,"offset":null,"error_code":50002,"error":"leader not available"}]}`,
+			shouldFail: true,
+		},
+		{
+			name:       "proxy error",
+			status:     http.StatusNotFound,
+			response:   `{"error_code":40401,"message":"Topic not found"}`,
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var request *http.Request
+			var body kafkaRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				data, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(data, &body)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer srv.Close()
+
+			sink, err := NewKafkaSink(srv.URL+"/", "bookings", time.Second)
+			require.Nil(t, err)
+			err = sink.Publish(context.Background(), testEvent)
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+			} else {
+				require.Nil(t, err)
+			}
+
+			require.Equal(t, "/topics/bookings", request.URL.Path)
+			require.Equal(t, kafkaContentType, request.Header.Get("Content-Type"))
+			require.Len(t, body.Records, 1)
+			require.Equal(t, "booking-id", body.Records[0].Key)
+			require.Equal(t, newEnvelope(testEvent), body.Records[0].Value)
+		})
+	}
+}
+
+// natsServer is a stand-in for a NATS server that speaks just enough of the
+// protocol to take publishes, rejecting them once reject is set.
+type natsServer struct {
+	listener net.Listener
+	messages chan string
+	connects chan string
+	reject   bool
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	s := &natsServer{listener: l, messages: make(chan string, 10), connects: make(chan string, 10)}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *natsServer) url() string {
+	return "nats://user:secret@" + s.listener.Addr().String()
+}
+
+func (s *natsServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			s.connects <- strings.TrimPrefix(line, "CONNECT ")
+		case strings.HasPrefix(line, "PUB "):
+			fields := strings.Fields(line)
+			n, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			if s.reject {
+				fmt.Fprint(conn, "-ERR 'Permissions Violation for Publish'\r\n")
+				return
+			}
+			s.messages <- fields[1] + " " + string(payload[:n])
+		case line == "PING":
+			// a server ping first, the sink has to answer it before its pong
+			fmt.Fprint(conn, "PING\r\n")
+			fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	srv := newNATSServer(t)
+	sink, err := NewNATSSink(srv.url(), "shipping.bookings", time.Second)
+	require.Nil(t, err)
+	defer sink.Close(context.Background())
+
+	require.Nil(t, sink.Publish(context.Background(), testEvent))
+	require.Nil(t, sink.Publish(context.Background(), testEvent))
+
+	var connect natsConnect
+	require.Nil(t, json.Unmarshal([]byte(<-srv.connects), &connect))
+	require.Equal(t, "user", connect.User)
+	require.Equal(t, "secret", connect.Pass)
+	require.Len(t, srv.connects, 0, "expected the connection to be reused")
+
+	for i := 0; i < 2; i++ {
+		subject, payload, _ := strings.Cut(<-srv.messages, " ")
+		require.Equal(t, "shipping.bookings", subject)
+		var e envelope
+		require.Nil(t, json.Unmarshal([]byte(payload), &e))
+		require.Equal(t, newEnvelope(testEvent), e)
+	}
+}
+
+func TestNATSSinkErrors(t *testing.T) {
+	srv := newNATSServer(t)
+	srv.reject = true
+	sink, err := NewNATSSink(srv.url(), "shipping.bookings", time.Second)
+	require.Nil(t, err)
+	defer sink.Close(context.Background())
+
+	err = sink.Publish(context.Background(), testEvent)
+	require.NotNil(t, err, "expected an error, got nil")
+	require.Contains(t, err.Error(), "Permissions Violation")
+
+	srv.listener.Close()
+	require.NotNil(t, sink.Publish(context.Background(), testEvent), "expected an error without a server")
+
+	_, err = NewNATSSink("http://localhost:4222", "shipping.bookings", time.Second)
+	require.NotNil(t, err, "expected an error for a non nats url")
+	_, err = NewNATSSink("nats://localhost", "shipping bookings", time.Second)
+	require.NotNil(t, err, "expected an error for an invalid subject")
+}
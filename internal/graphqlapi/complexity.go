@@ -0,0 +1,171 @@
+package graphqlapi
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is what a list field is assumed to return when nothing in
+// the query bounds it.
+const defaultListSize = 10
+
+// limitArgument bounds the number of items a paged field returns, the list
+// it pages over costs its items times the page size the limit gives.
+const limitArgument = "limit"
+
+type cost struct {
+	complexity int
+	depth      int
+}
+
+// analyzer computes the cost of an operation before it is executed. Every
+// field costs one, the fields selected below a list cost once per item.
+// Introspection fields cost one and are not descended into.
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+func analyze(doc *ast.Document, operationName string, variables map[string]interface{}, query *graphql.Object) cost {
+	a := analyzer{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		visiting:  map[string]bool{},
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operation = d
+			}
+		}
+	}
+	if operation == nil {
+		return cost{}
+	}
+
+	return a.selectionSet(operation.SelectionSet, query, 0)
+}
+
+func (a analyzer) selectionSet(set *ast.SelectionSet, parent graphql.Type, pageSize int) cost {
+	var total cost
+	if set == nil {
+		return total
+	}
+
+	object, _ := graphql.GetNamed(parent).(*graphql.Object)
+	for _, selection := range set.Selections {
+		var c cost
+		switch s := selection.(type) {
+		case *ast.Field:
+			c = a.field(s, object, pageSize)
+		case *ast.InlineFragment:
+			c = a.selectionSet(s.SelectionSet, parent, pageSize)
+		case *ast.FragmentSpread:
+			fragment, ok := a.fragments[s.Name.Value]
+			if !ok || a.visiting[s.Name.Value] {
+				continue
+			}
+			a.visiting[s.Name.Value] = true
+			c = a.selectionSet(fragment.SelectionSet, parent, pageSize)
+			delete(a.visiting, s.Name.Value)
+		}
+
+		total.complexity += c.complexity
+		if c.depth > total.depth {
+			total.depth = c.depth
+		}
+	}
+	return total
+}
+
+func (a analyzer) field(field *ast.Field, parent *graphql.Object, pageSize int) cost {
+	if strings.HasPrefix(field.Name.Value, "__") || parent == nil {
+		return cost{complexity: 1, depth: 1}
+	}
+
+	definition, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return cost{complexity: 1, depth: 1}
+	}
+
+	items := 1
+	childPageSize := 0
+	if _, isList := graphql.GetNullable(definition.Type).(*graphql.List); isList {
+		items = defaultListSize
+		if pageSize > 0 {
+			items = pageSize
+		}
+	}
+	if limit, ok := a.limit(field, definition); ok {
+		childPageSize = boundedPageSize(limit)
+	}
+
+	children := a.selectionSet(field.SelectionSet, definition.Type, childPageSize)
+	return cost{
+		complexity: 1 + items*children.complexity,
+		depth:      1 + children.depth,
+	}
+}
+
+// limit returns the value of the limit argument of the field, falling back
+// to its default value.
+func (a analyzer) limit(field *ast.Field, definition *graphql.FieldDefinition) (int, bool) {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != limitArgument {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				return n, true
+			}
+		case *ast.Variable:
+			if n, ok := toInt(a.variables[v.Name.Value]); ok {
+				return n, true
+			}
+		}
+	}
+
+	for _, arg := range definition.Args {
+		if arg.Name() == limitArgument {
+			return toInt(arg.DefaultValue)
+		}
+	}
+	return 0, false
+}
+
+// boundedPageSize is the number of items a page of the limit holds, bounded
+// the same way as booking.Service.ListBookings bounds it.
+func boundedPageSize(limit int) int {
+	if limit <= 0 {
+		return booking.DefaultPageSize
+	}
+	if limit > booking.MaxPageSize {
+		return booking.MaxPageSize
+	}
+	return limit
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	case int64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
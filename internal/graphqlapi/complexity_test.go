@@ -0,0 +1,136 @@
+package graphqlapi
+
+import (
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueryType() *graphql.Object {
+	item := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.String},
+			"tags": &graphql.Field{Type: graphql.NewList(graphql.String)},
+		},
+	})
+	page := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Page",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(item))},
+			"next":  &graphql.Field{Type: graphql.Int},
+		},
+	})
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"item":  &graphql.Field{Type: item},
+			"items": &graphql.Field{Type: graphql.NewList(item)},
+			"page": &graphql.Field{
+				Type: page,
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 5},
+				},
+			},
+		},
+	})
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		query              string
+		operationName      string
+		variables          map[string]interface{}
+		expectedComplexity int
+		expectedDepth      int
+	}{
+		{
+			name:               "single field",
+			query:              `{ item { id } }`,
+			expectedComplexity: 2,
+			expectedDepth:      2,
+		},
+		{
+			name:               "list without limit",
+			query:              `{ items { id } }`,
+			expectedComplexity: 1 + defaultListSize,
+			expectedDepth:      2,
+		},
+		{
+			name:               "nested lists",
+			query:              `{ items { id tags } }`,
+			expectedComplexity: 1 + defaultListSize*2,
+			expectedDepth:      2,
+		},
+		{
+			name:               "page with default limit",
+			query:              `{ page { items { id } next } }`,
+			expectedComplexity: 1 + (1 + 5) + 1,
+			expectedDepth:      3,
+		},
+		{
+			name:               "page with literal limit",
+			query:              `{ page(limit: 20) { items { id } } }`,
+			expectedComplexity: 1 + 1 + 20,
+			expectedDepth:      3,
+		},
+		{
+			name:               "page with variable limit",
+			query:              `query Page($limit: Int) { page(limit: $limit) { items { id } } }`,
+			variables:          map[string]interface{}{"limit": float64(100)},
+			expectedComplexity: 1 + 1 + 100,
+			expectedDepth:      3,
+		},
+		{
+			name:               "page without a positive limit",
+			query:              `{ page(limit: 0) { items { id } } }`,
+			expectedComplexity: 1 + 1 + booking.DefaultPageSize,
+			expectedDepth:      3,
+		},
+		{
+			name:               "page above the maximum limit",
+			query:              `query Page($limit: Int) { page(limit: $limit) { items { id } } }`,
+			variables:          map[string]interface{}{"limit": float64(100000)},
+			expectedComplexity: 1 + 1 + booking.MaxPageSize,
+			expectedDepth:      3,
+		},
+		{
+			name:               "fragments",
+			query:              `{ items { ...fields } } fragment fields on Item { id ... on Item { tags } }`,
+			expectedComplexity: 1 + defaultListSize*2,
+			expectedDepth:      2,
+		},
+		{
+			name:               "named operation",
+			query:              `query A { item { id } } query B { items { id } }`,
+			operationName:      "B",
+			expectedComplexity: 1 + defaultListSize,
+			expectedDepth:      2,
+		},
+		{
+			name:               "introspection",
+			query:              `{ __schema { types { name fields { name } } } }`,
+			expectedComplexity: 1,
+			expectedDepth:      1,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			doc, err := parser.Parse(parser.ParseParams{Source: tc.query})
+			require.Nil(t, err)
+
+			c := analyze(doc, tc.operationName, tc.variables, testQueryType())
+			require.Equal(t, tc.expectedComplexity, c.complexity)
+			require.Equal(t, tc.expectedDepth, c.depth)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package graphqlapi
+
+import (
+	"net/http"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+)
+
+const internalMessage = "the server failed to handle the request"
+
+// extensions carries the error code, field errors and context of err the
+// same way problem documents do in the HTTP API.
+func extensions(err error) map[string]interface{} {
+	ext := map[string]interface{}{"code": errors.GetCode(err)}
+	if errors.HTTPStatus(err) >= http.StatusInternalServerError {
+		return ext
+	}
+
+	if fields := errors.GetFields(err); len(fields) > 0 {
+		ext["errors"] = fields
+	}
+	for k, v := range errors.GetContext(err).Fields() {
+		if k != "operation" {
+			ext[k] = v
+		}
+	}
+	return ext
+}
+
+func requestError(err error) gqlerrors.FormattedError {
+	message := err.Error()
+	if errors.HTTPStatus(err) >= http.StatusInternalServerError {
+		message = internalMessage
+	}
+
+	return gqlerrors.FormattedError{
+		Message:    message,
+		Locations:  []location.SourceLocation{},
+		Extensions: extensions(err),
+	}
+}
+
+// invalidQuery adds the invalid_query code to errors reported by the parser
+// and validator.
+func invalidQuery(formatted []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i := range formatted {
+		formatted[i].Extensions = map[string]interface{}{"code": errors.CodeInvalidQuery}
+	}
+	return formatted
+}
+
+// resolverErrors replaces the errors returned by resolvers with their API
+// error, internal errors are not shown to clients.
+func resolverErrors(formatted []gqlerrors.FormattedError) ([]gqlerrors.FormattedError, []error) {
+	var errs []error
+	for i, f := range formatted {
+		located, ok := f.OriginalError().(*gqlerrors.Error)
+		if !ok || located.OriginalError == nil {
+			continue
+		}
+
+		err := located.OriginalError
+		errs = append(errs, err)
+		if errors.HTTPStatus(err) >= http.StatusInternalServerError {
+			formatted[i].Message = internalMessage
+		}
+		formatted[i].Extensions = extensions(err)
+	}
+	return formatted, errs
+}
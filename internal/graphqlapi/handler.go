@@ -0,0 +1,204 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// persistedQueryNotFound is the message clients using automatic persisted
+// queries look for to retry with the query text.
+const persistedQueryNotFound = "PersistedQueryNotFound"
+
+type Config struct {
+	MaxComplexity int
+	MaxDepth      int
+	// PersistedOnly rejects queries that are not already persisted, new
+	// queries are otherwise persisted when sent together with their hash.
+	PersistedOnly bool
+}
+
+type request struct {
+	Query         string                 `json:"query,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	Extensions    requestExtensions      `json:"extensions,omitempty"`
+}
+
+type requestExtensions struct {
+	PersistedQuery *persistedQuery `json:"persistedQuery,omitempty"`
+}
+
+type persistedQuery struct {
+	Version    int    `json:"version" binding:"required"`
+	Sha256Hash string `json:"sha256Hash" binding:"required,len=64"`
+}
+
+type response struct {
+	Data       interface{}                `json:"data,omitempty"`
+	Errors     []gqlerrors.FormattedError `json:"errors,omitempty"`
+	Extensions map[string]interface{}     `json:"extensions,omitempty"`
+}
+
+type persistedQueryStore interface {
+	Get(hash string) (string, bool)
+	Add(query string) string
+}
+
+type handler struct {
+	schema  graphql.Schema
+	queries persistedQueryStore
+	config  Config
+}
+
+func NewHandler(schema graphql.Schema, queries persistedQueryStore, config Config) *handler {
+	return &handler{schema: schema, queries: queries, config: config}
+}
+
+// Query serves GraphQL over HTTP, as a JSON body on POST or as query
+// parameters on GET where variables and extensions are JSON encoded.
+func (h handler) Query(c *gin.Context) {
+	req, err := readRequest(c)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	query, persist, err := h.resolveQuery(req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"})})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response{Errors: invalidQuery(gqlerrors.FormatErrors(err))})
+		return
+	}
+	if result := graphql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		c.JSON(http.StatusBadRequest, response{Errors: invalidQuery(result.Errors)})
+		return
+	}
+	if !isQuery(doc, req.OperationName) {
+		h.fail(c, errors.FromCode(errors.CodeInvalidQuery, "only query operations are supported", errors.ErrorInput))
+		return
+	}
+
+	cost := analyze(doc, req.OperationName, req.Variables, h.schema.QueryType())
+	if h.config.MaxDepth > 0 && cost.depth > h.config.MaxDepth {
+		h.fail(c, errors.FromCode(errors.CodeQueryTooComplex, fmt.Sprintf("query depth %d exceeds the maximum of %d", cost.depth, h.config.MaxDepth), errors.ErrorInput))
+		return
+	}
+	if h.config.MaxComplexity > 0 && cost.complexity > h.config.MaxComplexity {
+		h.fail(c, errors.FromCode(errors.CodeQueryTooComplex, fmt.Sprintf("query complexity %d exceeds the maximum of %d", cost.complexity, h.config.MaxComplexity), errors.ErrorInput))
+		return
+	}
+	// only queries that would run are persisted
+	if persist {
+		h.queries.Add(query)
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       c,
+	})
+
+	formatted, errs := resolverErrors(result.Errors)
+	for _, err := range errs {
+		_ = c.Error(err)
+	}
+	c.JSON(http.StatusOK, response{
+		Data:   result.Data,
+		Errors: formatted,
+		Extensions: map[string]interface{}{
+			"cost": map[string]int{"complexity": cost.complexity, "depth": cost.depth, "maxComplexity": h.config.MaxComplexity},
+		},
+	})
+}
+
+// resolveQuery returns the text of the query, looking it up when the request
+// carries only a persisted query hash. A new query sent with its hash should
+// be persisted once it has passed every check.
+func (h handler) resolveQuery(req request) (string, bool, error) {
+	var hash string
+	if pq := req.Extensions.PersistedQuery; pq != nil {
+		hash = pq.Sha256Hash
+	}
+
+	if req.Query == "" {
+		if hash == "" {
+			return "", false, errors.FromCode(errors.CodeInvalidQuery, "no query in request", errors.ErrorInput)
+		}
+		query, ok := h.queries.Get(hash)
+		if !ok {
+			return "", false, errors.FromCode(errors.CodePersistedQueryNotFound, persistedQueryNotFound, errors.ErrorInput)
+		}
+		return query, false, nil
+	}
+
+	if hash != "" && hashOf(req.Query) != hash {
+		return "", false, errors.FromCode(errors.CodePersistedQueryMismatch, "sha256Hash does not match the query", errors.ErrorInput)
+	}
+	if _, ok := h.queries.Get(hashOf(req.Query)); ok {
+		return req.Query, false, nil
+	}
+	if h.config.PersistedOnly {
+		return "", false, errors.FromCode(errors.CodePersistedQueryRequired, "only persisted queries are allowed", errors.ErrorForbidden)
+	}
+	return req.Query, hash != "", nil
+}
+
+func (h handler) fail(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.JSON(errors.HTTPStatus(err), response{Errors: []gqlerrors.FormattedError{requestError(err)}})
+}
+
+func readRequest(c *gin.Context) (request, error) {
+	var req request
+	if c.Request.Method != http.MethodGet {
+		if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+			return req, errors.FromCode(errors.CodeInvalidRequest, "request body is not valid JSON", errors.ErrorInput)
+		}
+		return req, nil
+	}
+
+	req.Query = c.Query("query")
+	req.OperationName = c.Query("operationName")
+	if v := c.Query("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+			return req, errors.Validation("invalid variables", errors.FieldError{Field: "variables", Code: errors.FieldInvalidType, Message: "variables must be a JSON object"})
+		}
+	}
+	if e := c.Query("extensions"); e != "" {
+		if err := json.Unmarshal([]byte(e), &req.Extensions); err != nil {
+			return req, errors.Validation("invalid extensions", errors.FieldError{Field: "extensions", Code: errors.FieldInvalidType, Message: "extensions must be a JSON object"})
+		}
+	}
+	return req, nil
+}
+
+func isQuery(doc *ast.Document, operationName string) bool {
+	for _, definition := range doc.Definitions {
+		op, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return op.Operation == ast.OperationTypeQuery
+		}
+	}
+	return false
+}
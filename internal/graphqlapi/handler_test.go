@@ -0,0 +1,230 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveQuery(t *testing.T) {
+	t.Parallel()
+
+	const (
+		persisted = `{ locations { code } }`
+		adHoc     = `{ location(code: "SE") { code } }`
+	)
+
+	testCases := []struct {
+		name          string
+		persistedOnly bool
+		req           request
+		expectedQuery string
+		expectedCode  errors.Code
+		expectPersist bool
+	}{
+		{
+			name:          "query",
+			req:           request{Query: adHoc},
+			expectedQuery: adHoc,
+		},
+		{
+			name:         "no query",
+			req:          request{},
+			expectedCode: errors.CodeInvalidQuery,
+		},
+		{
+			name:          "persisted hash",
+			req:           withHash(request{}, hashOf(persisted)),
+			expectedQuery: persisted,
+		},
+		{
+			name:         "unknown hash",
+			req:          withHash(request{}, hashOf(adHoc)),
+			expectedCode: errors.CodePersistedQueryNotFound,
+		},
+		{
+			name:          "query with hash is to be persisted",
+			req:           withHash(request{Query: adHoc}, hashOf(adHoc)),
+			expectedQuery: adHoc,
+			expectPersist: true,
+		},
+		{
+			name:         "query with wrong hash",
+			req:          withHash(request{Query: adHoc}, hashOf(persisted)),
+			expectedCode: errors.CodePersistedQueryMismatch,
+		},
+		{
+			name:          "persisted only allows persisted query text",
+			persistedOnly: true,
+			req:           request{Query: persisted},
+			expectedQuery: persisted,
+		},
+		{
+			name:          "persisted only rejects ad hoc query",
+			persistedOnly: true,
+			req:           request{Query: adHoc},
+			expectedCode:  errors.CodePersistedQueryRequired,
+		},
+		{
+			name:          "persisted only does not persist",
+			persistedOnly: true,
+			req:           withHash(request{Query: adHoc}, hashOf(adHoc)),
+			expectedCode:  errors.CodePersistedQueryRequired,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			queries := NewPersistedQueries(DefaultMaxPersistedQueries)
+			queries.Add(persisted)
+			h := handler{queries: queries, config: Config{PersistedOnly: tc.persistedOnly}}
+
+			query, persist, err := h.resolveQuery(tc.req)
+			if tc.expectedCode != "" {
+				require.Equal(t, tc.expectedCode, errors.GetCode(err))
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedQuery, query)
+			require.Equal(t, tc.expectPersist, persist)
+		})
+	}
+}
+
+func TestQueryPersists(t *testing.T) {
+	t.Parallel()
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: testQueryType()})
+	require.Nil(t, err)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectPersist  bool
+	}{
+		{
+			name:           "valid query",
+			query:          `{ item { id } }`,
+			expectedStatus: http.StatusOK,
+			expectPersist:  true,
+		},
+		{
+			name:           "invalid query",
+			query:          `{ item { nothing } }`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unparsable query",
+			query:          `{ item {`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too complex query",
+			query:          `{ items { id } }`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			queries := NewPersistedQueries(DefaultMaxPersistedQueries)
+			h := NewHandler(schema, queries, Config{MaxComplexity: 5})
+
+			body, err := json.Marshal(withHash(request{Query: tc.query}, hashOf(tc.query)))
+			require.Nil(t, err)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+			h.Query(c)
+
+			require.Equal(t, tc.expectedStatus, recorder.Code)
+			_, persisted := queries.Get(hashOf(tc.query))
+			require.Equal(t, tc.expectPersist, persisted)
+		})
+	}
+}
+
+func TestPersistedQueries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "queries.json")
+	loaded := `{ locations { code } }`
+	require.Nil(t, os.WriteFile(path, []byte(`{"`+hashOf(loaded)+`": "{ locations { code } }"}`), 0o600))
+	queries, err := LoadPersistedQueries(path, 2)
+	require.Nil(t, err)
+
+	queries.Add(`{ a }`)
+	queries.Add(`{ b }`)
+	// reading a query keeps it from being evicted next
+	_, ok := queries.Get(hashOf(`{ a }`))
+	require.True(t, ok)
+	queries.Add(`{ c }`)
+
+	for query, expected := range map[string]bool{loaded: true, `{ a }`: true, `{ b }`: false, `{ c }`: true} {
+		_, ok := queries.Get(hashOf(query))
+		require.Equal(t, expected, ok, query)
+	}
+	require.Equal(t, 3, queries.Len())
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	t.Parallel()
+
+	query := `{ locations { code } }`
+	testCases := []struct {
+		name       string
+		content    string
+		shouldFail bool
+	}{
+		{
+			name:    "valid",
+			content: `{"` + hashOf(query) + `": "{ locations { code } }"}`,
+		},
+		{
+			name:       "hash mismatch",
+			content:    `{"` + hashOf("other") + `": "{ locations { code } }"}`,
+			shouldFail: true,
+		},
+		{
+			name:       "invalid JSON",
+			content:    `[`,
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "queries.json")
+			require.Nil(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			queries, err := LoadPersistedQueries(path, DefaultMaxPersistedQueries)
+			if tc.shouldFail {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			got, ok := queries.Get(hashOf(query))
+			require.True(t, ok)
+			require.Equal(t, query, got)
+		})
+	}
+}
+
+func withHash(req request, hash string) request {
+	req.Extensions.PersistedQuery = &persistedQuery{Version: 1, Sha256Hash: hash}
+	return req
+}
@@ -0,0 +1,40 @@
+package graphqlapi
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func QueryOperation() openapi.Operation {
+	op := openapi.Operation{
+		OperationId: "graphql",
+		Summary:     "Run a GraphQL query, or a persisted query by its hash",
+		Tags:        []string{"graphql"},
+		RequestBody: openapi.JSONBody(request{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Query result, resolver errors are listed in errors", response{}),
+			"400": openapi.JSONResponse("Invalid, too complex or unknown persisted query", response{}),
+			"403": openapi.JSONResponse("Query is not persisted", response{}),
+		},
+	}
+
+	// clients send null for members they leave out and may add extensions of
+	// their own
+	for name, property := range op.Schema().Properties {
+		property.Nullable = true
+		if name == "extensions" {
+			property.AdditionalProperties = nil
+		}
+	}
+	return op
+}
+
+func QueryGetOperation() openapi.Operation {
+	op := QueryOperation()
+	op.OperationId = "graphqlGet"
+	op.Summary = "Run a GraphQL query given as query parameters, variables and extensions are JSON encoded"
+	op.RequestBody = nil
+	for _, name := range []string{"query", "operationName", "variables", "extensions"} {
+		op.Parameters = append(op.Parameters, openapi.Parameter{Name: name, In: "query", Schema: &openapi.Schema{Type: "string"}})
+	}
+	return op
+}
@@ -0,0 +1,118 @@
+package graphqlapi
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultMaxPersistedQueries is how many queries clients may persist before
+// the least recently used are evicted.
+const DefaultMaxPersistedQueries = 1000
+
+type persistedEntry struct {
+	hash  string
+	query string
+}
+
+// persistedQueries keeps the queries loaded from a file for good and the
+// queries clients persist up to a maximum, evicting the least recently used.
+type persistedQueries struct {
+	loaded     map[string]string
+	added      map[string]*list.Element
+	recent     *list.List
+	maxEntries int
+	mtx        *sync.Mutex
+}
+
+// NewPersistedQueries returns a store of queries by the hex encoded SHA-256
+// hash of their text, holding at most maxEntries persisted by clients.
+func NewPersistedQueries(maxEntries int) persistedQueries {
+	return persistedQueries{
+		loaded:     map[string]string{},
+		added:      map[string]*list.Element{},
+		recent:     list.New(),
+		maxEntries: maxEntries,
+		mtx:        &sync.Mutex{},
+	}
+}
+
+// LoadPersistedQueries reads a JSON object of queries keyed by their hash,
+// as generated by the persisted query tooling of most GraphQL clients. The
+// loaded queries are never evicted.
+func LoadPersistedQueries(path string, maxEntries int) (persistedQueries, error) {
+	p := NewPersistedQueries(maxEntries)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+
+	var queries map[string]string
+	if err := json.Unmarshal(b, &queries); err != nil {
+		return p, fmt.Errorf("invalid persisted queries %s: %w", path, err)
+	}
+	for hash, query := range queries {
+		if hashOf(query) != hash {
+			return p, fmt.Errorf("persisted query %s does not match its hash", hash)
+		}
+		p.loaded[hash] = query
+	}
+	return p, nil
+}
+
+func (p persistedQueries) Get(hash string) (string, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if query, ok := p.loaded[hash]; ok {
+		return query, true
+	}
+	e, ok := p.added[hash]
+	if !ok {
+		return "", false
+	}
+	p.recent.MoveToFront(e)
+	return e.Value.(persistedEntry).query, true
+}
+
+func (p persistedQueries) Add(query string) string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	hash := hashOf(query)
+	if _, ok := p.loaded[hash]; ok {
+		return hash
+	}
+	if e, ok := p.added[hash]; ok {
+		p.recent.MoveToFront(e)
+		return hash
+	}
+	if p.maxEntries <= 0 {
+		return hash
+	}
+
+	p.added[hash] = p.recent.PushFront(persistedEntry{hash: hash, query: query})
+	for p.recent.Len() > p.maxEntries {
+		oldest := p.recent.Back()
+		p.recent.Remove(oldest)
+		delete(p.added, oldest.Value.(persistedEntry).hash)
+	}
+	return hash
+}
+
+func (p persistedQueries) Len() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return len(p.loaded) + len(p.added)
+}
+
+func hashOf(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package graphqlapi
+
+import (
+	"context"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/graphql-go/graphql"
+)
+
+// NewSchema returns the query schema over bookings, locations and tariffs.
+// The rate and price tables need the same roles as /tariffs.
+func NewSchema(bookingService *booking.Service, billingService billing.Service) (graphql.Schema, error) {
+	fields := billing.GraphQLQueries(billingService)
+	for name, field := range billing.GraphQLTariffQueries(billingService) {
+		fields[name] = requireRole(field, auth.RoleOperator, auth.RoleAdmin)
+	}
+
+	links := booking.GraphQLLinks{
+		LocationType: billing.LocationType,
+		Location: func(ctx context.Context, code string) (interface{}, error) {
+			return billingService.GetLocation(ctx, code)
+		},
+		QuoteType: billing.QuoteType,
+		Quote: func(ctx context.Context, origin, destination string, weight float32, serviceLevel billing.ServiceLevel) (interface{}, error) {
+			return billingService.Quote(ctx, origin, destination, weight, serviceLevel)
+		},
+		ServiceLevelType: billing.ServiceLevelType,
+	}
+	for name, field := range booking.GraphQLQueries(bookingService, links) {
+		fields[name] = field
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: fields}),
+	})
+}
+
+func requireRole(field *graphql.Field, allowed ...auth.Role) *graphql.Field {
+	resolve := field.Resolve
+	field.Resolve = func(p graphql.ResolveParams) (interface{}, error) {
+		roles, _ := p.Context.Value(auth.RolesKey).([]auth.Role)
+		if !auth.HasAnyRole(roles, allowed...) {
+			return nil, errors.FromCode(errors.CodeForbidden, "insufficient role", errors.ErrorForbidden)
+		}
+		return resolve(p)
+	}
+	return field
+}
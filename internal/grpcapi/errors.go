@@ -0,0 +1,82 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const errorDomain = "shipping-api"
+
+func codeOf(t errors.ErrorType) codes.Code {
+	switch t {
+	case errors.ErrorNotFound:
+		return codes.NotFound
+	case errors.ErrorInput:
+		return codes.InvalidArgument
+	case errors.ErrorUnauthorized:
+		return codes.Unauthenticated
+	case errors.ErrorForbidden:
+		return codes.PermissionDenied
+	case errors.ErrorConflict:
+		return codes.AlreadyExists
+	case errors.ErrorRateLimited:
+		return codes.ResourceExhausted
+	case errors.ErrorUnavailable:
+		return codes.Unavailable
+	case errors.ErrorInternal:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
+
+// toStatus converts err to a gRPC status carrying the error code as an
+// ErrorInfo reason and field errors as a BadRequest, the same information the
+// HTTP API puts in its problem documents. Details of internal errors are not
+// sent to clients.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := codeOf(errors.GetType(err))
+	msg := err.Error()
+	metadata := map[string]string{}
+	if code == codes.Internal || code == codes.Unknown {
+		msg = "the server failed to handle the request"
+	} else {
+		for k, v := range errors.GetContext(err).Fields() {
+			metadata[k] = fmt.Sprint(v)
+		}
+	}
+
+	st := status.New(code, msg)
+	withDetails, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   string(errors.GetCode(err)),
+		Domain:   errorDomain,
+		Metadata: metadata,
+	})
+	if detailErr == nil {
+		st = withDetails
+	}
+
+	if fields := errors.GetFields(err); len(fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+		for _, f := range fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		if withDetails, detailErr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); detailErr == nil {
+			st = withDetails
+		}
+	}
+
+	return st.Err()
+}
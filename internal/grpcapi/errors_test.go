@@ -0,0 +1,116 @@
+package grpcapi
+
+import (
+	errs "errors"
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		err              error
+		expectedCode     codes.Code
+		expectedMessage  string
+		expectedReason   string
+		expectedMetadata map[string]string
+		expectedFields   []string
+	}{
+		{
+			name:             "not found",
+			err:              errors.NotFound(errors.CodeBookingNotFound, "booking", "b1"),
+			expectedCode:     codes.NotFound,
+			expectedMessage:  "booking b1 not found",
+			expectedReason:   "booking_not_found",
+			expectedMetadata: map[string]string{"entity": "booking", "entityId": "b1"},
+		},
+		{
+			name:            "validation",
+			err:             errors.Validation("invalid booking", errors.FieldError{Field: "weight", Code: errors.FieldOutOfRange, Message: "weight must be positive"}),
+			expectedCode:    codes.InvalidArgument,
+			expectedMessage: "invalid booking",
+			expectedReason:  "validation_failed",
+			expectedFields:  []string{"weight"},
+		},
+		{
+			name:            "unauthorized",
+			err:             errors.FromCode(errors.CodeUnauthorized, "invalid api key", errors.ErrorUnauthorized),
+			expectedCode:    codes.Unauthenticated,
+			expectedMessage: "invalid api key",
+			expectedReason:  "unauthorized",
+		},
+		{
+			name:            "forbidden",
+			err:             errors.FromCode(errors.CodeForbidden, "insufficient role", errors.ErrorForbidden),
+			expectedCode:    codes.PermissionDenied,
+			expectedMessage: "insufficient role",
+			expectedReason:  "forbidden",
+		},
+		{
+			name:            "rate limited",
+			err:             errors.FromCode(errors.CodeRateLimited, "rate limit exceeded", errors.ErrorRateLimited),
+			expectedCode:    codes.ResourceExhausted,
+			expectedMessage: "rate limit exceeded",
+			expectedReason:  "rate_limited",
+		},
+		{
+			name:            "internal error is hidden",
+			err:             errors.WrapStore(errs.New("connection refused"), "booking", "add_booking"),
+			expectedCode:    codes.Internal,
+			expectedMessage: "the server failed to handle the request",
+			expectedReason:  "internal",
+		},
+		{
+			name:            "status is kept",
+			err:             status.Error(codes.Canceled, "canceled"),
+			expectedCode:    codes.Canceled,
+			expectedMessage: "canceled",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			st, ok := status.FromError(toStatus(tc.err))
+			require.True(t, ok)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Equal(t, tc.expectedMessage, st.Message())
+
+			var (
+				reason   string
+				metadata map[string]string
+				fields   []string
+			)
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					reason = d.Reason
+					metadata = d.Metadata
+				case *errdetails.BadRequest:
+					for _, v := range d.FieldViolations {
+						fields = append(fields, v.Field)
+					}
+				}
+			}
+			require.Equal(t, tc.expectedReason, reason)
+			if tc.expectedMetadata != nil {
+				require.Equal(t, tc.expectedMetadata, metadata)
+			}
+			require.Equal(t, tc.expectedFields, fields)
+		})
+	}
+}
+
+func TestToStatusNil(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, toStatus(nil))
+}
@@ -0,0 +1,240 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+	"github.com/slaengkast/shipping-api/internal/ratelimit"
+	"github.com/slaengkast/shipping-api/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeyMetadata        = "x-api-key"
+	authorizationMetadata = "authorization"
+	requestIdMetadata     = "x-request-id"
+	bearerPrefix          = "Bearer "
+)
+
+var tracer = tracing.Tracer("github.com/slaengkast/shipping-api/internal/grpcapi")
+
+type authenticator interface {
+	Authenticate(context.Context, string) (string, error)
+}
+
+type tokenVerifier interface {
+	Verify(context.Context, string) (string, []auth.Role, error)
+}
+
+type limiter interface {
+	Allow(ctx context.Context, route, client string) ratelimit.Result
+}
+
+type rpcMetrics interface {
+	RPCHandled(method, code string, duration time.Duration)
+}
+
+type clientIdKey struct{}
+
+type authErrorKey struct{}
+
+func clientIdFromContext(ctx context.Context) string {
+	clientId, _ := ctx.Value(clientIdKey{}).(string)
+	return clientId
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// metadataCarrier lets the propagator read the trace context from the
+// incoming metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return firstMetadata(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// observe continues the trace of the caller, attaches a logger carrying the
+// request id and records the outcome of the call in the metrics and the log.
+// Errors are converted to statuses here so that everything outside sees the
+// gRPC code.
+func observe(metrics rpcMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		start := time.Now()
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		ctx, span := tracer.Start(ctx, strings.TrimPrefix(info.FullMethod, "/"),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.method", info.FullMethod),
+			),
+		)
+		defer span.End()
+
+		id := firstMetadata(md, requestIdMetadata)
+		if id == "" || len(id) > 128 {
+			id = uuid.New().String()
+		}
+		span.SetAttributes(attribute.String("rpc.request_id", id))
+		logger := log.With().Str(logging.RequestIdKey, id).Logger()
+		ctx = logging.WithLogger(ctx, logger)
+
+		res, err := handler(ctx, req)
+		err = toStatus(err)
+
+		code := status.Code(err)
+		metrics.RPCHandled(info.FullMethod, code.String(), time.Since(start))
+		span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+
+		logger = logging.FromContext(ctx, "grpc")
+		event := logger.Info()
+		if code == codes.Internal || code == codes.Unknown {
+			event = logger.Error()
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		event.
+			Str("method", info.FullMethod).
+			Str("code", code.String()).
+			Dur("latency", time.Since(start)).
+			Str("clientId", clientIdFromContext(ctx)).
+			Err(err).
+			Msg("rpc")
+		return res, err
+	}
+}
+
+// recovery turns a panic in a handler into an internal error.
+func recovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = errors.FromMessage(fmt.Sprintf("panic: %v", recovered), errors.ErrorInternal)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// authenticate accepts the same credentials as the HTTP API, passed as
+// metadata. It only identifies the client, authorize rejects the call, so
+// that rateLimit in between also limits failed attempts.
+func authenticate(authenticator authenticator, tokenVerifier tokenVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		var (
+			clientId string
+			roles    []auth.Role
+			err      error
+		)
+		if header := firstMetadata(md, authorizationMetadata); strings.HasPrefix(header, bearerPrefix) {
+			clientId, roles, err = tokenVerifier.Verify(ctx, strings.TrimPrefix(header, bearerPrefix))
+		} else {
+			clientId, err = authenticator.Authenticate(ctx, firstMetadata(md, apiKeyMetadata))
+			roles = []auth.Role{auth.RoleCustomer}
+		}
+
+		if err != nil {
+			if errors.GetType(err) != errors.ErrorUnauthorized {
+				err = errors.FromCode(errors.CodeUnauthorized, err.Error(), errors.ErrorUnauthorized)
+			}
+			return handler(context.WithValue(ctx, authErrorKey{}, err), req)
+		}
+
+		return handler(auth.WithRoles(context.WithValue(ctx, clientIdKey{}, clientId), roles), req)
+	}
+}
+
+// authorize rejects calls that authenticate could not identify and requires
+// one of the roles that may book.
+func authorize(allowed ...auth.Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err, ok := ctx.Value(authErrorKey{}).(error); ok {
+			return nil, err
+		}
+		if !auth.HasAnyRole(auth.RolesFromContext(ctx), allowed...) {
+			return nil, errors.FromCode(errors.CodeForbidden, "insufficient role", errors.ErrorForbidden)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// rateLimit limits clients per method, the routes of the limiter are given as
+// "RPC <full method>". Calls that are not authenticated are limited by the
+// peer address.
+func rateLimit(limiter limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		client := "ip:" + peerHost(ctx)
+		if clientId := clientIdFromContext(ctx); clientId != "" {
+			client = "client:" + clientId
+		}
+
+		result := limiter.Allow(ctx, "RPC "+info.FullMethod, client)
+		if !result.Allowed {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(result.RetryAfter.Seconds())+1)))
+			return nil, errors.FromCode(errors.CodeRateLimited, "rate limit exceeded", errors.ErrorRateLimited)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// peerHost is the address of the caller without its port.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// only applies interceptor to the methods of service, so that health checks
+// and reflection stay open.
+func only(service string, interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	prefix := "/" + service + "/"
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
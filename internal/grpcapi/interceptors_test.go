@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/ratelimit"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRateLimitUnauthenticated(t *testing.T) {
+	t.Parallel()
+
+	authService := auth.NewService(auth.NewInMemoryKeyStore())
+	_, key, err := authService.CreateKey(context.Background(), "test-client")
+	require.Nil(t, err)
+	limit, err := ratelimit.NewLimit(0.001, 1)
+	require.Nil(t, err)
+	limiter := ratelimit.NewLimiter(ratelimit.NewInMemoryStore(), limit, nil)
+
+	// the interceptors in the order the server chains them
+	interceptors := []grpc.UnaryServerInterceptor{
+		authenticate(authService, (*auth.TokenVerifier)(nil)),
+		rateLimit(limiter),
+		authorize(auth.RoleCustomer),
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/shipping.v1.ShippingService/GetBooking"}
+	call := func(key string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(apiKeyMetadata, key))
+		var handler grpc.UnaryHandler = func(ctx context.Context, _ interface{}) (interface{}, error) { return nil, nil }
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		_, err := handler(ctx, nil)
+		return err
+	}
+
+	require.Equal(t, errors.ErrorUnauthorized, errors.GetType(call("invalid")))
+	// guessing keys is limited by the peer address
+	require.Equal(t, errors.CodeRateLimited, errors.GetCode(call("guessed")))
+	// while clients are limited on their own
+	require.Nil(t, call(key))
+}
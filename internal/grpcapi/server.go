@@ -0,0 +1,93 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	shippingv1 "github.com/slaengkast/shipping-api/proto/shipping/v1"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type Config struct {
+	Port int
+}
+
+type server struct {
+	grpcServer *grpc.Server
+	health     *health.Server
+	config     Config
+}
+
+func New(
+	bookingService *booking.Service,
+	billingService billing.Service,
+	authenticator authenticator,
+	tokenVerifier tokenVerifier,
+	limiter limiter,
+	metrics rpcMetrics,
+	config Config,
+) *server {
+	service := shippingv1.ShippingService_ServiceDesc.ServiceName
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		observe(metrics),
+		recovery(),
+		only(service, authenticate(authenticator, tokenVerifier)),
+		only(service, rateLimit(limiter)),
+		only(service, authorize(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin)),
+	))
+
+	shippingv1.RegisterShippingServiceServer(grpcServer, &shippingService{
+		bookingService: bookingService,
+		billingService: billingService,
+	})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+
+	return &server{grpcServer: grpcServer, health: healthServer, config: config}
+}
+
+func (s *server) Run() error {
+	log.Info().Msgf("starting grpc server on port %d", s.config.Port)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+func (s *server) Serve(listener net.Listener) error {
+	return s.grpcServer.Serve(listener)
+}
+
+// Shutdown reports not serving to health checks and waits for in-flight
+// calls, calls still running when ctx is done are cancelled.
+func (s *server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,255 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/metrics"
+	"github.com/slaengkast/shipping-api/internal/ratelimit"
+	shippingv1 "github.com/slaengkast/shipping-api/proto/shipping/v1"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var (
+	listener    = bufconn.Listen(1 << 20)
+	apiKey      string
+	otherApiKey string
+)
+
+func dial(t *testing.T) *grpc.ClientConn {
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), apiKeyMetadata, key)
+}
+
+func TestBookAndGet(t *testing.T) {
+	t.Parallel()
+
+	client := shippingv1.NewShippingServiceClient(dial(t))
+
+	booked, err := client.BookShipping(withKey(apiKey), &shippingv1.BookShippingRequest{Origin: "SE", Destination: "DK", Weight: 400})
+	require.Nil(t, err)
+	require.NotEqual(t, "", booked.GetId())
+
+	res, err := client.GetBooking(withKey(apiKey), &shippingv1.GetBookingRequest{Id: booked.GetId()})
+	require.Nil(t, err)
+	require.Equal(t, booked.GetId(), res.GetBooking().GetId())
+	require.Equal(t, "SE", res.GetBooking().GetOrigin())
+	require.Equal(t, "DK", res.GetBooking().GetDestination())
+	require.InDelta(t, 400, res.GetBooking().GetWeight(), 1e-9)
+	require.InDelta(t, 3000, res.GetBooking().GetPrice(), 1e-9)
+	require.Equal(t, "SEK", res.GetBooking().GetCurrency())
+
+	_, err = client.GetBooking(withKey(otherApiKey), &shippingv1.GetBookingRequest{Id: booked.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestListBookings(t *testing.T) {
+	t.Parallel()
+
+	client := shippingv1.NewShippingServiceClient(dial(t))
+	ctx := withKey(otherApiKey)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.BookShipping(ctx, &shippingv1.BookShippingRequest{Origin: "SE", Destination: "DE", Weight: float32(10 + i)})
+		require.Nil(t, err)
+	}
+
+	var (
+		ids   []string
+		token string
+		pages int
+	)
+	for {
+		res, err := client.ListBookings(ctx, &shippingv1.ListBookingsRequest{PageSize: 2, PageToken: token})
+		require.Nil(t, err)
+		for _, b := range res.GetBookings() {
+			ids = append(ids, b.GetId())
+		}
+		pages++
+		token = res.GetNextPageToken()
+		if token == "" {
+			break
+		}
+	}
+	require.GreaterOrEqual(t, len(ids), 3)
+	require.GreaterOrEqual(t, pages, 2)
+
+	_, err := client.ListBookings(ctx, &shippingv1.ListBookingsRequest{PageToken: "not-a-token"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestQuote(t *testing.T) {
+	t.Parallel()
+
+	client := shippingv1.NewShippingServiceClient(dial(t))
+
+	res, err := client.Quote(withKey(apiKey), &shippingv1.QuoteRequest{Origin: "SE", Destination: "DK", Weight: 400})
+	require.Nil(t, err)
+	require.InDelta(t, 3000, res.GetPrice(), 1e-9)
+	require.Equal(t, "SEK", res.GetCurrency())
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	client := shippingv1.NewShippingServiceClient(dial(t))
+
+	testCases := []struct {
+		name         string
+		ctx          context.Context
+		call         func(context.Context) error
+		expectedCode codes.Code
+	}{
+		{
+			name: "missing api key",
+			ctx:  context.Background(),
+			call: func(ctx context.Context) error {
+				_, err := client.GetBooking(ctx, &shippingv1.GetBookingRequest{Id: "some-id"})
+				return err
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "invalid api key",
+			ctx:  withKey("invalid"),
+			call: func(ctx context.Context) error {
+				_, err := client.GetBooking(ctx, &shippingv1.GetBookingRequest{Id: "some-id"})
+				return err
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "unknown booking",
+			ctx:  withKey(apiKey),
+			call: func(ctx context.Context) error {
+				_, err := client.GetBooking(ctx, &shippingv1.GetBookingRequest{Id: "some-id"})
+				return err
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "unknown location",
+			ctx:  withKey(apiKey),
+			call: func(ctx context.Context) error {
+				_, err := client.Quote(ctx, &shippingv1.QuoteRequest{Origin: "XX", Destination: "DK", Weight: 10})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid weight",
+			ctx:  withKey(apiKey),
+			call: func(ctx context.Context) error {
+				_, err := client.BookShipping(ctx, &shippingv1.BookShippingRequest{Origin: "SE", Destination: "DK", Weight: -1})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expectedCode, status.Code(tc.call(tc.ctx)))
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	res, err := healthpb.NewHealthClient(dial(t)).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: shippingv1.ShippingService_ServiceDesc.ServiceName,
+	})
+	require.Nil(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus())
+}
+
+func startGrpc() error {
+	rateStore := billing.NewInMemoryRateStore(map[string]float32{
+		"domestic":      1.0,
+		"eu":            1.5,
+		"international": 2.5,
+	})
+	priceStore := billing.NewInMemoryPriceStore(map[string]float32{
+		"small":  100,
+		"medium": 300,
+		"large":  500,
+		"huge":   2000,
+	})
+	locationStore := billing.NewInMemoryLocationStore()
+	for _, code := range []string{"SE", "DK", "DE"} {
+		location, err := billing.NewLocation(code, true)
+		if err != nil {
+			return err
+		}
+		if err := locationStore.AddLocation(context.Background(), location); err != nil {
+			return err
+		}
+	}
+
+	m := metrics.New()
+	carrierService, err := carrier.NewService(carrier.Lanes{"*-*": {"fake"}}, carrier.NewFake("fake", carrier.FakeConfig{}))
+	if err != nil {
+		return err
+	}
+	billingService := billing.NewService(rateStore, priceStore, locationStore, carrierService, calendar.NewCalendars(calendar.New(calendar.Config{}), nil), billing.Config{CarrierTimeout: time.Second, ServiceLevels: billing.DefaultServiceLevels()}, m)
+	bookingService := booking.NewService(booking.NewInMemoryStore(), billingService, carrierService, m)
+
+	authService := auth.NewService(auth.NewInMemoryKeyStore())
+	if _, apiKey, err = authService.CreateKey(context.Background(), "test-client"); err != nil {
+		return err
+	}
+	if _, otherApiKey, err = authService.CreateKey(context.Background(), "other-client"); err != nil {
+		return err
+	}
+
+	limit, err := ratelimit.NewLimit(1000, 1000)
+	if err != nil {
+		return err
+	}
+	limiter := ratelimit.NewLimiter(ratelimit.NewInMemoryStore(), limit, nil)
+
+	s := New(&bookingService, billingService, authService, (*auth.TokenVerifier)(nil), limiter, m, Config{})
+	go func() {
+		if err := s.Serve(listener); err != nil {
+			panic(err.Error())
+		}
+	}()
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	if err := startGrpc(); err != nil {
+		fmt.Print(err.Error())
+	}
+	os.Exit(m.Run())
+}
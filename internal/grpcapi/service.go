@@ -0,0 +1,95 @@
+package grpcapi
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	shippingv1 "github.com/slaengkast/shipping-api/proto/shipping/v1"
+)
+
+type bookingView interface {
+	Id() string
+	Origin() string
+	Destination() string
+	Weight() float32
+	Price() float32
+}
+
+type shippingService struct {
+	shippingv1.UnimplementedShippingServiceServer
+	bookingService *booking.Service
+	billingService billing.Service
+}
+
+func (s *shippingService) BookShipping(ctx context.Context, req *shippingv1.BookShippingRequest) (*shippingv1.BookShippingResponse, error) {
+	id, err := s.bookingService.BookShipping(ctx, clientIdFromContext(ctx), req.GetOrigin(), req.GetDestination(), req.GetWeight(), booking.Selection{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &shippingv1.BookShippingResponse{Id: id}, nil
+}
+
+func (s *shippingService) GetBooking(ctx context.Context, req *shippingv1.GetBookingRequest) (*shippingv1.GetBookingResponse, error) {
+	b, err := s.bookingService.ViewBooking(ctx, clientIdFromContext(ctx), req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &shippingv1.GetBookingResponse{Booking: toBooking(b)}, nil
+}
+
+// ListBookings pages through the bookings of the client, page tokens are the
+// offset of the next page.
+func (s *shippingService) ListBookings(ctx context.Context, req *shippingv1.ListBookingsRequest) (*shippingv1.ListBookingsResponse, error) {
+	offset := 0
+	if token := req.GetPageToken(); token != "" {
+		var err error
+		offset, err = strconv.Atoi(token)
+		if err != nil || offset < 0 {
+			return nil, errors.Validation("invalid page token", errors.FieldError{
+				Field:   "page_token",
+				Code:    errors.FieldInvalid,
+				Message: "page_token must be the next_page_token of a previous response",
+			})
+		}
+	}
+
+	bookings, next, err := s.bookingService.ListBookings(ctx, clientIdFromContext(ctx), offset, int(req.GetPageSize()))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &shippingv1.ListBookingsResponse{Bookings: make([]*shippingv1.Booking, 0, len(bookings))}
+	for _, b := range bookings {
+		res.Bookings = append(res.Bookings, toBooking(b))
+	}
+	if next >= 0 {
+		res.NextPageToken = strconv.Itoa(next)
+	}
+	return res, nil
+}
+
+func (s *shippingService) Quote(ctx context.Context, req *shippingv1.QuoteRequest) (*shippingv1.QuoteResponse, error) {
+	price, err := s.billingService.CalculateShippingCost(ctx, req.GetOrigin(), req.GetDestination(), req.GetWeight(), billing.ServiceLevelStandard)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shippingv1.QuoteResponse{Price: price, Currency: carrier.Currency}, nil
+}
+
+func toBooking(b bookingView) *shippingv1.Booking {
+	return &shippingv1.Booking{
+		Id:          b.Id(),
+		Origin:      b.Origin(),
+		Destination: b.Destination(),
+		Weight:      b.Weight(),
+		Price:       b.Price(),
+		Currency:    carrier.Currency,
+	}
+}
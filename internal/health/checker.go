@@ -0,0 +1,124 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusOk       = "ok"
+	StatusFailed   = "failed"
+)
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      func(context.Context) error
+}
+
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+func (r Report) IsReady() bool {
+	return r.Status == StatusReady
+}
+
+type Checker struct {
+	checks       []check
+	mtx          *sync.RWMutex
+	shuttingDown *atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{mtx: &sync.RWMutex{}, shuttingDown: &atomic.Bool{}}
+}
+
+func (c *Checker) Register(name string, timeout time.Duration, fn func(context.Context) error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// SetShuttingDown makes every following readiness report not ready, so load
+// balancers stop routing to the instance while it drains.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs all registered checks concurrently, each bounded by its own
+// timeout.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mtx.RLock()
+	checks := make([]check, len(c.checks))
+	copy(checks, c.checks)
+	c.mtx.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: results}
+	if c.shuttingDown.Load() {
+		report.Status = StatusNotReady
+		report.Checks = append(report.Checks, CheckResult{Name: "shutdown", Status: StatusFailed, Error: "shutting down", Duration: "0s"})
+	}
+	for _, r := range results {
+		if r.Status != StatusOk {
+			report.Status = StatusNotReady
+		}
+	}
+
+	return report
+}
+
+func run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	result := CheckResult{Name: c.name, Status: StatusOk, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
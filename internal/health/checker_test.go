@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady(t *testing.T) {
+	testCases := []struct {
+		name           string
+		check          func(context.Context) error
+		shuttingDown   bool
+		expectedStatus string
+		expectedCheck  string
+	}{
+		{
+			name:           "passing check",
+			check:          func(context.Context) error { return nil },
+			expectedStatus: StatusReady,
+			expectedCheck:  StatusOk,
+		},
+		{
+			name:           "failing check",
+			check:          func(context.Context) error { return errors.New("check error") },
+			expectedStatus: StatusNotReady,
+			expectedCheck:  StatusFailed,
+		},
+		{
+			name: "slow check",
+			check: func(context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+			expectedStatus: StatusNotReady,
+			expectedCheck:  StatusFailed,
+		},
+		{
+			name:           "panicking check",
+			check:          func(context.Context) error { panic("check panic") },
+			expectedStatus: StatusNotReady,
+			expectedCheck:  StatusFailed,
+		},
+		{
+			name:           "shutting down",
+			check:          func(context.Context) error { return nil },
+			shuttingDown:   true,
+			expectedStatus: StatusNotReady,
+			expectedCheck:  StatusOk,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			checker := NewChecker()
+			checker.Register("passing", 50*time.Millisecond, func(context.Context) error { return nil })
+			checker.Register("test", 50*time.Millisecond, tc.check)
+			if tc.shuttingDown {
+				checker.SetShuttingDown()
+			}
+
+			report := checker.Ready(context.Background())
+
+			require.Equal(t, tc.expectedStatus, report.Status)
+			require.Equal(t, StatusOk, report.Checks[0].Status)
+			require.Equal(t, "test", report.Checks[1].Name)
+			require.Equal(t, tc.expectedCheck, report.Checks[1].Status)
+		})
+	}
+}
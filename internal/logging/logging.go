@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const RequestIdKey = "requestId"
+
+// WithLogger returns a context carrying the request scoped logger l.
+func WithLogger(ctx context.Context, l zerolog.Logger) context.Context {
+	return l.WithContext(ctx)
+}
+
+// FromContext returns the request scoped logger of ctx tagged with component,
+// falling back to the global logger outside of requests.
+func FromContext(ctx context.Context, component string) zerolog.Logger {
+	l := zerolog.Ctx(ctx)
+	if l.GetLevel() == zerolog.Disabled {
+		l = &log.Logger
+	}
+	return l.With().Str("component", component).Logger()
+}
+
+func StoreOperation(ctx context.Context, store, operation string, duration time.Duration, err error) {
+	// the operation is already part of the event
+	fields := errors.GetContext(err).Fields()
+	delete(fields, "operation")
+
+	logger := FromContext(ctx, "store")
+	logger.Debug().
+		Str("store", store).
+		Str("operation", operation).
+		Dur("duration", duration).
+		Err(err).
+		Fields(fields).
+		Msg("store operation")
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), zerolog.New(&buf).With().Str(RequestIdKey, "test-request-id").Logger())
+
+	logger := FromContext(ctx, "test")
+	logger.Info().Msg("message")
+
+	var entry map[string]interface{}
+	require.Nilf(t, json.Unmarshal(buf.Bytes(), &entry), "unexpected error")
+	require.Equal(t, "test-request-id", entry[RequestIdKey])
+	require.Equal(t, "test", entry["component"])
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	logger := FromContext(context.Background(), "test")
+	require.NotEqual(t, zerolog.Disabled, logger.GetLevel())
+}
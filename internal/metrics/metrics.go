@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shipping_api"
+
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	bookings        *prometheus.CounterVec
+	prices          *prometheus.HistogramVec
+	lookupErrors    *prometheus.CounterVec
+	storeDuration   *prometheus.HistogramVec
+	rpcs            *prometheus.CounterVec
+	rpcDuration     *prometheus.HistogramVec
+	webhooks        *prometheus.CounterVec
+	events          *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of handled HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		bookings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "booking",
+			Name:      "bookings_created_total",
+			Help:      "Number of created bookings per lane.",
+		}, []string{"origin", "destination"}),
+		prices: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "billing",
+			Name:      "quoted_price_sek",
+			Help:      "Distribution of quoted shipping prices.",
+			Buckets:   []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000},
+		}, []string{"region"}),
+		lookupErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "billing",
+			Name:      "lookup_errors_total",
+			Help:      "Number of failed billing lookups by lookup and error type.",
+		}, []string{"lookup", "type"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "store",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of store operations.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}, []string{"store", "operation", "outcome"}),
+		rpcs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Number of handled gRPC calls.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of handled gRPC calls.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		webhooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "attempts_total",
+			Help:      "Number of webhook delivery attempts by event and resulting delivery status.",
+		}, []string{"event", "status"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "events",
+			Name:      "relayed_total",
+			Help:      "Number of outbox events relayed to the sinks by event and outcome.",
+		}, []string{"event", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.bookings,
+		m.prices,
+		m.lookupErrors,
+		m.storeDuration,
+		m.rpcs,
+		m.rpcDuration,
+		m.webhooks,
+		m.events,
+	)
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) RequestHandled(method, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, statusLabel).Inc()
+	m.requestDuration.WithLabelValues(method, route, statusLabel).Observe(duration.Seconds())
+}
+
+func (m *Metrics) BookingCreated(origin, destination string) {
+	m.bookings.WithLabelValues(origin, destination).Inc()
+}
+
+func (m *Metrics) PriceQuoted(region string, price float32) {
+	m.prices.WithLabelValues(region).Observe(float64(price))
+}
+
+func (m *Metrics) LookupFailed(lookup string, err error) {
+	m.lookupErrors.WithLabelValues(lookup, errors.GetType(err).String()).Inc()
+}
+
+func (m *Metrics) StoreOperation(store, operation string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = errors.GetType(err).String()
+	}
+	m.storeDuration.WithLabelValues(store, operation, outcome).Observe(duration.Seconds())
+}
+
+func (m *Metrics) RPCHandled(method, code string, duration time.Duration) {
+	m.rpcs.WithLabelValues(method, code).Inc()
+	m.rpcDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+func (m *Metrics) WebhookAttempted(event, status string) {
+	m.webhooks.WithLabelValues(event, status).Inc()
+}
+
+func (m *Metrics) EventRelayed(event, outcome string) {
+	m.events.WithLabelValues(event, outcome).Inc()
+}
@@ -0,0 +1,283 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	Version         = "3.0.3"
+	jsonContentType = "application/json"
+	problemType     = "application/problem+json"
+)
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]*PathItem{},
+	}
+}
+
+// JSONBody returns a required request body described by the type of v. The
+// schema does not allow unknown properties.
+func JSONBody(v interface{}) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{jsonContentType: {Schema: strict(SchemaOf(v))}},
+	}
+}
+
+// JSONBodyOneOf returns a required request body that is described by the
+// type of one of vs, the types must have different JSON types such as an
+// object and an array.
+func JSONBodyOneOf(vs ...interface{}) *RequestBody {
+	schema := &Schema{OneOf: make([]*Schema, 0, len(vs))}
+	for _, v := range vs {
+		schema.OneOf = append(schema.OneOf, strict(SchemaOf(v)))
+	}
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{jsonContentType: {Schema: schema}},
+	}
+}
+
+// JSONResponse returns a response whose body is described by the type of v.
+func JSONResponse(description string, v interface{}) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{jsonContentType: {Schema: SchemaOf(v)}},
+	}
+}
+
+// QueryParameters describes the fields of v as query parameters named by
+// their form tags, like JSONBody required fields and bounds come from the
+// binding tags.
+func QueryParameters(v interface{}) []Parameter {
+	t := reflect.TypeOf(v)
+	parameters := make([]Parameter, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		schema := schemaOf(f.Type)
+		schema.Description = f.Tag.Get("description")
+		required := applyBinding(schema, f.Tag.Get("binding"))
+		parameters = append(parameters, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+	return parameters
+}
+
+func EmptyResponse(description string) Response {
+	return Response{Description: description}
+}
+
+// Schema returns the schema of the request body, nil when there is none.
+func (o Operation) Schema() *Schema {
+	if o.RequestBody == nil {
+		return nil
+	}
+	return o.RequestBody.Content[jsonContentType].Schema
+}
+
+// Add documents op under the gin route method path, path parameters written
+// as :name become {name} and are added to the operation.
+func (d *Document) Add(method, path string, op Operation) {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			name := s[1:]
+			segments[i] = "{" + name + "}"
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	path = strings.Join(segments, "/")
+
+	if op.Responses == nil {
+		op.Responses = map[string]Response{}
+	}
+	for status, response := range d.defaultResponses(op) {
+		if _, ok := op.Responses[status]; !ok {
+			op.Responses[status] = response
+		}
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	switch method {
+	case http.MethodGet:
+		item.Get = &op
+	case http.MethodPost:
+		item.Post = &op
+	case http.MethodPut:
+		item.Put = &op
+	case http.MethodPatch:
+		item.Patch = &op
+	case http.MethodDelete:
+		item.Delete = &op
+	}
+}
+
+// Operation returns the operation documented for the gin route method path.
+func (d *Document) Operation(method, path string) (Operation, bool) {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+
+	item, ok := d.Paths[strings.Join(segments, "/")]
+	if !ok {
+		return Operation{}, false
+	}
+
+	var op *Operation
+	switch method {
+	case http.MethodGet:
+		op = item.Get
+	case http.MethodPost:
+		op = item.Post
+	case http.MethodPut:
+		op = item.Put
+	case http.MethodPatch:
+		op = item.Patch
+	case http.MethodDelete:
+		op = item.Delete
+	}
+	if op == nil {
+		return Operation{}, false
+	}
+	return *op, true
+}
+
+// SecureWith adds a security scheme that operations can require.
+func (d *Document) SecureWith(name string, scheme SecurityScheme) {
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = map[string]SecurityScheme{}
+	}
+	d.Components.SecuritySchemes[name] = scheme
+}
+
+// DefineSchema adds a named schema to the components, operations refer to it
+// with Ref.
+func (d *Document) DefineSchema(name string, schema *Schema) {
+	if d.Components.Schemas == nil {
+		d.Components.Schemas = map[string]*Schema{}
+	}
+	d.Components.Schemas[name] = schema
+}
+
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// ProblemResponse returns an error response with a problem+json body, the
+// Problem schema has to be defined on the document.
+func ProblemResponse(description string) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{problemType: {Schema: Ref("Problem")}},
+	}
+}
+
+func (d *Document) defaultResponses(op Operation) map[string]Response {
+	if _, ok := d.Components.Schemas["Problem"]; !ok {
+		return nil
+	}
+
+	responses := map[string]Response{
+		"500": ProblemResponse("Internal error"),
+	}
+	if op.RequestBody != nil || hasQuery(op.Parameters) {
+		responses["400"] = ProblemResponse("Invalid request")
+	}
+	if len(op.Security) > 0 {
+		responses["401"] = ProblemResponse("Missing or invalid credentials")
+		responses["403"] = ProblemResponse("Insufficient role")
+		responses["429"] = ProblemResponse("Rate limit exceeded")
+	}
+	return responses
+}
+
+func hasQuery(parameters []Parameter) bool {
+	for _, p := range parameters {
+		if p.In == "query" {
+			return true
+		}
+	}
+	return false
+}
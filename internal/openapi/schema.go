@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf describes the JSON encoding of v. Property names come from the
+// json tags and required fields and bounds from the binding tags, so the
+// schema follows the request and response types of the handlers.
+func SchemaOf(v interface{}) *Schema {
+	return schemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem())
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, omitEmpty := jsonName(f)
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == f.Name && f.Type.Kind() == reflect.Struct {
+			embedded := structSchema(f.Type)
+			for n, p := range embedded.Properties {
+				s.Properties[n] = p
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		property := schemaOf(f.Type)
+		property.Description = f.Tag.Get("description")
+		if applyBinding(property, f.Tag.Get("binding")) && !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = property
+	}
+	return s
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	parts := strings.Split(f.Tag.Get("json"), ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// applyBinding adds the bounds of the validator tag to s and reports whether
+// the field is required. Rules after dive apply to the elements and are left
+// to the schema of the element type.
+func applyBinding(s *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "len":
+			setBound(s, param, true, false)
+			setBound(s, param, false, false)
+		case "oneof":
+			s.Enum = strings.Fields(param)
+		case "gt", "gte", "min":
+			setBound(s, param, true, name == "gt")
+		case "lt", "lte", "max":
+			setBound(s, param, false, name == "lt")
+		}
+	}
+	return required
+}
+
+func setBound(s *Schema, param string, lower, exclusive bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	if s.Type == "array" {
+		count := int(value)
+		if lower {
+			s.MinItems = &count
+		} else {
+			s.MaxItems = &count
+		}
+		return
+	}
+
+	if s.Type == "string" {
+		length := int(value)
+		if exclusive && lower {
+			length++
+		} else if exclusive {
+			length--
+		}
+		if lower {
+			s.MinLength = &length
+		} else {
+			s.MaxLength = &length
+		}
+		return
+	}
+
+	if lower {
+		s.Minimum = &value
+		s.ExclusiveMinimum = exclusive
+	} else {
+		s.Maximum = &value
+		s.ExclusiveMaximum = exclusive
+	}
+}
+
+func strict(s *Schema) *Schema {
+	if s.Type == "object" && s.AdditionalProperties == nil {
+		s.AdditionalProperties = false
+	}
+	for _, p := range s.Properties {
+		strict(p)
+	}
+	if s.Items != nil {
+		strict(s.Items)
+	}
+	for _, alternative := range s.OneOf {
+		strict(alternative)
+	}
+	return s
+}
@@ -0,0 +1,265 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	Street string `json:"street" binding:"required"`
+	Zip    string `json:"zip,omitempty" binding:"min=5,max=5"`
+}
+
+type testRequest struct {
+	Origin   string             `json:"origin" binding:"required"`
+	Weight   float32            `json:"weight" binding:"required,gt=0,lte=1000"`
+	Count    int                `json:"count"`
+	Service  string             `json:"service" binding:"oneof=standard express"`
+	Address  *address           `json:"address"`
+	Tags     []string           `json:"tags"`
+	Extra    map[string]float32 `json:"extra"`
+	Pickup   time.Time          `json:"pickup"`
+	Internal string             `json:"-"`
+	private  string
+}
+
+type testQuery struct {
+	Origin string `form:"origin" json:"origin" binding:"required"`
+	Limit  int    `form:"limit" json:"limit" binding:"gte=0,lte=500" description:"Page size"`
+	Ignore string `json:"ignore"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	t.Parallel()
+
+	s := SchemaOf(testRequest{})
+	require.Equal(t, "object", s.Type)
+	require.Equal(t, []string{"origin", "weight"}, s.Required)
+	require.Len(t, s.Properties, 8)
+
+	weight := s.Properties["weight"]
+	require.Equal(t, "number", weight.Type)
+	require.Equal(t, 0.0, *weight.Minimum)
+	require.True(t, weight.ExclusiveMinimum)
+	require.Equal(t, 1000.0, *weight.Maximum)
+	require.False(t, weight.ExclusiveMaximum)
+
+	require.Equal(t, "integer", s.Properties["count"].Type)
+	require.Equal(t, []string{"standard", "express"}, s.Properties["service"].Enum)
+	require.True(t, s.Properties["address"].Nullable)
+	require.Equal(t, []string{"street"}, s.Properties["address"].Required)
+	require.Equal(t, 5, *s.Properties["address"].Properties["zip"].MinLength)
+	require.Equal(t, "string", s.Properties["tags"].Items.Type)
+	require.Equal(t, "number", s.Properties["extra"].AdditionalProperties.(*Schema).Type)
+	require.Equal(t, "date-time", s.Properties["pickup"].Format)
+}
+
+func TestQueryParameters(t *testing.T) {
+	t.Parallel()
+
+	parameters := QueryParameters(testQuery{})
+	require.Len(t, parameters, 2)
+	require.Equal(t, Parameter{Name: "origin", In: "query", Required: true, Schema: &Schema{Type: "string"}}, parameters[0])
+	require.Equal(t, "limit", parameters[1].Name)
+	require.False(t, parameters[1].Required)
+	require.Equal(t, "integer", parameters[1].Schema.Type)
+	require.Equal(t, "Page size", parameters[1].Schema.Description)
+	require.Equal(t, 500.0, *parameters[1].Schema.Maximum)
+}
+
+func TestValidate(t *testing.T) {
+	schema := JSONBody(testRequest{}).Content[jsonContentType].Schema
+
+	testCases := []struct {
+		name           string
+		body           string
+		expectedFields map[string]string
+	}{
+		{
+			name:           "valid body",
+			body:           `{"origin":"SE","weight":10,"count":2,"service":"express","address":{"street":"Main"},"tags":["a"],"extra":{"fee":1.5}}`,
+			expectedFields: map[string]string{},
+		},
+		{
+			name:           "null address",
+			body:           `{"origin":"SE","weight":10,"address":null}`,
+			expectedFields: map[string]string{},
+		},
+		{
+			name: "missing required",
+			body: `{}`,
+			expectedFields: map[string]string{
+				"origin": errors.FieldRequired,
+				"weight": errors.FieldRequired,
+			},
+		},
+		{
+			name: "wrong types",
+			body: `{"origin":1,"weight":"heavy","count":1.5,"tags":"a"}`,
+			expectedFields: map[string]string{
+				"origin": errors.FieldInvalidType,
+				"weight": errors.FieldInvalidType,
+				"count":  errors.FieldInvalidType,
+				"tags":   errors.FieldInvalidType,
+			},
+		},
+		{
+			name: "out of range",
+			body: `{"origin":"SE","weight":0,"address":{"street":"Main","zip":"123"}}`,
+			expectedFields: map[string]string{
+				"weight":      errors.FieldOutOfRange,
+				"address.zip": errors.FieldOutOfRange,
+			},
+		},
+		{
+			name: "nested errors",
+			body: `{"origin":"SE","weight":1,"address":{},"tags":[1],"extra":{"fee":"x"}}`,
+			expectedFields: map[string]string{
+				"address.street": errors.FieldRequired,
+				"tags[0]":        errors.FieldInvalidType,
+				"extra.fee":      errors.FieldInvalidType,
+			},
+		},
+		{
+			name: "unknown fields",
+			body: `{"origin":"SE","weight":1,"color":"red","address":{"street":"Main","floor":2}}`,
+			expectedFields: map[string]string{
+				"color":         errors.FieldInvalid,
+				"address.floor": errors.FieldInvalid,
+			},
+		},
+		{
+			name: "not in enum",
+			body: `{"origin":"SE","weight":1,"service":"overnight"}`,
+			expectedFields: map[string]string{
+				"service": errors.FieldInvalid,
+			},
+		},
+		{
+			name: "not an object",
+			body: `[]`,
+			expectedFields: map[string]string{
+				"": errors.FieldInvalidType,
+			},
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			decoder := json.NewDecoder(bytes.NewReader([]byte(tc.body)))
+			decoder.UseNumber()
+			var value interface{}
+			require.Nil(t, decoder.Decode(&value))
+
+			actual := map[string]string{}
+			for _, f := range schema.Validate(value) {
+				actual[f.Field] = f.Code
+			}
+			require.Equal(t, tc.expectedFields, actual)
+		})
+	}
+}
+
+func TestValidateOneOf(t *testing.T) {
+	schema := JSONBodyOneOf(address{}, []address{}).Content[jsonContentType].Schema
+
+	testCases := []struct {
+		name           string
+		body           string
+		expectedFields map[string]string
+	}{
+		{
+			name:           "valid object",
+			body:           `{"street":"Main"}`,
+			expectedFields: map[string]string{},
+		},
+		{
+			name:           "valid array",
+			body:           `[{"street":"Main"},{"street":"Side","zip":"12345"}]`,
+			expectedFields: map[string]string{},
+		},
+		{
+			name: "invalid object",
+			body: `{"zip":"1"}`,
+			expectedFields: map[string]string{
+				"street": errors.FieldRequired,
+				"zip":    errors.FieldOutOfRange,
+			},
+		},
+		{
+			name: "invalid array item",
+			body: `[{"street":"Main"},{"street":"Side","floor":2}]`,
+			expectedFields: map[string]string{
+				"[1].floor": errors.FieldInvalid,
+			},
+		},
+		{
+			name: "no alternative of the type",
+			body: `"Main"`,
+			expectedFields: map[string]string{
+				"": errors.FieldInvalidType,
+			},
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			decoder := json.NewDecoder(bytes.NewReader([]byte(tc.body)))
+			decoder.UseNumber()
+			var value interface{}
+			require.Nil(t, decoder.Decode(&value))
+
+			actual := map[string]string{}
+			for _, f := range schema.Validate(value) {
+				actual[f.Field] = f.Code
+			}
+			require.Equal(t, tc.expectedFields, actual)
+		})
+	}
+}
+
+func TestDocument(t *testing.T) {
+	t.Parallel()
+
+	doc := New("test", "1.0.0")
+	doc.DefineSchema("Problem", &Schema{Type: "object"})
+	doc.Add("GET", "/api/shipping/:id", Operation{
+		OperationId: "getBooking",
+		Security:    []map[string][]string{{"apiKey": {}}},
+		Responses:   map[string]Response{"200": EmptyResponse("ok")},
+	})
+	doc.Add("POST", "/api/shipping", Operation{OperationId: "bookShipping", RequestBody: JSONBody(testRequest{})})
+
+	op, ok := doc.Operation("GET", "/api/shipping/:id")
+	require.True(t, ok)
+	require.Equal(t, "getBooking", op.OperationId)
+	require.Equal(t, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, op.Parameters)
+	require.Contains(t, op.Responses, "200")
+	require.Contains(t, op.Responses, "401")
+	require.Contains(t, op.Responses, "500")
+	require.NotContains(t, op.Responses, "400")
+	require.Nil(t, op.Schema())
+
+	op, ok = doc.Operation("POST", "/api/shipping")
+	require.True(t, ok)
+	require.Contains(t, op.Responses, "400")
+	require.NotNil(t, op.Schema())
+	require.Equal(t, false, op.Schema().AdditionalProperties)
+
+	doc.Add("GET", "/api/shipping", Operation{OperationId: "listBookings", Parameters: QueryParameters(testQuery{})})
+	op, ok = doc.Operation("GET", "/api/shipping")
+	require.True(t, ok)
+	require.Contains(t, op.Responses, "400")
+
+	_, ok = doc.Operation("DELETE", "/api/shipping")
+	require.False(t, ok)
+	require.Contains(t, doc.Paths, "/api/shipping/{id}")
+}
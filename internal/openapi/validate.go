@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+// Validate checks a value decoded from JSON with UseNumber against s and
+// returns an error for every violation, named by the path of the field.
+func (s *Schema) Validate(value interface{}) []errors.FieldError {
+	var fieldErrors []errors.FieldError
+	s.validate("", value, &fieldErrors)
+	return fieldErrors
+}
+
+func (s *Schema) validate(path string, value interface{}, fieldErrors *[]errors.FieldError) {
+	add := func(code, format string, args ...interface{}) {
+		*fieldErrors = append(*fieldErrors, errors.FieldError{
+			Field:   path,
+			Code:    code,
+			Message: fmt.Sprintf("%s "+format, append([]interface{}{displayName(path)}, args...)...),
+		})
+	}
+
+	if len(s.OneOf) > 0 {
+		s.validateOneOf(path, value, fieldErrors)
+		return
+	}
+
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			add(errors.FieldInvalidType, "must be of type %s", s.Type)
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			add(errors.FieldInvalidType, "must be of type object")
+			return
+		}
+		s.validateObject(path, object, fieldErrors)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			add(errors.FieldInvalidType, "must be of type array")
+			return
+		}
+		if s.MinItems != nil && len(array) < *s.MinItems {
+			add(errors.FieldOutOfRange, "must contain at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(array) > *s.MaxItems {
+			add(errors.FieldOutOfRange, "must contain at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range array {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, fieldErrors)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			add(errors.FieldInvalidType, "must be of type string")
+			return
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			add(errors.FieldOutOfRange, "must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			add(errors.FieldOutOfRange, "must be at most %d characters", *s.MaxLength)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			add(errors.FieldInvalid, "must be one of %s", strings.Join(s.Enum, ", "))
+		}
+	case "number", "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			add(errors.FieldInvalidType, "must be of type %s", s.Type)
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := number.Int64(); err != nil {
+				add(errors.FieldInvalidType, "must be of type integer")
+				return
+			}
+		}
+		f, err := number.Float64()
+		if err != nil {
+			add(errors.FieldInvalidType, "must be of type %s", s.Type)
+			return
+		}
+		s.validateBounds(f, add)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			add(errors.FieldInvalidType, "must be of type boolean")
+		}
+	}
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}, fieldErrors *[]errors.FieldError) {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			field := join(path, name)
+			*fieldErrors = append(*fieldErrors, errors.FieldError{
+				Field:   field,
+				Code:    errors.FieldRequired,
+				Message: fmt.Sprintf("%s is required", field),
+			})
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := join(path, name)
+		if property, ok := s.Properties[name]; ok {
+			property.validate(field, object[name], fieldErrors)
+			continue
+		}
+
+		switch additional := s.AdditionalProperties.(type) {
+		case *Schema:
+			additional.validate(field, object[name], fieldErrors)
+		case bool:
+			if !additional {
+				*fieldErrors = append(*fieldErrors, errors.FieldError{
+					Field:   field,
+					Code:    errors.FieldInvalid,
+					Message: fmt.Sprintf("%s is not a known field", field),
+				})
+			}
+		}
+	}
+}
+
+// validateOneOf validates value against the alternative of its JSON type.
+func (s *Schema) validateOneOf(path string, value interface{}, fieldErrors *[]errors.FieldError) {
+	types := make([]string, 0, len(s.OneOf))
+	for _, alternative := range s.OneOf {
+		if matchesType(alternative.Type, value) {
+			alternative.validate(path, value, fieldErrors)
+			return
+		}
+		types = append(types, alternative.Type)
+	}
+	*fieldErrors = append(*fieldErrors, errors.FieldError{
+		Field:   path,
+		Code:    errors.FieldInvalidType,
+		Message: fmt.Sprintf("%s must be of type %s", displayName(path), strings.Join(types, " or ")),
+	})
+}
+
+func matchesType(t string, value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case string:
+		return t == "string"
+	case json.Number:
+		return t == "number" || t == "integer"
+	case bool:
+		return t == "boolean"
+	default:
+		return false
+	}
+}
+
+func (s *Schema) validateBounds(f float64, add func(code, format string, args ...interface{})) {
+	if s.Minimum != nil {
+		if s.ExclusiveMinimum && f <= *s.Minimum {
+			add(errors.FieldOutOfRange, "must be greater than %v", *s.Minimum)
+		} else if f < *s.Minimum {
+			add(errors.FieldOutOfRange, "must be at least %v", *s.Minimum)
+		}
+	}
+	if s.Maximum != nil {
+		if s.ExclusiveMaximum && f >= *s.Maximum {
+			add(errors.FieldOutOfRange, "must be less than %v", *s.Maximum)
+		} else if f > *s.Maximum {
+			add(errors.FieldOutOfRange, "must be at most %v", *s.Maximum)
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayName(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return path
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
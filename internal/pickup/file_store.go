@@ -0,0 +1,157 @@
+package pickup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type filePickup struct {
+	BookingId string    `json:"bookingId"`
+	ClientId  string    `json:"clientId"`
+	Origin    string    `json:"origin"`
+	Date      string    `json:"date"`
+	Window    string    `json:"window"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// fileStore keeps the pickups in memory and writes all of them to a JSON file
+// on every change, so that reserved slots survive a restart together with
+// the bookings. Saves are written one at a time and replace the file whole.
+type fileStore struct {
+	inMemoryStore
+	path    string
+	saveMtx *sync.Mutex
+}
+
+func NewFileStore(path string) (fileStore, error) {
+	s := fileStore{inMemoryStore: NewInMemoryStore(), path: path, saveMtx: &sync.Mutex{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, errors.WrapStore(err, "pickup", "load")
+	}
+
+	var pickups []filePickup
+	if err := json.Unmarshal(data, &pickups); err != nil {
+		return s, errors.WrapStore(err, "pickup", "load")
+	}
+	for _, f := range pickups {
+		p, err := fromFilePickup(f)
+		if err != nil {
+			return s, errors.WrapStore(err, "pickup", "load")
+		}
+		s.pickups[p.bookingId] = marshalPickup(p)
+		s.reserved[p.slot()]++
+	}
+	return s, nil
+}
+
+func (r fileStore) Reserve(ctx context.Context, p *pickup, capacity int) error {
+	if err := r.inMemoryStore.Reserve(ctx, p, capacity); err != nil {
+		return err
+	}
+	return r.save()
+}
+
+func (r fileStore) Reschedule(ctx context.Context, p *pickup, capacity int) error {
+	if err := r.inMemoryStore.Reschedule(ctx, p, capacity); err != nil {
+		return err
+	}
+	return r.save()
+}
+
+func (r fileStore) CancelPickup(ctx context.Context, bookingId string) error {
+	if err := r.inMemoryStore.CancelPickup(ctx, bookingId); err != nil {
+		return err
+	}
+	return r.save()
+}
+
+func (r fileStore) Close(_ context.Context) error {
+	return r.save()
+}
+
+func (r fileStore) save() error {
+	r.saveMtx.Lock()
+	defer r.saveMtx.Unlock()
+
+	r.mtx.RLock()
+	pickups := make([]filePickup, 0, len(r.pickups))
+	for _, m := range r.pickups {
+		pickups = append(pickups, toFilePickup(m))
+	}
+	r.mtx.RUnlock()
+
+	data, err := json.MarshalIndent(pickups, "", "  ")
+	if err != nil {
+		return errors.WrapStore(err, "pickup", "save")
+	}
+	if err := writeFile(r.path, data); err != nil {
+		return errors.WrapStore(err, "pickup", "save")
+	}
+	return nil
+}
+
+// writeFile writes the data to a temporary file next to path and renames it
+// over path once it is synced.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func toFilePickup(m pickupModel) filePickup {
+	return filePickup{
+		BookingId: m.bookingId,
+		ClientId:  m.clientId,
+		Origin:    m.origin,
+		Date:      m.date.Format(dateLayout),
+		Window:    m.window.String(),
+		CreatedAt: m.createdAt,
+		UpdatedAt: m.updatedAt,
+	}
+}
+
+// fromFilePickup goes through the constructor so that a hand edited file can
+// not load an invalid pickup.
+func fromFilePickup(f filePickup) (*pickup, error) {
+	date, err := time.Parse(dateLayout, f.Date)
+	if err != nil {
+		return nil, err
+	}
+	window, err := ParseWindow(f.Window)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewPickup(f.BookingId, f.ClientId, f.Origin, date, window, f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.updatedAt = f.UpdatedAt
+	return p, nil
+}
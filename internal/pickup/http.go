@@ -0,0 +1,173 @@
+package pickup
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type getSlotsRequest struct {
+	Origin string    `form:"origin" binding:"required"`
+	From   time.Time `form:"from" time_format:"2006-01-02" description:"Date as YYYY-MM-DD, defaults to the next pickup from now"`
+	Days   int       `form:"days" binding:"omitempty,min=1,max=31" description:"Number of business days, defaults to 5"`
+}
+
+type slotResponse struct {
+	Date      string `json:"date" binding:"required" description:"Date as YYYY-MM-DD"`
+	Window    string `json:"window" binding:"required" description:"Time of day as HH:MM-HH:MM in the time zone of the origin"`
+	Capacity  int    `json:"capacity" binding:"required"`
+	Available int    `json:"available"`
+}
+
+type getSlotsResponse struct {
+	Origin string         `json:"origin" binding:"required"`
+	Slots  []slotResponse `json:"slots" binding:"required"`
+}
+
+type pickupRequest struct {
+	Date   string `json:"date" binding:"required,datetime=2006-01-02" description:"Date as YYYY-MM-DD"`
+	Window string `json:"window" binding:"required" description:"One of the windows of the slots of the origin, as HH:MM-HH:MM"`
+}
+
+type pickupResponse struct {
+	BookingId string    `json:"bookingId" binding:"required"`
+	Origin    string    `json:"origin" binding:"required"`
+	Date      string    `json:"date" binding:"required" description:"Date as YYYY-MM-DD"`
+	Window    string    `json:"window" binding:"required"`
+	CreatedAt time.Time `json:"createdAt" binding:"required"`
+	UpdatedAt time.Time `json:"updatedAt" binding:"required"`
+}
+
+type listPickupsRequest struct {
+	Origin string    `form:"origin" binding:"required"`
+	Date   time.Time `form:"date" binding:"required" time_format:"2006-01-02" description:"Date as YYYY-MM-DD"`
+}
+
+type listedPickupResponse struct {
+	pickupResponse
+	ClientId string `json:"clientId" binding:"required"`
+}
+
+type listPickupsResponse struct {
+	Pickups []listedPickupResponse `json:"pickups" binding:"required"`
+}
+
+type handler struct {
+	pickupService Service
+}
+
+func NewHandler(pickupService Service) *handler {
+	return &handler{pickupService: pickupService}
+}
+
+func (h handler) GetSlots(c *gin.Context) {
+	var req getSlotsRequest
+	if err := problem.BindQuery(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 5
+	}
+
+	slots, err := h.pickupService.Slots(c, req.Origin, req.From, req.Days)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	res := getSlotsResponse{Origin: req.Origin, Slots: make([]slotResponse, 0, len(slots))}
+	for _, s := range slots {
+		res.Slots = append(res.Slots, slotResponse{Date: s.Date.Format(dateLayout), Window: s.Window.String(), Capacity: s.Capacity, Available: s.Available})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h handler) SchedulePickup(c *gin.Context) {
+	var req pickupRequest
+	if err := problem.Bind(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+	date, _ := time.Parse(dateLayout, req.Date)
+
+	p, err := h.pickupService.SchedulePickup(c, c.GetString(auth.ClientIdKey), c.Param("id"), date, req.Window)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path)
+	c.JSON(http.StatusCreated, toPickupResponse(p))
+}
+
+func (h handler) GetPickup(c *gin.Context) {
+	p, err := h.pickupService.GetPickup(c, c.GetString(auth.ClientIdKey), c.Param("id"))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toPickupResponse(p))
+}
+
+func (h handler) ReschedulePickup(c *gin.Context) {
+	var req pickupRequest
+	if err := problem.Bind(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+	date, _ := time.Parse(dateLayout, req.Date)
+
+	p, err := h.pickupService.ReschedulePickup(c, c.GetString(auth.ClientIdKey), c.Param("id"), date, req.Window)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toPickupResponse(p))
+}
+
+func (h handler) CancelPickup(c *gin.Context) {
+	if err := h.pickupService.CancelPickup(c, c.GetString(auth.ClientIdKey), c.Param("id")); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h handler) ListPickups(c *gin.Context) {
+	var req listPickupsRequest
+	if err := problem.BindQuery(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	pickups, err := h.pickupService.ListPickups(c, req.Origin, req.Date)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	res := listPickupsResponse{Pickups: make([]listedPickupResponse, 0, len(pickups))}
+	for _, p := range pickups {
+		res.Pickups = append(res.Pickups, listedPickupResponse{pickupResponse: toPickupResponse(p), ClientId: p.ClientId()})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func toPickupResponse(p *pickup) pickupResponse {
+	return pickupResponse{
+		BookingId: p.BookingId(),
+		Origin:    p.Origin(),
+		Date:      p.Date().Format(dateLayout),
+		Window:    p.Window().String(),
+		CreatedAt: p.CreatedAt(),
+		UpdatedAt: p.UpdatedAt(),
+	}
+}
@@ -0,0 +1,164 @@
+package pickup
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type pickupModel struct {
+	bookingId string
+	clientId  string
+	origin    string
+	date      time.Time
+	window    Window
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// inMemoryStore counts the pickups of every slot under the same lock as it
+// stores them, so concurrent reservations never exceed the capacity.
+type inMemoryStore struct {
+	pickups  map[string]pickupModel
+	reserved map[string]int
+	mtx      *sync.RWMutex
+}
+
+func NewInMemoryStore() inMemoryStore {
+	return inMemoryStore{
+		pickups:  make(map[string]pickupModel),
+		reserved: make(map[string]int),
+		mtx:      &sync.RWMutex{},
+	}
+}
+
+func (r inMemoryStore) GetPickup(_ context.Context, bookingId string) (*pickup, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	m, ok := r.pickups[bookingId]
+	if !ok {
+		return nil, errors.NotFound(errors.CodePickupNotFound, "pickup", bookingId)
+	}
+	return unmarshalPickup(m), nil
+}
+
+// Reserve adds the pickup if its slot has fewer than capacity pickups.
+func (r inMemoryStore) Reserve(_ context.Context, p *pickup, capacity int) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.pickups[p.bookingId]; ok {
+		return errors.FromCode(errors.CodePickupAlreadyScheduled, "pickup already scheduled", errors.ErrorConflict)
+	}
+	if r.reserved[p.slot()] >= capacity {
+		return errors.FromCode(errors.CodePickupSlotFull, "pickup slot is full", errors.ErrorConflict)
+	}
+	r.reserved[p.slot()]++
+	r.pickups[p.bookingId] = marshalPickup(p)
+	return nil
+}
+
+// Reschedule moves the pickup to its new slot if that has fewer than
+// capacity pickups, else the pickup keeps its slot.
+func (r inMemoryStore) Reschedule(_ context.Context, p *pickup, capacity int) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	m, ok := r.pickups[p.bookingId]
+	if !ok {
+		return errors.NotFound(errors.CodePickupNotFound, "pickup", p.bookingId)
+	}
+	previous := unmarshalPickup(m).slot()
+	if previous != p.slot() {
+		if r.reserved[p.slot()] >= capacity {
+			return errors.FromCode(errors.CodePickupSlotFull, "pickup slot is full", errors.ErrorConflict)
+		}
+		r.release(previous)
+		r.reserved[p.slot()]++
+	}
+	r.pickups[p.bookingId] = marshalPickup(p)
+	return nil
+}
+
+func (r inMemoryStore) CancelPickup(_ context.Context, bookingId string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	m, ok := r.pickups[bookingId]
+	if !ok {
+		return errors.NotFound(errors.CodePickupNotFound, "pickup", bookingId)
+	}
+	r.release(unmarshalPickup(m).slot())
+	delete(r.pickups, bookingId)
+	return nil
+}
+
+// ListPickups returns the pickups at the origin on the date by window, in
+// the order they were scheduled.
+func (r inMemoryStore) ListPickups(_ context.Context, origin string, date time.Time) ([]*pickup, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	pickups := []*pickup{}
+	for _, m := range r.pickups {
+		if m.origin == origin && m.date.Equal(date) {
+			pickups = append(pickups, unmarshalPickup(m))
+		}
+	}
+	sort.Slice(pickups, func(i, j int) bool {
+		if pickups[i].window.From != pickups[j].window.From {
+			return pickups[i].window.From < pickups[j].window.From
+		}
+		return pickups[i].createdAt.Before(pickups[j].createdAt)
+	})
+	return pickups, nil
+}
+
+// Reserved returns the number of pickups in each window at the origin on
+// the date.
+func (r inMemoryStore) Reserved(_ context.Context, origin string, date time.Time) (map[Window]int, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	reserved := map[Window]int{}
+	for _, m := range r.pickups {
+		if m.origin == origin && m.date.Equal(date) {
+			reserved[m.window]++
+		}
+	}
+	return reserved, nil
+}
+
+func (r inMemoryStore) release(slot string) {
+	if r.reserved[slot]--; r.reserved[slot] <= 0 {
+		delete(r.reserved, slot)
+	}
+}
+
+func marshalPickup(p *pickup) pickupModel {
+	return pickupModel{
+		bookingId: p.bookingId,
+		clientId:  p.clientId,
+		origin:    p.origin,
+		date:      p.date,
+		window:    p.window,
+		createdAt: p.createdAt,
+		updatedAt: p.updatedAt,
+	}
+}
+
+func unmarshalPickup(m pickupModel) *pickup {
+	return &pickup{
+		bookingId: m.bookingId,
+		clientId:  m.clientId,
+		origin:    m.origin,
+		date:      m.date,
+		window:    m.window,
+		createdAt: m.createdAt,
+		updatedAt: m.updatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package pickup
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func GetSlotsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getPickupSlots",
+		Summary:     "List the pickup slots of an origin on the next business days and their available capacity",
+		Tags:        []string{"pickups"},
+		Parameters:  openapi.QueryParameters(getSlotsRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Slots by date and window", getSlotsResponse{}),
+		},
+	}
+}
+
+func ListPickupsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "listPickups",
+		Summary:     "List the pickups of every client at an origin on a day",
+		Tags:        []string{"pickups"},
+		Parameters:  openapi.QueryParameters(listPickupsRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Pickups by window, in the order they were scheduled", listPickupsResponse{}),
+		},
+	}
+}
+
+func SchedulePickupOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "schedulePickup",
+		Summary:     "Reserve a pickup slot for a booking",
+		Tags:        []string{"pickups"},
+		RequestBody: openapi.JSONBody(pickupRequest{}),
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSONResponse("Pickup scheduled", pickupResponse{}),
+			"404": openapi.ProblemResponse("Booking not found"),
+			"409": openapi.ProblemResponse("Slot full, pickup already scheduled or booking not booked"),
+		},
+	}
+}
+
+func GetPickupOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getPickup",
+		Summary:     "Get the pickup of a booking",
+		Tags:        []string{"pickups"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Pickup", pickupResponse{}),
+			"404": openapi.ProblemResponse("Booking or pickup not found"),
+		},
+	}
+}
+
+func ReschedulePickupOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "reschedulePickup",
+		Summary:     "Move the pickup of a booking to another slot",
+		Tags:        []string{"pickups"},
+		RequestBody: openapi.JSONBody(pickupRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Pickup rescheduled", pickupResponse{}),
+			"404": openapi.ProblemResponse("Booking or pickup not found"),
+			"409": openapi.ProblemResponse("Slot full or booking not booked"),
+		},
+	}
+}
+
+func CancelPickupOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "cancelPickup",
+		Summary:     "Cancel the pickup of a booking and release its slot",
+		Tags:        []string{"pickups"},
+		Responses: map[string]openapi.Response{
+			"204": openapi.EmptyResponse("Pickup cancelled"),
+			"404": openapi.ProblemResponse("Booking or pickup not found"),
+		},
+	}
+}
@@ -0,0 +1,84 @@
+package pickup
+
+import (
+	"errors"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// pickup is the slot a booking is picked up in, a booking has at most one.
+type pickup struct {
+	bookingId string
+	clientId  string
+	origin    string
+	date      time.Time
+	window    Window
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+func NewPickup(bookingId, clientId, origin string, date time.Time, window Window, createdAt time.Time) (*pickup, error) {
+	if bookingId == "" {
+		return nil, errors.New("booking id is empty")
+	}
+	if clientId == "" {
+		return nil, errors.New("client id is empty")
+	}
+	if origin == "" {
+		return nil, errors.New("origin is empty")
+	}
+	if date.IsZero() {
+		return nil, errors.New("date is empty")
+	}
+
+	return &pickup{
+		bookingId: bookingId,
+		clientId:  clientId,
+		origin:    origin,
+		date:      date,
+		window:    window,
+		createdAt: createdAt,
+		updatedAt: createdAt,
+	}, nil
+}
+
+func (p *pickup) BookingId() string {
+	return p.bookingId
+}
+
+func (p *pickup) ClientId() string {
+	return p.clientId
+}
+
+func (p *pickup) Origin() string {
+	return p.origin
+}
+
+// Date is midnight UTC of the day of the pickup.
+func (p *pickup) Date() time.Time {
+	return p.date
+}
+
+func (p *pickup) Window() Window {
+	return p.window
+}
+
+func (p *pickup) CreatedAt() time.Time {
+	return p.createdAt
+}
+
+func (p *pickup) UpdatedAt() time.Time {
+	return p.updatedAt
+}
+
+func (p *pickup) reschedule(date time.Time, window Window, at time.Time) {
+	p.date = date
+	p.window = window
+	p.updatedAt = at
+}
+
+// slot identifies the capacity a pickup takes up.
+func (p *pickup) slot() string {
+	return p.origin + "/" + p.date.Format(dateLayout) + "/" + p.window.String()
+}
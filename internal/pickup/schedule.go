@@ -0,0 +1,108 @@
+package pickup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AnyLocation is the schedule of locations without one of their own.
+const AnyLocation = "*"
+
+// Window is the time of day a pickup is made in.
+type Window struct {
+	From time.Duration
+	To   time.Duration
+}
+
+// ParseWindow parses a window such as 09:00-12:00.
+func ParseWindow(s string) (Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q, expected <from>-<to> as 15:04-15:04", s)
+	}
+	f, err := parseTimeOfDay(from)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q, expected <from>-<to> as 15:04-15:04", s)
+	}
+	t, err := parseTimeOfDay(to)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q, expected <from>-<to> as 15:04-15:04", s)
+	}
+	if t <= f {
+		return Window{}, fmt.Errorf("invalid window %q, must end after it starts", s)
+	}
+	return Window{From: f, To: t}, nil
+}
+
+func (w Window) String() string {
+	return formatTimeOfDay(w.From) + "-" + formatTimeOfDay(w.To)
+}
+
+// Schedule is the pickup windows of a location, each taking up to Capacity
+// pickups a day.
+type Schedule struct {
+	Windows  []Window
+	Capacity int
+}
+
+// Schedules are the schedules of locations by code.
+type Schedules map[string]Schedule
+
+// ParseSchedule parses the schedule of a location such as
+// SE=09:00-12:00,13:00-17:00/20.
+func ParseSchedule(s string) (string, Schedule, error) {
+	location, rest, ok := strings.Cut(s, "=")
+	if !ok || location == "" {
+		return "", Schedule{}, fmt.Errorf("invalid pickup schedule %q, expected <location>=<window>[,<window>]/<capacity>", s)
+	}
+	windows, capacity, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", Schedule{}, fmt.Errorf("invalid pickup schedule %q, expected <location>=<window>[,<window>]/<capacity>", s)
+	}
+
+	schedule := Schedule{}
+	var err error
+	if schedule.Capacity, err = strconv.Atoi(capacity); err != nil || schedule.Capacity < 1 {
+		return "", Schedule{}, fmt.Errorf("invalid pickup capacity %q, must be a positive number", capacity)
+	}
+	for _, w := range strings.Split(windows, ",") {
+		window, err := ParseWindow(strings.TrimSpace(w))
+		if err != nil {
+			return "", Schedule{}, err
+		}
+		schedule.Windows = append(schedule.Windows, window)
+	}
+	return strings.ToUpper(location), schedule, nil
+}
+
+// schedule returns the schedule of the location, or of AnyLocation.
+func (s Schedules) schedule(location string) (Schedule, bool) {
+	if schedule, ok := s[location]; ok {
+		return schedule, true
+	}
+	schedule, ok := s[AnyLocation]
+	return schedule, ok
+}
+
+func (s Schedule) window(name string) (Window, bool) {
+	for _, w := range s.Windows {
+		if w.String() == name {
+			return w, true
+		}
+	}
+	return Window{}, false
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
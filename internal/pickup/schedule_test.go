@@ -0,0 +1,70 @@
+package pickup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	testCases := []struct {
+		name             string
+		schedule         string
+		expectedLocation string
+		expectedSchedule Schedule
+		shouldFail       bool
+	}{
+		{
+			name:             "windows",
+			schedule:         "se=09:00-12:00, 13:00-17:30/20",
+			expectedLocation: "SE",
+			expectedSchedule: Schedule{Windows: []Window{{From: 9 * time.Hour, To: 12 * time.Hour}, {From: 13 * time.Hour, To: 17*time.Hour + 30*time.Minute}}, Capacity: 20},
+		},
+		{
+			name:             "any location",
+			schedule:         "*=08:00-16:00/1",
+			expectedLocation: AnyLocation,
+			expectedSchedule: Schedule{Windows: []Window{{From: 8 * time.Hour, To: 16 * time.Hour}}, Capacity: 1},
+		},
+		{
+			name:       "no location",
+			schedule:   "09:00-12:00/20",
+			shouldFail: true,
+		},
+		{
+			name:       "no capacity",
+			schedule:   "SE=09:00-12:00",
+			shouldFail: true,
+		},
+		{
+			name:       "zero capacity",
+			schedule:   "SE=09:00-12:00/0",
+			shouldFail: true,
+		},
+		{
+			name:       "invalid window",
+			schedule:   "SE=9-12/20",
+			shouldFail: true,
+		},
+		{
+			name:       "window ending before it starts",
+			schedule:   "SE=12:00-09:00/20",
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			location, schedule, err := ParseSchedule(tc.schedule)
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedLocation, location)
+			require.Equal(t, tc.expectedSchedule, schedule)
+		})
+	}
+}
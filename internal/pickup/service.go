@@ -0,0 +1,258 @@
+package pickup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+)
+
+type store interface {
+	GetPickup(ctx context.Context, bookingId string) (*pickup, error)
+	Reserve(ctx context.Context, p *pickup, capacity int) error
+	Reschedule(ctx context.Context, p *pickup, capacity int) error
+	CancelPickup(ctx context.Context, bookingId string) error
+	ListPickups(ctx context.Context, origin string, date time.Time) ([]*pickup, error)
+	Reserved(ctx context.Context, origin string, date time.Time) (map[Window]int, error)
+}
+
+type calendars interface {
+	Get(location string) (calendar.Calendar, bool)
+}
+
+// Slot is a pickup window on a day at a location and how many more pickups
+// it takes.
+type Slot struct {
+	Date      time.Time
+	Window    Window
+	Capacity  int
+	Available int
+}
+
+const MaxSlotDays = 31
+
+type Service struct {
+	store          store
+	bookingService *booking.Service
+	calendars      calendars
+	schedules      Schedules
+	now            func() time.Time
+}
+
+func NewService(store store, bookingService *booking.Service, calendars calendars, schedules Schedules) Service {
+	return Service{
+		store:          store,
+		bookingService: bookingService,
+		calendars:      calendars,
+		schedules:      schedules,
+		now:            time.Now,
+	}
+}
+
+// Slots returns the slots at the origin on the given number of business days
+// from the date on, starting no earlier than the next pickup from now. Windows
+// that have ended today at the origin are left out, a day without windows
+// left does not count.
+func (s Service) Slots(ctx context.Context, origin string, from time.Time, days int) ([]Slot, error) {
+	if origin == "" {
+		return nil, errors.Validation("empty origin", errors.FieldError{Field: "origin", Code: errors.FieldRequired, Message: "origin is required"})
+	}
+	if days < 1 || days > MaxSlotDays {
+		return nil, errors.Validation("days out of range", errors.FieldError{Field: "days", Code: errors.FieldOutOfRange, Message: fmt.Sprintf("days must be between 1 and %d", MaxSlotDays)})
+	}
+	schedule, ok := s.schedules.schedule(origin)
+	if !ok {
+		return []Slot{}, nil
+	}
+
+	c, _ := s.calendars.Get(origin)
+	now := s.now()
+	date := c.NextPickup(now)
+	if from = toDate(from); from.After(date) {
+		date = from
+	}
+	slots := make([]Slot, 0, days*len(schedule.Windows))
+	for ; days > 0; date = date.AddDate(0, 0, 1) {
+		if !c.IsBusinessDay(date) {
+			continue
+		}
+		reserved, err := s.store.Reserved(ctx, origin, date)
+		if err != nil {
+			return nil, err
+		}
+		open := false
+		for _, w := range schedule.Windows {
+			if ended(c, date, w, now) {
+				continue
+			}
+			open = true
+			available := schedule.Capacity - reserved[w]
+			if available < 0 {
+				available = 0
+			}
+			slots = append(slots, Slot{Date: date, Window: w, Capacity: schedule.Capacity, Available: available})
+		}
+		if open {
+			days--
+		}
+	}
+	return slots, nil
+}
+
+// SchedulePickup reserves a slot for a booked booking of the client, a
+// booking has at most one pickup.
+func (s Service) SchedulePickup(ctx context.Context, clientId, bookingId string, date time.Time, window string) (*pickup, error) {
+	logger := logging.FromContext(ctx, "pickup")
+	logger.Info().Str("clientId", clientId).Str("bookingId", bookingId).Time("date", date).Str("window", window).Msg("scheduling pickup")
+
+	b, err := s.pickable(ctx, clientId, bookingId)
+	if err != nil {
+		return nil, err
+	}
+	w, schedule, err := s.slot(b.Origin(), date, window)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := NewPickup(bookingId, b.ClientId(), b.Origin(), toDate(date), w, time.Now().UTC())
+	if err != nil {
+		return nil, errors.Validation(err.Error())
+	}
+	if err := s.store.Reserve(ctx, p, schedule.Capacity); err != nil {
+		return nil, errors.WithEntity(err, "pickup", bookingId)
+	}
+	// a booking cancelled since it was checked may have had its pickups
+	// released before this one was reserved, it is released here instead
+	if _, err := s.pickable(ctx, clientId, bookingId); err != nil {
+		if err := s.store.CancelPickup(ctx, bookingId); err != nil && errors.GetType(err) != errors.ErrorNotFound {
+			logger.Error().Err(err).Str("bookingId", bookingId).Msg("failed to release the pickup of a booking that is no longer booked")
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// ReschedulePickup moves the pickup of a booking of the client to another
+// slot, it keeps its slot if the new one is full.
+func (s Service) ReschedulePickup(ctx context.Context, clientId, bookingId string, date time.Time, window string) (*pickup, error) {
+	logger := logging.FromContext(ctx, "pickup")
+	logger.Info().Str("clientId", clientId).Str("bookingId", bookingId).Time("date", date).Str("window", window).Msg("rescheduling pickup")
+
+	b, err := s.pickable(ctx, clientId, bookingId)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.store.GetPickup(ctx, bookingId)
+	if err != nil {
+		return nil, err
+	}
+	w, schedule, err := s.slot(b.Origin(), date, window)
+	if err != nil {
+		return nil, err
+	}
+
+	p.reschedule(toDate(date), w, time.Now().UTC())
+	if err := s.store.Reschedule(ctx, p, schedule.Capacity); err != nil {
+		return nil, errors.WithEntity(err, "pickup", bookingId)
+	}
+	return p, nil
+}
+
+func (s Service) GetPickup(ctx context.Context, clientId, bookingId string) (*pickup, error) {
+	if _, err := s.bookingService.GetBooking(ctx, clientId, bookingId); err != nil {
+		return nil, err
+	}
+	return s.store.GetPickup(ctx, bookingId)
+}
+
+func (s Service) CancelPickup(ctx context.Context, clientId, bookingId string) error {
+	logger := logging.FromContext(ctx, "pickup")
+	logger.Info().Str("clientId", clientId).Str("bookingId", bookingId).Msg("cancelling pickup")
+
+	if _, err := s.bookingService.GetBooking(ctx, clientId, bookingId); err != nil {
+		return err
+	}
+	return s.store.CancelPickup(ctx, bookingId)
+}
+
+// ListPickups returns the pickups of every client at the origin on the date.
+func (s Service) ListPickups(ctx context.Context, origin string, date time.Time) ([]*pickup, error) {
+	if origin == "" {
+		return nil, errors.Validation("empty origin", errors.FieldError{Field: "origin", Code: errors.FieldRequired, Message: "origin is required"})
+	}
+	return s.store.ListPickups(ctx, origin, toDate(date))
+}
+
+// Publish releases the slot of cancelled bookings.
+func (s Service) Publish(ctx context.Context, e booking.Event) error {
+	if e.Type != booking.EventBookingCancelled {
+		return nil
+	}
+	err := s.store.CancelPickup(ctx, e.BookingId)
+	if err != nil && errors.GetType(err) == errors.ErrorNotFound {
+		return nil
+	}
+	return err
+}
+
+type bookingGetter interface {
+	ClientId() string
+	Origin() string
+	Status() booking.Status
+}
+
+func (s Service) pickable(ctx context.Context, clientId, bookingId string) (bookingGetter, error) {
+	b, err := s.bookingService.GetBooking(ctx, clientId, bookingId)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status() != booking.StatusBooked {
+		return nil, errors.WithEntity(errors.FromCode(errors.CodeBookingNotPickable, fmt.Sprintf("booking is %s", b.Status()), errors.ErrorConflict), "booking", bookingId)
+	}
+	return b, nil
+}
+
+// slot returns the window of the schedule of the origin if the date is a
+// business day at the origin no earlier than the next pickup from now, and
+// the window has not ended yet.
+func (s Service) slot(origin string, date time.Time, window string) (Window, Schedule, error) {
+	schedule, ok := s.schedules.schedule(origin)
+	if !ok {
+		return Window{}, Schedule{}, errors.WithField(errors.FromCode(errors.CodePickupSlotUnavailable, fmt.Sprintf("no pickups from %s", origin), errors.ErrorInput), "origin")
+	}
+	w, ok := schedule.window(window)
+	if !ok {
+		return Window{}, Schedule{}, errors.WithField(errors.FromCode(errors.CodePickupSlotUnavailable, fmt.Sprintf("no pickup window %s at %s", window, origin), errors.ErrorInput), "window")
+	}
+
+	c, _ := s.calendars.Get(origin)
+	date = toDate(date)
+	now := s.now()
+	if date.Before(c.NextPickup(now)) || !c.IsBusinessDay(date) {
+		return Window{}, Schedule{}, errors.WithField(errors.FromCode(errors.CodePickupSlotUnavailable, fmt.Sprintf("no pickups at %s on %s", origin, date.Format(dateLayout)), errors.ErrorInput), "date")
+	}
+	if ended(c, date, w, now) {
+		return Window{}, Schedule{}, errors.WithField(errors.FromCode(errors.CodePickupSlotUnavailable, fmt.Sprintf("pickup window %s at %s has ended", window, origin), errors.ErrorInput), "window")
+	}
+	return w, schedule, nil
+}
+
+// ended tells whether the window on the date is over by now, in the time zone
+// of the calendar of the origin.
+func ended(c calendar.Calendar, date time.Time, w Window, now time.Time) bool {
+	local := now.In(c.TimeZone())
+	if !toDate(local).Equal(date) {
+		return false
+	}
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	return sinceMidnight >= w.To
+}
+
+// toDate returns midnight UTC of the day of t in its own zone.
+func toDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
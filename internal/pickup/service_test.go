@@ -0,0 +1,446 @@
+package pickup
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/booking/bookingtest"
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+)
+
+// 2030-01-07 is a monday
+var monday = time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+type testBundle struct {
+	bookingtest.Bundle
+	service Service
+}
+
+func newTestBundle(t *testing.T, capacity int) testBundle {
+	bundle := bookingtest.New(t)
+	calendars := calendar.NewCalendars(calendar.New(calendar.Config{CutOff: 15 * time.Hour}), map[string]calendar.Calendar{
+		"SE": calendar.New(calendar.Config{CutOff: 15 * time.Hour, Holidays: []calendar.Holiday{{Date: monday.AddDate(0, 0, 1)}}}),
+	})
+	schedules := Schedules{"SE": {Windows: []Window{{From: 9 * time.Hour, To: 12 * time.Hour}, {From: 13 * time.Hour, To: 17 * time.Hour}}, Capacity: capacity}}
+	return testBundle{
+		Bundle:  bundle,
+		service: NewService(NewInMemoryStore(), bundle.BookingService, calendars, schedules),
+	}
+}
+
+// relay publishes the pending booking events to the service.
+func (b testBundle) relay(t *testing.T) {
+	b.Relay(t, b.service)
+}
+
+func TestSchedulePickup(t *testing.T) {
+	testCases := []struct {
+		name          string
+		clientId      string
+		origin        string
+		cancelled     bool
+		scheduled     bool
+		full          bool
+		date          time.Time
+		window        string
+		expectedCode  apierrors.Code
+		expectedField string
+	}{
+		{
+			name:     "good pickup",
+			clientId: "test-client",
+			origin:   "SE",
+			date:     monday,
+			window:   "09:00-12:00",
+		},
+		{
+			name:         "other client",
+			clientId:     "other-client",
+			origin:       "SE",
+			date:         monday,
+			window:       "09:00-12:00",
+			expectedCode: apierrors.CodeBookingNotFound,
+		},
+		{
+			name:         "cancelled booking",
+			clientId:     "test-client",
+			origin:       "SE",
+			cancelled:    true,
+			date:         monday,
+			window:       "09:00-12:00",
+			expectedCode: apierrors.CodeBookingNotPickable,
+		},
+		{
+			name:         "already scheduled",
+			clientId:     "test-client",
+			origin:       "SE",
+			scheduled:    true,
+			date:         monday,
+			window:       "13:00-17:00",
+			expectedCode: apierrors.CodePickupAlreadyScheduled,
+		},
+		{
+			name:         "full slot",
+			clientId:     "test-client",
+			origin:       "SE",
+			full:         true,
+			date:         monday,
+			window:       "09:00-12:00",
+			expectedCode: apierrors.CodePickupSlotFull,
+		},
+		{
+			name:          "unknown window",
+			clientId:      "test-client",
+			origin:        "SE",
+			date:          monday,
+			window:        "08:00-12:00",
+			expectedCode:  apierrors.CodePickupSlotUnavailable,
+			expectedField: "window",
+		},
+		{
+			name:          "weekend",
+			clientId:      "test-client",
+			origin:        "SE",
+			date:          monday.AddDate(0, 0, -1),
+			window:        "09:00-12:00",
+			expectedCode:  apierrors.CodePickupSlotUnavailable,
+			expectedField: "date",
+		},
+		{
+			name:          "holiday",
+			clientId:      "test-client",
+			origin:        "SE",
+			date:          monday.AddDate(0, 0, 1),
+			window:        "09:00-12:00",
+			expectedCode:  apierrors.CodePickupSlotUnavailable,
+			expectedField: "date",
+		},
+		{
+			name:          "past date",
+			clientId:      "test-client",
+			origin:        "SE",
+			date:          time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC),
+			window:        "09:00-12:00",
+			expectedCode:  apierrors.CodePickupSlotUnavailable,
+			expectedField: "date",
+		},
+		{
+			name:          "origin without pickups",
+			clientId:      "test-client",
+			origin:        "NO",
+			date:          monday,
+			window:        "09:00-12:00",
+			expectedCode:  apierrors.CodePickupSlotUnavailable,
+			expectedField: "origin",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			bundle := newTestBundle(t, 1)
+
+			id := bundle.Book(t, "test-client", tc.origin)
+			if tc.cancelled {
+				_, err := bundle.BookingService.CancelBooking(ctx, "test-client", id)
+				require.Nil(t, err)
+			}
+			if tc.scheduled {
+				_, err := bundle.service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+				require.Nil(t, err)
+			}
+			if tc.full {
+				_, err := bundle.service.SchedulePickup(ctx, "test-client", bundle.Book(t, "test-client", tc.origin), tc.date, tc.window)
+				require.Nil(t, err)
+			}
+
+			p, err := bundle.service.SchedulePickup(ctx, tc.clientId, id, tc.date, tc.window)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				require.Equal(t, tc.expectedField, apierrors.GetContext(err).Field)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, id, p.BookingId())
+			require.Equal(t, tc.origin, p.Origin())
+			require.Equal(t, tc.date, p.Date())
+			require.Equal(t, tc.window, p.Window().String())
+
+			stored, err := bundle.service.GetPickup(ctx, tc.clientId, id)
+			require.Nil(t, err)
+			require.Equal(t, p, stored)
+		})
+	}
+}
+
+func TestSchedulePickupConcurrently(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	const capacity = 5
+	bundle := newTestBundle(t, capacity)
+
+	ids := make([]string, 4*capacity)
+	for i := range ids {
+		ids[i] = bundle.Book(t, "test-client", "SE")
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mtx       sync.Mutex
+		scheduled int
+		full      int
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			_, err := bundle.service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err == nil {
+				scheduled++
+			} else if apierrors.GetCode(err) == apierrors.CodePickupSlotFull {
+				full++
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	require.Equal(t, capacity, scheduled)
+	require.Equal(t, len(ids)-capacity, full)
+	pickups, err := bundle.service.ListPickups(ctx, "SE", monday)
+	require.Nil(t, err)
+	require.Len(t, pickups, capacity)
+}
+
+// racingStore runs before ahead of every reservation, to act between the
+// check of a booking and the reservation of its pickup.
+type racingStore struct {
+	inMemoryStore
+	before func()
+}
+
+func (r racingStore) Reserve(ctx context.Context, p *pickup, capacity int) error {
+	r.before()
+	return r.inMemoryStore.Reserve(ctx, p, capacity)
+}
+
+func TestSchedulePickupWhileCancelling(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 1)
+	id := bundle.Book(t, "test-client", "SE")
+
+	store := NewInMemoryStore()
+	bundle.service.store = racingStore{inMemoryStore: store, before: func() {
+		_, err := bundle.BookingService.CancelBooking(ctx, "test-client", id)
+		require.Nil(t, err)
+		bundle.relay(t)
+	}}
+
+	_, err := bundle.service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+	require.Equal(t, apierrors.CodeBookingNotPickable, apierrors.GetCode(err))
+	pickups, err := store.ListPickups(ctx, "SE", monday)
+	require.Nil(t, err)
+	require.Empty(t, pickups, "expected the pickup of the cancelled booking to be released")
+}
+
+func TestReschedulePickup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 1)
+
+	first := bundle.Book(t, "test-client", "SE")
+	second := bundle.Book(t, "test-client", "SE")
+	_, err := bundle.service.SchedulePickup(ctx, "test-client", first, monday, "09:00-12:00")
+	require.Nil(t, err)
+	_, err = bundle.service.SchedulePickup(ctx, "test-client", second, monday, "13:00-17:00")
+	require.Nil(t, err)
+
+	_, err = bundle.service.ReschedulePickup(ctx, "test-client", first, monday, "13:00-17:00")
+	require.Equal(t, apierrors.CodePickupSlotFull, apierrors.GetCode(err))
+	p, err := bundle.service.GetPickup(ctx, "test-client", first)
+	require.Nil(t, err)
+	require.Equal(t, "09:00-12:00", p.Window().String(), "expected a full slot to keep the pickup in its slot")
+
+	wednesday := monday.AddDate(0, 0, 2)
+	p, err = bundle.service.ReschedulePickup(ctx, "test-client", first, wednesday, "13:00-17:00")
+	require.Nil(t, err)
+	require.Equal(t, wednesday, p.Date())
+	require.True(t, p.UpdatedAt().After(p.CreatedAt()) || p.UpdatedAt().Equal(p.CreatedAt()))
+
+	_, err = bundle.service.ReschedulePickup(ctx, "test-client", second, monday, "09:00-12:00")
+	require.Nil(t, err, "expected the previous slot to be released")
+
+	_, err = bundle.service.ReschedulePickup(ctx, "test-client", bundle.Book(t, "test-client", "SE"), monday, "09:00-12:00")
+	require.Equal(t, apierrors.CodePickupNotFound, apierrors.GetCode(err))
+}
+
+func TestCancelPickup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 1)
+
+	id := bundle.Book(t, "test-client", "SE")
+	_, err := bundle.service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+	require.Nil(t, err)
+
+	require.Equal(t, apierrors.CodeBookingNotFound, apierrors.GetCode(bundle.service.CancelPickup(ctx, "other-client", id)))
+	require.Nil(t, bundle.service.CancelPickup(ctx, "test-client", id))
+	require.Equal(t, apierrors.CodePickupNotFound, apierrors.GetCode(bundle.service.CancelPickup(ctx, "test-client", id)))
+
+	_, err = bundle.service.SchedulePickup(ctx, "test-client", bundle.Book(t, "test-client", "SE"), monday, "09:00-12:00")
+	require.Nil(t, err, "expected the cancelled pickup to release its slot")
+}
+
+func TestPublishCancelledBooking(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 1)
+
+	id := bundle.Book(t, "test-client", "SE")
+	_, err := bundle.service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+	require.Nil(t, err)
+
+	require.Nil(t, bundle.service.Publish(ctx, booking.Event{Type: booking.EventBookingStatusChanged, BookingId: id}))
+	_, err = bundle.service.GetPickup(ctx, "test-client", id)
+	require.Nil(t, err)
+
+	require.Nil(t, bundle.service.Publish(ctx, booking.Event{Type: booking.EventBookingCancelled, BookingId: id}))
+	_, err = bundle.service.GetPickup(ctx, "test-client", id)
+	require.Equal(t, apierrors.CodePickupNotFound, apierrors.GetCode(err))
+	require.Nil(t, bundle.service.Publish(ctx, booking.Event{Type: booking.EventBookingCancelled, BookingId: id}), "expected bookings without pickup to be fine")
+}
+
+func TestCancelBookingCarrierFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 1)
+
+	id := bundle.Book(t, "test-client", "SE")
+	_, err := bundle.service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+	require.Nil(t, err)
+	bundle.relay(t)
+
+	bundle.Carrier.Fail(apierrors.FromCode(apierrors.CodeCarrierUnavailable, "carrier down", apierrors.ErrorUnavailable))
+	_, err = bundle.BookingService.CancelBooking(ctx, "test-client", id)
+	require.Equal(t, apierrors.CodeCarrierUnavailable, apierrors.GetCode(err))
+	bundle.relay(t)
+	_, err = bundle.service.GetPickup(ctx, "test-client", id)
+	require.Nil(t, err, "expected the booking to keep its pickup")
+
+	bundle.Carrier.Fail(nil)
+	_, err = bundle.BookingService.CancelBooking(ctx, "test-client", id)
+	require.Nil(t, err)
+	bundle.relay(t)
+	_, err = bundle.service.GetPickup(ctx, "test-client", id)
+	require.Equal(t, apierrors.CodePickupNotFound, apierrors.GetCode(err))
+}
+
+func TestSlots(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 2)
+
+	_, err := bundle.service.SchedulePickup(ctx, "test-client", bundle.Book(t, "test-client", "SE"), monday, "13:00-17:00")
+	require.Nil(t, err)
+
+	slots, err := bundle.service.Slots(ctx, "SE", monday.AddDate(0, 0, -2), 3)
+	require.Nil(t, err)
+	require.Equal(t, []Slot{
+		{Date: monday, Window: Window{From: 9 * time.Hour, To: 12 * time.Hour}, Capacity: 2, Available: 2},
+		{Date: monday, Window: Window{From: 13 * time.Hour, To: 17 * time.Hour}, Capacity: 2, Available: 1},
+		{Date: monday.AddDate(0, 0, 2), Window: Window{From: 9 * time.Hour, To: 12 * time.Hour}, Capacity: 2, Available: 2},
+		{Date: monday.AddDate(0, 0, 2), Window: Window{From: 13 * time.Hour, To: 17 * time.Hour}, Capacity: 2, Available: 2},
+		{Date: monday.AddDate(0, 0, 3), Window: Window{From: 9 * time.Hour, To: 12 * time.Hour}, Capacity: 2, Available: 2},
+		{Date: monday.AddDate(0, 0, 3), Window: Window{From: 13 * time.Hour, To: 17 * time.Hour}, Capacity: 2, Available: 2},
+	}, slots)
+
+	slots, err = bundle.service.Slots(ctx, "NO", monday, 3)
+	require.Nil(t, err)
+	require.Empty(t, slots)
+
+	_, err = bundle.service.Slots(ctx, "SE", monday, MaxSlotDays+1)
+	require.Equal(t, apierrors.CodeValidationFailed, apierrors.GetCode(err))
+}
+
+func TestSlotsEnded(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t, 2)
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	require.Nil(t, err)
+	calendars := calendar.NewCalendars(calendar.New(calendar.Config{CutOff: 15 * time.Hour}), map[string]calendar.Calendar{
+		"SE": calendar.New(calendar.Config{TimeZone: stockholm, CutOff: 18 * time.Hour}),
+	})
+	schedules := Schedules{"SE": {Windows: []Window{{From: 9 * time.Hour, To: 12 * time.Hour}, {From: 13 * time.Hour, To: 17 * time.Hour}}, Capacity: 2}}
+	service := NewService(NewInMemoryStore(), bundle.BookingService, calendars, schedules)
+	// 12:30 in Stockholm, the morning window has ended there but not in UTC
+	service.now = func() time.Time { return monday.Add(11*time.Hour + 30*time.Minute) }
+
+	slots, err := service.Slots(ctx, "SE", monday, 2)
+	require.Nil(t, err)
+	require.Equal(t, []Slot{
+		{Date: monday, Window: Window{From: 13 * time.Hour, To: 17 * time.Hour}, Capacity: 2, Available: 2},
+		{Date: monday.AddDate(0, 0, 1), Window: Window{From: 9 * time.Hour, To: 12 * time.Hour}, Capacity: 2, Available: 2},
+		{Date: monday.AddDate(0, 0, 1), Window: Window{From: 13 * time.Hour, To: 17 * time.Hour}, Capacity: 2, Available: 2},
+	}, slots)
+
+	id := bundle.Book(t, "test-client", "SE")
+	_, err = service.SchedulePickup(ctx, "test-client", id, monday, "09:00-12:00")
+	require.Equal(t, apierrors.CodePickupSlotUnavailable, apierrors.GetCode(err))
+	_, err = service.SchedulePickup(ctx, "test-client", id, monday, "13:00-17:00")
+	require.Nil(t, err)
+
+	// after the last window of the day, but before the cut-off, the day does
+	// not count
+	service.now = func() time.Time { return monday.Add(16*time.Hour + 30*time.Minute) }
+	slots, err = service.Slots(ctx, "SE", monday, 1)
+	require.Nil(t, err)
+	require.Len(t, slots, 2)
+	require.Equal(t, monday.AddDate(0, 0, 1), slots[0].Date)
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := t.TempDir() + "/pickups.json"
+	window := Window{From: 9 * time.Hour, To: 12 * time.Hour}
+	createdAt := monday.Add(-24 * time.Hour)
+
+	store, err := NewFileStore(path)
+	require.Nil(t, err)
+	first, err := NewPickup("first", "test-client", "SE", monday, window, createdAt)
+	require.Nil(t, err)
+	require.Nil(t, store.Reserve(ctx, first, 1))
+	second, err := NewPickup("second", "test-client", "SE", monday.AddDate(0, 0, 1), window, createdAt)
+	require.Nil(t, err)
+	require.Nil(t, store.Reserve(ctx, second, 1))
+	require.Nil(t, store.CancelPickup(ctx, "second"))
+
+	reloaded, err := NewFileStore(path)
+	require.Nil(t, err)
+	p, err := reloaded.GetPickup(ctx, "first")
+	require.Nil(t, err)
+	require.Equal(t, first, p)
+	_, err = reloaded.GetPickup(ctx, "second")
+	require.Equal(t, apierrors.CodePickupNotFound, apierrors.GetCode(err))
+
+	third, err := NewPickup("third", "test-client", "SE", monday, window, createdAt)
+	require.Nil(t, err)
+	err = reloaded.Reserve(ctx, third, 1)
+	require.Equal(t, apierrors.CodePickupSlotFull, apierrors.GetCode(err), "expected the reserved slot to survive a restart")
+
+	require.Nil(t, os.WriteFile(path, []byte(`[{"bookingId": "first", "clientId": "test-client", "origin": "SE", "date": "2030-01-07", "window": "12:00-09:00"}]`), 0o600))
+	_, err = NewFileStore(path)
+	require.NotNil(t, err, "expected an invalid window to fail loading")
+}
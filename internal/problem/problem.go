@@ -0,0 +1,148 @@
+package problem
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	ContentType = "application/problem+json"
+	typePrefix  = "urn:shipping-api:problem:"
+)
+
+// Problem is an RFC 7807 problem details object, code and errors are
+// extension members.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      errors.Code         `json:"code"`
+	Errors    []errors.FieldError `json:"errors,omitempty"`
+	RequestId string              `json:"requestId,omitempty"`
+}
+
+func New(c *gin.Context, err error) Problem {
+	status := errors.HTTPStatus(err)
+	code := errors.GetCode(err)
+
+	detail := err.Error()
+	if status >= http.StatusInternalServerError {
+		detail = "the server failed to handle the request"
+	}
+
+	return Problem{
+		Type:      typePrefix + string(code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		Errors:    errors.GetFields(err),
+		RequestId: c.GetString(logging.RequestIdKey),
+	}
+}
+
+func Write(c *gin.Context, err error) {
+	p := New(c, err)
+	_ = c.Error(err)
+	c.Header("Content-Type", ContentType)
+	c.JSON(p.Status, p)
+}
+
+func Abort(c *gin.Context, err error) {
+	Write(c, err)
+	c.Abort()
+}
+
+var registerTagName sync.Once
+
+// Bind decodes the JSON body into obj and validates it, failures are returned
+// as input errors listing the offending fields by their JSON names.
+func Bind(c *gin.Context, obj interface{}) error {
+	registerTagName.Do(registerJSONNames)
+	return bindError(c.ShouldBindJSON(obj), "request body")
+}
+
+// BindQuery decodes the query parameters into obj using its form tags and
+// validates it like Bind, the json tags should name the same parameters.
+func BindQuery(c *gin.Context, obj interface{}) error {
+	registerTagName.Do(registerJSONNames)
+	return bindError(c.ShouldBindQuery(obj), "query")
+}
+
+func registerJSONNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonName)
+	}
+}
+
+func bindError(err error, source string) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		validationErrors validator.ValidationErrors
+		typeError        *json.UnmarshalTypeError
+		syntaxError      *json.SyntaxError
+		numError         *strconv.NumError
+	)
+	switch {
+	case stderrors.As(err, &validationErrors):
+		fields := make([]errors.FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, fieldError(fe))
+		}
+		return errors.Validation(source+" failed validation", fields...)
+	case stderrors.As(err, &typeError):
+		return errors.Validation(source+" failed validation", errors.FieldError{
+			Field:   typeError.Field,
+			Code:    errors.FieldInvalidType,
+			Message: fmt.Sprintf("%s must be of type %s", typeError.Field, typeError.Type),
+		})
+	case stderrors.As(err, &numError):
+		return errors.Validation(source+" failed validation", errors.FieldError{
+			Code:    errors.FieldInvalidType,
+			Message: fmt.Sprintf("%q is not a number", numError.Num),
+		})
+	case stderrors.As(err, &syntaxError), stderrors.Is(err, io.EOF), stderrors.Is(err, io.ErrUnexpectedEOF):
+		return errors.FromCode(errors.CodeInvalidRequest, "request body is not valid JSON", errors.ErrorInput)
+	default:
+		return errors.FromCode(errors.CodeInvalidRequest, err.Error(), errors.ErrorInput)
+	}
+}
+
+func fieldError(fe validator.FieldError) errors.FieldError {
+	switch fe.Tag() {
+	case "required":
+		return errors.FieldError{Field: fe.Field(), Code: errors.FieldRequired, Message: fmt.Sprintf("%s is required", fe.Field())}
+	case "gt", "gte", "lt", "lte", "min", "max":
+		return errors.FieldError{Field: fe.Field(), Code: errors.FieldOutOfRange, Message: fmt.Sprintf("%s must be %s %s", fe.Field(), fe.Tag(), fe.Param())}
+	default:
+		return errors.FieldError{Field: fe.Field(), Code: errors.FieldInvalid, Message: fmt.Sprintf("%s failed on %s", fe.Field(), fe.Tag())}
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	if name == "-" || name == "" {
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,147 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type testRequest struct {
+	Origin string  `json:"origin" binding:"required"`
+	Weight float32 `json:"weight" binding:"required,gt=0"`
+}
+
+func TestBind(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedCode   errors.Code
+		expectedFields []string
+		shouldFail     bool
+	}{
+		{
+			name:       "valid body",
+			body:       `{"origin":"SE","weight":10}`,
+			shouldFail: false,
+		},
+		{
+			name:           "missing fields",
+			body:           `{}`,
+			expectedCode:   errors.CodeValidationFailed,
+			expectedFields: []string{"origin", "weight"},
+			shouldFail:     true,
+		},
+		{
+			name:           "out of range",
+			body:           `{"origin":"SE","weight":-1}`,
+			expectedCode:   errors.CodeValidationFailed,
+			expectedFields: []string{"weight"},
+			shouldFail:     true,
+		},
+		{
+			name:           "wrong type",
+			body:           `{"origin":"SE","weight":"heavy"}`,
+			expectedCode:   errors.CodeValidationFailed,
+			expectedFields: []string{"weight"},
+			shouldFail:     true,
+		},
+		{
+			name:         "malformed json",
+			body:         `{"origin":`,
+			expectedCode: errors.CodeInvalidRequest,
+			shouldFail:   true,
+		},
+		{
+			name:         "empty body",
+			body:         ``,
+			expectedCode: errors.CodeInvalidRequest,
+			shouldFail:   true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var req testRequest
+			err := Bind(c, &req)
+			if !tc.shouldFail {
+				require.Nilf(t, err, "unexpected error")
+				return
+			}
+
+			require.NotNil(t, err, "expected an error, got nil")
+			require.Equal(t, errors.ErrorInput, errors.GetType(err))
+			require.Equal(t, tc.expectedCode, errors.GetCode(err))
+			fields := []string{}
+			for _, f := range errors.GetFields(err) {
+				fields = append(fields, f.Field)
+			}
+			require.ElementsMatch(t, tc.expectedFields, fields)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   errors.Code
+		expectedDetail string
+	}{
+		{
+			name:           "coded error",
+			err:            errors.FromCode(errors.CodeLocationNotFound, "no location with code XX", errors.ErrorInput),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   errors.CodeLocationNotFound,
+			expectedDetail: "no location with code XX",
+		},
+		{
+			name:           "error without code",
+			err:            errors.FromMessage("conflict", errors.ErrorConflict),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   errors.CodeConflict,
+			expectedDetail: "conflict",
+		},
+		{
+			name:           "internal errors are not leaked",
+			err:            errors.FromMessage("database password is wrong", errors.ErrorInternal),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   errors.CodeInternal,
+			expectedDetail: "the server failed to handle the request",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/shipping/id", nil)
+
+			Write(c, tc.err)
+
+			require.Equal(t, tc.expectedStatus, w.Code)
+			require.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+			var p Problem
+			require.Nilf(t, json.Unmarshal(w.Body.Bytes(), &p), "unexpected error")
+			require.Equal(t, tc.expectedStatus, p.Status)
+			require.Equal(t, tc.expectedCode, p.Code)
+			require.Equal(t, "urn:shipping-api:problem:"+string(tc.expectedCode), p.Type)
+			require.Equal(t, tc.expectedDetail, p.Detail)
+			require.Equal(t, "/api/shipping/id", p.Instance)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+type inMemoryStore struct {
+	buckets   map[string]*bucket
+	lastSweep *time.Time
+	mtx       *sync.Mutex
+}
+
+func NewInMemoryStore() inMemoryStore {
+	return inMemoryStore{buckets: make(map[string]*bucket, 0), lastSweep: &time.Time{}, mtx: &sync.Mutex{}}
+}
+
+func (r inMemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.sweep(now)
+
+	b, ok := r.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.burst), updated: now, limit: limit}
+		r.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.burst), b.tokens+elapsed*limit.rate)
+		b.updated = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(limit, b.tokens, allowed), nil
+}
+
+// sweep drops buckets that have refilled completely, they are equivalent to a
+// bucket that was never created.
+func (r inMemoryStore) sweep(now time.Time) {
+	if now.Sub(*r.lastSweep) < sweepInterval {
+		return
+	}
+	*r.lastSweep = now
+
+	for key, b := range r.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.limit.rate >= float64(b.limit.burst) {
+			delete(r.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type Limit struct {
+	rate  float64
+	burst int
+}
+
+func NewLimit(rate float64, burst int) (Limit, error) {
+	if rate <= 0 {
+		return Limit{}, errors.FromMessage("rate must be positive", errors.ErrorInput)
+	}
+	if burst < 1 {
+		return Limit{}, errors.FromMessage("burst must be at least 1", errors.ErrorInput)
+	}
+
+	return Limit{rate: rate, burst: burst}, nil
+}
+
+// ParseLimit parses a limit on the form <requests per second>:<burst>, e.g.
+// 0.5:10.
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return Limit{}, errors.FromMessage(fmt.Sprintf("invalid limit %q, expected <rate>:<burst>", s), errors.ErrorInput)
+	}
+
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return Limit{}, errors.FromMessage(fmt.Sprintf("invalid rate %q", parts[0]), errors.ErrorInput)
+	}
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Limit{}, errors.FromMessage(fmt.Sprintf("invalid burst %q", parts[1]), errors.ErrorInput)
+	}
+
+	return NewLimit(rate, burst)
+}
+
+// ParseRouteLimit parses a limit for a single route on the form
+// <method> <route>=<rate>:<burst>, e.g. "POST /api/shipping=1:5".
+func ParseRouteLimit(s string) (string, Limit, error) {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return "", Limit{}, errors.FromMessage(fmt.Sprintf("invalid route limit %q, expected <method> <route>=<rate>:<burst>", s), errors.ErrorInput)
+	}
+
+	route := strings.Join(strings.Fields(s[:i]), " ")
+	if len(strings.Fields(route)) != 2 {
+		return "", Limit{}, errors.FromMessage(fmt.Sprintf("invalid route %q, expected <method> <route>", s[:i]), errors.ErrorInput)
+	}
+
+	limit, err := ParseLimit(s[i+1:])
+	if err != nil {
+		return "", Limit{}, err
+	}
+
+	return route, limit, nil
+}
+
+func (l Limit) Rate() float64 {
+	return l.rate
+}
+
+func (l Limit) Burst() int {
+	return l.burst
+}
+
+func (l Limit) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limit         string
+		expectedRate  float64
+		expectedBurst int
+		shouldFail    bool
+	}{
+		{
+			name:          "valid limit",
+			limit:         "0.5:10",
+			expectedRate:  0.5,
+			expectedBurst: 10,
+		},
+		{
+			name:       "missing burst",
+			limit:      "10",
+			shouldFail: true,
+		},
+		{
+			name:       "invalid rate",
+			limit:      "fast:10",
+			shouldFail: true,
+		},
+		{
+			name:       "zero rate",
+			limit:      "0:10",
+			shouldFail: true,
+		},
+		{
+			name:       "zero burst",
+			limit:      "1:0",
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			limit, err := ParseLimit(tc.limit)
+
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedRate, limit.Rate())
+			require.Equal(t, tc.expectedBurst, limit.Burst())
+		})
+	}
+}
+
+func TestParseRouteLimit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limit         string
+		expectedRoute string
+		shouldFail    bool
+	}{
+		{
+			name:          "valid route limit",
+			limit:         "POST /api/shipping=1:5",
+			expectedRoute: "POST /api/shipping",
+		},
+		{
+			name:          "extra whitespace",
+			limit:         " GET   /api/shipping/:id =1:5",
+			expectedRoute: "GET /api/shipping/:id",
+		},
+		{
+			name:       "missing method",
+			limit:      "/api/shipping=1:5",
+			shouldFail: true,
+		},
+		{
+			name:       "missing limit",
+			limit:      "POST /api/shipping",
+			shouldFail: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			route, _, err := ParseRouteLimit(tc.limit)
+
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedRoute, route)
+		})
+	}
+}
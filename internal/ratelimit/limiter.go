@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/logging"
+)
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps a token bucket per key. The in-memory store only limits the
+// requests of a single instance, the Redis store shares the buckets between
+// every instance using it.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type Limiter struct {
+	store        Store
+	defaultLimit Limit
+	routeLimits  map[string]Limit
+	now          func() time.Time
+}
+
+func NewLimiter(store Store, defaultLimit Limit, routeLimits map[string]Limit) Limiter {
+	return Limiter{
+		store:        store,
+		defaultLimit: defaultLimit,
+		routeLimits:  routeLimits,
+		now:          time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the client on the route, routes are
+// given as "<method> <route>". If the store fails the request is let through
+// rather than taking the API down with it.
+func (l Limiter) Allow(ctx context.Context, route, client string) Result {
+	limit, ok := l.routeLimits[route]
+	if !ok {
+		limit = l.defaultLimit
+	}
+
+	result, err := l.store.Take(ctx, route+"|"+client, limit, l.now())
+	if err != nil {
+		logger := logging.FromContext(ctx, "ratelimit")
+		logger.Warn().Err(err).Str("route", route).Msg("rate limit store failed, allowing request")
+		return Result{Allowed: true, Limit: limit.burst, Remaining: limit.burst}
+	}
+
+	return result
+}
+
+// newResult is the result of a take that leaves tokens in the bucket.
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	result := Result{Allowed: allowed, Limit: limit.burst, Remaining: int(tokens)}
+	if !allowed {
+		result.RetryAfter = limit.durationFor(1 - tokens)
+	}
+	result.ResetAfter = limit.durationFor(float64(limit.burst) - tokens)
+	return result
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllow(t *testing.T) {
+	t.Parallel()
+
+	defaultLimit, err := NewLimit(1, 2)
+	require.Nilf(t, err, "unexpected error")
+	routeLimit, err := NewLimit(1, 1)
+	require.Nilf(t, err, "unexpected error")
+
+	now := time.Unix(0, 0)
+	limiter := NewLimiter(NewInMemoryStore(), defaultLimit, map[string]Limit{"POST /api/shipping": routeLimit})
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.True(t, limiter.Allow(ctx, "GET /api/shipping/:id", "client").Allowed)
+	require.True(t, limiter.Allow(ctx, "GET /api/shipping/:id", "client").Allowed)
+	result := limiter.Allow(ctx, "GET /api/shipping/:id", "client")
+	require.False(t, result.Allowed)
+	require.Equal(t, 2, result.Limit)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	require.True(t, limiter.Allow(ctx, "GET /api/shipping/:id", "other-client").Allowed)
+
+	require.True(t, limiter.Allow(ctx, "POST /api/shipping", "client").Allowed)
+	require.False(t, limiter.Allow(ctx, "POST /api/shipping", "client").Allowed)
+
+	now = now.Add(time.Second)
+	result = limiter.Allow(ctx, "GET /api/shipping/:id", "client")
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, 2*time.Second, result.ResetAfter)
+}
+
+func TestAllowStoreError(t *testing.T) {
+	t.Parallel()
+
+	limit, err := NewLimit(1, 1)
+	require.Nilf(t, err, "unexpected error")
+	limiter := NewLimiter(storeMock{err: errors.New("store error")}, limit, nil)
+
+	require.True(t, limiter.Allow(context.Background(), "POST /api/shipping", "client").Allowed)
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+
+	limit, err := NewLimit(1, 1)
+	require.Nilf(t, err, "unexpected error")
+	store := NewInMemoryStore()
+	now := time.Unix(0, 0).Add(sweepInterval)
+
+	_, err = store.Take(context.Background(), "client", limit, now)
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, store.buckets, 1)
+
+	_, err = store.Take(context.Background(), "other-client", limit, now.Add(sweepInterval))
+	require.Nilf(t, err, "unexpected error")
+	require.Len(t, store.buckets, 1)
+}
+
+type storeMock struct {
+	result Result
+	err    error
+}
+
+func (s storeMock) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	return s.result, s.err
+}
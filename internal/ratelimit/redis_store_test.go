@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// redisServer is a stand-in for a Redis server that speaks just enough of the
+// protocol to run the take script, it takes from buckets without refilling
+// them and fails every command once fail is set.
+type redisServer struct {
+	listener net.Listener
+	commands chan []string
+	tokens   map[string]int
+	fail     bool
+}
+
+func newRedisServer(t *testing.T) *redisServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	s := &redisServer{listener: l, commands: make(chan []string, 10), tokens: make(map[string]int)}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *redisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *redisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.commands <- args
+		switch {
+		case s.fail:
+			fmt.Fprint(conn, "-ERR failing\r\n")
+		case args[0] == "EVAL":
+			key, burst := args[3], args[5]
+			if _, ok := s.tokens[key]; !ok {
+				s.tokens[key], _ = strconv.Atoi(burst)
+			}
+			allowed := 0
+			if s.tokens[key] >= 1 {
+				s.tokens[key]--
+				allowed = 1
+			}
+			tokens := strconv.Itoa(s.tokens[key])
+			fmt.Fprintf(conn, "*2\r\n:%d\r\n$%d\r\n%s\r\n", allowed, len(tokens), tokens)
+		default:
+			fmt.Fprint(conn, "+OK\r\n")
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func TestNewRedisStore(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		url           string
+		expectedError bool
+	}{
+		{
+			name: "host",
+			url:  "redis://localhost",
+		},
+		{
+			name: "password and database",
+			url:  "redis://:secret@localhost:6380/2",
+		},
+		{
+			name:          "wrong scheme",
+			url:           "http://localhost",
+			expectedError: true,
+		},
+		{
+			name:          "missing host",
+			url:           "redis://",
+			expectedError: true,
+		},
+		{
+			name:          "invalid database",
+			url:           "redis://localhost/cache",
+			expectedError: true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewRedisStore(tc.url, time.Second)
+			if tc.expectedError {
+				require.NotNil(t, err, "expected an error, got nil")
+				return
+			}
+			require.Nilf(t, err, "unexpected error")
+		})
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Parallel()
+
+	server := newRedisServer(t)
+	store, err := NewRedisStore("redis://user:secret@"+server.listener.Addr().String()+"/1", time.Second)
+	require.Nil(t, err)
+	t.Cleanup(func() { store.Close(context.Background()) })
+	limit, err := NewLimit(1, 1)
+	require.Nil(t, err)
+	ctx := context.Background()
+	now := time.UnixMilli(1000)
+
+	result, err := store.Take(ctx, "GET /api/tariffs|client:test", limit, now)
+	require.Nil(t, err)
+	require.Equal(t, Result{Allowed: true, Limit: 1, Remaining: 0, ResetAfter: time.Second}, result)
+	require.Equal(t, []string{"AUTH", "user", "secret"}, <-server.commands)
+	require.Equal(t, []string{"SELECT", "1"}, <-server.commands)
+	require.Equal(t, []string{"EVAL", takeScript, "1", "ratelimit:GET /api/tariffs|client:test", "1", "1", "1000"}, <-server.commands)
+
+	result, err = store.Take(ctx, "GET /api/tariffs|client:test", limit, now)
+	require.Nil(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	<-server.commands
+
+	server.fail = true
+	_, err = store.Take(ctx, "GET /api/tariffs|client:test", limit, now)
+	require.NotNil(t, err, "expected an error, got nil")
+	<-server.commands
+
+	// the failed connection is replaced, authenticating again
+	server.fail = false
+	_, err = store.Take(ctx, "GET /api/tariffs|client:test", limit, now)
+	require.Nil(t, err)
+	require.Equal(t, "AUTH", (<-server.commands)[0])
+}
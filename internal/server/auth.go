@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyHeader        = "X-API-Key"
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+	authErrorKey        = "authError"
+)
+
+type authenticator interface {
+	Authenticate(context.Context, string) (string, error)
+}
+
+type tokenVerifier interface {
+	Verify(context.Context, string) (string, []auth.Role, error)
+}
+
+// authMiddleware accepts either a bearer token or an api key, api keys always
+// act as customers. It only identifies the client, requireAuthentication
+// rejects the request, so that the rate limiter in between also limits
+// failed attempts.
+func authMiddleware(authenticator authenticator, tokenVerifier tokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			clientId string
+			roles    []auth.Role
+			err      error
+		)
+
+		if header := c.GetHeader(authorizationHeader); strings.HasPrefix(header, bearerPrefix) {
+			clientId, roles, err = tokenVerifier.Verify(c, strings.TrimPrefix(header, bearerPrefix))
+		} else {
+			clientId, err = authenticator.Authenticate(c, c.GetHeader(apiKeyHeader))
+			roles = []auth.Role{auth.RoleCustomer}
+		}
+
+		if err != nil {
+			if errors.GetType(err) != errors.ErrorUnauthorized {
+				err = errors.FromCode(errors.CodeUnauthorized, err.Error(), errors.ErrorUnauthorized)
+			}
+			c.Set(authErrorKey, err)
+			c.Next()
+			return
+		}
+
+		c.Set(auth.ClientIdKey, clientId)
+		c.Set(auth.RolesKey, roles)
+		c.Next()
+	}
+}
+
+// requireAuthentication rejects requests that authMiddleware could not
+// identify.
+func requireAuthentication() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err, ok := c.Value(authErrorKey).(error); ok {
+			problem.Abort(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func requireRole(allowed ...auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, _ := c.Value(auth.RolesKey).([]auth.Role)
+		if !auth.HasAnyRole(roles, allowed...) {
+			problem.Abort(c, errors.FromCode(errors.CodeForbidden, "insufficient role", errors.ErrorForbidden))
+			return
+		}
+
+		c.Next()
+	}
+}
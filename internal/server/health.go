@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/slaengkast/shipping-api/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type healthChecker interface {
+	Ready(context.Context) health.Report
+	SetShuttingDown()
+}
+
+func (s *server) handleHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+}
+
+func (s *server) handleLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+func (s *server) handleReadiness(c *gin.Context) {
+	report := s.healthChecker.Ready(c)
+	if !report.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
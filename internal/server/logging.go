@@ -0,0 +1,88 @@
+package server
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIdHeader = "X-Request-ID"
+
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIdMiddleware keeps a well formed incoming X-Request-ID, or generates
+// one, and attaches a logger carrying it to the request context.
+func requestIdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIdHeader)
+		if !validRequestId.MatchString(id) {
+			id = uuid.New().String()
+		}
+
+		c.Header(requestIdHeader, id)
+		c.Set(logging.RequestIdKey, id)
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("http.request_id", id))
+
+		logger := log.With().Str(logging.RequestIdKey, id).Logger()
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+		c.Next()
+	}
+}
+
+// accessLogMiddleware logs every request, successful requests are sampled so
+// that only every successSampling:th one is logged.
+func accessLogMiddleware(successSampling uint32) gin.HandlerFunc {
+	if successSampling == 0 {
+		successSampling = 1
+	}
+	sampler := &zerolog.BasicSampler{N: successSampling}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		logger := logging.FromContext(c.Request.Context(), "http")
+
+		var event *zerolog.Event
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Info()
+		default:
+			sampled := logger.Sample(sampler)
+			event = sampled.Info()
+		}
+
+		event.
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Str("route", c.FullPath()).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Int64("bytesIn", c.Request.ContentLength).
+			Int("bytesOut", c.Writer.Size()).
+			Str("clientIp", c.ClientIP()).
+			Str("userAgent", c.Request.UserAgent()).
+			Str("clientId", c.GetString(auth.ClientIdKey)).
+			Str("errors", c.Errors.ByType(gin.ErrorTypePrivate).String())
+
+		if last := c.Errors.Last(); last != nil {
+			event = event.
+				Str("errorCode", string(apierrors.GetCode(last.Err))).
+				Fields(apierrors.GetContext(last.Err).Fields())
+		}
+		event.Msg("request")
+	}
+}
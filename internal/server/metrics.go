@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type requestMetrics interface {
+	RequestHandled(method, route string, status int, duration time.Duration)
+	Handler() http.Handler
+}
+
+func metricsMiddleware(metrics requestMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.RequestHandled(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
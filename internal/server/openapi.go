@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/health"
+	"github.com/slaengkast/shipping-api/internal/openapi"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBodySize = 1 << 20
+
+type statusResponse struct {
+	Status string `json:"status" binding:"required"`
+}
+
+func newDocument() *openapi.Document {
+	doc := openapi.New("Shipping API", "1.0.0")
+	doc.DefineSchema("Problem", openapi.SchemaOf(problem.Problem{}))
+	doc.SecureWith("apiKey", openapi.SecurityScheme{Type: "apiKey", Name: apiKeyHeader, In: "header"})
+	doc.SecureWith("bearer", openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+	return doc
+}
+
+// secured marks op as requiring an api key or a bearer token.
+func secured(op openapi.Operation) openapi.Operation {
+	op.Security = []map[string][]string{{"apiKey": {}}, {"bearer": {}}}
+	return op
+}
+
+// handle registers the route and documents it with op, requests with a body
+// are validated against the schema of op before reaching the handler.
+func (s *server) handle(group *gin.RouterGroup, method, path string, handler gin.HandlerFunc, op openapi.Operation) {
+	s.openapi.Add(method, joinPath(group.BasePath(), path), op)
+
+	if schema := op.Schema(); schema != nil {
+		group.Handle(method, path, validateRequest(schema), handler)
+		return
+	}
+	group.Handle(method, path, handler)
+}
+
+func (s *server) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openapi)
+}
+
+func validateRequest(schema *openapi.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		if err != nil {
+			problem.Abort(c, apierrors.FromCode(apierrors.CodeInvalidRequest, "request body could not be read", apierrors.ErrorInput))
+			return
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil || decoder.More() {
+			problem.Abort(c, apierrors.FromCode(apierrors.CodeInvalidRequest, "request body is not valid JSON", apierrors.ErrorInput))
+			return
+		}
+
+		if fields := schema.Validate(value); len(fields) > 0 {
+			problem.Abort(c, apierrors.Validation("request body does not match the schema", fields...))
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func healthOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "health",
+		Summary:     "Health check",
+		Tags:        []string{"probes"},
+		Responses:   map[string]openapi.Response{"200": openapi.JSONResponse("Healthy", statusResponse{})},
+	}
+}
+
+func livenessOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "liveness",
+		Summary:     "Liveness probe, the process is up",
+		Tags:        []string{"probes"},
+		Responses:   map[string]openapi.Response{"200": openapi.JSONResponse("Alive", statusResponse{})},
+	}
+}
+
+func readinessOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "readiness",
+		Summary:     "Readiness probe, runs the dependency checks",
+		Tags:        []string{"probes"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Ready", health.Report{}),
+			"503": openapi.JSONResponse("Not ready or shutting down", health.Report{}),
+		},
+	}
+}
+
+func metricsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "metrics",
+		Summary:     "Prometheus metrics",
+		Tags:        []string{"probes"},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "Metrics in the Prometheus text format",
+				Content:     map[string]openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}},
+			},
+		},
+	}
+}
+
+func openAPIOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "openapi",
+		Summary:     "This document",
+		Tags:        []string{"probes"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "OpenAPI document", Content: map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "object"}}}},
+		},
+	}
+}
+
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	if base == "/" {
+		return path
+	}
+	return base + path
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/problem"
+	"github.com/slaengkast/shipping-api/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type limiter interface {
+	Allow(ctx context.Context, route, client string) ratelimit.Result
+}
+
+// rateLimitMiddleware limits authenticated clients by their client id and
+// falls back to the remote address for anything else, it runs before
+// unauthenticated requests are rejected so guessing credentials is limited.
+// Routes are limited without their version, every version of a route shares
+// its limit and its buckets.
+func rateLimitMiddleware(limiter limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := "ip:" + c.ClientIP()
+		if clientId := c.GetString(auth.ClientIdKey); clientId != "" {
+			client = "client:" + clientId
+		}
+
+		result := limiter.Allow(c, c.Request.Method+" "+unversioned(c.FullPath()), client)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.ResetAfter))
+
+		if !result.Allowed {
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			problem.Abort(c, errors.FromCode(errors.CodeRateLimited, "rate limit exceeded", errors.ErrorRateLimited))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
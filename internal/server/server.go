@@ -1,8 +1,18 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/graphqlapi"
+	"github.com/slaengkast/shipping-api/internal/openapi"
+	"github.com/slaengkast/shipping-api/internal/problem"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -11,62 +21,240 @@ import (
 type bookingHandler interface {
 	BookShipping(c *gin.Context)
 	GetBooking(c *gin.Context)
+	ListBookings(c *gin.Context)
+	CancelBooking(c *gin.Context)
+	BookShippingV2(c *gin.Context)
+	GetBookingV2(c *gin.Context)
+	ListBookingsV2(c *gin.Context)
+	CancelBookingV2(c *gin.Context)
+}
+
+type tariffHandler interface {
+	GetTariffs(c *gin.Context)
+	GetQuote(c *gin.Context)
+}
+
+type calendarHandler interface {
+	GetCalendar(c *gin.Context)
+	GetPickup(c *gin.Context)
+}
+
+type pickupHandler interface {
+	GetSlots(c *gin.Context)
+	ListPickups(c *gin.Context)
+	SchedulePickup(c *gin.Context)
+	GetPickup(c *gin.Context)
+	ReschedulePickup(c *gin.Context)
+	CancelPickup(c *gin.Context)
+}
+
+type trackingHandler interface {
+	IngestEvents(c *gin.Context)
+	GetTracking(c *gin.Context)
+}
+
+type graphqlHandler interface {
+	Query(c *gin.Context)
+}
+
+type webhookHandler interface {
+	CreateSubscription(c *gin.Context)
+	ListSubscriptions(c *gin.Context)
+	DeleteSubscription(c *gin.Context)
+	ListDeadLetters(c *gin.Context)
+	Redeliver(c *gin.Context)
+}
+
+type streamHandler interface {
+	BookingEvents(c *gin.Context)
+	Events(c *gin.Context)
+	Drain()
+}
+
+type apiKeyHandler interface {
+	CreateKey(c *gin.Context)
+	RevokeKey(c *gin.Context)
+}
+
+type closer interface {
+	Close(context.Context) error
+}
+
+type Config struct {
+	Port              int
+	AccessLogSampling uint32
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	DrainDelay        time.Duration
+	LegacyDeprecation time.Time
+	LegacySunset      time.Time
+	// TrustedProxies may set the client address with X-Forwarded-For, the
+	// address requests are rate limited by before they are authenticated.
+	TrustedProxies []string
 }
 
 type server struct {
-	router         *gin.Engine
-	port           int
-	bookingHandler bookingHandler
+	router          *gin.Engine
+	httpServer      *http.Server
+	config          Config
+	closers         []closer
+	closersMtx      *sync.Mutex
+	bookingHandler  bookingHandler
+	tariffHandler   tariffHandler
+	calendarHandler calendarHandler
+	pickupHandler   pickupHandler
+	trackingHandler trackingHandler
+	apiKeyHandler   apiKeyHandler
+	graphqlHandler  graphqlHandler
+	webhookHandler  webhookHandler
+	streamHandler   streamHandler
+	authenticator   authenticator
+	tokenVerifier   tokenVerifier
+	limiter         limiter
+	metrics         requestMetrics
+	healthChecker   healthChecker
+	openapi         *openapi.Document
 }
 
-func New(bookingHandler bookingHandler, port int) *server {
+func New(
+	bookingHandler bookingHandler,
+	tariffHandler tariffHandler,
+	calendarHandler calendarHandler,
+	pickupHandler pickupHandler,
+	trackingHandler trackingHandler,
+	apiKeyHandler apiKeyHandler,
+	graphqlHandler graphqlHandler,
+	webhookHandler webhookHandler,
+	streamHandler streamHandler,
+	authenticator authenticator,
+	tokenVerifier tokenVerifier,
+	limiter limiter,
+	metrics requestMetrics,
+	healthChecker healthChecker,
+	config Config,
+) *server {
 	router := gin.New()
+	router.ContextWithFallback = true
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", config.Port),
+		Handler:           router,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+	// event streams would otherwise keep the drain waiting until they end
+	httpServer.RegisterOnShutdown(streamHandler.Drain)
 	return &server{
-		router:         router,
-		port:           port,
-		bookingHandler: bookingHandler,
+		router:          router,
+		httpServer:      httpServer,
+		config:          config,
+		closersMtx:      &sync.Mutex{},
+		bookingHandler:  bookingHandler,
+		tariffHandler:   tariffHandler,
+		calendarHandler: calendarHandler,
+		pickupHandler:   pickupHandler,
+		trackingHandler: trackingHandler,
+		apiKeyHandler:   apiKeyHandler,
+		graphqlHandler:  graphqlHandler,
+		webhookHandler:  webhookHandler,
+		streamHandler:   streamHandler,
+		authenticator:   authenticator,
+		tokenVerifier:   tokenVerifier,
+		limiter:         limiter,
+		metrics:         metrics,
+		healthChecker:   healthChecker,
+		openapi:         newDocument(),
 	}
 }
 
 func (s *server) Run() error {
-	log.Info().Msgf("starting http server on port %d", s.port)
+	log.Info().Msgf("starting http server on port %d", s.config.Port)
+
+	if err := s.router.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
-	s.router.Use(logMiddleware())
-	s.router.Use(gin.Recovery())
+	s.router.Use(tracingMiddleware())
+	s.router.Use(requestIdMiddleware())
+	s.router.Use(accessLogMiddleware(s.config.AccessLogSampling))
+	s.router.Use(metricsMiddleware(s.metrics))
+	s.router.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		problem.Abort(c, apierrors.FromMessage(fmt.Sprintf("panic: %v", recovered), apierrors.ErrorInternal))
+	}))
+	s.router.NoRoute(func(c *gin.Context) {
+		problem.Write(c, apierrors.FromCode(apierrors.CodeRouteNotFound, fmt.Sprintf("no route for %s %s", c.Request.Method, c.Request.URL.Path), apierrors.ErrorNotFound))
+	})
 	s.setupRoutes()
 
-	return s.router.Run(fmt.Sprintf(":%d", s.port))
+	if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
-func (s *server) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+// OnShutdown registers closers, typically stores, that are closed in reverse
+// order once all in-flight requests have been drained.
+func (s *server) OnShutdown(closers ...closer) {
+	s.closersMtx.Lock()
+	defer s.closersMtx.Unlock()
+
+	s.closers = append(s.closers, closers...)
 }
 
-func (s *server) setupRoutes() {
-	s.router.GET("/health", s.handleHealth)
+// Shutdown reports not ready for the configured drain delay, then stops
+// accepting connections and waits for in-flight requests until ctx is done.
+// The registered closers are run either way.
+func (s *server) Shutdown(ctx context.Context) error {
+	s.healthChecker.SetShuttingDown()
+	select {
+	case <-time.After(s.config.DrainDelay):
+	case <-ctx.Done():
+	}
 
-	apiRouter := s.router.Group("api")
-	{
-		apiRouter.GET("/shipping/:id", s.bookingHandler.GetBooking)
-		apiRouter.POST("/shipping", s.bookingHandler.BookShipping)
+	log.Info().Msg("draining http server")
+	shutdownErr := s.httpServer.Shutdown(ctx)
+	if shutdownErr != nil {
+		log.Error().Err(shutdownErr).Msg("failed to drain http server")
 	}
-}
 
-func logMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Next()
-		status := c.Writer.Status()
-		method := c.Request.Method
-		path := c.Request.URL.Path
-		switch status {
-		case http.StatusInternalServerError:
-			log.Error().Int("status", status).Str("method", method).Str("path", path).Msg("")
-		case http.StatusBadRequest:
-			log.Info().Int("status", status).Str("method", method).Str("path", path).Msg("")
-		case http.StatusNotFound:
-			log.Info().Int("status", status).Str("method", method).Str("path", path).Msg("")
-		case http.StatusConflict:
-			log.Info().Int("status", status).Str("method", method).Str("path", path).Msg("")
+	s.closersMtx.Lock()
+	defer s.closersMtx.Unlock()
+
+	var closeErr error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].Close(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to close")
+			closeErr = err
 		}
 	}
+
+	if shutdownErr != nil {
+		return shutdownErr
+	}
+	return closeErr
+}
+
+func (s *server) setupRoutes() {
+	root := &s.router.RouterGroup
+	s.handle(root, http.MethodGet, "/health", s.handleHealth, healthOperation())
+	s.handle(root, http.MethodGet, "/livez", s.handleLiveness, livenessOperation())
+	s.handle(root, http.MethodGet, "/readyz", s.handleReadiness, readinessOperation())
+	s.handle(root, http.MethodGet, "/metrics", gin.WrapH(s.metrics.Handler()), metricsOperation())
+	s.handle(root, http.MethodGet, "/openapi.json", s.handleOpenAPI, openAPIOperation())
+
+	s.setupLegacyRoutes()
+	s.setupV1Routes(s.apiGroup(v1Prefix), secured)
+	s.setupV2Routes(s.apiGroup(v2Prefix))
+	s.setupGraphQLRoutes()
+}
+
+func (s *server) setupGraphQLRoutes() {
+	graphqlRouter := s.apiGroup("/graphql")
+	graphqlRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(graphqlRouter, http.MethodGet, "", s.graphqlHandler.Query, secured(graphqlapi.QueryGetOperation()))
+		s.handle(graphqlRouter, http.MethodPost, "", s.graphqlHandler.Query, secured(graphqlapi.QueryOperation()))
+	}
 }
@@ -1,18 +1,49 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/slaengkast/shipping-api/client"
+	"github.com/slaengkast/shipping-api/internal/auth"
 	"github.com/slaengkast/shipping-api/internal/billing"
 	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/carrier"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/events"
+	"github.com/slaengkast/shipping-api/internal/graphqlapi"
+	"github.com/slaengkast/shipping-api/internal/health"
+	"github.com/slaengkast/shipping-api/internal/metrics"
+	"github.com/slaengkast/shipping-api/internal/openapi"
+	"github.com/slaengkast/shipping-api/internal/pickup"
+	"github.com/slaengkast/shipping-api/internal/problem"
+	"github.com/slaengkast/shipping-api/internal/ratelimit"
+	"github.com/slaengkast/shipping-api/internal/stream"
+	"github.com/slaengkast/shipping-api/internal/tracking"
+	"github.com/slaengkast/shipping-api/internal/webhook"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 const (
@@ -20,43 +51,1682 @@ const (
 	address = "localhost"
 )
 
+var (
+	apiKey      string
+	otherApiKey string
+	// clients of their own so that no other test triggers their webhooks
+	webhookApiKey    string
+	deadLetterApiKey string
+	spanRecorder     = tracetest.NewInMemoryExporter()
+)
+
 func TestBookShipping(t *testing.T) {
 	t.Parallel()
 
-	client := NewClient(fmt.Sprintf("http://%s:%d", address, port))
+	client := newClient(apiKey)
+
+	id, err := client.BookShipping(context.Background(), "SE", "DK", 400)
+	require.Nil(t, err)
+	require.NotEqual(t, "", id)
+}
+
+func TestGetBooking(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(apiKey)
+
+	id, err := client.BookShipping(context.Background(), "SE", "DK", 400)
+	require.Nil(t, err)
+	require.NotEqual(t, "", id)
+
+	booking, err := client.GetBooking(context.Background(), id)
+	require.Nil(t, err)
+	require.Equal(t, id, booking.Id)
+	require.InDelta(t, 400, booking.Weight, 1e-9)
+	require.Equal(t, "SE", booking.Origin)
+	require.Equal(t, "DK", booking.Destination)
+	require.InDelta(t, 3000, booking.Price, 1e-9)
+	require.Equal(t, "SEK", booking.Currency)
+}
+
+func TestGetBookingOtherClient(t *testing.T) {
+	t.Parallel()
+
+	id, err := newClient(apiKey).BookShipping(context.Background(), "SE", "DK", 400)
+	require.Nil(t, err)
+	require.NotEqual(t, "", id)
+
+	booking, err := newClient(otherApiKey).GetBooking(context.Background(), id)
+	require.Nil(t, booking)
+	require.ErrorIs(t, err, client.ErrNotFound)
+	require.Equal(t, client.CodeBookingNotFound, client.GetCode(err))
+
+	for _, path := range []string{"/api/v1/shipping/" + id, "/api/v2/shipping/" + id} {
+		var problem struct {
+			Status int         `json:"status"`
+			Code   client.Code `json:"code"`
+		}
+		require.Equal(t, http.StatusNotFound, getJSON(t, path, otherApiKey, &problem), path)
+		require.Equal(t, http.StatusNotFound, problem.Status, path)
+		require.Equal(t, client.CodeBookingNotFound, problem.Code, path)
+	}
+
+	operator := client.New(client.Config{BaseUrl: fmt.Sprintf("http://%s:%d", address, port), Token: operatorToken})
+	booking, err = operator.GetBooking(context.Background(), id)
+	require.Nil(t, err, "expected operators to get the bookings of every client")
+	require.Equal(t, id, booking.Id)
+
+	// reading is all, operators only change their own bookings
+	_, err = operator.CancelBooking(context.Background(), id)
+	require.Equal(t, client.CodeBookingNotFound, client.GetCode(err))
+	booking, err = newClient(apiKey).GetBooking(context.Background(), id)
+	require.Nil(t, err)
+	require.Equal(t, client.StatusBooked, booking.Status)
+}
+
+func TestCancelBooking(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(apiKey)
+	id, err := c.BookShipping(context.Background(), "SE", "DK", 400)
+	require.Nil(t, err)
+
+	cancelled, err := c.CancelBooking(context.Background(), id)
+	require.Nil(t, err)
+	require.Equal(t, id, cancelled.Id)
+	require.Equal(t, client.StatusCancelled, cancelled.Status)
+
+	page, err := c.ListBookings(context.Background(), 0, booking.MaxPageSize)
+	require.Nil(t, err)
+	statuses := map[string]client.Status{}
+	for _, b := range page.Bookings {
+		statuses[b.Id] = b.Status
+	}
+	require.Equal(t, client.StatusCancelled, statuses[id])
+
+	_, err = c.CancelBooking(context.Background(), id)
+	require.ErrorIs(t, err, client.ErrConflict)
+	require.Equal(t, client.CodeBookingNotCancellable, client.GetCode(err))
+
+	_, err = newClient(otherApiKey).CancelBooking(context.Background(), id)
+	require.Equal(t, client.CodeBookingNotFound, client.GetCode(err))
+}
+
+func TestListBookingsPages(t *testing.T) {
+	t.Parallel()
+
+	res, err := listBookings(t, "limit=1")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = listBookings(t, "limit=1000")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	var body problem.Problem
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+	require.Equal(t, apierrors.CodeValidationFailed, body.Code)
+	require.Equal(t, "limit", body.Errors[0].Field)
+}
+
+func listBookings(t *testing.T, query string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/api/v2/shipping?%s", address, port, query), nil)
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, apiKey)
+	return http.DefaultClient.Do(req)
+}
+
+func TestQuote(t *testing.T) {
+	t.Parallel()
+
+	quote, err := newClient(apiKey).Quote(context.Background(), "SE", "DK", 400)
+	require.Nil(t, err)
+	require.Equal(t, "eu", quote.Region)
+	require.Equal(t, "huge", quote.WeightClass)
+	require.InDelta(t, 3000, quote.Price, 1e-9)
+	require.Equal(t, "SEK", quote.Currency)
+
+	_, err = newClient(apiKey).Quote(context.Background(), "SE", "DK", 0)
+	require.Equal(t, client.CodeValidationFailed, client.GetCode(err))
+}
+
+func TestRateShopping(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newClient(apiKey)
+
+	quote, err := c.Quote(ctx, "SE", "DK", 400)
+	require.Nil(t, err)
+	require.Equal(t, []client.Option{
+		{Id: "fake:standard", Carrier: "fake", ServiceLevel: "standard", Price: 500, Currency: "SEK", TransitDays: 4},
+		{Id: "express:standard", Carrier: "express", ServiceLevel: "standard", Price: 1300, Currency: "SEK", TransitDays: 1},
+	}, quote.Options)
+
+	var fastest struct {
+		Options []client.Option `json:"options"`
+	}
+	require.Equal(t, http.StatusOK, getJSON(t, "/api/v1/quote?origin=SE&destination=DK&weight=400&policy=fastest", apiKey, &fastest))
+	require.Equal(t, "express", fastest.Options[0].Carrier)
+
+	// the slow carrier of the lane does not quote within the carrier timeout
+	quote, err = c.Quote(ctx, "SE", "UG", 1)
+	require.Nil(t, err)
+	require.Len(t, quote.Options, 1)
+	require.Equal(t, "fake", quote.Options[0].Carrier)
+
+	parcels := []client.Parcel{{Weight: 400}}
+	testCases := []struct {
+		name            string
+		option          string
+		policy          client.Policy
+		expectedCode    client.Code
+		expectedCarrier string
+		expectedPrice   string
+	}{
+		{
+			name:            "preferred carrier",
+			expectedCarrier: "fake",
+			expectedPrice:   "3000.00",
+		},
+		{
+			name:            "cheapest",
+			policy:          client.PolicyCheapest,
+			expectedCarrier: "fake",
+			expectedPrice:   "500.00",
+		},
+		{
+			name:            "fastest",
+			policy:          client.PolicyFastest,
+			expectedCarrier: "express",
+			expectedPrice:   "1300.00",
+		},
+		{
+			name:            "option",
+			option:          "express:standard",
+			expectedCarrier: "express",
+			expectedPrice:   "1300.00",
+		},
+		{
+			name:         "unknown option",
+			option:       "slow:standard",
+			expectedCode: client.CodeOptionNotFound,
+		},
+		{
+			name:         "option and policy",
+			option:       "express:standard",
+			policy:       client.PolicyFastest,
+			expectedCode: client.CodeValidationFailed,
+		},
+		{
+			name:         "unknown policy",
+			policy:       "slowest",
+			expectedCode: client.CodeValidationFailed,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			id, err := c.BookShippingV2(ctx, client.BookingRequestV2{
+				Origin:      client.Address{Country: "SE"},
+				Destination: client.Address{Country: "DK"},
+				Parcels:     parcels,
+				Option:      tc.option,
+				Policy:      tc.policy,
+			})
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, client.GetCode(err))
+				return
+			}
+
+			require.Nil(t, err)
+			b, err := c.GetBookingV2(ctx, id)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedCarrier, b.Carrier)
+			require.Equal(t, tc.expectedPrice, b.Price.Amount)
+		})
+	}
+}
+
+func TestServiceLevels(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newClient(apiKey)
+
+	var quote client.Quote
+	require.Equal(t, http.StatusOK, getJSON(t, "/api/v1/quote?origin=SE&destination=DE&weight=5&serviceLevel=express", apiKey, &quote))
+	require.Equal(t, client.ServiceLevelExpress, quote.ServiceLevel)
+	require.InDelta(t, 270, quote.Price, 1e-3)
+	require.Equal(t, 1, quote.TransitDays)
+	require.NotEmpty(t, quote.EstimatedPickup)
+	require.NotEmpty(t, quote.EstimatedDelivery)
+	require.Len(t, quote.Options, 1)
+	require.Equal(t, "levels:express", quote.Options[0].Id)
+
+	standard, err := c.Quote(ctx, "SE", "DE", 5)
+	require.Nil(t, err)
+	require.Equal(t, client.ServiceLevelStandard, standard.ServiceLevel)
+	require.InDelta(t, 150, standard.Price, 1e-3)
+	require.Len(t, standard.Options, 3)
+
+	var problem struct {
+		Code client.Code `json:"code"`
+	}
+	require.Equal(t, http.StatusBadRequest, getJSON(t, "/api/v1/quote?origin=SE&destination=DK&weight=5&serviceLevel=economy", apiKey, &problem))
+	require.Equal(t, client.CodeServiceLevelNotOffered, problem.Code)
+
+	testCases := []struct {
+		name                string
+		option              string
+		policy              client.Policy
+		serviceLevel        client.ServiceLevel
+		expectedCode        client.Code
+		expectedPrice       string
+		expectedLevel       client.ServiceLevel
+		expectedTransitDays int
+	}{
+		{
+			name:                "tariff",
+			expectedPrice:       "150.00",
+			expectedLevel:       client.ServiceLevelStandard,
+			expectedTransitDays: 3,
+		},
+		{
+			name:                "tariff of service level",
+			serviceLevel:        client.ServiceLevelExpress,
+			expectedPrice:       "270.00",
+			expectedLevel:       client.ServiceLevelExpress,
+			expectedTransitDays: 1,
+		},
+		{
+			name:                "cheapest at service level",
+			policy:              client.PolicyCheapest,
+			serviceLevel:        client.ServiceLevelEconomy,
+			expectedPrice:       "84.00",
+			expectedLevel:       client.ServiceLevelEconomy,
+			expectedTransitDays: 5,
+		},
+		{
+			name:                "option",
+			option:              "levels:express",
+			expectedPrice:       "189.00",
+			expectedLevel:       client.ServiceLevelExpress,
+			expectedTransitDays: 1,
+		},
+		{
+			name:         "option at another service level",
+			option:       "levels:express",
+			serviceLevel: client.ServiceLevelEconomy,
+			expectedCode: client.CodeOptionNotFound,
+		},
+		{
+			name:         "unknown service level",
+			serviceLevel: "overnight",
+			expectedCode: client.CodeValidationFailed,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			id, err := c.BookShippingV2(ctx, client.BookingRequestV2{
+				Origin:       client.Address{Country: "SE"},
+				Destination:  client.Address{Country: "DE"},
+				Parcels:      []client.Parcel{{Weight: 5}},
+				Option:       tc.option,
+				Policy:       tc.policy,
+				ServiceLevel: tc.serviceLevel,
+			})
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, client.GetCode(err))
+				return
+			}
+
+			require.Nil(t, err)
+			b, err := c.GetBookingV2(ctx, id)
+			require.Nil(t, err)
+			require.Equal(t, "levels", b.Carrier)
+			require.Equal(t, tc.expectedPrice, b.Price.Amount)
+			require.Equal(t, tc.expectedLevel, b.ServiceLevel)
+			require.Equal(t, tc.expectedTransitDays, b.TransitDays)
+			require.NotEmpty(t, b.EstimatedPickup)
+			require.NotEmpty(t, b.EstimatedDelivery)
+		})
+	}
+}
+
+func TestPickups(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newClient(apiKey)
+	operator := client.New(client.Config{BaseUrl: fmt.Sprintf("http://%s:%d", address, port), Token: operatorToken})
+
+	// 2031-01-06 is a monday no other test picks up on
+	const date = "2031-01-06"
+	slots, err := c.PickupSlots(ctx, "SE", date, 1)
+	require.Nil(t, err)
+	require.Equal(t, []client.Slot{
+		{Date: date, Window: "09:00-12:00", Capacity: 2, Available: 2},
+		{Date: date, Window: "13:00-17:00", Capacity: 2, Available: 2},
+	}, slots.Slots)
+
+	ids := make([]string, 3)
+	for i := range ids {
+		ids[i], err = c.BookShipping(ctx, "SE", "DK", 1)
+		require.Nil(t, err)
+	}
+	morning := client.PickupRequest{Date: date, Window: "09:00-12:00"}
+	afternoon := client.PickupRequest{Date: date, Window: "13:00-17:00"}
+
+	p, err := c.SchedulePickup(ctx, ids[0], morning)
+	require.Nil(t, err)
+	require.Equal(t, ids[0], p.BookingId)
+	require.Equal(t, "SE", p.Origin)
+	require.Equal(t, date, p.Date)
+	require.Equal(t, "09:00-12:00", p.Window)
+	_, err = c.SchedulePickup(ctx, ids[1], morning)
+	require.Nil(t, err)
+	_, err = c.SchedulePickup(ctx, ids[2], morning)
+	require.Equal(t, client.CodePickupSlotFull, client.GetCode(err))
+	_, err = c.SchedulePickup(ctx, ids[0], afternoon)
+	require.Equal(t, client.CodePickupAlreadyScheduled, client.GetCode(err))
+	_, err = c.SchedulePickup(ctx, ids[2], client.PickupRequest{Date: "2031-01-04", Window: "09:00-12:00"})
+	require.Equal(t, client.CodePickupSlotUnavailable, client.GetCode(err))
+
+	p, err = c.ReschedulePickup(ctx, ids[1], afternoon)
+	require.Nil(t, err)
+	require.Equal(t, "13:00-17:00", p.Window)
+	_, err = c.SchedulePickup(ctx, ids[2], morning)
+	require.Nil(t, err, "expected the rescheduled pickup to release its slot")
+
+	slots, err = c.PickupSlots(ctx, "SE", date, 1)
+	require.Nil(t, err)
+	require.Equal(t, 0, slots.Slots[0].Available)
+	require.Equal(t, 1, slots.Slots[1].Available)
+
+	pickups, err := operator.ListPickups(ctx, "SE", date)
+	require.Nil(t, err)
+	require.Len(t, pickups, 3)
+	require.Equal(t, []string{"09:00-12:00", "09:00-12:00", "13:00-17:00"}, []string{pickups[0].Window, pickups[1].Window, pickups[2].Window})
+	require.Equal(t, "test-client", pickups[0].ClientId)
+
+	_, err = newClient(otherApiKey).GetPickup(ctx, ids[0])
+	require.Equal(t, client.CodeBookingNotFound, client.GetCode(err))
+
+	require.Nil(t, c.CancelPickup(ctx, ids[2]))
+	_, err = c.GetPickup(ctx, ids[2])
+	require.Equal(t, client.CodePickupNotFound, client.GetCode(err))
+
+	_, err = c.CancelBooking(ctx, ids[0])
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		_, err := c.GetPickup(ctx, ids[0])
+		return client.GetCode(err) == client.CodePickupNotFound
+	}, 5*time.Second, 10*time.Millisecond, "expected the pickup of the cancelled booking to be released")
+	_, err = c.SchedulePickup(ctx, ids[0], morning)
+	require.Equal(t, client.CodeBookingNotPickable, client.GetCode(err))
+}
+
+func TestTracking(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newClient(apiKey)
+	operator := client.New(client.Config{BaseUrl: fmt.Sprintf("http://%s:%d", address, port), Token: operatorToken})
+
+	ids := make([]string, 2)
+	var err error
+	for i := range ids {
+		ids[i], err = c.BookShipping(ctx, "SE", "DK", 1)
+		require.Nil(t, err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	pickedUp := client.TrackingEvent{BookingId: ids[0], Timestamp: now.Add(time.Minute), Location: "SE-STO", Code: client.TrackingPickedUp}
+
+	result, err := operator.IngestTrackingEvents(ctx,
+		client.TrackingEvent{BookingId: ids[0], Timestamp: now.Add(3 * time.Minute), Location: "DK-CPH", Code: client.TrackingDelivered, Description: "Left at the door"},
+		pickedUp,
+		client.TrackingEvent{BookingId: ids[0], Timestamp: now.Add(2 * time.Minute), Location: "DK-CPH", Code: client.TrackingOutForDelivery},
+		client.TrackingEvent{BookingId: ids[1], Timestamp: now.Add(time.Minute), Location: "SE-GOT", Code: client.TrackingPickedUp},
+	)
+	require.Nil(t, err)
+	require.Equal(t, client.IngestResult{Accepted: 4}, *result)
+	result, err = operator.IngestTrackingEvents(ctx, pickedUp)
+	require.Nil(t, err)
+	require.Equal(t, client.IngestResult{Duplicates: 1}, *result)
+
+	tracking, err := c.GetTracking(ctx, ids[0])
+	require.Nil(t, err)
+	require.Equal(t, client.StatusDelivered, tracking.Status)
+	require.Len(t, tracking.Events, 3)
+	require.Equal(t, []client.TrackingCode{client.TrackingPickedUp, client.TrackingOutForDelivery, client.TrackingDelivered},
+		[]client.TrackingCode{tracking.Events[0].Code, tracking.Events[1].Code, tracking.Events[2].Code})
+	require.Equal(t, now.Add(time.Minute), tracking.Events[0].Timestamp)
+	require.Equal(t, "Left at the door", tracking.Events[2].Description)
+	require.NotEmpty(t, tracking.Events[0].Id)
+
+	b, err := c.GetBooking(ctx, ids[1])
+	require.Nil(t, err)
+	require.Equal(t, client.StatusPickedUp, b.Status)
+	_, err = c.CancelBooking(ctx, ids[1])
+	require.Equal(t, client.CodeBookingNotCancellable, client.GetCode(err))
+
+	_, err = newClient(otherApiKey).GetTracking(ctx, ids[0])
+	require.Equal(t, client.CodeBookingNotFound, client.GetCode(err))
+	_, err = operator.IngestTrackingEvents(ctx, client.TrackingEvent{BookingId: "unknown", Timestamp: now, Location: "SE-STO", Code: client.TrackingPickedUp})
+	require.Equal(t, client.CodeBookingNotFound, client.GetCode(err))
+	_, err = operator.IngestTrackingEvents(ctx, pickedUp, client.TrackingEvent{BookingId: ids[1], Timestamp: now, Location: "SE-STO", Code: "lost"})
+	require.Equal(t, client.CodeValidationFailed, client.GetCode(err))
+	require.Equal(t, "[1].code", client.GetFields(err)[0].Field)
+}
+
+func TestCalendars(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newClient(apiKey)
+
+	se, err := c.GetCalendar(ctx, "SE")
+	require.Nil(t, err)
+	require.False(t, se.Default)
+	require.Equal(t, "Europe/Stockholm", se.TimeZone)
+	require.Equal(t, "15:00", se.CutOff)
+	require.Equal(t, []client.Holiday{
+		{Date: "2026-12-24", Name: "Christmas Eve", Yearly: true},
+		{Date: "2026-12-25", Name: "Christmas Day", Yearly: true},
+	}, se.Holidays)
+
+	dk, err := c.GetCalendar(ctx, "DK")
+	require.Nil(t, err)
+	require.True(t, dk.Default)
+	require.Equal(t, "UTC", dk.TimeZone)
+	require.Empty(t, dk.Holidays)
+
+	testCases := []struct {
+		name           string
+		location       string
+		at             time.Time
+		expectedPickup string
+	}{
+		{
+			name:           "before the cut-off",
+			location:       "SE",
+			at:             time.Date(2026, 12, 22, 13, 59, 0, 0, time.UTC),
+			expectedPickup: "2026-12-22",
+		},
+		{
+			name:           "after the cut-off in the time zone",
+			location:       "SE",
+			at:             time.Date(2026, 12, 22, 14, 0, 0, 0, time.UTC),
+			expectedPickup: "2026-12-23",
+		},
+		{
+			name:           "over holidays and a weekend",
+			location:       "SE",
+			at:             time.Date(2027, 12, 23, 16, 0, 0, 0, time.UTC),
+			expectedPickup: "2027-12-27",
+		},
+		{
+			name:           "default calendar",
+			location:       "DK",
+			at:             time.Date(2026, 12, 24, 16, 0, 0, 0, time.UTC),
+			expectedPickup: "2026-12-24",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pickup, err := c.NextPickup(ctx, tc.location, tc.at)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedPickup, pickup.Pickup)
+		})
+	}
+
+	var problem struct {
+		Code client.Code `json:"code"`
+	}
+	require.Equal(t, http.StatusBadRequest, getJSON(t, "/api/v2/calendars/SE/pickup?at=tomorrow", apiKey, &problem))
+	require.Equal(t, client.CodeInvalidRequest, problem.Code)
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver answers every delivery with status and passes it on.
+func webhookReceiver(status int) (*httptest.Server, chan receivedWebhook) {
+	received := make(chan receivedWebhook, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	return srv, received
+}
+
+func postJSON(t *testing.T, path, key string, body interface{}) *http.Response {
+	b, err := json.Marshal(body)
+	require.Nil(t, err)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%d%s", address, port, path), strings.NewReader(string(b)))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apiKeyHeader, key)
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	return res
+}
+
+func getJSON(t *testing.T, path, key string, v interface{}) int {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d%s", address, port, path), nil)
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, key)
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Nil(t, json.NewDecoder(res.Body).Decode(v))
+	return res.StatusCode
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+
+	receiver, received := webhookReceiver(http.StatusNoContent)
+	defer receiver.Close()
+
+	key := webhookApiKey
+
+	res := postJSON(t, "/api/v1/webhooks", key, map[string]interface{}{
+		"url":    receiver.URL,
+		"events": []string{"booking.created", "booking.cancelled"},
+	})
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var sub struct {
+		Id     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&sub))
+	require.NotEqual(t, "", sub.Secret)
+
+	var list struct {
+		Subscriptions []map[string]interface{} `json:"subscriptions"`
+	}
+	require.Equal(t, http.StatusOK, getJSON(t, "/api/v1/webhooks", key, &list))
+	require.Len(t, list.Subscriptions, 1)
+	require.NotContains(t, list.Subscriptions[0], "secret", "expected the secret to only be returned once")
+
+	c := newClient(key)
+	id, err := c.BookShipping(context.Background(), "SE", "DK", 3)
+	require.Nil(t, err)
+	_, err = c.CancelBooking(context.Background(), id)
+	require.Nil(t, err)
+
+	for _, expected := range []string{"booking.created", "booking.cancelled"} {
+		select {
+		case r := <-received:
+			timestamp, err := strconv.ParseInt(r.header.Get(webhook.TimestampHeader), 10, 64)
+			require.Nil(t, err)
+			require.Equal(t, webhook.Sign(sub.Secret, r.header.Get(webhook.IdHeader), time.Unix(timestamp, 0), r.body), r.header.Get(webhook.SignatureHeader))
+
+			var event struct {
+				Id   string `json:"id"`
+				Type string `json:"type"`
+				Data struct {
+					BookingId string `json:"bookingId"`
+				} `json:"data"`
+			}
+			require.Nil(t, json.Unmarshal(r.body, &event))
+			require.Equal(t, expected, event.Type)
+			require.Equal(t, id, event.Data.BookingId)
+			require.Equal(t, r.header.Get(webhook.IdHeader), event.Id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %s to be delivered", expected)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://%s:%d/api/v1/webhooks/%s", address, port, sub.Id), nil)
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, otherApiKey)
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode, "expected other clients to not see the subscription")
+
+	req.Header.Set(apiKeyHeader, key)
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	receiver, received := webhookReceiver(http.StatusInternalServerError)
+	defer receiver.Close()
+
+	key := deadLetterApiKey
+
+	res := postJSON(t, "/api/v2/webhooks", key, map[string]interface{}{"url": receiver.URL, "events": []string{"booking.created"}})
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	_, err := newClient(key).BookShipping(context.Background(), "SE", "DK", 3)
+	require.Nil(t, err)
+
+	type deadLetters struct {
+		Deliveries []struct {
+			Id             string `json:"id"`
+			Status         string `json:"status"`
+			Attempts       int    `json:"attempts"`
+			LastStatusCode int    `json:"lastStatusCode"`
+		} `json:"deliveries"`
+	}
+	var letters deadLetters
+	require.Eventually(t, func() bool {
+		letters = deadLetters{}
+		getJSON(t, "/api/v2/webhooks/dead-letters", key, &letters)
+		return len(letters.Deliveries) == 1
+	}, 5*time.Second, 20*time.Millisecond, "expected the delivery to be dead lettered")
+	require.Equal(t, "failed", letters.Deliveries[0].Status)
+	require.Equal(t, 2, letters.Deliveries[0].Attempts)
+	require.Equal(t, http.StatusInternalServerError, letters.Deliveries[0].LastStatusCode)
+	require.Len(t, received, 2)
+
+	res = postJSON(t, "/api/v2/webhooks/deliveries/"+letters.Deliveries[0].Id+"/redeliver", otherApiKey, nil)
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode, "expected other clients to not see the delivery")
+
+	res = postJSON(t, "/api/v2/webhooks/deliveries/"+letters.Deliveries[0].Id+"/redeliver", key, nil)
+	res.Body.Close()
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	require.Eventually(t, func() bool { return len(received) == 4 }, 5*time.Second, 20*time.Millisecond, "expected the delivery to be attempted again")
+}
+
+type sentEvent struct {
+	id   string
+	name string
+	data string
+}
+
+// openStream opens an event stream and returns a reader for its events, the
+// stream is closed when the test ends.
+func openStream(t *testing.T, path, header, value, lastEventId string) (*http.Response, func() sentEvent) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d%s", address, port, path), nil)
+	require.Nil(t, err)
+	req.Header.Set(header, value)
+	if lastEventId != "" {
+		req.Header.Set(stream.LastEventIdHeader, lastEventId)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+
+	r := bufio.NewReader(res.Body)
+	// next returns the next event, a heartbeat is returned as an event named
+	// heartbeat
+	next := func() sentEvent {
+		var e sentEvent
+		for {
+			line, err := r.ReadString('\n')
+			require.Nil(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && (e.id != "" || e.name != ""):
+				return e
+			case line == ": heartbeat":
+				e.name = "heartbeat"
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+	return res, next
+}
+
+// nextEvent skips heartbeats.
+func nextEvent(next func() sentEvent) sentEvent {
+	for {
+		if e := next(); e.name != "heartbeat" {
+			return e
+		}
+	}
+}
+
+func TestBookingEvents(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(apiKey)
+	id, err := c.BookShipping(context.Background(), "SE", "DK", 3)
+	require.Nil(t, err)
+	path := "/api/v1/shipping/" + id + "/events"
+
+	res, next := openStream(t, path, apiKeyHeader, apiKey, "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	created := nextEvent(next)
+	require.Equal(t, "booking.created", created.name)
+	priced := nextEvent(next)
+	require.Equal(t, "booking.priced", priced.name)
+	var data struct {
+		Id        string  `json:"id"`
+		BookingId string  `json:"bookingId"`
+		Price     float32 `json:"price"`
+	}
+	require.Nil(t, json.Unmarshal([]byte(priced.data), &data))
+	require.Equal(t, priced.id, data.Id)
+	require.Equal(t, id, data.BookingId)
+	require.Equal(t, float32(150), data.Price)
+
+	_, err = c.CancelBooking(context.Background(), id)
+	require.Nil(t, err)
+	require.Equal(t, "booking.cancelled", nextEvent(next).name)
+	require.Equal(t, "heartbeat", next().name, "expected a heartbeat on an idle stream")
+
+	_, resumed := openStream(t, path, apiKeyHeader, apiKey, created.id)
+	require.Equal(t, priced.id, nextEvent(resumed).id, "expected the stream to resume after Last-Event-ID")
+	require.Equal(t, "booking.cancelled", nextEvent(resumed).name)
+
+	res, _ = openStream(t, path, apiKeyHeader, otherApiKey, "")
+	require.Equal(t, http.StatusNotFound, res.StatusCode, "expected other clients to not see the booking")
+}
+
+func TestEventsFirehose(t *testing.T) {
+	t.Parallel()
+
+	res, _ := openStream(t, "/api/v2/events", apiKeyHeader, apiKey, "")
+	require.Equal(t, http.StatusForbidden, res.StatusCode, "expected customers to not see other clients")
+
+	res, next := openStream(t, "/api/v2/events", authorizationHeader, bearerPrefix+operatorToken, "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	id, err := newClient(otherApiKey).BookShipping(context.Background(), "SE", "DK", 3)
+	require.Nil(t, err)
+	for {
+		e := nextEvent(next)
+		var data struct {
+			BookingId string `json:"bookingId"`
+			ClientId  string `json:"clientId"`
+		}
+		require.Nil(t, json.Unmarshal([]byte(e.data), &data))
+		if data.BookingId == id {
+			require.Equal(t, "booking.created", e.name)
+			require.Equal(t, "other-client", data.ClientId)
+			return
+		}
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedField  string
+	}{
+		{
+			name:           "missing field",
+			method:         http.MethodPost,
+			path:           "/api/shipping",
+			body:           `{"origin":"SE","destination":"DK"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "validation_failed",
+			expectedField:  "weight",
+		},
+		{
+			name:           "malformed body",
+			method:         http.MethodPost,
+			path:           "/api/shipping",
+			body:           `{"origin":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_request",
+		},
+		{
+			name:           "unknown location",
+			method:         http.MethodPost,
+			path:           "/api/shipping",
+			body:           `{"origin":"XX","destination":"DK","weight":10}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "location_not_found",
+		},
+		{
+			name:           "unknown booking",
+			method:         http.MethodGet,
+			path:           "/api/shipping/does-not-exist",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "booking_not_found",
+		},
+		{
+			name:           "unknown route",
+			method:         http.MethodGet,
+			path:           "/api/nothing",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "route_not_found",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(tc.method, fmt.Sprintf("http://%s:%d%s", address, port, tc.path), strings.NewReader(tc.body))
+			require.Nil(t, err)
+			req.Header.Set(apiKeyHeader, apiKey)
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.expectedStatus, res.StatusCode)
+			require.Equal(t, problem.ContentType, res.Header.Get("Content-Type"))
+
+			var p problem.Problem
+			require.Nil(t, json.NewDecoder(res.Body).Decode(&p))
+			require.Equal(t, tc.expectedStatus, p.Status)
+			require.Equal(t, tc.expectedCode, string(p.Code))
+			require.Equal(t, tc.path, p.Instance)
+			require.NotEqual(t, "", p.RequestId)
+			if tc.expectedField != "" {
+				require.Len(t, p.Errors, 1)
+				require.Equal(t, tc.expectedField, p.Errors[0].Field)
+			}
+		})
+	}
+}
+
+func TestUnauthenticated(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		apiKey string
+	}{
+		{
+			name:   "missing api key",
+			apiKey: "",
+		},
+		{
+			name:   "invalid api key",
+			apiKey: "invalid",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/api/shipping/some-id", address, port), nil)
+			require.Nil(t, err)
+			if tc.apiKey != "" {
+				req.Header.Set(apiKeyHeader, tc.apiKey)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{
+			name:           "customer token can book",
+			method:         http.MethodPost,
+			path:           "/api/shipping",
+			header:         authorizationHeader,
+			value:          bearerPrefix + customerToken,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "api key can not read tariffs",
+			method:         http.MethodGet,
+			path:           "/api/tariffs",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "operator can read tariffs",
+			method:         http.MethodGet,
+			path:           "/api/tariffs",
+			header:         authorizationHeader,
+			value:          bearerPrefix + operatorToken,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "api key can not list pickups",
+			method:         http.MethodGet,
+			path:           "/api/v1/pickups?origin=SE&date=2030-01-07",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "api key can list pickup slots",
+			method:         http.MethodGet,
+			path:           "/api/v1/pickups/slots?origin=SE",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "api key can not ingest tracking events",
+			method:         http.MethodPost,
+			path:           "/api/v1/tracking/events",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "operator can not revoke keys",
+			method:         http.MethodDelete,
+			path:           "/api/admin/apikeys/some-id",
+			header:         authorizationHeader,
+			value:          bearerPrefix + operatorToken,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin can revoke keys",
+			method:         http.MethodDelete,
+			path:           "/api/admin/apikeys/some-id",
+			header:         authorizationHeader,
+			value:          bearerPrefix + adminToken,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid token",
+			method:         http.MethodGet,
+			path:           "/api/tariffs",
+			header:         authorizationHeader,
+			value:          bearerPrefix + "invalid",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(tc.method, fmt.Sprintf("http://%s:%d%s", address, port, tc.path), nil)
+			require.Nil(t, err)
+			req.Header.Set(tc.header, tc.value)
+
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.expectedStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	request := func(path string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d%s", address, port, path), nil)
+		require.Nil(t, err)
+		req.Header.Set(authorizationHeader, bearerPrefix+customerToken)
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := request("/api/tariffs")
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Equal(t, "1", res.Header.Get("RateLimit-Limit"))
+	require.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+
+	res = request("/api/tariffs")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.NotEqual(t, "", res.Header.Get("Retry-After"))
+
+	// the versions of a route share its limit
+	for _, path := range []string{"/api/v1/tariffs", "/api/v2/tariffs"} {
+		res = request(path)
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		require.Equal(t, "1", res.Header.Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitUnauthenticated(t *testing.T) {
+	t.Parallel()
+
+	request := func(key string) *http.Response {
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://%s:%d/api/admin/apikeys/some-id", address, port), nil)
+		require.Nil(t, err)
+		req.Header.Set(apiKeyHeader, key)
+
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := request("invalid")
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, "0", res.Header.Get("RateLimit-Remaining"))
+
+	// guessing keys is limited by the remote address
+	res = request("guessed")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.NotEqual(t, "", res.Header.Get("Retry-After"))
+
+	// while clients are limited on their own
+	res = request(apiKey)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(apiKey)
+	_, err := client.BookShipping(context.Background(), "DE", "US", 5)
+	require.Nil(t, err)
+
+	res, err := http.Get(fmt.Sprintf("http://%s:%d/metrics", address, port))
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := io.ReadAll(res.Body)
+	require.Nil(t, err)
+	require.Contains(t, string(body), `shipping_api_http_requests_total{method="POST",route="/api/v1/shipping",status="201"}`)
+	require.Contains(t, string(body), `shipping_api_booking_bookings_created_total{destination="US",origin="DE"}`)
+	require.Contains(t, string(body), `shipping_api_billing_quoted_price_sek_count{region="international"}`)
+	require.Contains(t, string(body), `shipping_api_store_operation_duration_seconds_count{operation="add_booking",outcome="success",store="booking"}`)
+}
+
+func TestTracePropagation(t *testing.T) {
+	t.Parallel()
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "test")
+	client := newClient(apiKey)
+	_, err := client.BookShipping(ctx, "SE", "US", 5)
+	require.Nil(t, err)
+	span.End()
+
+	names := make(map[string]bool)
+	for _, s := range spanRecorder.GetSpans() {
+		if s.SpanContext.TraceID() == span.SpanContext().TraceID() {
+			names[s.Name] = true
+		}
+	}
+	require.True(t, names["POST /api/v1/shipping"], "expected server span in trace")
+	require.True(t, names["booking.Service.BookShipping"], "expected booking span in trace")
+	require.True(t, names["billing.Service.CalculateShippingCost"], "expected billing span in trace")
+	require.True(t, names["locationStore.GetByCode"], "expected location store span in trace")
+	require.True(t, names["bookingStore.AddBooking"], "expected booking store span in trace")
+}
+
+func TestRequestId(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		requestId  string
+		shouldKeep bool
+	}{
+		{
+			name:       "valid request id",
+			requestId:  "test-request-id",
+			shouldKeep: true,
+		},
+		{
+			name:       "missing request id",
+			requestId:  "",
+			shouldKeep: false,
+		},
+		{
+			name:       "invalid request id",
+			requestId:  "invalid request id",
+			shouldKeep: false,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/health", address, port), nil)
+			require.Nil(t, err)
+			req.Header.Set(requestIdHeader, tc.requestId)
 
-	id, err := client.BookShipping("SE", "DK", 400)
-	require.Nil(t, err)
-	require.NotEqual(t, "", id)
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+
+			requestId := res.Header.Get(requestIdHeader)
+			require.NotEqual(t, "", requestId)
+			if tc.shouldKeep {
+				require.Equal(t, tc.requestId, requestId)
+			} else {
+				require.NotEqual(t, tc.requestId, requestId)
+			}
+		})
+	}
 }
 
-func TestGetBooking(t *testing.T) {
+func TestProbes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus string
+	}{
+		{
+			name:           "liveness",
+			path:           "/livez",
+			expectedStatus: "alive",
+		},
+		{
+			name:           "readiness",
+			path:           "/readyz",
+			expectedStatus: "ready",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			res, err := http.Get(fmt.Sprintf("http://%s:%d%s", address, port, tc.path))
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			var body map[string]interface{}
+			require.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+			require.Equal(t, tc.expectedStatus, body["status"])
+		})
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
 	t.Parallel()
 
-	client := NewClient(fmt.Sprintf("http://%s:%d", address, port))
+	res, err := http.Get(fmt.Sprintf("http://%s:%d/openapi.json", address, port))
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var doc openapi.Document
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&doc))
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+
+	paths := []string{}
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	require.ElementsMatch(t, []string{
+		"/health", "/livez", "/readyz", "/metrics", "/openapi.json",
+		"/api/shipping", "/api/shipping/{id}", "/api/shipping/{id}/cancel", "/api/shipping/{id}/events", "/api/shipping/{id}/pickup", "/api/shipping/{id}/tracking", "/api/pickups", "/api/pickups/slots", "/api/tariffs", "/api/quote", "/api/calendars/{location}", "/api/calendars/{location}/pickup", "/api/tracking/events", "/api/events",
+		"/api/admin/apikeys", "/api/admin/apikeys/{id}",
+		"/api/webhooks", "/api/webhooks/{id}", "/api/webhooks/dead-letters", "/api/webhooks/deliveries/{id}/redeliver",
+		"/api/v1/shipping", "/api/v1/shipping/{id}", "/api/v1/shipping/{id}/cancel", "/api/v1/shipping/{id}/events", "/api/v1/shipping/{id}/pickup", "/api/v1/shipping/{id}/tracking", "/api/v1/pickups", "/api/v1/pickups/slots", "/api/v1/tariffs", "/api/v1/quote", "/api/v1/calendars/{location}", "/api/v1/calendars/{location}/pickup", "/api/v1/tracking/events", "/api/v1/events",
+		"/api/v1/admin/apikeys", "/api/v1/admin/apikeys/{id}",
+		"/api/v1/webhooks", "/api/v1/webhooks/{id}", "/api/v1/webhooks/dead-letters", "/api/v1/webhooks/deliveries/{id}/redeliver",
+		"/api/v2/shipping", "/api/v2/shipping/{id}", "/api/v2/shipping/{id}/cancel", "/api/v2/shipping/{id}/events", "/api/v2/shipping/{id}/pickup", "/api/v2/shipping/{id}/tracking", "/api/v2/pickups", "/api/v2/pickups/slots", "/api/v2/tariffs", "/api/v2/quote", "/api/v2/calendars/{location}", "/api/v2/calendars/{location}/pickup", "/api/v2/tracking/events", "/api/v2/events",
+		"/api/v2/admin/apikeys", "/api/v2/admin/apikeys/{id}",
+		"/api/v2/webhooks", "/api/v2/webhooks/{id}", "/api/v2/webhooks/dead-letters", "/api/v2/webhooks/deliveries/{id}/redeliver",
+		"/graphql",
+	}, paths)
+
+	operationIds := map[string]bool{}
+	for _, item := range doc.Paths {
+		for _, op := range []*openapi.Operation{item.Get, item.Post, item.Put, item.Patch, item.Delete} {
+			if op == nil {
+				continue
+			}
+			require.False(t, operationIds[op.OperationId], "duplicate operation id %s", op.OperationId)
+			operationIds[op.OperationId] = true
+		}
+	}
+	legacy, ok := doc.Operation(http.MethodPost, "/api/shipping")
+	require.True(t, ok)
+	require.True(t, legacy.Deprecated)
 
-	id, err := client.BookShipping("SE", "DK", 400)
+	client := newClient(apiKey)
+	id, err := client.BookShipping(context.Background(), "SE", "DK", 12)
 	require.Nil(t, err)
 	require.NotEqual(t, "", id)
 
-	booking, err := client.GetBooking(id)
-	require.InDelta(t, 400, booking["weight"], 1e-9)
-	require.Equal(t, "SE", booking["origin"])
-	require.Equal(t, "DK", booking["destination"])
+	testCases := []struct {
+		name   string
+		method string
+		route  string
+		path   string
+		header string
+		value  string
+		status string
+	}{
+		{
+			name:   "get booking",
+			method: http.MethodGet,
+			route:  "/api/shipping/:id",
+			path:   "/api/shipping/" + id,
+			header: apiKeyHeader,
+			value:  apiKey,
+			status: "200",
+		},
+		{
+			name:   "get booking v2",
+			method: http.MethodGet,
+			route:  "/api/v2/shipping/:id",
+			path:   "/api/v2/shipping/" + id,
+			header: apiKeyHeader,
+			value:  apiKey,
+			status: "200",
+		},
+		{
+			name:   "get tariffs",
+			method: http.MethodGet,
+			route:  "/api/v1/tariffs",
+			path:   "/api/v1/tariffs",
+			header: authorizationHeader,
+			value:  bearerPrefix + adminToken,
+			status: "200",
+		},
+		{
+			name:   "readiness",
+			method: http.MethodGet,
+			route:  "/readyz",
+			path:   "/readyz",
+			status: "200",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			op, ok := doc.Operation(tc.method, tc.route)
+			require.True(t, ok, "expected operation to be documented")
+			schema := op.Responses[tc.status].Content["application/json"].Schema
+			require.NotNil(t, schema)
+
+			req, err := http.NewRequest(tc.method, fmt.Sprintf("http://%s:%d%s", address, port, tc.path), nil)
+			require.Nil(t, err)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.status, fmt.Sprint(res.StatusCode))
+
+			decoder := json.NewDecoder(res.Body)
+			decoder.UseNumber()
+			var value interface{}
+			require.Nil(t, decoder.Decode(&value))
+			require.Empty(t, schema.Validate(value))
+		})
+	}
+}
+
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, header, value string, body map[string]interface{}) (int, graphqlResponse) {
+	b, err := json.Marshal(body)
+	require.Nil(t, err)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%d/graphql", address, port), strings.NewReader(string(b)))
+	require.Nil(t, err)
+	req.Header.Set(header, value)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+
+	var gr graphqlResponse
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&gr))
+	return res.StatusCode, gr
+}
+
+func TestGraphQL(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(apiKey)
+	id, err := client.BookShipping(context.Background(), "SE", "DK", 400)
+	require.Nil(t, err)
+
+	status, res := postGraphQL(t, apiKeyHeader, apiKey, map[string]interface{}{
+		"query": `query Booking($id: ID!) {
+			booking(id: $id) {
+				id price currency status serviceLevel transitDays estimatedPickup estimatedDelivery
+				origin { code euMember }
+				destination { code }
+				priceBreakdown { region weightClass rate basePrice total serviceLevel multiplier }
+				statusHistory { status at }
+			}
+		}`,
+		"variables": map[string]interface{}{"id": id},
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, res.Errors)
+
+	booking := res.Data["booking"].(map[string]interface{})
+	require.Equal(t, id, booking["id"])
 	require.InDelta(t, 3000, booking["price"], 1e-9)
+	require.Equal(t, "BOOKED", booking["status"])
+	require.Equal(t, "STANDARD", booking["serviceLevel"])
+	require.EqualValues(t, 3, booking["transitDays"])
+	require.NotEmpty(t, booking["estimatedPickup"])
+	require.NotEmpty(t, booking["estimatedDelivery"])
+	require.Equal(t, map[string]interface{}{"code": "SE", "euMember": true}, booking["origin"])
+	breakdown := booking["priceBreakdown"].([]interface{})
+	require.Len(t, breakdown, 1)
+	require.Equal(t, "eu", breakdown[0].(map[string]interface{})["region"])
+	require.InDelta(t, 3000, breakdown[0].(map[string]interface{})["total"], 1e-9)
+	require.Equal(t, "STANDARD", breakdown[0].(map[string]interface{})["serviceLevel"])
+	require.Len(t, booking["statusHistory"], 1)
+
+	status, res = postGraphQL(t, apiKeyHeader, apiKey, map[string]interface{}{
+		"query": `{ quote(origin: "SE", destination: "DK", weight: 400, serviceLevel: EXPRESS) { total serviceLevel multiplier transitDays estimatedPickup estimatedDelivery } }`,
+	})
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, res.Errors)
+	quote := res.Data["quote"].(map[string]interface{})
+	require.InDelta(t, 5400, quote["total"], 1e-3)
+	require.Equal(t, "EXPRESS", quote["serviceLevel"])
+	require.EqualValues(t, 1, quote["transitDays"])
+	require.NotEmpty(t, quote["estimatedPickup"])
+
+	testCases := []struct {
+		name           string
+		header         string
+		value          string
+		query          string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "unknown booking",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			query:          `{ booking(id: "does-not-exist") { id } }`,
+			expectedStatus: http.StatusOK,
+			expectedCode:   "booking_not_found",
+		},
+		{
+			name:           "other client's booking",
+			header:         apiKeyHeader,
+			value:          otherApiKey,
+			query:          fmt.Sprintf(`{ booking(id: %q) { id } }`, id),
+			expectedStatus: http.StatusOK,
+			expectedCode:   "booking_not_found",
+		},
+		{
+			name:           "customer can not read rates",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			query:          `{ rates { region rate } }`,
+			expectedStatus: http.StatusOK,
+			expectedCode:   "forbidden",
+		},
+		{
+			name:           "operator can read rates",
+			header:         authorizationHeader,
+			value:          bearerPrefix + operatorToken,
+			query:          `{ rates { region rate } prices { weightClass price } }`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid query",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			query:          `{ booking(id: "some-id") { nothing } }`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_query",
+		},
+		{
+			name:           "too complex",
+			header:         apiKeyHeader,
+			value:          apiKey,
+			query:          `{ bookings(limit: 100) { bookings { id priceBreakdown { total } } } }`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "query_too_complex",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			status, res := postGraphQL(t, tc.header, tc.value, map[string]interface{}{"query": tc.query})
+			require.Equal(t, tc.expectedStatus, status)
+			if tc.expectedCode == "" {
+				require.Empty(t, res.Errors)
+				return
+			}
+			require.Len(t, res.Errors, 1)
+			require.Equal(t, tc.expectedCode, res.Errors[0].Extensions["code"])
+		})
+	}
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	t.Parallel()
+
+	query := `{ locations { code euMember } }`
+	sum := sha256.Sum256([]byte(query))
+	extensions := map[string]interface{}{
+		"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hex.EncodeToString(sum[:])},
+	}
+
+	status, res := postGraphQL(t, apiKeyHeader, apiKey, map[string]interface{}{"extensions": extensions})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "PersistedQueryNotFound", res.Errors[0].Message)
+
+	status, res = postGraphQL(t, apiKeyHeader, apiKey, map[string]interface{}{"query": query, "extensions": extensions})
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, res.Errors)
+
+	status, res = postGraphQL(t, apiKeyHeader, apiKey, map[string]interface{}{"extensions": extensions})
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, res.Errors)
+	require.Len(t, res.Data["locations"], 5)
+
+	encoded, err := json.Marshal(extensions)
+	require.Nil(t, err)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/graphql?extensions=%s", address, port, url.QueryEscape(string(encoded))), nil)
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, apiKey)
+	getRes, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer getRes.Body.Close()
+	require.Equal(t, http.StatusOK, getRes.StatusCode)
+}
+
+func TestRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%d/api/shipping", address, port), strings.NewReader(`{"origin":"SE","destination":"DK","weight":"10","color":"red"}`))
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, apiKey)
+
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	var p problem.Problem
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&p))
+	require.Equal(t, "validation_failed", string(p.Code))
+	require.Equal(t, []apierrors.FieldError{
+		{Field: "color", Code: apierrors.FieldInvalid, Message: "color is not a known field"},
+		{Field: "weight", Code: apierrors.FieldInvalidType, Message: "weight must be of type number"},
+	}, p.Errors)
+}
+
+func TestVersions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		path              string
+		expectDeprecation bool
+		expectedSuccessor string
+	}{
+		{
+			name:              "legacy",
+			path:              "/api/shipping",
+			expectDeprecation: true,
+			expectedSuccessor: `</api/v1/shipping>; rel="successor-version"`,
+		},
+		{
+			name: "v1",
+			path: "/api/v1/shipping",
+		},
+		{
+			name: "v2",
+			path: "/api/v2/shipping",
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			body := `{"origin":"SE","destination":"DK","weight":3}`
+			if tc.name == "v2" {
+				body = `{"origin":{"country":"SE"},"destination":{"country":"DK"},"parcels":[{"weight":3}]}`
+			}
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%d%s", address, port, tc.path), strings.NewReader(body))
+			require.Nil(t, err)
+			req.Header.Set(apiKeyHeader, apiKey)
+
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusCreated, res.StatusCode)
+			require.True(t, strings.HasPrefix(res.Header.Get("Location"), tc.path+"/"))
+
+			if !tc.expectDeprecation {
+				require.Equal(t, "", res.Header.Get("Deprecation"))
+				require.Equal(t, "", res.Header.Get("Sunset"))
+				return
+			}
+			require.Equal(t, fmt.Sprintf("@%d", legacyDeprecation.Unix()), res.Header.Get("Deprecation"))
+			require.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", res.Header.Get("Sunset"))
+			require.Equal(t, tc.expectedSuccessor, res.Header.Get("Link"))
+		})
+	}
+}
+
+func TestBookingV2(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"origin": {"name": "Sender", "street": "Storgatan 1", "postalCode": "11122", "city": "Stockholm", "country": "SE"},
+		"destination": {"country": "DE"},
+		"parcels": [{"weight": 5, "length": 30, "width": 20, "height": 10}, {"weight": 20}]
+	}`
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%d/api/v2/shipping", address, port), strings.NewReader(body))
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, apiKey)
+
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var created map[string]string
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&created))
+
+	req, err = http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s:%d/api/v2/shipping/%s", address, port, created["id"]), nil)
+	require.Nil(t, err)
+	req.Header.Set(apiKeyHeader, apiKey)
+
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var booking map[string]interface{}
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&booking))
+	require.Equal(t, "Stockholm", booking["origin"].(map[string]interface{})["city"])
+	require.Equal(t, "DE", booking["destination"].(map[string]interface{})["country"])
+	require.Len(t, booking["parcels"], 2)
+	require.InDelta(t, 25, booking["weight"], 1e-9)
+	// 100 for the small and 300 for the medium parcel at the eu rate of 1.5
+	require.Equal(t, map[string]interface{}{"amount": "600.00", "currency": "SEK"}, booking["price"])
+
+	client := newClient(apiKey)
+	v1Booking, err := client.GetBooking(context.Background(), created["id"])
+	require.Nil(t, err)
+	require.Equal(t, "SE", v1Booking.Origin)
+	require.InDelta(t, 600, v1Booking.Price, 1e-9)
+
+	v2Booking, err := client.GetBookingV2(context.Background(), created["id"])
+	require.Nil(t, err)
+	require.Equal(t, "Stockholm", v2Booking.Origin.City)
+	require.Len(t, v2Booking.Parcels, 2)
+	require.Equal(t, "600.00", v2Booking.Price.Amount)
 }
 
 func TestHealth(t *testing.T) {
 	t.Parallel()
 
-	client := NewClient(fmt.Sprintf("http://%s:%d", address, port))
+	client := newClient(apiKey)
 
-	status, err := client.Health()
+	status, err := client.Health(context.Background())
 	require.Nil(t, err)
 	require.Equal(t, "healthy", status)
 }
 
+const (
+	customerToken = "customer-token"
+	operatorToken = "operator-token"
+	adminToken    = "admin-token"
+)
+
+type tokenVerifierMock struct{}
+
+func (v tokenVerifierMock) Verify(_ context.Context, token string) (string, []auth.Role, error) {
+	switch token {
+	case customerToken:
+		return "customer", []auth.Role{auth.RoleCustomer}, nil
+	case operatorToken:
+		return "operator", []auth.Role{auth.RoleOperator}, nil
+	case adminToken:
+		return "admin", []auth.Role{auth.RoleAdmin}, nil
+	default:
+		return "", nil, errors.New("invalid token")
+	}
+}
+
+func newClient(key string) *client.Client {
+	return client.New(client.Config{BaseUrl: fmt.Sprintf("http://%s:%d", address, port), APIKey: key})
+}
+
 func startHttp() error {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanRecorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
 	rateStore := billing.NewInMemoryRateStore(
 		map[string]float32{
 			"domestic":      1.0,
@@ -83,6 +1753,8 @@ func startHttp() error {
 		{"UG", false},
 	}
 
+	m := metrics.New()
+
 	locationStore := billing.NewInMemoryLocationStore()
 	for _, l := range locations {
 		location, err := billing.NewLocation(l.code, l.hasEuMembership)
@@ -94,22 +1766,125 @@ func startHttp() error {
 			panic(err)
 		}
 	}
-	billingService := billing.NewService(rateStore, priceStore, locationStore)
+	carrierService, err := carrier.NewService(
+		carrier.Lanes{"*-*": {"fake", "express"}, "SE-UG": {"fake", "slow"}, "SE-DE": {"levels"}},
+		carrier.NewFake("fake", carrier.FakeConfig{BasePrice: 100, PricePerKg: 1, TransitDays: 4}),
+		carrier.NewFake("express", carrier.FakeConfig{BasePrice: 500, PricePerKg: 2, TransitDays: 1}),
+		carrier.NewFake("slow", carrier.FakeConfig{BasePrice: 10, TransitDays: 1, Delay: time.Second}),
+		carrier.NewFake("levels", carrier.FakeConfig{BasePrice: 100, PricePerKg: 1, TransitDays: 3, ServiceLevels: []string{"economy", "standard", "express"}}),
+	)
+	if err != nil {
+		return err
+	}
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		return err
+	}
+	calendars := calendar.NewCalendars(calendar.New(calendar.Config{CutOff: 24 * time.Hour}), map[string]calendar.Calendar{
+		"SE": calendar.New(calendar.Config{TimeZone: stockholm, CutOff: 15 * time.Hour, Holidays: []calendar.Holiday{
+			{Date: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), Name: "Christmas Eve", Yearly: true},
+			{Date: time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC), Name: "Christmas Day", Yearly: true},
+		}}),
+	})
+	billingService := billing.NewService(
+		billing.NewInstrumentedRateStore(rateStore, m),
+		billing.NewInstrumentedPriceStore(priceStore, m),
+		billing.NewInstrumentedLocationStore(locationStore, m),
+		carrierService,
+		calendars,
+		billing.Config{CarrierTimeout: 100 * time.Millisecond, ServiceLevels: billing.DefaultServiceLevels()},
+		m,
+	)
 
 	bookingStore := booking.NewInMemoryStore()
-	bookingService := booking.NewService(bookingStore, billingService)
+	bookingService := booking.NewService(booking.NewInstrumentedStore(bookingStore, m), billingService, carrierService, m)
+
+	webhookStore := webhook.NewInMemoryStore()
+	webhookService := webhook.NewService(webhookStore)
+	bus := events.NewBus()
+	bus.Subscribe(webhookService)
+	pickupService := pickup.NewService(pickup.NewInMemoryStore(), &bookingService, calendars, pickup.Schedules{
+		"SE": {Windows: []pickup.Window{{From: 9 * time.Hour, To: 12 * time.Hour}, {From: 13 * time.Hour, To: 17 * time.Hour}}, Capacity: 2},
+	})
+	bus.Subscribe(pickupService)
+	trackingService := tracking.NewService(tracking.NewInMemoryStore(), &bookingService)
+	streamConfig := stream.Config{Heartbeat: 50 * time.Millisecond, MaxDuration: 5 * time.Second, History: 100, Buffer: 16}
+	broker, err := stream.NewBroker(streamConfig)
+	if err != nil {
+		return err
+	}
+	bus.Subscribe(broker)
+	relay := events.NewRelay(bookingStore, events.Config{Interval: 10 * time.Millisecond, BatchSize: 100}, m, bus)
+	go relay.Run()
+	dispatcher := webhook.NewDispatcher(webhookStore, webhook.Config{
+		MaxAttempts: 2,
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+		Timeout:     time.Second,
+		Interval:    10 * time.Millisecond,
+		BatchSize:   100,
+		// the test receivers listen on loopback
+		AllowPrivateNetworks: true,
+	}, m)
+	go dispatcher.Run()
+	webhookHandler := webhook.NewHandler(webhookService)
 
 	bookingHandler := booking.NewHandler(bookingService)
-	s := New(bookingHandler, port)
+	tariffHandler := billing.NewHandler(billingService)
+	calendarHandler := calendar.NewHandler(calendars)
+	pickupHandler := pickup.NewHandler(pickupService)
+	trackingHandler := tracking.NewHandler(trackingService)
+	streamHandler := stream.NewHandler(broker, &bookingService, streamConfig)
+
+	authService := auth.NewService(auth.NewInMemoryKeyStore())
+	apiKeyHandler := auth.NewHandler(authService)
+	if _, apiKey, err = authService.CreateKey(context.Background(), "test-client"); err != nil {
+		return err
+	}
+	if _, otherApiKey, err = authService.CreateKey(context.Background(), "other-client"); err != nil {
+		return err
+	}
+	if _, webhookApiKey, err = authService.CreateKey(context.Background(), "webhook-client"); err != nil {
+		return err
+	}
+	if _, deadLetterApiKey, err = authService.CreateKey(context.Background(), "dead-letter-client"); err != nil {
+		return err
+	}
+
+	checker := health.NewChecker()
+	checker.Register("booking_store", time.Second, bookingStore.Ping)
+	checker.Register("billing_tables", time.Second, billingService.CheckTables)
+
+	defaultLimit, err := ratelimit.NewLimit(1000, 1000)
+	if err != nil {
+		return err
+	}
+	routeLimit, err := ratelimit.NewLimit(0.001, 1)
+	if err != nil {
+		return err
+	}
+	limiter := ratelimit.NewLimiter(
+		ratelimit.NewInMemoryStore(),
+		defaultLimit,
+		map[string]ratelimit.Limit{"GET /api/tariffs": routeLimit, "DELETE /api/admin/apikeys/:id": routeLimit},
+	)
+
+	schema, err := graphqlapi.NewSchema(&bookingService, billingService)
+	if err != nil {
+		return err
+	}
+	graphqlHandler := graphqlapi.NewHandler(schema, graphqlapi.NewPersistedQueries(graphqlapi.DefaultMaxPersistedQueries), graphqlapi.Config{MaxComplexity: 300, MaxDepth: 6})
+
+	s := New(bookingHandler, tariffHandler, calendarHandler, pickupHandler, trackingHandler, apiKeyHandler, graphqlHandler, webhookHandler, streamHandler, authService, tokenVerifierMock{}, limiter, m, checker, Config{Port: port, AccessLogSampling: 1, LegacyDeprecation: legacyDeprecation, LegacySunset: legacySunset})
 	go func() {
 		if err := s.Run(); err != nil {
 			panic(err.Error())
 		}
 	}()
-	return waitUntilReady()
+	return waitUntilReady(port)
 }
 
-func waitUntilReady() error {
+func waitUntilReady(port int) error {
 	waitChan := make(chan int, 0)
 
 	go func() {
@@ -135,6 +1910,251 @@ func waitUntilReady() error {
 	}
 }
 
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	const shutdownPort = port + 1
+
+	limit, err := ratelimit.NewLimit(1000, 1000)
+	require.Nil(t, err)
+	closer := &closerMock{}
+	checker := health.NewChecker()
+	drained := make(chan struct{})
+	s := New(
+		slowBookingHandlerMock{delay: 500 * time.Millisecond},
+		tariffHandlerMock{},
+		calendarHandlerMock{},
+		pickupHandlerMock{},
+		trackingHandlerMock{},
+		apiKeyHandlerMock{},
+		graphqlHandlerMock{},
+		webhookHandlerMock{},
+		streamHandlerMock{drained: drained, drain: &sync.Once{}},
+		authenticatorMock{},
+		tokenVerifierMock{},
+		ratelimit.NewLimiter(ratelimit.NewInMemoryStore(), limit, nil),
+		metrics.New(),
+		checker,
+		Config{Port: shutdownPort, AccessLogSampling: 1, DrainDelay: 200 * time.Millisecond},
+	)
+	s.OnShutdown(closer)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run() }()
+	require.Nil(t, waitUntilReady(shutdownPort))
+
+	status := make(chan int, 1)
+	go func() {
+		res, err := http.Post(fmt.Sprintf("http://%s:%d/api/shipping", address, shutdownPort), "application/json", strings.NewReader(`{"origin":"SE","destination":"DK","weight":10}`))
+		if err != nil {
+			status <- 0
+			return
+		}
+		res.Body.Close()
+		status <- res.StatusCode
+	}()
+	streamEnded := make(chan struct{})
+	go func() {
+		defer close(streamEnded)
+		res, err := http.Get(fmt.Sprintf("http://%s:%d/api/shipping/some-id/events", address, shutdownPort))
+		if err != nil {
+			return
+		}
+		defer res.Body.Close()
+		_, _ = io.Copy(io.Discard, res.Body)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- s.Shutdown(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	res, err := http.Get(fmt.Sprintf("http://%s:%d/readyz", address, shutdownPort))
+	require.Nil(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode, "expected not ready while draining")
+
+	require.Nil(t, <-shutdownErr, "expected open event streams to not hold up the drain")
+	<-streamEnded
+
+	require.Equal(t, http.StatusCreated, <-status, "expected in-flight request to complete")
+	require.Nil(t, <-runErr)
+	require.True(t, closer.closed, "expected closer to be closed")
+}
+
+type slowBookingHandlerMock struct {
+	delay time.Duration
+}
+
+func (h slowBookingHandlerMock) BookShipping(c *gin.Context) {
+	time.Sleep(h.delay)
+	c.Status(http.StatusCreated)
+}
+
+func (h slowBookingHandlerMock) GetBooking(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h slowBookingHandlerMock) ListBookings(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h slowBookingHandlerMock) CancelBooking(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h slowBookingHandlerMock) BookShippingV2(c *gin.Context) {
+	h.BookShipping(c)
+}
+
+func (h slowBookingHandlerMock) GetBookingV2(c *gin.Context) {
+	h.GetBooking(c)
+}
+
+func (h slowBookingHandlerMock) ListBookingsV2(c *gin.Context) {
+	h.ListBookings(c)
+}
+
+func (h slowBookingHandlerMock) CancelBookingV2(c *gin.Context) {
+	h.CancelBooking(c)
+}
+
+type tariffHandlerMock struct{}
+
+func (h tariffHandlerMock) GetTariffs(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h tariffHandlerMock) GetQuote(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+type calendarHandlerMock struct{}
+
+func (h calendarHandlerMock) GetCalendar(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h calendarHandlerMock) GetPickup(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+type pickupHandlerMock struct{}
+
+func (h pickupHandlerMock) GetSlots(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h pickupHandlerMock) ListPickups(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h pickupHandlerMock) SchedulePickup(c *gin.Context) {
+	c.Status(http.StatusCreated)
+}
+
+func (h pickupHandlerMock) GetPickup(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h pickupHandlerMock) ReschedulePickup(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h pickupHandlerMock) CancelPickup(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+type trackingHandlerMock struct{}
+
+func (h trackingHandlerMock) IngestEvents(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (h trackingHandlerMock) GetTracking(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+type apiKeyHandlerMock struct{}
+
+func (h apiKeyHandlerMock) CreateKey(c *gin.Context) {
+	c.Status(http.StatusCreated)
+}
+
+func (h apiKeyHandlerMock) RevokeKey(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+type graphqlHandlerMock struct{}
+
+func (h graphqlHandlerMock) Query(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+type webhookHandlerMock struct{}
+
+func (h webhookHandlerMock) CreateSubscription(c *gin.Context) {
+	c.Status(http.StatusNotImplemented)
+}
+
+func (h webhookHandlerMock) ListSubscriptions(c *gin.Context) {
+	c.Status(http.StatusNotImplemented)
+}
+
+func (h webhookHandlerMock) DeleteSubscription(c *gin.Context) {
+	c.Status(http.StatusNotImplemented)
+}
+
+func (h webhookHandlerMock) ListDeadLetters(c *gin.Context) {
+	c.Status(http.StatusNotImplemented)
+}
+
+func (h webhookHandlerMock) Redeliver(c *gin.Context) {
+	c.Status(http.StatusNotImplemented)
+}
+
+// streamHandlerMock keeps streams open until drained.
+type streamHandlerMock struct {
+	drained chan struct{}
+	drain   *sync.Once
+}
+
+func (h streamHandlerMock) BookingEvents(c *gin.Context) {
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	<-h.drained
+}
+
+func (h streamHandlerMock) Events(c *gin.Context) {
+	h.BookingEvents(c)
+}
+
+func (h streamHandlerMock) Drain() {
+	h.drain.Do(func() { close(h.drained) })
+}
+
+type authenticatorMock struct{}
+
+func (a authenticatorMock) Authenticate(_ context.Context, key string) (string, error) {
+	return "test-client", nil
+}
+
+type closerMock struct {
+	closed bool
+}
+
+func (c *closerMock) Close(_ context.Context) error {
+	c.closed = true
+	return nil
+}
+
+var (
+	legacyDeprecation = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	legacySunset      = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+)
+
 func TestMain(m *testing.M) {
 	if err := startHttp(); err != nil {
 		fmt.Print(err.Error())
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/billing"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/calendar"
+	"github.com/slaengkast/shipping-api/internal/openapi"
+	"github.com/slaengkast/shipping-api/internal/pickup"
+	"github.com/slaengkast/shipping-api/internal/stream"
+	"github.com/slaengkast/shipping-api/internal/tracking"
+	"github.com/slaengkast/shipping-api/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	legacyPrefix = "/api"
+	v1Prefix     = "/api/v1"
+	v2Prefix     = "/api/v2"
+)
+
+// unversioned returns the route as served under the legacy prefix, so that a
+// route is the same whichever version it is called through.
+func unversioned(route string) string {
+	for _, prefix := range []string{v1Prefix, v2Prefix} {
+		if route == prefix || strings.HasPrefix(route, prefix+"/") {
+			return legacyPrefix + strings.TrimPrefix(route, prefix)
+		}
+	}
+	return route
+}
+
+// deprecationMiddleware announces that the routes are deprecated since
+// deprecation and removed at sunset, and links to the route replacing them
+// under successorPrefix.
+func deprecationMiddleware(deprecation, sunset time.Time, prefix, successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !deprecation.IsZero() {
+			c.Header("Deprecation", fmt.Sprintf("@%d", deprecation.Unix()))
+		}
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		successor := successorPrefix + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		c.Next()
+	}
+}
+
+func (s *server) apiGroup(prefix string) *gin.RouterGroup {
+	group := s.router.Group(prefix)
+	group.Use(authMiddleware(s.authenticator, s.tokenVerifier))
+	group.Use(rateLimitMiddleware(s.limiter))
+	group.Use(requireAuthentication())
+	return group
+}
+
+// setupLegacyRoutes keeps the unversioned routes working with the v1 shape
+// until the configured sunset.
+func (s *server) setupLegacyRoutes() {
+	group := s.apiGroup(legacyPrefix)
+	group.Use(deprecationMiddleware(s.config.LegacyDeprecation, s.config.LegacySunset, legacyPrefix, v1Prefix))
+	s.setupV1Routes(group, func(op openapi.Operation) openapi.Operation {
+		op.OperationId += "Legacy"
+		op.Deprecated = true
+		return secured(op)
+	})
+}
+
+func (s *server) setupV1Routes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	bookingRouter := group.Group("shipping")
+	bookingRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(bookingRouter, http.MethodGet, "/:id", s.bookingHandler.GetBooking, document(booking.GetBookingOperation()))
+		s.handle(bookingRouter, http.MethodGet, "", s.bookingHandler.ListBookings, document(booking.ListBookingsOperation()))
+		s.handle(bookingRouter, http.MethodPost, "", s.bookingHandler.BookShipping, document(booking.BookShippingOperation()))
+		s.handle(bookingRouter, http.MethodPost, "/:id/cancel", s.bookingHandler.CancelBooking, document(booking.CancelBookingOperation()))
+		s.handle(bookingRouter, http.MethodGet, "/:id/events", s.streamHandler.BookingEvents, document(stream.BookingEventsOperation()))
+		s.handle(bookingRouter, http.MethodPost, "/:id/pickup", s.pickupHandler.SchedulePickup, document(pickup.SchedulePickupOperation()))
+		s.handle(bookingRouter, http.MethodGet, "/:id/pickup", s.pickupHandler.GetPickup, document(pickup.GetPickupOperation()))
+		s.handle(bookingRouter, http.MethodPut, "/:id/pickup", s.pickupHandler.ReschedulePickup, document(pickup.ReschedulePickupOperation()))
+		s.handle(bookingRouter, http.MethodDelete, "/:id/pickup", s.pickupHandler.CancelPickup, document(pickup.CancelPickupOperation()))
+		s.handle(bookingRouter, http.MethodGet, "/:id/tracking", s.trackingHandler.GetTracking, document(tracking.GetTrackingOperation()))
+	}
+
+	s.setupTariffRoutes(group, document)
+	s.setupCalendarRoutes(group, document)
+	s.setupPickupRoutes(group, document)
+	s.setupTrackingRoutes(group, document)
+	s.setupWebhookRoutes(group, document)
+	s.setupEventRoutes(group, document)
+	s.setupAdminRoutes(group, document)
+}
+
+func (s *server) setupV2Routes(group *gin.RouterGroup) {
+	document := func(op openapi.Operation) openapi.Operation {
+		if !strings.HasSuffix(op.OperationId, "V2") {
+			op.OperationId += "V2"
+		}
+		return secured(op)
+	}
+
+	bookingRouter := group.Group("shipping")
+	bookingRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(bookingRouter, http.MethodGet, "/:id", s.bookingHandler.GetBookingV2, document(booking.GetBookingV2Operation()))
+		s.handle(bookingRouter, http.MethodGet, "", s.bookingHandler.ListBookingsV2, document(booking.ListBookingsV2Operation()))
+		s.handle(bookingRouter, http.MethodPost, "", s.bookingHandler.BookShippingV2, document(booking.BookShippingV2Operation()))
+		s.handle(bookingRouter, http.MethodPost, "/:id/cancel", s.bookingHandler.CancelBookingV2, document(booking.CancelBookingV2Operation()))
+		s.handle(bookingRouter, http.MethodGet, "/:id/events", s.streamHandler.BookingEvents, document(stream.BookingEventsOperation()))
+		s.handle(bookingRouter, http.MethodPost, "/:id/pickup", s.pickupHandler.SchedulePickup, document(pickup.SchedulePickupOperation()))
+		s.handle(bookingRouter, http.MethodGet, "/:id/pickup", s.pickupHandler.GetPickup, document(pickup.GetPickupOperation()))
+		s.handle(bookingRouter, http.MethodPut, "/:id/pickup", s.pickupHandler.ReschedulePickup, document(pickup.ReschedulePickupOperation()))
+		s.handle(bookingRouter, http.MethodDelete, "/:id/pickup", s.pickupHandler.CancelPickup, document(pickup.CancelPickupOperation()))
+		s.handle(bookingRouter, http.MethodGet, "/:id/tracking", s.trackingHandler.GetTracking, document(tracking.GetTrackingOperation()))
+	}
+
+	s.setupTariffRoutes(group, document)
+	s.setupCalendarRoutes(group, document)
+	s.setupPickupRoutes(group, document)
+	s.setupTrackingRoutes(group, document)
+	s.setupWebhookRoutes(group, document)
+	s.setupEventRoutes(group, document)
+	s.setupAdminRoutes(group, document)
+}
+
+func (s *server) setupTariffRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	tariffRouter := group.Group("tariffs")
+	tariffRouter.Use(requireRole(auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(tariffRouter, http.MethodGet, "", s.tariffHandler.GetTariffs, document(billing.GetTariffsOperation()))
+	}
+
+	quoteRouter := group.Group("quote")
+	quoteRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(quoteRouter, http.MethodGet, "", s.tariffHandler.GetQuote, document(billing.GetQuoteOperation()))
+	}
+}
+
+func (s *server) setupCalendarRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	calendarRouter := group.Group("calendars")
+	calendarRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(calendarRouter, http.MethodGet, "/:location", s.calendarHandler.GetCalendar, document(calendar.GetCalendarOperation()))
+		s.handle(calendarRouter, http.MethodGet, "/:location/pickup", s.calendarHandler.GetPickup, document(calendar.GetPickupOperation()))
+	}
+}
+
+func (s *server) setupPickupRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	slotRouter := group.Group("pickups/slots")
+	slotRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(slotRouter, http.MethodGet, "", s.pickupHandler.GetSlots, document(pickup.GetSlotsOperation()))
+	}
+
+	pickupRouter := group.Group("pickups")
+	pickupRouter.Use(requireRole(auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(pickupRouter, http.MethodGet, "", s.pickupHandler.ListPickups, document(pickup.ListPickupsOperation()))
+	}
+}
+
+func (s *server) setupTrackingRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	trackingRouter := group.Group("tracking")
+	trackingRouter.Use(requireRole(auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(trackingRouter, http.MethodPost, "/events", s.trackingHandler.IngestEvents, document(tracking.IngestEventsOperation()))
+	}
+}
+
+func (s *server) setupWebhookRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	webhookRouter := group.Group("webhooks")
+	webhookRouter.Use(requireRole(auth.RoleCustomer, auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(webhookRouter, http.MethodPost, "", s.webhookHandler.CreateSubscription, document(webhook.CreateSubscriptionOperation()))
+		s.handle(webhookRouter, http.MethodGet, "", s.webhookHandler.ListSubscriptions, document(webhook.ListSubscriptionsOperation()))
+		s.handle(webhookRouter, http.MethodDelete, "/:id", s.webhookHandler.DeleteSubscription, document(webhook.DeleteSubscriptionOperation()))
+		s.handle(webhookRouter, http.MethodGet, "/dead-letters", s.webhookHandler.ListDeadLetters, document(webhook.ListDeadLettersOperation()))
+		s.handle(webhookRouter, http.MethodPost, "/deliveries/:id/redeliver", s.webhookHandler.Redeliver, document(webhook.RedeliverOperation()))
+	}
+}
+
+func (s *server) setupEventRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	eventRouter := group.Group("events")
+	eventRouter.Use(requireRole(auth.RoleOperator, auth.RoleAdmin))
+	{
+		s.handle(eventRouter, http.MethodGet, "", s.streamHandler.Events, document(stream.EventsOperation()))
+	}
+}
+
+func (s *server) setupAdminRoutes(group *gin.RouterGroup, document func(openapi.Operation) openapi.Operation) {
+	adminRouter := group.Group("admin")
+	adminRouter.Use(requireRole(auth.RoleAdmin))
+	{
+		s.handle(adminRouter, http.MethodPost, "/apikeys", s.apiKeyHandler.CreateKey, document(auth.CreateKeyOperation()))
+		s.handle(adminRouter, http.MethodDelete, "/apikeys/:id", s.apiKeyHandler.RevokeKey, document(auth.RevokeKeyOperation()))
+	}
+}
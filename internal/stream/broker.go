@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+// maxSeen is how many event ids are remembered to skip events published
+// again, apart from the history, which may be much shorter.
+const maxSeen = 10000
+
+type Config struct {
+	// Heartbeat is how long a stream may be idle before a comment is sent,
+	// which keeps proxies from closing it.
+	Heartbeat time.Duration
+	// MaxDuration ends streams before the server write timeout does, clients
+	// reconnect with Last-Event-ID.
+	MaxDuration time.Duration
+	// History is how many of the latest events are kept to resume from, at
+	// least 1.
+	History int
+	// Buffer is how many events may be queued for a stream before it is
+	// dropped as too slow.
+	Buffer int
+}
+
+type subscriber struct {
+	events chan booking.Event
+	match  func(booking.Event) bool
+}
+
+// broker fans the events out to the open streams. A stream that does not keep
+// up is closed rather than slowing down the others, its client resumes from
+// the history with Last-Event-ID.
+type broker struct {
+	config      Config
+	history     *[]booking.Event
+	seen        map[string]struct{}
+	seenOrder   *[]string
+	subscribers map[*subscriber]struct{}
+	closed      *bool
+	mtx         *sync.Mutex
+}
+
+func NewBroker(config Config) (*broker, error) {
+	if config.History < 1 {
+		return nil, errors.FromMessage("history must be at least 1", errors.ErrorInput)
+	}
+
+	closed := false
+	return &broker{
+		config:      config,
+		history:     &[]booking.Event{},
+		seen:        make(map[string]struct{}),
+		seenOrder:   &[]string{},
+		subscribers: make(map[*subscriber]struct{}),
+		closed:      &closed,
+		mtx:         &sync.Mutex{},
+	}, nil
+}
+
+// Publish is called by the event bus, it never fails. The bus publishes an
+// event again when another handler failed it, an event among the latest
+// maxSeen has been sent and is skipped.
+func (b *broker) Publish(_ context.Context, e booking.Event) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if _, ok := b.seen[e.Id]; ok {
+		return nil
+	}
+	b.seen[e.Id] = struct{}{}
+	*b.seenOrder = append(*b.seenOrder, e.Id)
+	if len(*b.seenOrder) > maxSeen {
+		delete(b.seen, (*b.seenOrder)[0])
+		*b.seenOrder = (*b.seenOrder)[1:]
+	}
+
+	*b.history = append(*b.history, e)
+	if len(*b.history) > b.config.History {
+		*b.history = append([]booking.Event(nil), (*b.history)[len(*b.history)-b.config.History:]...)
+	}
+
+	for s := range b.subscribers {
+		if !s.match(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			b.remove(s)
+		}
+	}
+	return nil
+}
+
+// subscribe returns the events in the history that match and come after the
+// event lastEventId, together with a subscriber for the events to come. All
+// matching events of the history are returned if lastEventId is not in it.
+func (b *broker) subscribe(match func(booking.Event) bool, lastEventId string) (*subscriber, []booking.Event) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	start := 0
+	if lastEventId != "" {
+		for i, e := range *b.history {
+			if e.Id == lastEventId {
+				start = i + 1
+				break
+			}
+		}
+	}
+	replay := make([]booking.Event, 0)
+	for _, e := range (*b.history)[start:] {
+		if match(e) {
+			replay = append(replay, e)
+		}
+	}
+
+	s := &subscriber{events: make(chan booking.Event, b.config.Buffer), match: match}
+	if *b.closed {
+		close(s.events)
+		return s, replay
+	}
+	b.subscribers[s] = struct{}{}
+	return s, replay
+}
+
+func (b *broker) unsubscribe(s *subscriber) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.remove(s)
+}
+
+// remove closes the events of s, the lock must be held.
+func (b *broker) remove(s *subscriber) {
+	if _, ok := b.subscribers[s]; !ok {
+		return
+	}
+	delete(b.subscribers, s)
+	close(s.events)
+}
+
+// Drain ends every open stream and every stream opened after, so that a
+// shutting down server does not wait for them.
+func (b *broker) Drain() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	*b.closed = true
+	for s := range b.subscribers {
+		b.remove(s)
+	}
+}
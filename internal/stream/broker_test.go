@@ -0,0 +1,148 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+
+	"github.com/stretchr/testify/require"
+)
+
+func all(booking.Event) bool { return true }
+
+func publishEvents(t *testing.T, b *broker, ids ...string) {
+	for _, id := range ids {
+		require.Nil(t, b.Publish(context.Background(), booking.Event{Id: id, BookingId: "booking-" + id}))
+	}
+}
+
+func eventIds(events []booking.Event) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lastEventId string
+		match       func(booking.Event) bool
+		expected    []string
+	}{
+		{
+			name:     "no last event id",
+			match:    all,
+			expected: []string{"2", "3", "4"},
+		},
+		{
+			name:        "resume",
+			lastEventId: "3",
+			match:       all,
+			expected:    []string{"4"},
+		},
+		{
+			name:        "resume at latest",
+			lastEventId: "4",
+			match:       all,
+			expected:    []string{},
+		},
+		{
+			name:        "unknown last event id",
+			lastEventId: "1",
+			match:       all,
+			expected:    []string{"2", "3", "4"},
+		},
+		{
+			name:     "filtered",
+			match:    func(e booking.Event) bool { return e.BookingId == "booking-3" },
+			expected: []string{"3"},
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewBroker(Config{History: 3, Buffer: 1})
+			require.Nil(t, err)
+			publishEvents(t, b, "1", "2", "3", "4")
+
+			_, replay := b.subscribe(tc.match, tc.lastEventId)
+			require.Equal(t, tc.expected, eventIds(replay))
+		})
+	}
+}
+
+func TestPublishSkipsSeen(t *testing.T) {
+	b, err := NewBroker(Config{History: 10, Buffer: 10})
+	require.Nil(t, err)
+	s, _ := b.subscribe(all, "")
+
+	// the bus publishes again after another handler failed
+	publishEvents(t, b, "1", "2", "1", "2", "3")
+	b.Drain()
+
+	received := []string{}
+	for e := range s.events {
+		received = append(received, e.Id)
+	}
+	require.Equal(t, []string{"1", "2", "3"}, received)
+	_, replay := b.subscribe(all, "")
+	require.Equal(t, []string{"1", "2", "3"}, eventIds(replay))
+}
+
+func TestPublishSkipsSeenOutsideHistory(t *testing.T) {
+	b, err := NewBroker(Config{History: 1, Buffer: 10})
+	require.Nil(t, err)
+	s, _ := b.subscribe(all, "")
+
+	publishEvents(t, b, "1", "2", "3", "1", "2")
+	b.Drain()
+
+	received := []string{}
+	for e := range s.events {
+		received = append(received, e.Id)
+	}
+	require.Equal(t, []string{"1", "2", "3"}, received)
+}
+
+func TestNewBrokerWithoutHistory(t *testing.T) {
+	_, err := NewBroker(Config{History: 0, Buffer: 10})
+	require.NotNil(t, err, "expected an error, got nil")
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	b, err := NewBroker(Config{History: 10, Buffer: 2})
+	require.Nil(t, err)
+	slow, _ := b.subscribe(all, "")
+	other, _ := b.subscribe(func(e booking.Event) bool { return e.Id == "1" }, "")
+
+	publishEvents(t, b, "1", "2", "3")
+
+	received := []string{}
+	for e := range slow.events {
+		received = append(received, e.Id)
+	}
+	require.Equal(t, []string{"1", "2"}, received, "expected the stream to be closed once its buffer was full")
+	require.Equal(t, "1", (<-other.events).Id)
+	require.Len(t, b.subscribers, 1, "expected subscribers that keep up to stay")
+}
+
+func TestDrain(t *testing.T) {
+	b, err := NewBroker(Config{History: 10, Buffer: 10})
+	require.Nil(t, err)
+	s, _ := b.subscribe(all, "")
+	b.Drain()
+
+	_, open := <-s.events
+	require.False(t, open, "expected open streams to end")
+	b.unsubscribe(s)
+
+	after, _ := b.subscribe(all, "")
+	_, open = <-after.events
+	require.False(t, open, "expected streams opened after a drain to end")
+	publishEvents(t, b, "1")
+}
@@ -0,0 +1,124 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LastEventIdHeader is sent by reconnecting EventSource clients.
+const LastEventIdHeader = "Last-Event-ID"
+
+// retryMillis tells clients how soon to reconnect after a stream ended.
+const retryMillis = 1000
+
+type eventPayload struct {
+	Id         string            `json:"id" binding:"required"`
+	Type       booking.EventType `json:"type" binding:"required"`
+	OccurredAt time.Time         `json:"occurredAt" binding:"required"`
+	ClientId   string            `json:"clientId" binding:"required"`
+	BookingId  string            `json:"bookingId" binding:"required"`
+	Status     booking.Status    `json:"status" binding:"required"`
+	Price      float32           `json:"price,omitempty"`
+	Currency   string            `json:"currency,omitempty"`
+}
+
+type handler struct {
+	broker         *broker
+	bookingService *booking.Service
+	config         Config
+}
+
+func NewHandler(broker *broker, bookingService *booking.Service, config Config) *handler {
+	return &handler{broker: broker, bookingService: bookingService, config: config}
+}
+
+// BookingEvents streams the events of one booking of the client.
+func (h handler) BookingEvents(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.bookingService.GetBooking(c, c.GetString(auth.ClientIdKey), id); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	h.stream(c, func(e booking.Event) bool { return e.BookingId == id })
+}
+
+// Events streams the events of every booking.
+func (h handler) Events(c *gin.Context) {
+	h.stream(c, func(booking.Event) bool { return true })
+}
+
+// Drain ends the open streams, see broker.Drain.
+func (h handler) Drain() {
+	h.broker.Drain()
+}
+
+func (h handler) stream(c *gin.Context, match func(booking.Event) bool) {
+	s, replay := h.broker.subscribe(match, c.GetHeader(LastEventIdHeader))
+	defer h.broker.unsubscribe(s)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", retryMillis); err != nil {
+		return
+	}
+	for _, e := range replay {
+		if err := writeEvent(c.Writer, e); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.config.Heartbeat)
+	defer heartbeat.Stop()
+	end := time.NewTimer(h.config.MaxDuration)
+	defer end.Stop()
+	for {
+		select {
+		case e, ok := <-s.events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(c.Writer, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-end.C:
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(w gin.ResponseWriter, e booking.Event) error {
+	data, err := json.Marshal(eventPayload{
+		Id:         e.Id,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		ClientId:   e.ClientId,
+		BookingId:  e.BookingId,
+		Status:     e.Status,
+		Price:      e.Price,
+		Currency:   e.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+	return err
+}
@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+// eventStreamResponse describes a text/event-stream whose data lines are
+// events.
+func eventStreamResponse(description string) openapi.Response {
+	return openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: openapi.SchemaOf(eventPayload{})}},
+	}
+}
+
+var lastEventIdParameter = openapi.Parameter{
+	Name:   LastEventIdHeader,
+	In:     "header",
+	Schema: &openapi.Schema{Type: "string"},
+}
+
+func BookingEventsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "streamBookingEvents",
+		Summary:     "Stream the events of a booking as Server-Sent Events",
+		Tags:        []string{"shipping"},
+		Parameters:  []openapi.Parameter{lastEventIdParameter},
+		Responses: map[string]openapi.Response{
+			"200": eventStreamResponse("Events of the booking, resumed after Last-Event-ID"),
+			"404": openapi.ProblemResponse("Booking not found"),
+		},
+	}
+}
+
+func EventsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "streamEvents",
+		Summary:     "Stream the events of every booking as Server-Sent Events",
+		Tags:        []string{"events"},
+		Parameters:  []openapi.Parameter{lastEventIdParameter},
+		Responses: map[string]openapi.Response{
+			"200": eventStreamResponse("Events of all bookings, resumed after Last-Event-ID"),
+		},
+	}
+}
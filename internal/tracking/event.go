@@ -0,0 +1,128 @@
+package tracking
+
+import (
+	"errors"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+// Code tells what happened to a parcel at a scan.
+type Code string
+
+const (
+	CodePickedUp       Code = "picked_up"
+	CodeArrivedAtHub   Code = "arrived_at_hub"
+	CodeDepartedHub    Code = "departed_hub"
+	CodeInTransit      Code = "in_transit"
+	CodeOutForDelivery Code = "out_for_delivery"
+	CodeDeliveryFailed Code = "delivery_failed"
+	CodeException      Code = "exception"
+	CodeDelivered      Code = "delivered"
+	CodeReturned       Code = "returned"
+)
+
+// Codes are the known codes in the order of the journey of a parcel.
+var Codes = []Code{CodePickedUp, CodeArrivedAtHub, CodeDepartedHub, CodeInTransit, CodeOutForDelivery, CodeDeliveryFailed, CodeException, CodeDelivered, CodeReturned}
+
+// codeStatus is the status of a booking whose latest scan has the code.
+var codeStatus = map[Code]booking.Status{
+	CodePickedUp:       booking.StatusPickedUp,
+	CodeArrivedAtHub:   booking.StatusInTransit,
+	CodeDepartedHub:    booking.StatusInTransit,
+	CodeInTransit:      booking.StatusInTransit,
+	CodeOutForDelivery: booking.StatusOutForDelivery,
+	CodeDeliveryFailed: booking.StatusException,
+	CodeException:      booking.StatusException,
+	CodeDelivered:      booking.StatusDelivered,
+	CodeReturned:       booking.StatusReturned,
+}
+
+// Scan is a tracking event as reported by a carrier.
+type Scan struct {
+	BookingId   string
+	Timestamp   time.Time
+	Location    string
+	Code        Code
+	Description string
+}
+
+// event is a scan of a booking, scans with the same time, location and code
+// are the same event.
+type event struct {
+	id          string
+	bookingId   string
+	timestamp   time.Time
+	location    string
+	code        Code
+	description string
+	receivedAt  time.Time
+}
+
+func NewEvent(id string, scan Scan, receivedAt time.Time) (*event, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+	if scan.BookingId == "" {
+		return nil, errors.New("booking id is empty")
+	}
+	if scan.Timestamp.IsZero() {
+		return nil, errors.New("timestamp is empty")
+	}
+	if scan.Location == "" {
+		return nil, errors.New("location is empty")
+	}
+	if _, ok := codeStatus[scan.Code]; !ok {
+		return nil, errors.New("unknown code")
+	}
+
+	return &event{
+		id:          id,
+		bookingId:   scan.BookingId,
+		timestamp:   scan.Timestamp.UTC(),
+		location:    scan.Location,
+		code:        scan.Code,
+		description: scan.Description,
+		receivedAt:  receivedAt,
+	}, nil
+}
+
+func (e *event) Id() string {
+	return e.id
+}
+
+func (e *event) BookingId() string {
+	return e.bookingId
+}
+
+// Timestamp is when the carrier scanned the parcel.
+func (e *event) Timestamp() time.Time {
+	return e.timestamp
+}
+
+func (e *event) Location() string {
+	return e.location
+}
+
+func (e *event) Code() Code {
+	return e.code
+}
+
+func (e *event) Description() string {
+	return e.description
+}
+
+// ReceivedAt is when the scan was reported.
+func (e *event) ReceivedAt() time.Time {
+	return e.receivedAt
+}
+
+// Status returns the status of a booking whose latest scan this is.
+func (e *event) Status() booking.Status {
+	return codeStatus[e.code]
+}
+
+// key identifies the scan regardless of when and how often it was reported.
+func (e *event) key() string {
+	return e.bookingId + "/" + e.timestamp.Format(time.RFC3339Nano) + "/" + e.location + "/" + string(e.code)
+}
@@ -0,0 +1,165 @@
+package tracking
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type fileEvent struct {
+	Id          string    `json:"id"`
+	BookingId   string    `json:"bookingId"`
+	Timestamp   time.Time `json:"timestamp"`
+	Location    string    `json:"location"`
+	Code        Code      `json:"code"`
+	Description string    `json:"description,omitempty"`
+	ReceivedAt  time.Time `json:"receivedAt"`
+}
+
+// fileStore keeps the tracking events in memory and writes all of them to a
+// JSON file whenever scans are added, so that timelines and the duplicate
+// check survive a restart together with the bookings. Scans are written under
+// the lock of the store and taken back in memory when the write fails, so
+// that a scan that is not in the file is not counted as a duplicate either.
+type fileStore struct {
+	inMemoryStore
+	path    string
+	saveMtx *sync.Mutex
+}
+
+func NewFileStore(path string) (fileStore, error) {
+	s := fileStore{inMemoryStore: NewInMemoryStore(), path: path, saveMtx: &sync.Mutex{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, errors.WrapStore(err, "tracking", "load")
+	}
+
+	var events []fileEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return s, errors.WrapStore(err, "tracking", "load")
+	}
+	loaded := make([]*event, 0, len(events))
+	for _, f := range events {
+		e, err := fromFileEvent(f)
+		if err != nil {
+			return s, errors.WrapStore(err, "tracking", "load")
+		}
+		loaded = append(loaded, e)
+	}
+	s.addEvents(loaded)
+	return s, nil
+}
+
+func (r fileStore) AddEvents(_ context.Context, events []*event) ([]*event, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	kept := make(map[string][]eventModel)
+	for _, e := range events {
+		kept[e.bookingId] = r.events[e.bookingId]
+	}
+	added := r.addEvents(events)
+	if len(added) == 0 {
+		return added, nil
+	}
+	if err := r.save(); err != nil {
+		for bookingId, models := range kept {
+			if models == nil {
+				delete(r.events, bookingId)
+			} else {
+				r.events[bookingId] = models
+			}
+		}
+		for _, e := range added {
+			delete(r.keys, e.key())
+		}
+		return nil, err
+	}
+	return added, nil
+}
+
+func (r fileStore) Close(_ context.Context) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.save()
+}
+
+// save writes the events to the file. The lock must be held, for reading at
+// least.
+func (r fileStore) save() error {
+	r.saveMtx.Lock()
+	defer r.saveMtx.Unlock()
+
+	events := make([]fileEvent, 0, len(r.keys))
+	for _, models := range r.events {
+		for _, m := range models {
+			events = append(events, toFileEvent(m))
+		}
+	}
+
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return errors.WrapStore(err, "tracking", "save")
+	}
+	if err := writeFile(r.path, data); err != nil {
+		return errors.WrapStore(err, "tracking", "save")
+	}
+	return nil
+}
+
+// writeFile writes the data to a temporary file next to path and renames it
+// over path once it is synced.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func toFileEvent(m eventModel) fileEvent {
+	return fileEvent{
+		Id:          m.id,
+		BookingId:   m.bookingId,
+		Timestamp:   m.timestamp,
+		Location:    m.location,
+		Code:        m.code,
+		Description: m.description,
+		ReceivedAt:  m.receivedAt,
+	}
+}
+
+// fromFileEvent goes through the constructor so that a hand edited file can
+// not load an invalid event.
+func fromFileEvent(f fileEvent) (*event, error) {
+	return NewEvent(f.Id, Scan{
+		BookingId:   f.BookingId,
+		Timestamp:   f.Timestamp,
+		Location:    f.Location,
+		Code:        f.Code,
+		Description: f.Description,
+	}, f.ReceivedAt)
+}
@@ -0,0 +1,119 @@
+package tracking
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type trackingEventRequest struct {
+	BookingId   string    `json:"bookingId" binding:"required"`
+	Timestamp   time.Time `json:"timestamp" binding:"required" description:"When the carrier scanned the parcel"`
+	Location    string    `json:"location" binding:"required" description:"Where the carrier scanned the parcel, such as the code of a hub"`
+	Code        Code      `json:"code" binding:"required,oneof=picked_up arrived_at_hub departed_hub in_transit out_for_delivery delivery_failed exception delivered returned"`
+	Description string    `json:"description" binding:"max=500"`
+}
+
+type ingestResponse struct {
+	Accepted   int `json:"accepted" description:"Number of events that were new"`
+	Duplicates int `json:"duplicates" description:"Number of events that had been reported before"`
+}
+
+type trackingEventResponse struct {
+	Id          string    `json:"id" binding:"required"`
+	Timestamp   time.Time `json:"timestamp" binding:"required"`
+	Location    string    `json:"location" binding:"required"`
+	Code        Code      `json:"code" binding:"required"`
+	Description string    `json:"description,omitempty"`
+	ReceivedAt  time.Time `json:"receivedAt" binding:"required"`
+}
+
+type trackingResponse struct {
+	BookingId string                  `json:"bookingId" binding:"required"`
+	Status    booking.Status          `json:"status" binding:"required,oneof=booked picked_up in_transit out_for_delivery delivered exception returned cancelling cancelled"`
+	Events    []trackingEventResponse `json:"events" binding:"required" description:"Events by the time of the scan, oldest first"`
+}
+
+type handler struct {
+	trackingService Service
+}
+
+func NewHandler(trackingService Service) *handler {
+	return &handler{trackingService: trackingService}
+}
+
+// IngestEvents takes a single event or an array of events.
+func (h handler) IngestEvents(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem.Write(c, apierrors.FromCode(apierrors.CodeInvalidRequest, "request body could not be read", apierrors.ErrorInput))
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var result Result
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var req []trackingEventRequest
+		if err := problem.Bind(c, &req); err != nil {
+			problem.Write(c, err)
+			return
+		}
+		scans := make([]Scan, 0, len(req))
+		for _, r := range req {
+			scans = append(scans, toScan(r))
+		}
+		result, err = h.trackingService.IngestBatch(c, scans)
+	} else {
+		var req trackingEventRequest
+		if err := problem.Bind(c, &req); err != nil {
+			problem.Write(c, err)
+			return
+		}
+		result, err = h.trackingService.Ingest(c, toScan(req))
+	}
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ingestResponse{Accepted: result.Accepted, Duplicates: result.Duplicates})
+}
+
+func (h handler) GetTracking(c *gin.Context) {
+	timeline, err := h.trackingService.Timeline(c, c.GetString(auth.ClientIdKey), c.Param("id"))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	res := trackingResponse{BookingId: timeline.BookingId, Status: timeline.Status, Events: make([]trackingEventResponse, 0, len(timeline.Events))}
+	for _, e := range timeline.Events {
+		res.Events = append(res.Events, trackingEventResponse{
+			Id:          e.Id(),
+			Timestamp:   e.Timestamp(),
+			Location:    e.Location(),
+			Code:        e.Code(),
+			Description: e.Description(),
+			ReceivedAt:  e.ReceivedAt(),
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func toScan(req trackingEventRequest) Scan {
+	return Scan{
+		BookingId:   req.BookingId,
+		Timestamp:   req.Timestamp,
+		Location:    req.Location,
+		Code:        req.Code,
+		Description: req.Description,
+	}
+}
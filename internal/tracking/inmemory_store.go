@@ -0,0 +1,100 @@
+package tracking
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type eventModel struct {
+	id          string
+	bookingId   string
+	timestamp   time.Time
+	location    string
+	code        Code
+	description string
+	receivedAt  time.Time
+}
+
+// inMemoryStore keeps the events of every booking ordered by time and
+// checks for duplicates under the same lock as it adds them, so a scan
+// reported concurrently is stored once.
+type inMemoryStore struct {
+	events map[string][]eventModel
+	keys   map[string]struct{}
+	mtx    *sync.RWMutex
+}
+
+func NewInMemoryStore() inMemoryStore {
+	return inMemoryStore{
+		events: make(map[string][]eventModel),
+		keys:   make(map[string]struct{}),
+		mtx:    &sync.RWMutex{},
+	}
+}
+
+// AddEvents adds the events that are not stored yet and returns them.
+func (r inMemoryStore) AddEvents(_ context.Context, events []*event) ([]*event, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.addEvents(events), nil
+}
+
+// addEvents is AddEvents with the lock held. It replaces the events of a
+// booking rather than changing them in place.
+func (r inMemoryStore) addEvents(events []*event) []*event {
+	added := make([]*event, 0, len(events))
+	for _, e := range events {
+		if _, ok := r.keys[e.key()]; ok {
+			continue
+		}
+		r.keys[e.key()] = struct{}{}
+
+		models := append(append([]eventModel(nil), r.events[e.bookingId]...), marshalEvent(e))
+		// events of the same time stay in the order they were reported
+		sort.SliceStable(models, func(i, j int) bool {
+			return models[i].timestamp.Before(models[j].timestamp)
+		})
+		r.events[e.bookingId] = models
+		added = append(added, e)
+	}
+	return added
+}
+
+// ListEvents returns the events of the booking, oldest first.
+func (r inMemoryStore) ListEvents(_ context.Context, bookingId string) ([]*event, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	events := make([]*event, 0, len(r.events[bookingId]))
+	for _, m := range r.events[bookingId] {
+		events = append(events, unmarshalEvent(m))
+	}
+	return events, nil
+}
+
+func marshalEvent(e *event) eventModel {
+	return eventModel{
+		id:          e.id,
+		bookingId:   e.bookingId,
+		timestamp:   e.timestamp,
+		location:    e.location,
+		code:        e.code,
+		description: e.description,
+		receivedAt:  e.receivedAt,
+	}
+}
+
+func unmarshalEvent(m eventModel) *event {
+	return &event{
+		id:          m.id,
+		bookingId:   m.bookingId,
+		timestamp:   m.timestamp,
+		location:    m.location,
+		code:        m.code,
+		description: m.description,
+		receivedAt:  m.receivedAt,
+	}
+}
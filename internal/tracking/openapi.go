@@ -0,0 +1,36 @@
+package tracking
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func IngestEventsOperation() openapi.Operation {
+	op := openapi.Operation{
+		OperationId: "ingestTrackingEvents",
+		Summary:     "Report a carrier scan of a booking, or a batch of scans of one or more bookings",
+		Tags:        []string{"tracking"},
+		RequestBody: openapi.JSONBodyOneOf(trackingEventRequest{}, []trackingEventRequest{}),
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Events ingested, events reported before are counted as duplicates", ingestResponse{}),
+			"404": openapi.ProblemResponse("Booking not found, no event of the request is ingested"),
+		},
+	}
+
+	// a batch holds at least one and at most MaxBatchSize events
+	minItems, maxItems := 1, MaxBatchSize
+	batch := op.Schema().OneOf[1]
+	batch.MinItems, batch.MaxItems = &minItems, &maxItems
+	return op
+}
+
+func GetTrackingOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "getTracking",
+		Summary:     "Get the tracking events of a booking and its status",
+		Tags:        []string{"tracking"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Tracking timeline", trackingResponse{}),
+			"404": openapi.ProblemResponse("Booking not found"),
+		},
+	}
+}
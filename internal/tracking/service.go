@@ -0,0 +1,195 @@
+package tracking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+
+	"github.com/google/uuid"
+)
+
+type store interface {
+	AddEvents(ctx context.Context, events []*event) ([]*event, error)
+	ListEvents(ctx context.Context, bookingId string) ([]*event, error)
+}
+
+const (
+	MaxBatchSize = 500
+	// maxClockSkew is how far in the future a scan may be timestamped.
+	maxClockSkew = 5 * time.Minute
+)
+
+// Result tells how many of the ingested scans were new and how many had
+// been reported before.
+type Result struct {
+	Accepted   int
+	Duplicates int
+}
+
+// Timeline is the tracking events of a booking, oldest first.
+type Timeline struct {
+	BookingId string
+	Status    booking.Status
+	Events    []*event
+}
+
+type Service struct {
+	store          store
+	bookingService *booking.Service
+}
+
+func NewService(store store, bookingService *booking.Service) Service {
+	return Service{
+		store:          store,
+		bookingService: bookingService,
+	}
+}
+
+// Ingest stores a scan and moves its booking to the status of its latest
+// scan.
+func (s Service) Ingest(ctx context.Context, scan Scan) (Result, error) {
+	return s.ingest(ctx, []Scan{scan}, func(_ int, name string) string { return name })
+}
+
+// IngestBatch is Ingest for several scans, of one or more bookings. Either
+// every scan is stored or none is, fields of invalid scans are named by
+// their index. The bookings take their statuses before the scans are stored,
+// so a batch that fails can be reported again as a whole.
+func (s Service) IngestBatch(ctx context.Context, scans []Scan) (Result, error) {
+	if len(scans) == 0 {
+		return Result{}, errors.Validation("no scans", errors.FieldError{Code: errors.FieldRequired, Message: "body must contain at least one event"})
+	}
+	if len(scans) > MaxBatchSize {
+		return Result{}, errors.Validation("too many scans", errors.FieldError{Code: errors.FieldOutOfRange, Message: fmt.Sprintf("body must contain at most %d events", MaxBatchSize)})
+	}
+	return s.ingest(ctx, scans, func(i int, name string) string { return fmt.Sprintf("[%d].%s", i, name) })
+}
+
+func (s Service) ingest(ctx context.Context, scans []Scan, field func(i int, name string) string) (Result, error) {
+	logger := logging.FromContext(ctx, "tracking")
+	logger.Info().Int("scans", len(scans)).Msg("ingesting scans")
+
+	if err := validate(scans, time.Now(), field); err != nil {
+		return Result{}, err
+	}
+
+	// check every booking before storing anything
+	var bookingIds []string
+	seen := map[string]bool{}
+	for _, scan := range scans {
+		if seen[scan.BookingId] {
+			continue
+		}
+		seen[scan.BookingId] = true
+		if _, err := s.bookingService.FindBooking(ctx, scan.BookingId); err != nil {
+			return Result{}, err
+		}
+		bookingIds = append(bookingIds, scan.BookingId)
+	}
+
+	receivedAt := time.Now().UTC()
+	events := make([]*event, 0, len(scans))
+	for _, scan := range scans {
+		e, err := NewEvent(uuid.New().String(), scan, receivedAt)
+		if err != nil {
+			return Result{}, errors.Validation(err.Error())
+		}
+		events = append(events, e)
+	}
+
+	// duplicates update the status too, so a retried report applies a
+	// status that failed to apply before
+	for _, id := range bookingIds {
+		if err := s.track(ctx, id, events); err != nil {
+			return Result{}, err
+		}
+	}
+
+	added, err := s.store.AddEvents(ctx, events)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Accepted: len(added), Duplicates: len(events) - len(added)}
+	logger.Info().Int("accepted", result.Accepted).Int("duplicates", result.Duplicates).Msg("ingested scans")
+	return result, nil
+}
+
+// track moves the booking to the status of its latest scan, of the stored
+// ones and the ones about to be stored. Of scans of the same time the last
+// reported is the latest, as in the timeline.
+func (s Service) track(ctx context.Context, bookingId string, pending []*event) error {
+	events, err := s.store.ListEvents(ctx, bookingId)
+	if err != nil {
+		return err
+	}
+	var latest *event
+	for _, e := range append(events, pending...) {
+		if e.BookingId() != bookingId {
+			continue
+		}
+		if latest == nil || !e.Timestamp().Before(latest.Timestamp()) {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	_, err = s.bookingService.TrackStatus(ctx, bookingId, latest.Status(), latest.Timestamp())
+	return err
+}
+
+// Timeline returns the tracking events of a booking of the client.
+func (s Service) Timeline(ctx context.Context, clientId, bookingId string) (Timeline, error) {
+	b, err := s.bookingService.GetBooking(ctx, clientId, bookingId)
+	if err != nil {
+		return Timeline{}, err
+	}
+	events, err := s.store.ListEvents(ctx, bookingId)
+	if err != nil {
+		return Timeline{}, err
+	}
+	return Timeline{BookingId: bookingId, Status: b.Status(), Events: events}, nil
+}
+
+func validate(scans []Scan, now time.Time, field func(i int, name string) string) error {
+	var fieldErrors []errors.FieldError
+	add := func(i int, name, code, format string, args ...interface{}) {
+		f := field(i, name)
+		fieldErrors = append(fieldErrors, errors.FieldError{Field: f, Code: code, Message: fmt.Sprintf("%s "+format, append([]interface{}{f}, args...)...)})
+	}
+
+	for i, scan := range scans {
+		if scan.BookingId == "" {
+			add(i, "bookingId", errors.FieldRequired, "is required")
+		}
+		if scan.Timestamp.IsZero() {
+			add(i, "timestamp", errors.FieldRequired, "is required")
+		} else if scan.Timestamp.After(now.Add(maxClockSkew)) {
+			add(i, "timestamp", errors.FieldOutOfRange, "must not be in the future")
+		}
+		if scan.Location == "" {
+			add(i, "location", errors.FieldRequired, "is required")
+		}
+		if _, ok := codeStatus[scan.Code]; !ok {
+			add(i, "code", errors.FieldInvalid, "must be one of %s", strings.Join(codeNames(), ", "))
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return errors.Validation("invalid scans", fieldErrors...)
+	}
+	return nil
+}
+
+func codeNames() []string {
+	names := make([]string, 0, len(Codes))
+	for _, c := range Codes {
+		names = append(names, string(c))
+	}
+	return names
+}
@@ -0,0 +1,358 @@
+package tracking
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/booking/bookingtest"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingStore fails to add events, as a store that is down would.
+type failingStore struct {
+	inMemoryStore
+}
+
+func (r failingStore) AddEvents(_ context.Context, _ []*event) ([]*event, error) {
+	return nil, apierrors.ErrUnavailable
+}
+
+type testBundle struct {
+	bookingtest.Bundle
+	service Service
+}
+
+func newTestBundle(t *testing.T) testBundle {
+	bundle := bookingtest.New(t)
+	return testBundle{
+		Bundle:  bundle,
+		service: NewService(NewInMemoryStore(), bundle.BookingService),
+	}
+}
+
+func (b testBundle) book(t *testing.T) string {
+	return b.Book(t, "test-client", "SE")
+}
+
+func TestIngest(t *testing.T) {
+	// scans happen minutes after the bookings of the test are booked
+	now := time.Now().UTC()
+	scan := func(minutes int, code Code) Scan {
+		return Scan{Timestamp: now.Add(time.Duration(minutes) * time.Minute), Location: "SE-STO", Code: code}
+	}
+
+	testCases := []struct {
+		name             string
+		scans            []Scan
+		batch            bool
+		unknownBooking   bool
+		expectedCode     apierrors.Code
+		expectedField    string
+		expectedResult   Result
+		expectedStatus   booking.Status
+		expectedTimeline []Code
+	}{
+		{
+			name:             "single scan",
+			scans:            []Scan{scan(1, CodePickedUp)},
+			expectedResult:   Result{Accepted: 1},
+			expectedStatus:   booking.StatusPickedUp,
+			expectedTimeline: []Code{CodePickedUp},
+		},
+		{
+			name:             "batch out of order",
+			scans:            []Scan{scan(3, CodeDelivered), scan(1, CodePickedUp), scan(2, CodeOutForDelivery)},
+			batch:            true,
+			expectedResult:   Result{Accepted: 3},
+			expectedStatus:   booking.StatusDelivered,
+			expectedTimeline: []Code{CodePickedUp, CodeOutForDelivery, CodeDelivered},
+		},
+		{
+			name:             "hub scans are in transit",
+			scans:            []Scan{scan(1, CodePickedUp), scan(2, CodeArrivedAtHub)},
+			batch:            true,
+			expectedResult:   Result{Accepted: 2},
+			expectedStatus:   booking.StatusInTransit,
+			expectedTimeline: []Code{CodePickedUp, CodeArrivedAtHub},
+		},
+		{
+			name:             "duplicate in batch",
+			scans:            []Scan{scan(1, CodePickedUp), scan(1, CodePickedUp)},
+			batch:            true,
+			expectedResult:   Result{Accepted: 1, Duplicates: 1},
+			expectedStatus:   booking.StatusPickedUp,
+			expectedTimeline: []Code{CodePickedUp},
+		},
+		{
+			name:          "unknown code in batch",
+			scans:         []Scan{scan(1, CodePickedUp), scan(2, "lost")},
+			batch:         true,
+			expectedCode:  apierrors.CodeValidationFailed,
+			expectedField: "[1].code",
+		},
+		{
+			name:          "future timestamp",
+			scans:         []Scan{scan(60, CodePickedUp)},
+			expectedCode:  apierrors.CodeValidationFailed,
+			expectedField: "timestamp",
+		},
+		{
+			name:          "missing location",
+			scans:         []Scan{{Timestamp: time.Now(), Code: CodePickedUp}},
+			expectedCode:  apierrors.CodeValidationFailed,
+			expectedField: "location",
+		},
+		{
+			name:         "empty batch",
+			batch:        true,
+			expectedCode: apierrors.CodeValidationFailed,
+		},
+		{
+			name:           "unknown booking",
+			scans:          []Scan{scan(1, CodePickedUp), scan(2, CodeInTransit)},
+			batch:          true,
+			unknownBooking: true,
+			expectedCode:   apierrors.CodeBookingNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			bundle := newTestBundle(t)
+			id := bundle.book(t)
+			scans := make([]Scan, 0, len(tc.scans))
+			for i, s := range tc.scans {
+				s.BookingId = id
+				if tc.unknownBooking && i == len(tc.scans)-1 {
+					s.BookingId = "unknown"
+				}
+				scans = append(scans, s)
+			}
+
+			var (
+				result Result
+				err    error
+			)
+			if tc.batch {
+				result, err = bundle.service.IngestBatch(ctx, scans)
+			} else {
+				result, err = bundle.service.Ingest(ctx, scans[0])
+			}
+
+			timeline, timelineErr := bundle.service.Timeline(ctx, "test-client", id)
+			require.Nil(t, timelineErr)
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				if tc.expectedField != "" {
+					require.Equal(t, tc.expectedField, apierrors.GetFields(err)[0].Field)
+				}
+				require.Empty(t, timeline.Events)
+				require.Equal(t, booking.StatusBooked, timeline.Status)
+				return
+			}
+
+			require.Nilf(t, err, "unexpected error")
+			require.Equal(t, tc.expectedResult, result)
+			require.Equal(t, tc.expectedStatus, timeline.Status)
+			codes := make([]Code, 0, len(timeline.Events))
+			for _, e := range timeline.Events {
+				codes = append(codes, e.Code())
+			}
+			require.Equal(t, tc.expectedTimeline, codes)
+		})
+	}
+}
+
+func TestIngestLateScan(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+	now := time.Now().UTC()
+
+	_, err := bundle.service.Ingest(ctx, Scan{BookingId: id, Timestamp: now.Add(2 * time.Minute), Location: "DK-CPH", Code: CodeOutForDelivery})
+	require.Nil(t, err)
+	// a scan reported late changes the timeline but not the status
+	_, err = bundle.service.Ingest(ctx, Scan{BookingId: id, Timestamp: now.Add(time.Minute), Location: "SE-STO", Code: CodePickedUp})
+	require.Nil(t, err)
+
+	timeline, err := bundle.service.Timeline(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Equal(t, booking.StatusOutForDelivery, timeline.Status)
+	require.Len(t, timeline.Events, 2)
+	require.Equal(t, CodePickedUp, timeline.Events[0].Code())
+
+	// reporting the same scan again is a duplicate
+	result, err := bundle.service.Ingest(ctx, Scan{BookingId: id, Timestamp: now.Add(time.Minute), Location: "SE-STO", Code: CodePickedUp, Description: "Picked up"})
+	require.Nil(t, err)
+	require.Equal(t, Result{Duplicates: 1}, result)
+
+	b, err := bundle.BookingService.GetBooking(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Len(t, b.StatusHistory(), 2)
+}
+
+func TestIngestCancelled(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+	_, err := bundle.BookingService.CancelBooking(ctx, "test-client", id)
+	require.Nil(t, err)
+
+	result, err := bundle.service.Ingest(ctx, Scan{BookingId: id, Timestamp: time.Now().UTC().Add(time.Minute), Location: "SE-STO", Code: CodePickedUp})
+	require.Nil(t, err)
+	require.Equal(t, Result{Accepted: 1}, result)
+
+	timeline, err := bundle.service.Timeline(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Equal(t, booking.StatusCancelled, timeline.Status)
+	require.Len(t, timeline.Events, 1)
+}
+
+func TestIngestConcurrently(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+	scan := Scan{BookingId: id, Timestamp: time.Now().UTC().Add(time.Minute), Location: "SE-STO", Code: CodePickedUp}
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		accepted int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := bundle.service.Ingest(ctx, scan)
+			require.Nil(t, err)
+			mtx.Lock()
+			accepted += result.Accepted
+			mtx.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, accepted)
+	timeline, err := bundle.service.Timeline(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Len(t, timeline.Events, 1)
+	require.Equal(t, booking.StatusPickedUp, timeline.Status)
+}
+
+func TestIngestStoreError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+	now := time.Now().UTC()
+	scans := []Scan{
+		{BookingId: id, Timestamp: now.Add(time.Minute), Location: "SE-STO", Code: CodePickedUp},
+		{BookingId: id, Timestamp: now.Add(2 * time.Minute), Location: "SE-STO", Code: CodeDepartedHub},
+	}
+
+	store := NewInMemoryStore()
+	failing := NewService(failingStore{store}, bundle.BookingService)
+	_, err := failing.IngestBatch(ctx, scans)
+	require.NotNil(t, err, "expected an error, got nil")
+	events, err := store.ListEvents(ctx, id)
+	require.Nil(t, err)
+	require.Empty(t, events)
+	// the booking took its status before the scans failed to store
+	b, err := bundle.BookingService.GetBooking(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Equal(t, booking.StatusInTransit, b.Status())
+
+	service := NewService(store, bundle.BookingService)
+	result, err := service.IngestBatch(ctx, scans)
+	require.Nil(t, err)
+	require.Equal(t, Result{Accepted: 2}, result)
+	timeline, err := service.Timeline(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Equal(t, booking.StatusInTransit, timeline.Status)
+	require.Len(t, timeline.Events, 2)
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+	path := filepath.Join(t.TempDir(), "tracking.json")
+	now := time.Now().UTC()
+	scans := []Scan{
+		{BookingId: id, Timestamp: now.Add(2 * time.Minute), Location: "SE-STO", Code: CodeDepartedHub, Description: "Left the hub"},
+		{BookingId: id, Timestamp: now.Add(time.Minute), Location: "SE-STO", Code: CodePickedUp},
+	}
+
+	store, err := NewFileStore(path)
+	require.Nil(t, err)
+	_, err = NewService(store, bundle.BookingService).IngestBatch(ctx, scans)
+	require.Nil(t, err)
+
+	reloaded, err := NewFileStore(path)
+	require.Nil(t, err)
+	service := NewService(reloaded, bundle.BookingService)
+	timeline, err := service.Timeline(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Len(t, timeline.Events, 2)
+	require.Equal(t, CodePickedUp, timeline.Events[0].Code())
+	require.Equal(t, "Left the hub", timeline.Events[1].Description())
+
+	// scans replayed after a restart are still duplicates
+	result, err := service.IngestBatch(ctx, scans)
+	require.Nil(t, err)
+	require.Equal(t, Result{Duplicates: 2}, result)
+}
+
+func TestFileStoreSaveError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+	dir := filepath.Join(t.TempDir(), "data")
+	scan := Scan{BookingId: id, Timestamp: time.Now().UTC().Add(time.Minute), Location: "SE-STO", Code: CodePickedUp}
+
+	store, err := NewFileStore(filepath.Join(dir, "tracking.json"))
+	require.Nil(t, err)
+	service := NewService(store, bundle.BookingService)
+	_, err = service.Ingest(ctx, scan)
+	require.NotNil(t, err, "expected an error, got nil")
+	events, err := store.ListEvents(ctx, id)
+	require.Nil(t, err)
+	require.Empty(t, events)
+
+	require.Nil(t, os.Mkdir(dir, 0700))
+	result, err := service.Ingest(ctx, scan)
+	require.Nil(t, err)
+	require.Equal(t, Result{Accepted: 1}, result, "expected the scan that failed to save to not be a duplicate")
+}
+
+func TestTimeline(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := newTestBundle(t)
+	id := bundle.book(t)
+
+	timeline, err := bundle.service.Timeline(ctx, "test-client", id)
+	require.Nil(t, err)
+	require.Equal(t, id, timeline.BookingId)
+	require.Equal(t, booking.StatusBooked, timeline.Status)
+	require.Empty(t, timeline.Events)
+
+	_, err = bundle.service.Timeline(ctx, "other-client", id)
+	require.Equal(t, apierrors.CodeBookingNotFound, apierrors.GetCode(err))
+}
@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed deliveries ran out of attempts, they make up the dead
+	// letters and are only sent again when redelivered.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// delivery is an event on its way to one subscription.
+type delivery struct {
+	id             string
+	subscriptionId string
+	event          booking.Event
+	status         DeliveryStatus
+	attempts       int
+	nextAttemptAt  time.Time
+	lastAttemptAt  time.Time
+	lastStatusCode int
+	lastError      string
+}
+
+func NewDelivery(id, subscriptionId string, event booking.Event, at time.Time) (*delivery, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+	if subscriptionId == "" {
+		return nil, errors.New("subscription id is empty")
+	}
+	if event.Id == "" {
+		return nil, errors.New("event id is empty")
+	}
+
+	return &delivery{
+		id:             id,
+		subscriptionId: subscriptionId,
+		event:          event,
+		status:         DeliveryPending,
+		nextAttemptAt:  at,
+	}, nil
+}
+
+func (d *delivery) Id() string {
+	return d.id
+}
+
+func (d *delivery) SubscriptionId() string {
+	return d.subscriptionId
+}
+
+func (d *delivery) ClientId() string {
+	return d.event.ClientId
+}
+
+func (d *delivery) Event() booking.Event {
+	return d.event
+}
+
+func (d *delivery) Status() DeliveryStatus {
+	return d.status
+}
+
+func (d *delivery) Attempts() int {
+	return d.attempts
+}
+
+func (d *delivery) NextAttemptAt() time.Time {
+	return d.nextAttemptAt
+}
+
+func (d *delivery) LastAttemptAt() time.Time {
+	return d.lastAttemptAt
+}
+
+// LastStatusCode is the status the subscriber answered the last attempt with,
+// zero when it could not be reached.
+func (d *delivery) LastStatusCode() int {
+	return d.lastStatusCode
+}
+
+func (d *delivery) LastError() string {
+	return d.lastError
+}
+
+func (d *delivery) succeeded(at time.Time, statusCode int) {
+	d.attempts++
+	d.status = DeliveryDelivered
+	d.lastAttemptAt = at
+	d.lastStatusCode = statusCode
+	d.lastError = ""
+}
+
+// failed records a failed attempt, the delivery is retried at next unless it
+// is the zero time which makes it a dead letter.
+func (d *delivery) failed(at time.Time, statusCode int, reason string, next time.Time) {
+	d.attempts++
+	d.lastAttemptAt = at
+	d.lastStatusCode = statusCode
+	d.lastError = reason
+	if next.IsZero() {
+		d.status = DeliveryFailed
+		return
+	}
+	d.nextAttemptAt = next
+}
+
+// redeliver queues the delivery again with a fresh set of attempts.
+func (d *delivery) redeliver(at time.Time) {
+	d.status = DeliveryPending
+	d.attempts = 0
+	d.nextAttemptAt = at
+}
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+)
+
+const userAgent = "shipping-api-webhooks"
+
+type deliveryStore interface {
+	GetSubscription(context.Context, string) (*subscription, error)
+	UpdateDelivery(context.Context, *delivery) error
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*delivery, error)
+}
+
+type metrics interface {
+	WebhookAttempted(event, outcome string)
+}
+
+type Config struct {
+	// MaxAttempts is how many times a delivery is tried before it becomes a
+	// dead letter.
+	MaxAttempts int
+	// MinBackoff is the wait after the first failed attempt, it doubles with
+	// every attempt up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout limits each attempt.
+	Timeout time.Duration
+	// Interval is how often due deliveries are looked for.
+	Interval  time.Duration
+	BatchSize int
+	// Workers is how many subscriptions are delivered to at once, every
+	// subscription gets one attempt at a time.
+	Workers int
+	// AllowPrivateNetworks lets deliveries reach loopback, private and link
+	// local addresses, which are refused so that subscriptions can not be
+	// used to reach internal services.
+	AllowPrivateNetworks bool
+}
+
+// eventPayload is the body of a delivery.
+type eventPayload struct {
+	Id         string            `json:"id"`
+	Type       booking.EventType `json:"type"`
+	OccurredAt time.Time         `json:"occurredAt"`
+	Data       eventData         `json:"data"`
+}
+
+type eventData struct {
+	BookingId string         `json:"bookingId"`
+	Status    booking.Status `json:"status"`
+	Price     float32        `json:"price,omitempty"`
+	Currency  string         `json:"currency,omitempty"`
+}
+
+type dispatcher struct {
+	store    deliveryStore
+	client   *http.Client
+	config   Config
+	metrics  metrics
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce *sync.Once
+}
+
+func NewDispatcher(store deliveryStore, config Config, metrics metrics) *dispatcher {
+	return &dispatcher{
+		store:    store,
+		client:   newClient(config),
+		config:   config,
+		metrics:  metrics,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		stopOnce: &sync.Once{},
+	}
+}
+
+// newClient returns a client that is not redirected and, unless private
+// networks are allowed, checks every address it connects to after the host
+// has been resolved, so that a host resolving to an internal address is
+// refused as well.
+func newClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// deliveries go straight to the subscriber, a proxy would be dialled in
+	// its place and escape the address check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			// the redirect response is returned, failing the attempt
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %s", address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to deliver to %s, it is not a public address", ip)
+	}
+	return nil
+}
+
+// Run sends due deliveries every interval until the dispatcher is closed.
+func (d *dispatcher) Run() {
+	defer close(d.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-d.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			if _, err := d.DeliverDue(ctx); err != nil {
+				logger := logging.FromContext(ctx, "webhook")
+				logger.Error().Err(err).Msg("failed to deliver webhooks")
+			}
+		}
+	}
+}
+
+// Close stops the dispatcher, attempts in flight are cancelled and stay
+// pending.
+func (d *dispatcher) Close(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stop) })
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeliverDue makes one attempt at every due delivery and returns how many
+// were delivered. Subscriptions are delivered to concurrently by up to
+// Workers at a time, the deliveries of a subscription are attempted one at a
+// time in the order they are due so that a slow subscriber only holds up its
+// own. A delivery that is not delivered holds up the rest of its
+// subscription until it is retried.
+func (d *dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := d.store.DueDeliveries(ctx, time.Now().UTC(), d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var subscriptions [][]*delivery
+	bySubscription := make(map[string]int)
+	for _, del := range deliveries {
+		i, ok := bySubscription[del.SubscriptionId()]
+		if !ok {
+			i = len(subscriptions)
+			bySubscription[del.SubscriptionId()] = i
+			subscriptions = append(subscriptions, nil)
+		}
+		subscriptions[i] = append(subscriptions[i], del)
+	}
+
+	workers := d.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(subscriptions) {
+		workers = len(subscriptions)
+	}
+
+	queue := make(chan []*delivery)
+	mtx := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	delivered := 0
+	var firstErr error
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pending := range queue {
+				for _, del := range pending {
+					if ctx.Err() != nil {
+						break
+					}
+					err := d.attempt(ctx, del)
+					mtx.Lock()
+					if err != nil && firstErr == nil {
+						firstErr = err
+					}
+					if err == nil && del.Status() == DeliveryDelivered {
+						delivered++
+					}
+					mtx.Unlock()
+					if err != nil || del.Status() != DeliveryDelivered {
+						// the rest of the subscription waits for this one to
+						// be retried, keeping its order
+						break
+					}
+				}
+			}
+		}()
+	}
+	for _, pending := range subscriptions {
+		queue <- pending
+	}
+	close(queue)
+	wg.Wait()
+
+	return delivered, firstErr
+}
+
+func (d *dispatcher) attempt(ctx context.Context, del *delivery) error {
+	logger := logging.FromContext(ctx, "webhook")
+	event := string(del.Event().Type)
+
+	sub, err := d.store.GetSubscription(ctx, del.SubscriptionId())
+	if errors.GetType(err) == errors.ErrorNotFound {
+		del.failed(time.Now().UTC(), 0, "subscription deleted", time.Time{})
+		d.metrics.WebhookAttempted(event, string(DeliveryFailed))
+		return d.store.UpdateDelivery(ctx, del)
+	}
+	if err != nil {
+		return err
+	}
+
+	statusCode, err := d.send(ctx, sub, del)
+	if ctx.Err() != nil {
+		// shutting down, the attempt did not count
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if err == nil {
+		del.succeeded(now, statusCode)
+		d.metrics.WebhookAttempted(event, string(DeliveryDelivered))
+		return d.store.UpdateDelivery(ctx, del)
+	}
+
+	var next time.Time
+	if del.Attempts()+1 < d.config.MaxAttempts {
+		next = now.Add(d.backoff(del.Attempts() + 1))
+	}
+	del.failed(now, statusCode, err.Error(), next)
+	d.metrics.WebhookAttempted(event, string(del.Status()))
+	logger.Warn().Err(err).Str("delivery", del.Id()).Str("url", sub.Url()).Int("attempts", del.Attempts()).Str("status", string(del.Status())).Msg("webhook attempt failed")
+	return d.store.UpdateDelivery(ctx, del)
+}
+
+// send posts the event to the subscription and returns the status it was
+// answered with, anything but 2xx is an error.
+func (d *dispatcher) send(ctx context.Context, sub *subscription, del *delivery) (int, error) {
+	e := del.Event()
+	body, err := json.Marshal(eventPayload{
+		Id:         e.Id,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		Data:       eventData{BookingId: e.BookingId, Status: e.Status, Price: e.Price, Currency: e.Currency},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(IdHeader, e.Id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret(), e.Id, now, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// the body is not used, reading some of it lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("subscriber responded %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff returns the wait after the n:th failed attempt.
+func (d *dispatcher) backoff(n int) time.Duration {
+	wait := d.config.MinBackoff
+	for i := 1; i < n && wait < d.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.config.MaxBackoff {
+		wait = d.config.MaxBackoff
+	}
+	return wait
+}
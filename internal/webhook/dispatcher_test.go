@@ -0,0 +1,339 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+
+	"github.com/stretchr/testify/require"
+)
+
+type metricsMock struct{}
+
+func (m metricsMock) WebhookAttempted(event, status string) {}
+
+func newTestDispatcher(store inMemoryStore, maxAttempts int) *dispatcher {
+	return NewDispatcher(store, Config{
+		MaxAttempts: maxAttempts,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		Timeout:     time.Second,
+		Interval:    time.Millisecond,
+		// the test receivers listen on loopback
+		AllowPrivateNetworks: true,
+	}, metricsMock{})
+}
+
+// publish subscribes url to booking.created and publishes one.
+func publish(t *testing.T, store inMemoryStore, url string) *subscription {
+	ctx := context.Background()
+	s := NewService(store)
+	sub, err := s.CreateSubscription(ctx, "test-client", url, []booking.EventType{booking.EventBookingCreated})
+	require.Nil(t, err)
+	require.Nil(t, s.Publish(ctx, booking.Event{Id: "event-id", Type: booking.EventBookingCreated, OccurredAt: time.Now(), ClientId: "test-client", BookingId: "booking-id"}))
+	return sub
+}
+
+func TestDeliver(t *testing.T) {
+	t.Parallel()
+
+	var header http.Header
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	store := NewInMemoryStore()
+	sub := publish(t, store, srv.URL)
+
+	delivered, err := newTestDispatcher(store, 3).DeliverDue(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 1, delivered)
+
+	require.Equal(t, "event-id", header.Get(IdHeader))
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	require.Nil(t, err)
+	require.Equal(t, Sign(sub.Secret(), "event-id", time.Unix(timestamp, 0), body), header.Get(SignatureHeader))
+	var payload eventPayload
+	require.Nil(t, json.Unmarshal(body, &payload))
+	require.Equal(t, booking.EventBookingCreated, payload.Type)
+	require.Equal(t, "booking-id", payload.Data.BookingId)
+
+	due, err := store.DueDeliveries(context.Background(), time.Now().Add(time.Hour), 0)
+	require.Nil(t, err)
+	require.Empty(t, due, "expected delivered deliveries to not be sent again")
+}
+
+func TestDeliverRetries(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	publish(t, store, srv.URL)
+	d := newTestDispatcher(store, 3)
+
+	for i := 0; i < 5; i++ {
+		_, err := d.DeliverDue(ctx)
+		require.Nil(t, err)
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.Equal(t, 3, attempts, "expected the delivery to stop after max attempts")
+
+	dead, err := store.ListDeliveries(ctx, "test-client", DeliveryFailed)
+	require.Nil(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, 3, dead[0].Attempts())
+	require.Equal(t, http.StatusBadGateway, dead[0].LastStatusCode())
+}
+
+func TestDeliverDeletedSubscription(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	sub := publish(t, store, "http://localhost:1")
+	require.Nil(t, store.DeleteSubscription(ctx, sub.Id()))
+
+	_, err := newTestDispatcher(store, 3).DeliverDue(ctx)
+	require.Nil(t, err)
+
+	dead, err := store.ListDeliveries(ctx, "test-client", DeliveryFailed)
+	require.Nil(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, 0, dead[0].LastStatusCode())
+}
+
+func TestDeliverConcurrently(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	inFlight, maxInFlight := 0, 0
+	mtx := &sync.Mutex{}
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mtx.Unlock()
+		<-release
+		mtx.Lock()
+		inFlight--
+		mtx.Unlock()
+	}))
+	defer slow.Close()
+	received := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer fast.Close()
+
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	s := NewService(store)
+	for i, url := range []string{slow.URL, fast.URL} {
+		_, err := s.CreateSubscription(ctx, "test-client", url, []booking.EventType{booking.EventBookingCreated})
+		require.Nil(t, err)
+		if i == 0 {
+			// the slow subscriber has two deliveries due
+			require.Nil(t, s.Publish(ctx, booking.Event{Id: "first", Type: booking.EventBookingCreated, ClientId: "test-client"}))
+		}
+	}
+	require.Nil(t, s.Publish(ctx, booking.Event{Id: "second", Type: booking.EventBookingCreated, ClientId: "test-client"}))
+
+	d := newTestDispatcher(store, 3)
+	d.config.Workers = 4
+	done := make(chan int)
+	go func() {
+		delivered, err := d.DeliverDue(ctx)
+		require.Nil(t, err)
+		done <- delivered
+	}()
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the fast subscriber to not wait for the slow one")
+	}
+	close(release)
+	require.Equal(t, 3, <-done)
+	require.Equal(t, 1, maxInFlight, "expected one attempt in flight per subscription")
+}
+
+func TestDeliverInOrder(t *testing.T) {
+	t.Parallel()
+
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(IdHeader)
+		received = append(received, id)
+		if id == "first" && len(received) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	s := NewService(store)
+	_, err := s.CreateSubscription(ctx, "test-client", srv.URL, []booking.EventType{booking.EventBookingCreated})
+	require.Nil(t, err)
+	now := time.Now()
+	require.Nil(t, s.Publish(ctx, booking.Event{Id: "first", Type: booking.EventBookingCreated, OccurredAt: now, ClientId: "test-client"}))
+	require.Nil(t, s.Publish(ctx, booking.Event{Id: "second", Type: booking.EventBookingCreated, OccurredAt: now.Add(time.Second), ClientId: "test-client"}))
+	d := newTestDispatcher(store, 3)
+	d.config.MinBackoff = 50 * time.Millisecond
+	d.config.MaxBackoff = 50 * time.Millisecond
+
+	delivered, err := d.DeliverDue(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 0, delivered)
+	require.Equal(t, []string{"first"}, received, "expected the second delivery to wait for the first")
+
+	// the second is not due before the first is retried
+	delivered, err = d.DeliverDue(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 0, delivered)
+	require.Equal(t, []string{"first"}, received)
+
+	time.Sleep(60 * time.Millisecond)
+	delivered, err = d.DeliverDue(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, delivered)
+	require.Equal(t, []string{"first", "first", "second"}, received)
+}
+
+func TestDeliverRefused(t *testing.T) {
+	t.Parallel()
+
+	received := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer srv.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, srv.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	testCases := []struct {
+		name                 string
+		url                  string
+		allowPrivateNetworks bool
+		expectedStatusCode   int
+	}{
+		{
+			name: "loopback",
+			url:  srv.URL,
+		},
+		{
+			name: "resolved to loopback",
+			url:  strings.Replace(srv.URL, "127.0.0.1", "localhost", 1),
+		},
+		{
+			name: "private",
+			url:  "http://10.0.0.1:1",
+		},
+		{
+			name: "link local",
+			url:  "http://169.254.169.254",
+		},
+		{
+			name: "unspecified",
+			url:  "http://0.0.0.0:1",
+		},
+		{
+			name:                 "redirect",
+			url:                  redirect.URL,
+			allowPrivateNetworks: true,
+			expectedStatusCode:   http.StatusFound,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewInMemoryStore()
+			publish(t, store, tc.url)
+			d := NewDispatcher(store, Config{MaxAttempts: 1, Timeout: time.Second, AllowPrivateNetworks: tc.allowPrivateNetworks}, metricsMock{})
+
+			delivered, err := d.DeliverDue(ctx)
+			require.Nil(t, err)
+			require.Equal(t, 0, delivered)
+
+			dead, err := store.ListDeliveries(ctx, "test-client", DeliveryFailed)
+			require.Nil(t, err)
+			require.Len(t, dead, 1)
+			require.Equal(t, tc.expectedStatusCode, dead[0].LastStatusCode())
+		})
+	}
+	require.False(t, received, "expected nothing to be delivered")
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(NewInMemoryStore(), Config{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}, metricsMock{})
+
+	testCases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(strconv.Itoa(tc.attempt), func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, d.backoff(tc.attempt))
+		})
+	}
+}
+
+func TestRunAndClose(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer srv.Close()
+
+	store := NewInMemoryStore()
+	publish(t, store, srv.URL)
+	d := newTestDispatcher(store, 3)
+	go d.Run()
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the running dispatcher to deliver")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.Nil(t, d.Close(ctx))
+	require.Nil(t, d.Close(ctx), "expected closing twice to be fine")
+}
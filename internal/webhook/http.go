@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/auth"
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createSubscriptionRequest struct {
+	Url    string              `json:"url" binding:"required"`
+	Events []booking.EventType `json:"events" binding:"required,min=1" description:"Types of the booking events to deliver"`
+}
+
+type subscriptionResponse struct {
+	Id        string              `json:"id" binding:"required"`
+	Url       string              `json:"url" binding:"required"`
+	Events    []booking.EventType `json:"events" binding:"required"`
+	CreatedAt time.Time           `json:"createdAt" binding:"required"`
+}
+
+type createSubscriptionResponse struct {
+	subscriptionResponse
+	Secret string `json:"secret" binding:"required" description:"Key the deliveries are signed with, only returned once"`
+}
+
+type listSubscriptionsResponse struct {
+	Subscriptions []subscriptionResponse `json:"subscriptions" binding:"required"`
+}
+
+type deliveryResponse struct {
+	Id             string            `json:"id" binding:"required"`
+	SubscriptionId string            `json:"subscriptionId" binding:"required"`
+	EventId        string            `json:"eventId" binding:"required"`
+	EventType      booking.EventType `json:"eventType" binding:"required"`
+	BookingId      string            `json:"bookingId" binding:"required"`
+	Status         DeliveryStatus    `json:"status" binding:"required,oneof=pending delivered failed"`
+	Attempts       int               `json:"attempts"`
+	LastAttemptAt  *time.Time        `json:"lastAttemptAt"`
+	LastStatusCode int               `json:"lastStatusCode,omitempty" description:"Status the subscriber answered the last attempt with"`
+	LastError      string            `json:"lastError,omitempty"`
+}
+
+type listDeliveriesResponse struct {
+	Deliveries []deliveryResponse `json:"deliveries" binding:"required"`
+}
+
+type handler struct {
+	webhookService Service
+}
+
+func NewHandler(webhookService Service) *handler {
+	return &handler{webhookService: webhookService}
+}
+
+func (h handler) CreateSubscription(c *gin.Context) {
+	var req createSubscriptionRequest
+	if err := problem.Bind(c, &req); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	sub, err := h.webhookService.CreateSubscription(c, c.GetString(auth.ClientIdKey), req.Url, req.Events)
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("%s/%s", c.Request.URL.Path, sub.Id()))
+	c.JSON(http.StatusCreated, createSubscriptionResponse{subscriptionResponse: toSubscriptionResponse(sub), Secret: sub.Secret()})
+}
+
+func (h handler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.webhookService.ListSubscriptions(c, c.GetString(auth.ClientIdKey))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	res := listSubscriptionsResponse{Subscriptions: make([]subscriptionResponse, 0, len(subscriptions))}
+	for _, sub := range subscriptions {
+		res.Subscriptions = append(res.Subscriptions, toSubscriptionResponse(sub))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h handler) DeleteSubscription(c *gin.Context) {
+	if err := h.webhookService.DeleteSubscription(c, c.GetString(auth.ClientIdKey), c.Param("id")); err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h handler) ListDeadLetters(c *gin.Context) {
+	deliveries, err := h.webhookService.ListDeadLetters(c, c.GetString(auth.ClientIdKey))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	res := listDeliveriesResponse{Deliveries: make([]deliveryResponse, 0, len(deliveries))}
+	for _, d := range deliveries {
+		res.Deliveries = append(res.Deliveries, toDeliveryResponse(d))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h handler) Redeliver(c *gin.Context) {
+	d, err := h.webhookService.Redeliver(c, c.GetString(auth.ClientIdKey), c.Param("id"))
+	if err != nil {
+		problem.Write(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toDeliveryResponse(d))
+}
+
+func toSubscriptionResponse(sub *subscription) subscriptionResponse {
+	return subscriptionResponse{
+		Id:        sub.Id(),
+		Url:       sub.Url(),
+		Events:    sub.Events(),
+		CreatedAt: sub.CreatedAt(),
+	}
+}
+
+func toDeliveryResponse(d *delivery) deliveryResponse {
+	e := d.Event()
+	res := deliveryResponse{
+		Id:             d.Id(),
+		SubscriptionId: d.SubscriptionId(),
+		EventId:        e.Id,
+		EventType:      e.Type,
+		BookingId:      e.BookingId,
+		Status:         d.Status(),
+		Attempts:       d.Attempts(),
+		LastStatusCode: d.LastStatusCode(),
+		LastError:      d.LastError(),
+	}
+	if at := d.LastAttemptAt(); !at.IsZero() {
+		res.LastAttemptAt = &at
+	}
+	return res
+}
@@ -0,0 +1,254 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+)
+
+type subscriptionModel struct {
+	id        string
+	clientId  string
+	url       string
+	secret    string
+	events    []booking.EventType
+	createdAt time.Time
+}
+
+type deliveryModel struct {
+	id             string
+	subscriptionId string
+	event          booking.Event
+	status         DeliveryStatus
+	attempts       int
+	nextAttemptAt  time.Time
+	lastAttemptAt  time.Time
+	lastStatusCode int
+	lastError      string
+}
+
+type inMemoryStore struct {
+	subscriptions map[string]subscriptionModel
+	deliveries    map[string]deliveryModel
+	mtx           *sync.RWMutex
+}
+
+func NewInMemoryStore() inMemoryStore {
+	return inMemoryStore{
+		subscriptions: make(map[string]subscriptionModel, 0),
+		deliveries:    make(map[string]deliveryModel, 0),
+		mtx:           &sync.RWMutex{},
+	}
+}
+
+func (r inMemoryStore) GetSubscription(_ context.Context, id string) (*subscription, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	m, ok := r.subscriptions[id]
+	if !ok {
+		return nil, errors.NotFound(errors.CodeSubscriptionNotFound, "subscription", id)
+	}
+	return unmarshalSubscription(m)
+}
+
+func (r inMemoryStore) AddSubscription(_ context.Context, s *subscription) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.subscriptions[s.Id()]; ok {
+		return errors.WithEntity(errors.FromMessage("subscription already exists", errors.ErrorConflict), "subscription", s.Id())
+	}
+
+	r.subscriptions[s.Id()] = marshalSubscription(s)
+	return nil
+}
+
+func (r inMemoryStore) DeleteSubscription(_ context.Context, id string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.subscriptions[id]; !ok {
+		return errors.NotFound(errors.CodeSubscriptionNotFound, "subscription", id)
+	}
+
+	delete(r.subscriptions, id)
+	return nil
+}
+
+// ListSubscriptions returns the subscriptions of the client, oldest first.
+func (r inMemoryStore) ListSubscriptions(_ context.Context, clientId string) ([]*subscription, error) {
+	r.mtx.RLock()
+	models := make([]subscriptionModel, 0)
+	for _, m := range r.subscriptions {
+		if m.clientId == clientId {
+			models = append(models, m)
+		}
+	}
+	r.mtx.RUnlock()
+
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].createdAt.Equal(models[j].createdAt) {
+			return models[i].id < models[j].id
+		}
+		return models[i].createdAt.Before(models[j].createdAt)
+	})
+
+	subscriptions := make([]*subscription, 0, len(models))
+	for _, m := range models {
+		s, err := unmarshalSubscription(m)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, nil
+}
+
+func (r inMemoryStore) GetDelivery(_ context.Context, id string) (*delivery, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	m, ok := r.deliveries[id]
+	if !ok {
+		return nil, errors.NotFound(errors.CodeDeliveryNotFound, "delivery", id)
+	}
+	return unmarshalDelivery(m)
+}
+
+func (r inMemoryStore) AddDelivery(_ context.Context, d *delivery) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.deliveries[d.Id()]; ok {
+		return errors.WithEntity(errors.FromMessage("delivery already exists", errors.ErrorConflict), "delivery", d.Id())
+	}
+
+	r.deliveries[d.Id()] = marshalDelivery(d)
+	return nil
+}
+
+func (r inMemoryStore) UpdateDelivery(_ context.Context, d *delivery) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, ok := r.deliveries[d.Id()]; !ok {
+		return errors.NotFound(errors.CodeDeliveryNotFound, "delivery", d.Id())
+	}
+
+	r.deliveries[d.Id()] = marshalDelivery(d)
+	return nil
+}
+
+// ListDeliveries returns the deliveries of the client with the status,
+// oldest event first.
+func (r inMemoryStore) ListDeliveries(_ context.Context, clientId string, status DeliveryStatus) ([]*delivery, error) {
+	return r.listDeliveries(func(m deliveryModel) bool {
+		return m.event.ClientId == clientId && m.status == status
+	}, 0)
+}
+
+// DueDeliveries returns at most limit pending deliveries whose next attempt
+// is due at now, oldest event first. A delivery waiting to be retried holds
+// back the later deliveries of its subscription, so that every subscription
+// gets its events in order.
+func (r inMemoryStore) DueDeliveries(_ context.Context, now time.Time, limit int) ([]*delivery, error) {
+	waiting := make(map[string]bool)
+	return r.listDeliveries(func(m deliveryModel) bool {
+		if m.status != DeliveryPending {
+			return false
+		}
+		if m.nextAttemptAt.After(now) {
+			waiting[m.subscriptionId] = true
+		}
+		return !waiting[m.subscriptionId]
+	}, limit)
+}
+
+// listDeliveries returns the deliveries include takes, oldest event first.
+// They are offered to include in that order.
+func (r inMemoryStore) listDeliveries(include func(deliveryModel) bool, limit int) ([]*delivery, error) {
+	r.mtx.RLock()
+	all := make([]deliveryModel, 0, len(r.deliveries))
+	for _, m := range r.deliveries {
+		all = append(all, m)
+	}
+	r.mtx.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].event.OccurredAt.Equal(all[j].event.OccurredAt) {
+			return all[i].id < all[j].id
+		}
+		return all[i].event.OccurredAt.Before(all[j].event.OccurredAt)
+	})
+	models := make([]deliveryModel, 0)
+	for _, m := range all {
+		if limit > 0 && len(models) == limit {
+			break
+		}
+		if include(m) {
+			models = append(models, m)
+		}
+	}
+
+	deliveries := make([]*delivery, 0, len(models))
+	for _, m := range models {
+		d, err := unmarshalDelivery(m)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func (r inMemoryStore) Close(_ context.Context) error {
+	return nil
+}
+
+func unmarshalSubscription(m subscriptionModel) (*subscription, error) {
+	return NewSubscription(m.id, m.clientId, m.url, m.secret, m.events, m.createdAt)
+}
+
+func marshalSubscription(s *subscription) subscriptionModel {
+	return subscriptionModel{
+		id:        s.id,
+		clientId:  s.clientId,
+		url:       s.url,
+		secret:    s.secret,
+		events:    s.Events(),
+		createdAt: s.createdAt,
+	}
+}
+
+func unmarshalDelivery(m deliveryModel) (*delivery, error) {
+	d, err := NewDelivery(m.id, m.subscriptionId, m.event, m.nextAttemptAt)
+	if err != nil {
+		return nil, err
+	}
+
+	d.status = m.status
+	d.attempts = m.attempts
+	d.lastAttemptAt = m.lastAttemptAt
+	d.lastStatusCode = m.lastStatusCode
+	d.lastError = m.lastError
+	return d, nil
+}
+
+func marshalDelivery(d *delivery) deliveryModel {
+	return deliveryModel{
+		id:             d.id,
+		subscriptionId: d.subscriptionId,
+		event:          d.event,
+		status:         d.status,
+		attempts:       d.attempts,
+		nextAttemptAt:  d.nextAttemptAt,
+		lastAttemptAt:  d.lastAttemptAt,
+		lastStatusCode: d.lastStatusCode,
+		lastError:      d.lastError,
+	}
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"github.com/slaengkast/shipping-api/internal/openapi"
+)
+
+func CreateSubscriptionOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "createWebhookSubscription",
+		Summary:     "Subscribe a url to booking events, the signing secret is only returned once",
+		Tags:        []string{"webhooks"},
+		RequestBody: openapi.JSONBody(createSubscriptionRequest{}),
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSONResponse("Subscription created", createSubscriptionResponse{}),
+		},
+	}
+}
+
+func ListSubscriptionsOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "listWebhookSubscriptions",
+		Summary:     "List the webhook subscriptions of the client",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Subscriptions, oldest first", listSubscriptionsResponse{}),
+		},
+	}
+}
+
+func DeleteSubscriptionOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "deleteWebhookSubscription",
+		Summary:     "Delete a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"204": openapi.EmptyResponse("Subscription deleted"),
+			"404": openapi.ProblemResponse("Subscription not found"),
+		},
+	}
+}
+
+func ListDeadLettersOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "listWebhookDeadLetters",
+		Summary:     "List the deliveries that failed every attempt",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSONResponse("Failed deliveries, oldest event first", listDeliveriesResponse{}),
+		},
+	}
+}
+
+func RedeliverOperation() openapi.Operation {
+	return openapi.Operation{
+		OperationId: "redeliverWebhook",
+		Summary:     "Send a delivery again with the same event id",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"202": openapi.JSONResponse("Delivery queued", deliveryResponse{}),
+			"404": openapi.ProblemResponse("Delivery or its subscription not found"),
+			"409": openapi.ProblemResponse("Delivery is already pending"),
+		},
+	}
+}
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	"github.com/slaengkast/shipping-api/internal/errors"
+	"github.com/slaengkast/shipping-api/internal/logging"
+
+	"github.com/google/uuid"
+)
+
+type store interface {
+	GetSubscription(context.Context, string) (*subscription, error)
+	AddSubscription(context.Context, *subscription) error
+	DeleteSubscription(context.Context, string) error
+	ListSubscriptions(ctx context.Context, clientId string) ([]*subscription, error)
+	GetDelivery(context.Context, string) (*delivery, error)
+	AddDelivery(context.Context, *delivery) error
+	UpdateDelivery(context.Context, *delivery) error
+	ListDeliveries(ctx context.Context, clientId string, status DeliveryStatus) ([]*delivery, error)
+}
+
+type Service struct {
+	store store
+}
+
+func NewService(store store) Service {
+	return Service{
+		store: store,
+	}
+}
+
+// CreateSubscription subscribes url to the events of the client's bookings.
+// The returned subscription holds the signing secret, it is not shown again.
+func (s Service) CreateSubscription(ctx context.Context, clientId, rawUrl string, events []booking.EventType) (*subscription, error) {
+	logger := logging.FromContext(ctx, "webhook")
+	logger.Info().Str("clientId", clientId).Str("url", rawUrl).Msg("creating subscription")
+
+	if clientId == "" {
+		return nil, errors.FromCode(errors.CodeMissingCredentials, "empty client id", errors.ErrorUnauthorized)
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Validation("invalid url", errors.FieldError{Field: "url", Code: errors.FieldInvalid, Message: "url must be an absolute http or https url"})
+	}
+	if len(events) == 0 {
+		return nil, errors.Validation("no events", errors.FieldError{Field: "events", Code: errors.FieldRequired, Message: "events must contain at least one event type"})
+	}
+	unique := make([]booking.EventType, 0, len(events))
+	seen := make(map[booking.EventType]bool, len(events))
+	for _, e := range events {
+		if !isEventType(e) {
+			return nil, errors.Validation("unknown event type", errors.FieldError{Field: "events", Code: errors.FieldInvalid, Message: fmt.Sprintf("unknown event type %s", e)})
+		}
+		if !seen[e] {
+			seen[e] = true
+			unique = append(unique, e)
+		}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, errors.FromError(err, errors.ErrorInternal)
+	}
+
+	sub, err := NewSubscription(uuid.New().String(), clientId, u.String(), secret, unique, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.AddSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s Service) ListSubscriptions(ctx context.Context, clientId string) ([]*subscription, error) {
+	if clientId == "" {
+		return nil, errors.FromCode(errors.CodeMissingCredentials, "empty client id", errors.ErrorUnauthorized)
+	}
+	return s.store.ListSubscriptions(ctx, clientId)
+}
+
+// DeleteSubscription stops deliveries to the subscription, deliveries that
+// are still pending end up as dead letters.
+func (s Service) DeleteSubscription(ctx context.Context, clientId, id string) error {
+	logger := logging.FromContext(ctx, "webhook")
+	logger.Info().Str("clientId", clientId).Str("id", id).Msg("deleting subscription")
+
+	if _, err := s.getSubscription(ctx, clientId, id); err != nil {
+		return err
+	}
+	return s.store.DeleteSubscription(ctx, id)
+}
+
+// Publish queues a delivery of the event to every subscription of the
+// client of the booking that subscribes to its type.
+func (s Service) Publish(ctx context.Context, e booking.Event) error {
+	subscriptions, err := s.store.ListSubscriptions(ctx, e.ClientId)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subscriptions {
+		if !sub.subscribesTo(e.Type) {
+			continue
+		}
+
+		d, err := NewDelivery(deliveryId(sub.Id(), e.Id), sub.Id(), e, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := s.store.AddDelivery(ctx, d); err != nil && errors.GetType(err) != errors.ErrorConflict {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveryId is the same every time an event is published to a subscription,
+// so an event that is published again does not get a second delivery.
+func deliveryId(subscriptionId, eventId string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(subscriptionId+"/"+eventId)).String()
+}
+
+// ListDeadLetters returns the deliveries of the client that ran out of
+// attempts, oldest event first.
+func (s Service) ListDeadLetters(ctx context.Context, clientId string) ([]*delivery, error) {
+	if clientId == "" {
+		return nil, errors.FromCode(errors.CodeMissingCredentials, "empty client id", errors.ErrorUnauthorized)
+	}
+	return s.store.ListDeliveries(ctx, clientId, DeliveryFailed)
+}
+
+// Redeliver sends a delivered or dead lettered delivery again, with the same
+// event id so that receivers can tell it is a duplicate.
+func (s Service) Redeliver(ctx context.Context, clientId, id string) (*delivery, error) {
+	logger := logging.FromContext(ctx, "webhook")
+	logger.Info().Str("clientId", clientId).Str("id", id).Msg("redelivering")
+
+	d, err := s.store.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.ClientId() != clientId {
+		return nil, errors.NotFound(errors.CodeDeliveryNotFound, "delivery", id)
+	}
+	if d.Status() == DeliveryPending {
+		return nil, errors.WithEntity(errors.FromCode(errors.CodeDeliveryPending, "delivery is already pending", errors.ErrorConflict), "delivery", id)
+	}
+	if _, err := s.getSubscription(ctx, clientId, d.SubscriptionId()); err != nil {
+		return nil, err
+	}
+
+	d.redeliver(time.Now().UTC())
+	if err := s.store.UpdateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s Service) getSubscription(ctx context.Context, clientId, id string) (*subscription, error) {
+	sub, err := s.store.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.ClientId() != clientId {
+		return nil, errors.NotFound(errors.CodeSubscriptionNotFound, "subscription", id)
+	}
+	return sub, nil
+}
+
+func isEventType(t booking.EventType) bool {
+	for _, e := range booking.EventTypes {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+	apierrors "github.com/slaengkast/shipping-api/internal/errors"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSubscription(t *testing.T) {
+	testCases := []struct {
+		name          string
+		clientId      string
+		url           string
+		events        []booking.EventType
+		expectedType  apierrors.ErrorType
+		expectedField string
+		shouldFail    bool
+	}{
+		{
+			name:     "good subscription",
+			clientId: "test-client",
+			url:      "https://erp.example.com/hooks",
+			events:   []booking.EventType{booking.EventBookingCreated, booking.EventBookingCancelled},
+		},
+		{
+			name:         "missing client id",
+			url:          "https://erp.example.com/hooks",
+			events:       []booking.EventType{booking.EventBookingCreated},
+			expectedType: apierrors.ErrorUnauthorized,
+			shouldFail:   true,
+		},
+		{
+			name:          "relative url",
+			clientId:      "test-client",
+			url:           "/hooks",
+			events:        []booking.EventType{booking.EventBookingCreated},
+			expectedType:  apierrors.ErrorInput,
+			expectedField: "url",
+			shouldFail:    true,
+		},
+		{
+			name:          "not http",
+			clientId:      "test-client",
+			url:           "ftp://erp.example.com/hooks",
+			events:        []booking.EventType{booking.EventBookingCreated},
+			expectedType:  apierrors.ErrorInput,
+			expectedField: "url",
+			shouldFail:    true,
+		},
+		{
+			name:          "no events",
+			clientId:      "test-client",
+			url:           "https://erp.example.com/hooks",
+			expectedType:  apierrors.ErrorInput,
+			expectedField: "events",
+			shouldFail:    true,
+		},
+		{
+			name:          "unknown event",
+			clientId:      "test-client",
+			url:           "https://erp.example.com/hooks",
+			events:        []booking.EventType{"booking.lost"},
+			expectedType:  apierrors.ErrorInput,
+			expectedField: "events",
+			shouldFail:    true,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewService(NewInMemoryStore())
+			sub, err := s.CreateSubscription(context.Background(), tc.clientId, tc.url, tc.events)
+			if tc.shouldFail {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedType, apierrors.GetType(err))
+				if tc.expectedField != "" {
+					require.Equal(t, tc.expectedField, apierrors.GetFields(err)[0].Field)
+				}
+				return
+			}
+
+			require.Nil(t, err)
+			require.Contains(t, sub.Secret(), secretPrefix)
+			require.Equal(t, tc.events, sub.Events())
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	store := NewInMemoryStore()
+	s := NewService(store)
+	ctx := context.Background()
+
+	created, err := s.CreateSubscription(ctx, "test-client", "https://a.example.com", []booking.EventType{booking.EventBookingCreated})
+	require.Nil(t, err)
+	_, err = s.CreateSubscription(ctx, "test-client", "https://b.example.com", []booking.EventType{booking.EventBookingCancelled})
+	require.Nil(t, err)
+	_, err = s.CreateSubscription(ctx, "other-client", "https://c.example.com", []booking.EventType{booking.EventBookingCreated})
+	require.Nil(t, err)
+
+	event := booking.Event{Id: "event-id", Type: booking.EventBookingCreated, OccurredAt: time.Now(), ClientId: "test-client", BookingId: "booking-id", Status: booking.StatusBooked}
+	require.Nil(t, s.Publish(ctx, event))
+
+	due, err := store.DueDeliveries(ctx, time.Now().Add(time.Second), 0)
+	require.Nil(t, err)
+	require.Len(t, due, 1, "expected only the subscription of the client to the event type to get a delivery")
+	require.Equal(t, created.Id(), due[0].SubscriptionId())
+	require.Equal(t, event, due[0].Event())
+
+	require.Nil(t, s.Publish(ctx, event))
+	due, err = store.DueDeliveries(ctx, time.Now().Add(time.Second), 0)
+	require.Nil(t, err)
+	require.Len(t, due, 1, "expected publishing an event again to not add a delivery")
+}
+
+func TestRedeliver(t *testing.T) {
+	testCases := []struct {
+		name         string
+		clientId     string
+		status       DeliveryStatus
+		deleted      bool
+		expectedCode apierrors.Code
+	}{
+		{
+			name:     "dead letter",
+			clientId: "test-client",
+			status:   DeliveryFailed,
+		},
+		{
+			name:     "delivered",
+			clientId: "test-client",
+			status:   DeliveryDelivered,
+		},
+		{
+			name:         "pending",
+			clientId:     "test-client",
+			status:       DeliveryPending,
+			expectedCode: apierrors.CodeDeliveryPending,
+		},
+		{
+			name:         "other client",
+			clientId:     "other-client",
+			status:       DeliveryFailed,
+			expectedCode: apierrors.CodeDeliveryNotFound,
+		},
+		{
+			name:         "deleted subscription",
+			clientId:     "test-client",
+			status:       DeliveryFailed,
+			deleted:      true,
+			expectedCode: apierrors.CodeSubscriptionNotFound,
+		},
+	}
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			store := NewInMemoryStore()
+			s := NewService(store)
+			sub, err := s.CreateSubscription(ctx, "test-client", "https://erp.example.com", []booking.EventType{booking.EventBookingCreated})
+			require.Nil(t, err)
+			d, err := NewDelivery("delivery-id", sub.Id(), booking.Event{Id: "event-id", ClientId: "test-client"}, time.Now())
+			require.Nil(t, err)
+			d.status = tc.status
+			d.attempts = 3
+			require.Nil(t, store.AddDelivery(ctx, d))
+			if tc.deleted {
+				require.Nil(t, s.DeleteSubscription(ctx, "test-client", sub.Id()))
+			}
+
+			actual, err := s.Redeliver(ctx, tc.clientId, d.Id())
+			if tc.expectedCode != "" {
+				require.NotNil(t, err, "expected an error, got nil")
+				require.Equal(t, tc.expectedCode, apierrors.GetCode(err))
+				return
+			}
+
+			require.Nil(t, err)
+			require.Equal(t, DeliveryPending, actual.Status())
+			require.Equal(t, 0, actual.Attempts())
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	IdHeader        = "Webhook-Id"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+
+	signatureVersion = "v1"
+)
+
+// Sign returns the signature header of a delivery, a base64 HMAC-SHA256 of
+// "<id>.<unix timestamp>.<body>" keyed with the decoded secret of the
+// subscription. Receivers compute the same and compare.
+func Sign(secret, id string, timestamp time.Time, body []byte) string {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, secretPrefix))
+	if err != nil {
+		key = []byte(secret)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+	return signatureVersion + "," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/slaengkast/shipping-api/internal/booking"
+)
+
+const secretPrefix = "whsec_"
+
+type subscription struct {
+	id        string
+	clientId  string
+	url       string
+	secret    string
+	events    []booking.EventType
+	createdAt time.Time
+}
+
+func NewSubscription(id, clientId, url, secret string, events []booking.EventType, createdAt time.Time) (*subscription, error) {
+	if id == "" {
+		return nil, errors.New("id is empty")
+	}
+	if clientId == "" {
+		return nil, errors.New("client id is empty")
+	}
+	if url == "" {
+		return nil, errors.New("url is empty")
+	}
+	if secret == "" {
+		return nil, errors.New("secret is empty")
+	}
+	if len(events) == 0 {
+		return nil, errors.New("no events")
+	}
+
+	return &subscription{
+		id:        id,
+		clientId:  clientId,
+		url:       url,
+		secret:    secret,
+		events:    append([]booking.EventType(nil), events...),
+		createdAt: createdAt,
+	}, nil
+}
+
+func (s *subscription) Id() string {
+	return s.id
+}
+
+func (s *subscription) ClientId() string {
+	return s.clientId
+}
+
+func (s *subscription) Url() string {
+	return s.url
+}
+
+// Secret is the key deliveries are signed with, it is only shown to the
+// client when the subscription is created.
+func (s *subscription) Secret() string {
+	return s.secret
+}
+
+func (s *subscription) Events() []booking.EventType {
+	return append([]booking.EventType(nil), s.events...)
+}
+
+func (s *subscription) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+func (s *subscription) subscribesTo(t booking.EventType) bool {
+	for _, e := range s.events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.StdEncoding.EncodeToString(b), nil
+}